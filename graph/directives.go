@@ -0,0 +1,29 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/ukane-philemon/scomp/graph/model"
+	"github.com/ukane-philemon/scomp/internal/admin"
+	customerror "github.com/ukane-philemon/scomp/internal/errors"
+)
+
+// HasRoleDirective implements the @hasRole directive. It ensures the request
+// is authenticated by an admin with at least role, or by an API key with at
+// least role if allowAPIKey is true.
+func HasRoleDirective(ctx context.Context, obj any, next graphql.Resolver, role model.Role, allowAPIKey bool) (any, error) {
+	if !reqAuthenticated(ctx) {
+		return nil, &customerror.ErrorUnauthorized{}
+	}
+
+	if reqAPIKey(ctx) != nil && !allowAPIKey {
+		return nil, &customerror.ErrorForbidden{}
+	}
+
+	if !admin.HasRole(reqRole(ctx), adminRole(role)) {
+		return nil, &customerror.ErrorForbidden{}
+	}
+
+	return next(ctx)
+}
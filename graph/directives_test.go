@@ -0,0 +1,74 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ukane-philemon/scomp/graph/model"
+	"github.com/ukane-philemon/scomp/internal/admin"
+	customerror "github.com/ukane-philemon/scomp/internal/errors"
+)
+
+func TestHasRoleDirective(t *testing.T) {
+	tests := []struct {
+		name          string
+		adminID       string
+		role          string
+		requiredRole  model.Role
+		apiKey        *admin.APIKey
+		allowAPIKey   bool
+		wantForbidden bool
+		wantUnauth    bool
+	}{
+		{name: "unauthenticated", requiredRole: model.RoleViewer, wantUnauth: true},
+		{name: "same role", adminID: "admin", role: admin.RoleTeacher, requiredRole: model.RoleTeacher},
+		{name: "higher role", adminID: "admin", role: admin.RoleOwner, requiredRole: model.RoleAdmin},
+		{name: "lower role", adminID: "admin", role: admin.RoleViewer, requiredRole: model.RoleTeacher, wantForbidden: true},
+		{name: "unknown role", adminID: "admin", role: "principal", requiredRole: model.RoleViewer, wantForbidden: true},
+		{name: "missing role", adminID: "admin", requiredRole: model.RoleViewer, wantForbidden: true},
+		{name: "api key allowed", adminID: "admin", role: admin.RoleAdmin, requiredRole: model.RoleAdmin, apiKey: new(admin.APIKey), allowAPIKey: true},
+		{name: "api key not allowed", adminID: "admin", role: admin.RoleAdmin, requiredRole: model.RoleViewer, apiKey: new(admin.APIKey), wantForbidden: true},
+		{name: "api key lower role", adminID: "admin", role: admin.RoleViewer, requiredRole: model.RoleAdmin, apiKey: new(admin.APIKey), allowAPIKey: true, wantForbidden: true},
+	}
+
+	for _, test := range tests {
+		ctx := context.Background()
+		if test.adminID != "" {
+			ctx = context.WithValue(ctx, adminCtxKey, test.adminID)
+		}
+		if test.role != "" {
+			ctx = context.WithValue(ctx, roleCtxKey, test.role)
+		}
+		if test.apiKey != nil {
+			ctx = context.WithValue(ctx, apiKeyCtxKey, test.apiKey)
+		}
+
+		var called bool
+		next := func(ctx context.Context) (any, error) {
+			called = true
+			return nil, nil
+		}
+
+		_, err := HasRoleDirective(ctx, nil, next, test.requiredRole, test.allowAPIKey)
+
+		var unauthorizedErr *customerror.ErrorUnauthorized
+		var forbiddenErr *customerror.ErrorForbidden
+		switch {
+		case test.wantUnauth:
+			if !errors.As(err, &unauthorizedErr) {
+				t.Errorf("%s: expected ErrorUnauthorized, got %v", test.name, err)
+			}
+		case test.wantForbidden:
+			if !errors.As(err, &forbiddenErr) {
+				t.Errorf("%s: expected ErrorForbidden, got %v", test.name, err)
+			}
+		case err != nil:
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		}
+
+		if wantCalled := !test.wantUnauth && !test.wantForbidden; called != wantCalled {
+			t.Errorf("%s: expected next called %v, got %v", test.name, wantCalled, called)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package graph
+
+import (
+	"context"
+	"sync"
+)
+
+// eventBufferSize is the number of events buffered for a subscription. Events
+// are dropped for subscriptions that do not keep up.
+const eventBufferSize = 32
+
+// eventBroker delivers the events of a class to the subscriptions of the
+// class. Only the subscriptions of this server receive its events. The zero
+// value is ready to use.
+type eventBroker[T any] struct {
+	mtx           sync.Mutex
+	subscriptions map[string]map[chan T]struct{}
+}
+
+// subscribe returns a channel that receives the events of classID until ctx
+// is canceled.
+func (b *eventBroker[T]) subscribe(ctx context.Context, classID string) <-chan T {
+	events := make(chan T, eventBufferSize)
+
+	b.mtx.Lock()
+	if b.subscriptions == nil {
+		b.subscriptions = make(map[string]map[chan T]struct{})
+	}
+	if b.subscriptions[classID] == nil {
+		b.subscriptions[classID] = make(map[chan T]struct{})
+	}
+	b.subscriptions[classID][events] = struct{}{}
+	b.mtx.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		b.mtx.Lock()
+		defer b.mtx.Unlock()
+		delete(b.subscriptions[classID], events)
+		if len(b.subscriptions[classID]) == 0 {
+			delete(b.subscriptions, classID)
+		}
+		close(events)
+	}()
+
+	return events
+}
+
+// publish sends event to the subscriptions of classID without blocking.
+func (b *eventBroker[T]) publish(classID string, event T) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	for events := range b.subscriptions[classID] {
+		select {
+		case events <- event:
+		default:
+		}
+	}
+}
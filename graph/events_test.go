@@ -0,0 +1,47 @@
+package graph
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestEventBroker(t *testing.T) {
+	var broker eventBroker[int]
+	ctx, cancel := context.WithCancel(context.Background())
+	events := broker.subscribe(ctx, "class")
+	otherEvents := broker.subscribe(context.Background(), "other class")
+
+	broker.publish("class", 1)
+	if event := <-events; event != 1 {
+		t.Fatalf("expected event 1, got %d", event)
+	}
+
+	select {
+	case event := <-otherEvents:
+		t.Fatalf("expected no event for another class, got %d", event)
+	default:
+	}
+
+	// Events are dropped instead of blocking when the subscription does not
+	// keep up.
+	for i := 0; i < eventBufferSize+1; i++ {
+		broker.publish("class", i)
+	}
+	if len(events) != eventBufferSize {
+		t.Fatalf("expected %d buffered events, got %d", eventBufferSize, len(events))
+	}
+
+	cancel()
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case _, open := <-events:
+			if !open {
+				return
+			}
+		case <-timeout:
+			t.Fatal("expected the subscription to be closed when its context is canceled")
+		}
+	}
+}
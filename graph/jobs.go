@@ -0,0 +1,235 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ukane-philemon/scomp/graph/model"
+	"github.com/ukane-philemon/scomp/internal/class"
+	"github.com/ukane-philemon/scomp/internal/db"
+	customerror "github.com/ukane-philemon/scomp/internal/errors"
+	"github.com/ukane-philemon/scomp/internal/job"
+	"github.com/ukane-philemon/scomp/internal/student"
+)
+
+// jobPollInterval is how often idle job workers check for due jobs.
+const jobPollInterval = time.Second
+
+// minReportStudents is the minimum number of students of a class to compute
+// its reports.
+const minReportStudents = 2
+
+// StartJobWorkers starts workers that run the queued jobs until ctx is
+// canceled. Use Wait to wait for the workers to finish their current job
+// after ctx is canceled.
+func (r *Resolver) StartJobWorkers(ctx context.Context, workers int) {
+	r.jobQueued = make(chan struct{}, 1)
+	for i := 0; i < workers; i++ {
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			r.runJobWorker(ctx)
+		}()
+	}
+}
+
+// queueComputeClassReport marks classInfo as computing and queues a job to
+// compute its reports, requested by requestedBy. Returns db.ErrorInvalidRequest
+// if the report of the class is already being computed.
+func (r *Resolver) queueComputeClassReport(classInfo *class.Class, requestedBy string) (*job.Job, error) {
+	errComputing := fmt.Errorf("%w: class report is already being computed", db.ErrorInvalidRequest)
+	if classInfo.State == class.StateComputing {
+		return nil, errComputing
+	}
+
+	// The state of the class is compared and swapped, so that only one of
+	// concurrent requests queues a job.
+	err := r.ClassRepository.Transition(classInfo.SchoolID, classInfo.ID, classInfo.State, class.StateComputing)
+	if err != nil {
+		if errors.Is(err, db.ErrorInvalidRequest) {
+			return nil, errComputing
+		}
+		return nil, err
+	}
+
+	reportJob, err := r.JobRepository.Create(classInfo.SchoolID, classInfo.ID, job.KindComputeClassReport, requestedBy)
+	if err != nil {
+		if tErr := r.ClassRepository.Transition(classInfo.SchoolID, classInfo.ID, class.StateComputing, classInfo.State); tErr != nil {
+			log.Printf("SERVER ERROR: failed to restore the state of class %s: %v", classInfo.ID, tErr)
+		}
+		return nil, err
+	}
+
+	return reportJob, nil
+}
+
+// notifyJobQueued wakes up an idle job worker to run a new job.
+func (r *Resolver) notifyJobQueued() {
+	select {
+	case r.jobQueued <- struct{}{}:
+	default:
+	}
+}
+
+// runJobWorker runs due jobs one at a time until ctx is canceled.
+func (r *Resolver) runJobWorker(ctx context.Context) {
+	for ctx.Err() == nil {
+		reportJob, err := r.JobRepository.Claim(time.Now())
+		if err != nil {
+			log.Printf("SERVER ERROR: JobRepo.Claim %v", err.Error())
+		} else if reportJob != nil {
+			r.runJob(reportJob)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-r.jobQueued:
+		case <-time.After(jobPollInterval):
+		}
+	}
+}
+
+// runJob runs a claimed job, saves its outcome and sends it to the
+// reportStatus subscriptions of the class. Failed attempts are retried with an
+// increasing delay unless the job failed because of an invalid request, e.g a
+// class without enough students, or has no attempts left.
+func (r *Resolver) runJob(reportJob *job.Job) {
+	r.publishReportStatus(reportJob, &model.ReportStatusEvent{Status: model.ReportStatusProgress})
+
+	var reportVersion int
+	var err error
+	switch {
+	case reportJob.Attempts > reportJob.MaxAttempts:
+		// The job was claimed again after its last attempt was interrupted.
+		err = fmt.Errorf("%w: job was interrupted after %d attempts", db.ErrorInvalidRequest, reportJob.MaxAttempts)
+	case reportJob.Kind == job.KindComputeClassReport:
+		reportVersion, err = r.runComputeClassReport(reportJob)
+	default:
+		err = fmt.Errorf("%w: unknown job kind %s", db.ErrorInvalidRequest, reportJob.Kind)
+	}
+
+	event := new(model.ReportStatusEvent)
+	switch {
+	case err == nil:
+		err = r.JobRepository.Succeed(reportJob.SchoolID, reportJob.ID, reportJob.Attempts, reportVersion)
+		event.Status, event.Progress, event.ReportVersion = model.ReportStatusCompleted, 100, reportVersion
+	case errors.Is(err, db.ErrorInvalidRequest):
+		event.Status, event.Error = model.ReportStatusFailed, err.Error()
+		err = r.failJob(reportJob, event.Error)
+	default:
+		// Server errors are not shown to admins.
+		log.Printf("SERVER ERROR: job %s attempt %d: %v", reportJob.ID, reportJob.Attempts, err.Error())
+		event.Error = (&customerror.ErrorUnknown{}).Error()
+		if reportJob.Attempts >= reportJob.MaxAttempts {
+			event.Status = model.ReportStatusFailed
+			err = r.failJob(reportJob, event.Error)
+		} else {
+			event.Status = model.ReportStatusQueued
+			err = r.JobRepository.Retry(reportJob.SchoolID, reportJob.ID, reportJob.Attempts, event.Error, time.Now().Add(job.RetryDelay(reportJob.Attempts)))
+		}
+	}
+	if err != nil {
+		log.Printf("SERVER ERROR: failed to save the outcome of job %s: %v", reportJob.ID, err.Error())
+		return
+	}
+
+	r.publishReportStatus(reportJob, event)
+}
+
+// failJob ends the computation of the class of reportJob and marks the job as
+// failed with errMsg. The class is reported again if it has a previous report
+// that is not stale, otherwise it is open. The job is not marked as failed if
+// the class cannot be updated, it fails again once its lease expires.
+func (r *Resolver) failJob(reportJob *job.Job, errMsg string) error {
+	classInfo, err := r.ClassRepository.Class(reportJob.SchoolID, reportJob.ClassID)
+	if err != nil && !errors.Is(err, db.ErrorInvalidRequest) {
+		return err
+	}
+
+	if err == nil && classInfo.State == class.StateComputing {
+		state := class.StateOpen
+		if classInfo.Report != nil && !classInfo.ReportStale {
+			state = class.StateReported
+		}
+
+		// The class is not computing anymore if it changed in between.
+		err = r.ClassRepository.Transition(reportJob.SchoolID, reportJob.ClassID, class.StateComputing, state)
+		if err != nil && !errors.Is(err, db.ErrorInvalidRequest) {
+			return err
+		}
+	}
+
+	return r.JobRepository.Fail(reportJob.SchoolID, reportJob.ID, reportJob.Attempts, errMsg)
+}
+
+// publishReportStatus sends event of reportJob to the reportStatus
+// subscriptions of the class of the job.
+func (r *Resolver) publishReportStatus(reportJob *job.Job, event *model.ReportStatusEvent) {
+	event.ClassID, event.JobID = reportJob.ClassID, reportJob.ID
+	r.reportEvents.publish(reportJob.ClassID, event)
+}
+
+// runComputeClassReport computes the reports of the class of reportJob and
+// returns the saved report version.
+func (r *Resolver) runComputeClassReport(reportJob *job.Job) (int, error) {
+	classInfo, err := r.ClassRepository.Class(reportJob.SchoolID, reportJob.ClassID)
+	if err != nil {
+		return 0, err
+	}
+
+	if classInfo.State != class.StateComputing {
+		return 0, fmt.Errorf("%w: class with ID %s is not computing its report", db.ErrorInvalidRequest, classInfo.ID)
+	}
+
+	gradingScale, err := r.gradingScale(reportJob.SchoolID, classInfo.GradingScaleID)
+	if err != nil {
+		return 0, err
+	}
+
+	studentNames, studentScores, err := r.reportStudents(reportJob.SchoolID, reportJob.ClassID)
+	if err != nil {
+		return 0, err
+	}
+
+	onProgress := func(progress int) {
+		r.publishReportStatus(reportJob, &model.ReportStatusEvent{Status: model.ReportStatusProgress, Progress: progress})
+	}
+
+	reportVersion, err := r.computeClassReport(reportJob.SchoolID, reportJob.RequestedBy, classInfo, gradingScale, studentNames, studentScores, onProgress)
+	if err != nil {
+		return 0, err
+	}
+
+	return reportVersion.Version, nil
+}
+
+// reportStudents returns the names and the subject scores of the students of
+// classID. Returns db.ErrorInvalidRequest if the class does not have enough
+// students to compute its reports.
+func (r *Resolver) reportStudents(schoolID, classID string) (map[string]string, map[string][]*student.SubjectScore, error) {
+	studentScores, err := r.StudentRepository.StudentScores(schoolID, classID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(studentScores) < minReportStudents {
+		return nil, nil, fmt.Errorf("%w: add at least %d students to this class before generating a report", db.ErrorInvalidRequest, minReportStudents)
+	}
+
+	// Student names break ties in class positions.
+	students, err := r.StudentRepository.Students(schoolID, classID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	studentNames := make(map[string]string, len(students))
+	for _, studentInfo := range students {
+		studentNames[studentInfo.ID] = studentInfo.Name
+	}
+
+	return studentNames, studentScores, nil
+}
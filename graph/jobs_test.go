@@ -0,0 +1,169 @@
+package graph
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ukane-philemon/scomp/internal/class"
+	"github.com/ukane-philemon/scomp/internal/db"
+	"github.com/ukane-philemon/scomp/internal/job"
+	"github.com/ukane-philemon/scomp/internal/memory"
+)
+
+// unavailableClassRepository fails to read classes like a database that is
+// down, for the first failures reads.
+type unavailableClassRepository struct {
+	class.Repository
+	failures int
+}
+
+func (cr *unavailableClassRepository) Class(schoolID, classID string) (*class.Class, error) {
+	if cr.failures > 0 {
+		cr.failures--
+		return nil, errors.New("database is down")
+	}
+	return cr.Repository.Class(schoolID, classID)
+}
+
+// unavailableOnce returns a class repository whose first read fails.
+func unavailableOnce(store *memory.Store) class.Repository {
+	return &unavailableClassRepository{Repository: memory.NewClassRepository(store), failures: 1}
+}
+
+// claimAttempt claims jobID for its next attempt, expiring the lease of the
+// previous attempt.
+func claimAttempt(t *testing.T, r *Resolver, jobID string, attempt int) *job.Job {
+	t.Helper()
+
+	now := time.Now().Add(time.Duration(attempt) * job.LeaseDuration)
+	claimedJob, err := r.JobRepository.Claim(now)
+	if err != nil {
+		t.Fatalf("Claim error: %v", err)
+	}
+
+	if claimedJob == nil || claimedJob.ID != jobID || claimedJob.Attempts != attempt {
+		t.Fatalf("expected attempt %d of job %s, got %+v", attempt, jobID, claimedJob)
+	}
+	return claimedJob
+}
+
+func TestRunJob(t *testing.T) {
+	tests := []struct {
+		name string
+		// attempts is the number of times the job is claimed before it runs.
+		attempts        int
+		classRepository func(store *memory.Store) class.Repository
+		wantState       string
+		// wantError is part of the error of the job, if set.
+		wantError string
+	}{
+		{
+			name:            "invalid request is not retried",
+			attempts:        1,
+			classRepository: memory.NewClassRepository,
+			wantState:       job.StateFailed,
+		},
+		{
+			name:            "server error is retried",
+			attempts:        1,
+			classRepository: unavailableOnce,
+			wantState:       job.StateQueued,
+		},
+		{
+			name:            "server error on the last attempt",
+			attempts:        job.DefaultMaxAttempts,
+			classRepository: unavailableOnce,
+			wantState:       job.StateFailed,
+		},
+		{
+			name:            "interrupted after the last attempt",
+			attempts:        job.DefaultMaxAttempts + 1,
+			classRepository: memory.NewClassRepository,
+			wantState:       job.StateFailed,
+			wantError:       "interrupted",
+		},
+	}
+
+	for _, test := range tests {
+		store := memory.New()
+		r := &Resolver{
+			ClassRepository: test.classRepository(store),
+			JobRepository:   memory.NewJobRepository(store),
+		}
+
+		// The job's class does not exist, an invalid request.
+		newJob, err := r.JobRepository.Create("school", "class", job.KindComputeClassReport, "teacher")
+		if err != nil {
+			t.Fatalf("%s: Create error: %v", test.name, err)
+		}
+
+		var claimedJob *job.Job
+		for attempt := 1; attempt <= test.attempts; attempt++ {
+			claimedJob = claimAttempt(t, r, newJob.ID, attempt)
+		}
+
+		r.runJob(claimedJob)
+
+		jobInfo, err := r.JobRepository.Job("school", newJob.ID)
+		if err != nil {
+			t.Fatalf("%s: Job error: %v", test.name, err)
+		}
+
+		if jobInfo.State != test.wantState || jobInfo.Error == "" {
+			t.Errorf("%s: expected the job to be %s with an error, got %s with error %q", test.name, test.wantState, jobInfo.State, jobInfo.Error)
+		}
+
+		if !strings.Contains(jobInfo.Error, test.wantError) {
+			t.Errorf("%s: expected the error of the job to contain %q, got %q", test.name, test.wantError, jobInfo.Error)
+		}
+
+		if jobInfo.State == job.StateQueued && jobInfo.RunAt < time.Now().Add(job.RetryBaseDelay).Unix()-1 {
+			t.Errorf("%s: expected the retry to be delayed", test.name)
+		}
+	}
+}
+
+func TestQueueComputeClassReport(t *testing.T) {
+	store := memory.New()
+	r := &Resolver{
+		ClassRepository:   memory.NewClassRepository(store),
+		StudentRepository: memory.NewStudentRepository(store),
+		JobRepository:     memory.NewJobRepository(store),
+	}
+
+	classID, err := r.ClassRepository.Create("school", "JSS 1", "teacher", class.ReportSettings{}, []*class.Subject{{Name: "Maths", MaxScore: 100, Weight: 1}})
+	if err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+
+	classInfo, err := r.ClassRepository.Class("school", classID)
+	if err != nil {
+		t.Fatalf("Class error: %v", err)
+	}
+
+	reportJob, err := r.queueComputeClassReport(classInfo, "teacher")
+	if err != nil {
+		t.Fatalf("queueComputeClassReport error: %v", err)
+	}
+
+	// A concurrent request read the class before it was computing.
+	_, err = r.queueComputeClassReport(classInfo, "teacher")
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for a computing class, got %v", err)
+	}
+
+	// The class has no students, the job fails and the class is open again.
+	claimedJob := claimAttempt(t, r, reportJob.ID, 1)
+	r.runJob(claimedJob)
+
+	classInfo, err = r.ClassRepository.Class("school", classID)
+	if err != nil {
+		t.Fatalf("Class error: %v", err)
+	}
+
+	if classInfo.State != class.StateOpen {
+		t.Fatalf("expected the class of a failed job to be open, got %s", classInfo.State)
+	}
+}
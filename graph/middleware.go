@@ -2,40 +2,242 @@ package graph
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
 	"net/http"
+	"time"
 
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/ukane-philemon/scomp/internal/admin"
 	"github.com/ukane-philemon/scomp/internal/auth"
+	"github.com/ukane-philemon/scomp/internal/db"
+	customerror "github.com/ukane-philemon/scomp/internal/errors"
+	"github.com/ukane-philemon/scomp/internal/session"
 )
 
 const (
-	jwtHeader   = "SCOMP-Authentication-Token"
-	adminCtxKey = "adminID"
+	jwtHeader     = "SCOMP-Authentication-Token"
+	apiKeyHeader  = "SCOMP-API-Key"
+	adminCtxKey   = "adminID"
+	sessionCtxKey = "sessionID"
+	roleCtxKey    = "role"
+	schoolCtxKey  = "schoolID"
+	clientCtxKey  = "client"
+	apiKeyCtxKey  = "apiKey"
 )
 
+// websocketAuthCheckInterval is how often the credentials of an open websocket
+// connection are checked again.
+var websocketAuthCheckInterval = time.Minute
+
 // AuthMiddleware ensures the the correct and valid auth token is provided in
-// this request.
-func AuthMiddleware(authRepo auth.Repository) func(next http.Handler) http.Handler {
+// this request and that the session of the token has not been revoked.
+// Requests without an auth token can be authenticated with an API key
+// instead.
+func AuthMiddleware(authRepo auth.Repository, sessionRepo session.Repository, adminRepo admin.Repository, apiKeyRepo admin.APIKeyRepository) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
-			authToken := req.Header.Get(jwtHeader)
-			if authToken == "" {
-				next.ServeHTTP(res, req)
-				return
+			// Set the clientCtxKey for use when creating sessions.
+			ctx := context.WithValue(req.Context(), clientCtxKey, clientInfo(req))
+
+			var err error
+			if authToken := req.Header.Get(jwtHeader); authToken != "" {
+				ctx, err = tokenAuthentication(ctx, authRepo, sessionRepo, authToken)
+			} else if key := req.Header.Get(apiKeyHeader); key != "" {
+				ctx, err = apiKeyAuthentication(ctx, adminRepo, apiKeyRepo, key)
 			}
+			if err != nil {
+				if errors.Is(err, db.ErrorInvalidRequest) {
+					http.Error(res, "not authorized", http.StatusForbidden)
+					return
+				}
 
-			uniqueID, validToken := authRepo.IsValid(authToken)
-			if !validToken {
-				http.Error(res, "not authorized", http.StatusForbidden)
+				log.Printf("SERVER ERROR: %v", err)
+				http.Error(res, "internal server error", http.StatusInternalServerError)
 				return
 			}
 
-			// Set the adminCtxKey for use by subsequent handlers.
-			req = req.WithContext(context.WithValue(req.Context(), adminCtxKey, uniqueID))
-			next.ServeHTTP(res, req)
+			next.ServeHTTP(res, req.WithContext(ctx))
 		})
 	}
 }
 
+// WebsocketInitFunc authenticates websocket connections with the auth token
+// or the API key of the connection_init payload, set with the same keys as
+// the HTTP headers. Connections whose upgrade request was authenticated by
+// AuthMiddleware do not need a payload. Authenticated connections are closed
+// once their session or API key is revoked.
+func WebsocketInitFunc(authRepo auth.Repository, sessionRepo session.Repository, adminRepo admin.Repository, apiKeyRepo admin.APIKeyRepository) transport.WebsocketInitFunc {
+	return func(ctx context.Context, initPayload transport.InitPayload) (context.Context, *transport.InitPayload, error) {
+		var err error
+		if authToken := initPayload.GetString(jwtHeader); authToken != "" {
+			ctx, err = tokenAuthentication(ctx, authRepo, sessionRepo, authToken)
+		} else if key := initPayload.GetString(apiKeyHeader); key != "" {
+			ctx, err = apiKeyAuthentication(ctx, adminRepo, apiKeyRepo, key)
+		}
+		if err != nil {
+			if errors.Is(err, db.ErrorInvalidRequest) {
+				return ctx, nil, &customerror.ErrorUnauthorized{}
+			}
+
+			log.Printf("SERVER ERROR: %v", err)
+			return ctx, nil, &customerror.ErrorUnknown{}
+		}
+
+		if !reqAuthenticated(ctx) {
+			return ctx, nil, nil
+		}
+
+		// The connection is closed when its context is canceled. Check the
+		// session or API key again every websocketAuthCheckInterval and close
+		// the connection once it is revoked, so that subscriptions do not
+		// outlive it.
+		ctx, cancel := context.WithCancel(ctx)
+		ticker := time.NewTicker(websocketAuthCheckInterval)
+		go func() {
+			defer cancel()
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+
+				authenticated, err := stillAuthenticated(ctx, sessionRepo, adminRepo, apiKeyRepo)
+				if err != nil {
+					// Keep the connection open until the next check.
+					log.Printf("SERVER ERROR: %v", err)
+					continue
+				}
+
+				if !authenticated {
+					return
+				}
+			}
+		}()
+
+		return ctx, nil, nil
+	}
+}
+
+// tokenAuthentication checks that authToken is valid and that the session of
+// the token has not been revoked, and returns ctx with the admin of the token
+// as the authenticated admin. Returns db.ErrorInvalidRequest if authToken
+// cannot be used.
+func tokenAuthentication(ctx context.Context, authRepo auth.Repository, sessionRepo session.Repository, authToken string) (context.Context, error) {
+	claims, validToken := authRepo.IsValid(authToken)
+	if !validToken {
+		return ctx, fmt.Errorf("%w: invalid auth token", db.ErrorInvalidRequest)
+	}
+
+	activeSession, err := sessionRepo.IsActive(claims.SessionID)
+	if err != nil {
+		return ctx, fmt.Errorf("sessionRepo.IsActive error: %w", err)
+	}
+
+	if !activeSession {
+		return ctx, fmt.Errorf("%w: session has ended", db.ErrorInvalidRequest)
+	}
+
+	// Set the adminCtxKey, sessionCtxKey, roleCtxKey and schoolCtxKey for use
+	// by subsequent handlers.
+	ctx = context.WithValue(ctx, adminCtxKey, claims.AdminID)
+	ctx = context.WithValue(ctx, sessionCtxKey, claims.SessionID)
+	ctx = context.WithValue(ctx, roleCtxKey, claims.Role)
+	ctx = context.WithValue(ctx, schoolCtxKey, claims.SchoolID)
+	return ctx, nil
+}
+
+// stillAuthenticated checks that the session or the API key that
+// authenticated ctx is still active and that the API key still has the same
+// role.
+func stillAuthenticated(ctx context.Context, sessionRepo session.Repository, adminRepo admin.Repository, apiKeyRepo admin.APIKeyRepository) (bool, error) {
+	if sessionID := reqSessionID(ctx); sessionID != "" {
+		activeSession, err := sessionRepo.IsActive(sessionID)
+		if err != nil {
+			return false, fmt.Errorf("sessionRepo.IsActive error: %w", err)
+		}
+		return activeSession, nil
+	}
+
+	apiKey := reqAPIKey(ctx)
+	if apiKey == nil {
+		return true, nil
+	}
+
+	apiKeys, err := apiKeyRepo.APIKeys(apiKey.SchoolID)
+	if err != nil {
+		return false, fmt.Errorf("apiKeyRepo.APIKeys error: %w", err)
+	}
+
+	for _, activeKey := range apiKeys {
+		if activeKey.ID != apiKey.ID {
+			continue
+		}
+
+		creator, err := adminRepo.Admin(activeKey.CreatedBy)
+		if err != nil {
+			if errors.Is(err, db.ErrorInvalidRequest) {
+				return false, nil
+			}
+			return false, fmt.Errorf("adminRepo.Admin error: %w", err)
+		}
+
+		return creator.SchoolID == activeKey.SchoolID && activeKey.Role(creator.Role) == reqRole(ctx), nil
+	}
+
+	return false, nil
+}
+
+// apiKeyAuthentication authenticates key and returns ctx with the permissions
+// of the key, capped at the current role of the admin that created the key.
+// The admin that created the key is the authenticated admin of the request.
+// Returns db.ErrorInvalidRequest if key cannot be used.
+func apiKeyAuthentication(ctx context.Context, adminRepo admin.Repository, apiKeyRepo admin.APIKeyRepository, key string) (context.Context, error) {
+	apiKey, err := apiKeyRepo.Authenticate(key)
+	if err != nil {
+		if errors.Is(err, db.ErrorInvalidRequest) {
+			return ctx, err
+		}
+		return ctx, fmt.Errorf("apiKeyRepo.Authenticate error: %w", err)
+	}
+
+	creator, err := adminRepo.Admin(apiKey.CreatedBy)
+	if err != nil {
+		if errors.Is(err, db.ErrorInvalidRequest) {
+			return ctx, err
+		}
+		return ctx, fmt.Errorf("adminRepo.Admin error: %w", err)
+	}
+
+	if creator.SchoolID != apiKey.SchoolID {
+		return ctx, fmt.Errorf("%w: API key creator is not an admin of the school", db.ErrorInvalidRequest)
+	}
+
+	ctx = context.WithValue(ctx, apiKeyCtxKey, apiKey)
+	ctx = context.WithValue(ctx, adminCtxKey, apiKey.CreatedBy)
+	ctx = context.WithValue(ctx, roleCtxKey, apiKey.Role(creator.Role))
+	ctx = context.WithValue(ctx, schoolCtxKey, apiKey.SchoolID)
+	return ctx, nil
+}
+
+// clientInfo returns information about the client that sent req.
+func clientInfo(req *http.Request) *session.ClientInfo {
+	ipAddress, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		ipAddress = req.RemoteAddr
+	}
+
+	return &session.ClientInfo{
+		UserAgent: req.UserAgent(),
+		IPAddress: ipAddress,
+	}
+}
+
 // reqAuthenticated checks that the request is authenticated.
 func reqAuthenticated(ctx context.Context) bool {
 	adminID := ctx.Value(adminCtxKey)
@@ -44,3 +246,47 @@ func reqAuthenticated(ctx context.Context) bool {
 	}
 	return adminID.(string) != ""
 }
+
+// reqAdminID returns the ID of the authenticated admin.
+func reqAdminID(ctx context.Context) string {
+	adminID, _ := ctx.Value(adminCtxKey).(string)
+	return adminID
+}
+
+// reqSessionID returns the session ID of the auth token used for the request.
+func reqSessionID(ctx context.Context) string {
+	sessionID, _ := ctx.Value(sessionCtxKey).(string)
+	return sessionID
+}
+
+// reqRole returns the role of the authenticated admin.
+func reqRole(ctx context.Context) string {
+	role, _ := ctx.Value(roleCtxKey).(string)
+	return role
+}
+
+// reqSchoolID returns the ID of the school of the authenticated admin.
+func reqSchoolID(ctx context.Context) string {
+	schoolID, _ := ctx.Value(schoolCtxKey).(string)
+	return schoolID
+}
+
+// reqAPIKey returns the API key used for the request, nil if the request was
+// not authenticated with an API key.
+func reqAPIKey(ctx context.Context) *admin.APIKey {
+	apiKey, _ := ctx.Value(apiKeyCtxKey).(*admin.APIKey)
+	return apiKey
+}
+
+// reqCanAccessClass checks that the request can access classID. Only API keys
+// can be restricted to some classes.
+func reqCanAccessClass(ctx context.Context, classID string) bool {
+	apiKey := reqAPIKey(ctx)
+	return apiKey == nil || apiKey.CanAccessClass(classID)
+}
+
+// reqClientInfo returns information about the client that sent the request.
+func reqClientInfo(ctx context.Context) *session.ClientInfo {
+	client, _ := ctx.Value(clientCtxKey).(*session.ClientInfo)
+	return client
+}
@@ -0,0 +1,354 @@
+package graph
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/ukane-philemon/scomp/internal/admin"
+	"github.com/ukane-philemon/scomp/internal/auth"
+	customerror "github.com/ukane-philemon/scomp/internal/errors"
+	"github.com/ukane-philemon/scomp/internal/memory"
+)
+
+// serveAuthenticated serves a request with the credential value in header
+// through AuthMiddleware and returns the response code and the admin ID of the
+// request context.
+func serveAuthenticated(t *testing.T, handler func(http.Handler) http.Handler, header, value string) (int, string) {
+	t.Helper()
+
+	var adminID string
+	next := http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		adminID = reqAdminID(req.Context())
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/scomp", nil)
+	if value != "" {
+		req.Header.Set(header, value)
+	}
+
+	res := httptest.NewRecorder()
+	handler(next).ServeHTTP(res, req)
+	return res.Code, adminID
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	authRepo, err := auth.NewRepository(auth.NewSecretKeysConfig(base64.StdEncoding.EncodeToString(make([]byte, 32))))
+	if err != nil {
+		t.Fatalf("auth.NewRepository error: %v", err)
+	}
+
+	store := memory.New()
+	sessionRepo := memory.NewSessionRepository(store)
+	handler := AuthMiddleware(authRepo, sessionRepo, memory.NewAdminRepository(store), memory.NewAPIKeyRepository(store))
+
+	sessionInfo, _, err := sessionRepo.Create("admin", nil)
+	if err != nil {
+		t.Fatalf("sessionRepo.Create error: %v", err)
+	}
+
+	authToken, err := authRepo.GenerateToken(&auth.Claims{AdminID: "admin", SessionID: sessionInfo.ID, Role: admin.RoleAdmin, SchoolID: "school"})
+	if err != nil {
+		t.Fatalf("GenerateToken error: %v", err)
+	}
+
+	unknownSessionToken, err := authRepo.GenerateToken(&auth.Claims{AdminID: "admin", SessionID: "unknown", Role: admin.RoleAdmin, SchoolID: "school"})
+	if err != nil {
+		t.Fatalf("GenerateToken error: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		authToken   string
+		wantCode    int
+		wantAdminID string
+	}{
+		{name: "no token", wantCode: http.StatusOK},
+		{name: "valid token", authToken: authToken, wantCode: http.StatusOK, wantAdminID: "admin"},
+		{name: "invalid token", authToken: "token", wantCode: http.StatusForbidden},
+		{name: "unknown session", authToken: unknownSessionToken, wantCode: http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		code, adminID := serveAuthenticated(t, handler, jwtHeader, test.authToken)
+		if code != test.wantCode || adminID != test.wantAdminID {
+			t.Errorf("%s: expected %d %q, got %d %q", test.name, test.wantCode, test.wantAdminID, code, adminID)
+		}
+	}
+
+	// Tokens of a revoked session are refused before they expire.
+	err = sessionRepo.Revoke("admin", sessionInfo.ID)
+	if err != nil {
+		t.Fatalf("sessionRepo.Revoke error: %v", err)
+	}
+
+	code, adminID := serveAuthenticated(t, handler, jwtHeader, authToken)
+	if code != http.StatusForbidden || adminID != "" {
+		t.Fatalf("expected a revoked session to be refused, got %d %q", code, adminID)
+	}
+}
+
+func TestAPIKeyAuthentication(t *testing.T) {
+	authRepo, err := auth.NewRepository(auth.NewSecretKeysConfig(base64.StdEncoding.EncodeToString(make([]byte, 32))))
+	if err != nil {
+		t.Fatalf("auth.NewRepository error: %v", err)
+	}
+
+	store := memory.New()
+	adminRepo, apiKeyRepo := memory.NewAdminRepository(store), memory.NewAPIKeyRepository(store)
+	handler := AuthMiddleware(authRepo, memory.NewSessionRepository(store), adminRepo, apiKeyRepo)
+
+	adminID, err := adminRepo.CreateAccount("school", "admin", "password", admin.RoleAdmin)
+	if err != nil {
+		t.Fatalf("CreateAccount error: %v", err)
+	}
+
+	apiKey, key, err := apiKeyRepo.Create("school", adminID, "Results portal", admin.APIKeyScopeRead, []string{"class"}, time.Hour)
+	if err != nil {
+		t.Fatalf("apiKeyRepo.Create error: %v", err)
+	}
+
+	// The request is served with the permissions of the key.
+	var ctx context.Context
+	next := http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		ctx = req.Context()
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/scomp", nil)
+	req.Header.Set(apiKeyHeader, key)
+	res := httptest.NewRecorder()
+	handler(next).ServeHTTP(res, req)
+
+	if res.Code != http.StatusOK || ctx == nil {
+		t.Fatalf("expected the request to be served, got %d", res.Code)
+	}
+
+	if reqAdminID(ctx) != adminID || reqRole(ctx) != admin.RoleViewer || reqSchoolID(ctx) != "school" || reqAPIKey(ctx).ID != apiKey.ID {
+		t.Fatalf("unexpected request context: %s %s %s", reqAdminID(ctx), reqRole(ctx), reqSchoolID(ctx))
+	}
+
+	if !reqCanAccessClass(ctx, "class") || reqCanAccessClass(ctx, "other class") {
+		t.Fatal("expected the request to access only the classes of the key")
+	}
+
+	if !reqCanAccessClass(context.Background(), "other class") {
+		t.Fatal("expected requests without an API key to access all classes")
+	}
+
+	err = apiKeyRepo.Revoke("school", apiKey.ID)
+	if err != nil {
+		t.Fatalf("apiKeyRepo.Revoke error: %v", err)
+	}
+
+	for _, key := range []string{key, "key"} {
+		code, adminID := serveAuthenticated(t, handler, apiKeyHeader, key)
+		if code != http.StatusForbidden || adminID != "" {
+			t.Errorf("expected key %q to be refused, got %d %q", key, code, adminID)
+		}
+	}
+}
+
+func TestAPIKeyRoleFollowsCreator(t *testing.T) {
+	authRepo, err := auth.NewRepository(auth.NewSecretKeysConfig(base64.StdEncoding.EncodeToString(make([]byte, 32))))
+	if err != nil {
+		t.Fatalf("auth.NewRepository error: %v", err)
+	}
+
+	store := memory.New()
+	adminRepo, apiKeyRepo := memory.NewAdminRepository(store), memory.NewAPIKeyRepository(store)
+	handler := AuthMiddleware(authRepo, memory.NewSessionRepository(store), adminRepo, apiKeyRepo)
+
+	adminID, err := adminRepo.CreateAccount("school", "admin", "password", admin.RoleAdmin)
+	if err != nil {
+		t.Fatalf("CreateAccount error: %v", err)
+	}
+
+	_, key, err := apiKeyRepo.Create("school", adminID, "Results portal", admin.APIKeyScopeWrite, nil, time.Hour)
+	if err != nil {
+		t.Fatalf("apiKeyRepo.Create error: %v", err)
+	}
+
+	serveRole := func() string {
+		var role string
+		next := http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+			role = reqRole(req.Context())
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/scomp", nil)
+		req.Header.Set(apiKeyHeader, key)
+		handler(next).ServeHTTP(httptest.NewRecorder(), req)
+		return role
+	}
+
+	if role := serveRole(); role != admin.RoleAdmin {
+		t.Fatalf("expected the role of a write key, got %q", role)
+	}
+
+	// The key of a demoted admin is demoted too.
+	err = adminRepo.SetRole("school", adminID, admin.RoleTeacher)
+	if err != nil {
+		t.Fatalf("SetRole error: %v", err)
+	}
+
+	if role := serveRole(); role != admin.RoleTeacher {
+		t.Fatalf("expected the key to have the role of its demoted creator, got %q", role)
+	}
+
+	// Keys of unknown admins are refused.
+	_, key, err = apiKeyRepo.Create("school", "unknown", "Results portal", admin.APIKeyScopeRead, nil, time.Hour)
+	if err != nil {
+		t.Fatalf("apiKeyRepo.Create error: %v", err)
+	}
+
+	code, _ := serveAuthenticated(t, handler, apiKeyHeader, key)
+	if code != http.StatusForbidden {
+		t.Fatalf("expected the key of an unknown admin to be refused, got %d", code)
+	}
+}
+
+func TestWebsocketInitFunc(t *testing.T) {
+	authRepo, err := auth.NewRepository(auth.NewSecretKeysConfig(base64.StdEncoding.EncodeToString(make([]byte, 32))))
+	if err != nil {
+		t.Fatalf("auth.NewRepository error: %v", err)
+	}
+
+	store := memory.New()
+	sessionRepo := memory.NewSessionRepository(store)
+	adminRepo, apiKeyRepo := memory.NewAdminRepository(store), memory.NewAPIKeyRepository(store)
+	initFunc := WebsocketInitFunc(authRepo, sessionRepo, adminRepo, apiKeyRepo)
+
+	sessionInfo, _, err := sessionRepo.Create("admin", nil)
+	if err != nil {
+		t.Fatalf("sessionRepo.Create error: %v", err)
+	}
+
+	authToken, err := authRepo.GenerateToken(&auth.Claims{AdminID: "admin", SessionID: sessionInfo.ID, Role: admin.RoleAdmin, SchoolID: "school"})
+	if err != nil {
+		t.Fatalf("GenerateToken error: %v", err)
+	}
+
+	keyAdminID, err := adminRepo.CreateAccount("school", "key admin", "password", admin.RoleAdmin)
+	if err != nil {
+		t.Fatalf("CreateAccount error: %v", err)
+	}
+
+	_, key, err := apiKeyRepo.Create("school", keyAdminID, "Results portal", admin.APIKeyScopeRead, nil, time.Hour)
+	if err != nil {
+		t.Fatalf("apiKeyRepo.Create error: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		payload     transport.InitPayload
+		wantErr     error
+		wantAdminID string
+	}{
+		{name: "no payload"},
+		{name: "valid token", payload: transport.InitPayload{jwtHeader: authToken}, wantAdminID: "admin"},
+		{name: "valid API key", payload: transport.InitPayload{apiKeyHeader: key}, wantAdminID: keyAdminID},
+		{name: "invalid token", payload: transport.InitPayload{jwtHeader: "token"}, wantErr: &customerror.ErrorUnauthorized{}},
+		{name: "invalid API key", payload: transport.InitPayload{apiKeyHeader: "key"}, wantErr: &customerror.ErrorUnauthorized{}},
+	}
+
+	for _, test := range tests {
+		ctx, cancel := context.WithCancel(context.Background())
+		ctx, _, err := initFunc(ctx, test.payload)
+		cancel()
+		if !reflect.DeepEqual(err, test.wantErr) {
+			t.Errorf("%s: expected error %v, got %v", test.name, test.wantErr, err)
+			continue
+		}
+
+		if adminID := reqAdminID(ctx); adminID != test.wantAdminID {
+			t.Errorf("%s: expected admin %q, got %q", test.name, test.wantAdminID, adminID)
+		}
+	}
+}
+
+func TestWebsocketClosedOnRevoke(t *testing.T) {
+	authRepo, err := auth.NewRepository(auth.NewSecretKeysConfig(base64.StdEncoding.EncodeToString(make([]byte, 32))))
+	if err != nil {
+		t.Fatalf("auth.NewRepository error: %v", err)
+	}
+
+	defer func(interval time.Duration) { websocketAuthCheckInterval = interval }(websocketAuthCheckInterval)
+	websocketAuthCheckInterval = 10 * time.Millisecond
+
+	store := memory.New()
+	sessionRepo := memory.NewSessionRepository(store)
+	adminRepo, apiKeyRepo := memory.NewAdminRepository(store), memory.NewAPIKeyRepository(store)
+	initFunc := WebsocketInitFunc(authRepo, sessionRepo, adminRepo, apiKeyRepo)
+
+	adminID, err := adminRepo.CreateAccount("school", "admin", "password", admin.RoleAdmin)
+	if err != nil {
+		t.Fatalf("CreateAccount error: %v", err)
+	}
+
+	sessionInfo, _, err := sessionRepo.Create(adminID, nil)
+	if err != nil {
+		t.Fatalf("sessionRepo.Create error: %v", err)
+	}
+
+	authToken, err := authRepo.GenerateToken(&auth.Claims{AdminID: adminID, SessionID: sessionInfo.ID, Role: admin.RoleAdmin, SchoolID: "school"})
+	if err != nil {
+		t.Fatalf("GenerateToken error: %v", err)
+	}
+
+	apiKey, key, err := apiKeyRepo.Create("school", adminID, "Results portal", admin.APIKeyScopeWrite, nil, time.Hour)
+	if err != nil {
+		t.Fatalf("apiKeyRepo.Create error: %v", err)
+	}
+
+	_, demotedKey, err := apiKeyRepo.Create("school", adminID, "Results portal", admin.APIKeyScopeWrite, nil, time.Hour)
+	if err != nil {
+		t.Fatalf("apiKeyRepo.Create error: %v", err)
+	}
+
+	init := func(payload transport.InitPayload) context.Context {
+		ctx, _, err := initFunc(context.Background(), payload)
+		if err != nil {
+			t.Fatalf("initFunc error: %v", err)
+		}
+		return ctx
+	}
+
+	tokenCtx := init(transport.InitPayload{jwtHeader: authToken})
+	keyCtx := init(transport.InitPayload{apiKeyHeader: key})
+	demotedKeyCtx := init(transport.InitPayload{apiKeyHeader: demotedKey})
+
+	// Connections stay open while their credentials are valid.
+	time.Sleep(5 * websocketAuthCheckInterval)
+	for name, ctx := range map[string]context.Context{"token": tokenCtx, "API key": keyCtx, "demoted API key": demotedKeyCtx} {
+		if ctx.Err() != nil {
+			t.Fatalf("expected the %s connection to stay open", name)
+		}
+	}
+
+	err = sessionRepo.Revoke(adminID, sessionInfo.ID)
+	if err != nil {
+		t.Fatalf("sessionRepo.Revoke error: %v", err)
+	}
+
+	err = apiKeyRepo.Revoke("school", apiKey.ID)
+	if err != nil {
+		t.Fatalf("apiKeyRepo.Revoke error: %v", err)
+	}
+
+	err = adminRepo.SetRole("school", adminID, admin.RoleTeacher)
+	if err != nil {
+		t.Fatalf("SetRole error: %v", err)
+	}
+
+	for name, ctx := range map[string]context.Context{"token": tokenCtx, "API key": keyCtx, "demoted API key": demotedKeyCtx} {
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second):
+			t.Fatalf("expected the %s connection to be closed", name)
+		}
+	}
+}
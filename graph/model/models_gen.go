@@ -3,14 +3,41 @@
 package model
 
 import (
+	"fmt"
+	"io"
+	"strconv"
+
 	"github.com/ukane-philemon/scomp/internal/class"
 	"github.com/ukane-philemon/scomp/internal/student"
 )
 
+type APIKey struct {
+	ID         string      `json:"id"`
+	Name       string      `json:"name"`
+	Scope      APIKeyScope `json:"scope"`
+	ClassIDs   []string    `json:"classIDs"`
+	CreatedBy  string      `json:"createdBy"`
+	CreatedAt  int         `json:"createdAt"`
+	ExpiresAt  int         `json:"expiresAt"`
+	LastUsedAt int         `json:"lastUsedAt"`
+}
+
 type AuthenticatedAdmin struct {
-	ID        string `json:"id"`
-	Username  string `json:"username"`
-	AuthToken string `json:"authToken"`
+	ID                 string              `json:"id"`
+	Username           string              `json:"username"`
+	Role               Role                `json:"role"`
+	SchoolID           string              `json:"schoolID"`
+	AuthToken          *string             `json:"authToken,omitempty"`
+	AuthTokenExpiresAt *int                `json:"authTokenExpiresAt,omitempty"`
+	RefreshToken       *string             `json:"refreshToken,omitempty"`
+	TwoFactorChallenge *TwoFactorChallenge `json:"twoFactorChallenge,omitempty"`
+}
+
+type ClassUpdatedEvent struct {
+	ClassID     string      `json:"classID"`
+	Update      ClassUpdate `json:"update"`
+	StudentID   string      `json:"studentID"`
+	StudentName string      `json:"studentName"`
 }
 
 type CompleteClassInfo struct {
@@ -18,8 +45,452 @@ type CompleteClassInfo struct {
 	Students []*student.Student `json:"students"`
 }
 
+type DistributionBucket struct {
+	MinPercentage float64 `json:"minPercentage"`
+	MaxPercentage float64 `json:"maxPercentage"`
+	Count         int     `json:"count"`
+}
+
+type Invitation struct {
+	ID        string `json:"id"`
+	Code      string `json:"code"`
+	Role      Role   `json:"role"`
+	ExpiresAt int    `json:"expiresAt"`
+}
+
 type Mutation struct {
 }
 
+type NewAPIKey struct {
+	Key    string  `json:"key"`
+	APIKey *APIKey `json:"apiKey"`
+}
+
+type PasswordReset struct {
+	ResetToken string `json:"resetToken"`
+	ExpiresAt  int    `json:"expiresAt"`
+}
+
 type Query struct {
 }
+
+type ReportStatusEvent struct {
+	ClassID       string       `json:"classID"`
+	JobID         string       `json:"jobID"`
+	Status        ReportStatus `json:"status"`
+	Progress      int          `json:"progress"`
+	Error         string       `json:"error"`
+	ReportVersion int          `json:"reportVersion"`
+}
+
+type ScoreDistribution struct {
+	Subject       *string               `json:"subject,omitempty"`
+	TotalStudents int                   `json:"totalStudents"`
+	Buckets       []*DistributionBucket `json:"buckets"`
+}
+
+type Subscription struct {
+}
+
+type TOTPEnrollment struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauthURI"`
+}
+
+type TwoFactorChallenge struct {
+	ChallengeToken string `json:"challengeToken"`
+	ExpiresAt      int    `json:"expiresAt"`
+}
+
+type APIKeyScope string
+
+const (
+	APIKeyScopeRead  APIKeyScope = "READ"
+	APIKeyScopeWrite APIKeyScope = "WRITE"
+)
+
+var AllAPIKeyScope = []APIKeyScope{
+	APIKeyScopeRead,
+	APIKeyScopeWrite,
+}
+
+func (e APIKeyScope) IsValid() bool {
+	switch e {
+	case APIKeyScopeRead, APIKeyScopeWrite:
+		return true
+	}
+	return false
+}
+
+func (e APIKeyScope) String() string {
+	return string(e)
+}
+
+func (e *APIKeyScope) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = APIKeyScope(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid APIKeyScope", str)
+	}
+	return nil
+}
+
+func (e APIKeyScope) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type ClassState string
+
+const (
+	ClassStateOpen      ClassState = "OPEN"
+	ClassStateComputing ClassState = "COMPUTING"
+	ClassStateReported  ClassState = "REPORTED"
+)
+
+var AllClassState = []ClassState{
+	ClassStateOpen,
+	ClassStateComputing,
+	ClassStateReported,
+}
+
+func (e ClassState) IsValid() bool {
+	switch e {
+	case ClassStateOpen, ClassStateComputing, ClassStateReported:
+		return true
+	}
+	return false
+}
+
+func (e ClassState) String() string {
+	return string(e)
+}
+
+func (e *ClassState) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ClassState(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ClassState", str)
+	}
+	return nil
+}
+
+func (e ClassState) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type ClassUpdate string
+
+const (
+	ClassUpdateStudentAdded   ClassUpdate = "STUDENT_ADDED"
+	ClassUpdateStudentUpdated ClassUpdate = "STUDENT_UPDATED"
+	ClassUpdateStudentRenamed ClassUpdate = "STUDENT_RENAMED"
+	ClassUpdateStudentRemoved ClassUpdate = "STUDENT_REMOVED"
+)
+
+var AllClassUpdate = []ClassUpdate{
+	ClassUpdateStudentAdded,
+	ClassUpdateStudentUpdated,
+	ClassUpdateStudentRenamed,
+	ClassUpdateStudentRemoved,
+}
+
+func (e ClassUpdate) IsValid() bool {
+	switch e {
+	case ClassUpdateStudentAdded, ClassUpdateStudentUpdated, ClassUpdateStudentRenamed, ClassUpdateStudentRemoved:
+		return true
+	}
+	return false
+}
+
+func (e ClassUpdate) String() string {
+	return string(e)
+}
+
+func (e *ClassUpdate) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ClassUpdate(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ClassUpdate", str)
+	}
+	return nil
+}
+
+func (e ClassUpdate) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type JobState string
+
+const (
+	JobStateQueued    JobState = "QUEUED"
+	JobStateRunning   JobState = "RUNNING"
+	JobStateSucceeded JobState = "SUCCEEDED"
+	JobStateFailed    JobState = "FAILED"
+)
+
+var AllJobState = []JobState{
+	JobStateQueued,
+	JobStateRunning,
+	JobStateSucceeded,
+	JobStateFailed,
+}
+
+func (e JobState) IsValid() bool {
+	switch e {
+	case JobStateQueued, JobStateRunning, JobStateSucceeded, JobStateFailed:
+		return true
+	}
+	return false
+}
+
+func (e JobState) String() string {
+	return string(e)
+}
+
+func (e *JobState) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = JobState(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid JobState", str)
+	}
+	return nil
+}
+
+func (e JobState) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type RankingBasis string
+
+const (
+	RankingBasisTotal    RankingBasis = "TOTAL"
+	RankingBasisAverage  RankingBasis = "AVERAGE"
+	RankingBasisWeighted RankingBasis = "WEIGHTED"
+)
+
+var AllRankingBasis = []RankingBasis{
+	RankingBasisTotal,
+	RankingBasisAverage,
+	RankingBasisWeighted,
+}
+
+func (e RankingBasis) IsValid() bool {
+	switch e {
+	case RankingBasisTotal, RankingBasisAverage, RankingBasisWeighted:
+		return true
+	}
+	return false
+}
+
+func (e RankingBasis) String() string {
+	return string(e)
+}
+
+func (e *RankingBasis) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = RankingBasis(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid RankingBasis", str)
+	}
+	return nil
+}
+
+func (e RankingBasis) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type RankingMode string
+
+const (
+	RankingModeCompetition RankingMode = "COMPETITION"
+	RankingModeDense       RankingMode = "DENSE"
+)
+
+var AllRankingMode = []RankingMode{
+	RankingModeCompetition,
+	RankingModeDense,
+}
+
+func (e RankingMode) IsValid() bool {
+	switch e {
+	case RankingModeCompetition, RankingModeDense:
+		return true
+	}
+	return false
+}
+
+func (e RankingMode) String() string {
+	return string(e)
+}
+
+func (e *RankingMode) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = RankingMode(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid RankingMode", str)
+	}
+	return nil
+}
+
+func (e RankingMode) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type ReportStatus string
+
+const (
+	ReportStatusQueued    ReportStatus = "QUEUED"
+	ReportStatusProgress  ReportStatus = "PROGRESS"
+	ReportStatusCompleted ReportStatus = "COMPLETED"
+	ReportStatusFailed    ReportStatus = "FAILED"
+)
+
+var AllReportStatus = []ReportStatus{
+	ReportStatusQueued,
+	ReportStatusProgress,
+	ReportStatusCompleted,
+	ReportStatusFailed,
+}
+
+func (e ReportStatus) IsValid() bool {
+	switch e {
+	case ReportStatusQueued, ReportStatusProgress, ReportStatusCompleted, ReportStatusFailed:
+		return true
+	}
+	return false
+}
+
+func (e ReportStatus) String() string {
+	return string(e)
+}
+
+func (e *ReportStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ReportStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ReportStatus", str)
+	}
+	return nil
+}
+
+func (e ReportStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type Role string
+
+const (
+	RoleOwner   Role = "OWNER"
+	RoleAdmin   Role = "ADMIN"
+	RoleTeacher Role = "TEACHER"
+	RoleViewer  Role = "VIEWER"
+)
+
+var AllRole = []Role{
+	RoleOwner,
+	RoleAdmin,
+	RoleTeacher,
+	RoleViewer,
+}
+
+func (e Role) IsValid() bool {
+	switch e {
+	case RoleOwner, RoleAdmin, RoleTeacher, RoleViewer:
+		return true
+	}
+	return false
+}
+
+func (e Role) String() string {
+	return string(e)
+}
+
+func (e *Role) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = Role(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid Role", str)
+	}
+	return nil
+}
+
+func (e Role) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type ScoreStatus string
+
+const (
+	ScoreStatusScored     ScoreStatus = "SCORED"
+	ScoreStatusAbsent     ScoreStatus = "ABSENT"
+	ScoreStatusExempt     ScoreStatus = "EXEMPT"
+	ScoreStatusIncomplete ScoreStatus = "INCOMPLETE"
+)
+
+var AllScoreStatus = []ScoreStatus{
+	ScoreStatusScored,
+	ScoreStatusAbsent,
+	ScoreStatusExempt,
+	ScoreStatusIncomplete,
+}
+
+func (e ScoreStatus) IsValid() bool {
+	switch e {
+	case ScoreStatusScored, ScoreStatusAbsent, ScoreStatusExempt, ScoreStatusIncomplete:
+		return true
+	}
+	return false
+}
+
+func (e ScoreStatus) String() string {
+	return string(e)
+}
+
+func (e *ScoreStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ScoreStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ScoreStatus", str)
+	}
+	return nil
+}
+
+func (e ScoreStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
@@ -1,57 +1,346 @@
 package graph
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"math"
 	"sort"
-	"strconv"
 	"sync"
 	"time"
 
+	"github.com/ukane-philemon/scomp/graph/model"
 	"github.com/ukane-philemon/scomp/internal/admin"
 	"github.com/ukane-philemon/scomp/internal/auth"
 	"github.com/ukane-philemon/scomp/internal/class"
+	"github.com/ukane-philemon/scomp/internal/db"
+	customerror "github.com/ukane-philemon/scomp/internal/errors"
+	"github.com/ukane-philemon/scomp/internal/grading"
+	"github.com/ukane-philemon/scomp/internal/history"
+	"github.com/ukane-philemon/scomp/internal/job"
+	"github.com/ukane-philemon/scomp/internal/report"
+	"github.com/ukane-philemon/scomp/internal/school"
+	"github.com/ukane-philemon/scomp/internal/session"
 	"github.com/ukane-philemon/scomp/internal/student"
 )
 
+// defaultInvitationValidity is how long invitations are valid for if a
+// validity is not specified.
+const defaultInvitationValidity = 72 * time.Hour
+
+// defaultAPIKeyValidity is how long API keys are valid for if a validity is
+// not specified.
+const defaultAPIKeyValidity = 90 * 24 * time.Hour
+
+// maxDistributionBuckets is the maximum number of buckets of a score
+// distribution.
+const maxDistributionBuckets = 100
+
+// totpIssuer is the issuer shown by authenticator apps for TOTP secrets.
+const totpIssuer = "SCOMP"
+
 type Resolver struct {
 	wg sync.WaitGroup
+	// jobQueued wakes up idle job workers, see StartJobWorkers.
+	jobQueued chan struct{}
+	// reportEvents and classEvents deliver the events of the reportStatus
+	// and classUpdated subscriptions.
+	reportEvents eventBroker[*model.ReportStatusEvent]
+	classEvents  eventBroker[*model.ClassUpdatedEvent]
 
 	AdminRepository          admin.Repository
+	InvitationRepository     admin.InvitationRepository
+	APIKeyRepository         admin.APIKeyRepository
+	SchoolRepository         school.Repository
+	GradingScaleRepository   grading.Repository
 	ClassRepository          class.Repository
 	StudentRepository        student.Repository
+	ReportVersionRepository  history.Repository
+	JobRepository            job.Repository
+	ReportStore              report.Store
+	SessionRepository        session.Repository
 	AuthenticationRepository auth.Repository
+	// PasswordPolicy is the policy of new passwords.
+	PasswordPolicy *admin.PasswordPolicy
 }
 
-// Wait waits for all pending asynchronous activities to finish.
+// Wait waits for all pending asynchronous activities, including the job
+// workers, to finish.
 func (r *Resolver) Wait() {
 	r.wg.Wait()
 }
 
+// authenticatedAdmin generates an auth token for the session of adminInfo and
+// returns the login details of the admin.
+func (r *Resolver) authenticatedAdmin(adminInfo *admin.Admin, sessionID, refreshToken string) (*model.AuthenticatedAdmin, error) {
+	authToken, err := r.AuthenticationRepository.GenerateToken(&auth.Claims{
+		AdminID:   adminInfo.ID,
+		SessionID: sessionID,
+		Role:      adminInfo.Role,
+		SchoolID:  adminInfo.SchoolID,
+	})
+	if err != nil {
+		return nil, handleError(err)
+	}
+
+	authTokenExpiresAt := int(time.Now().Add(auth.JWTExpiry).Unix())
+	return &model.AuthenticatedAdmin{
+		ID:                 adminInfo.ID,
+		Username:           adminInfo.Username,
+		SchoolID:           adminInfo.SchoolID,
+		Role:               modelRole(adminInfo.Role),
+		AuthToken:          &authToken,
+		AuthTokenExpiresAt: &authTokenExpiresAt,
+		RefreshToken:       &refreshToken,
+	}, nil
+}
+
+// gradingScale returns the grading scale of schoolID that match
+// gradingScaleID, or the default grading scale if gradingScaleID is empty.
+func (r *Resolver) gradingScale(schoolID, gradingScaleID string) (*grading.GradingScale, error) {
+	if gradingScaleID == "" {
+		return grading.DefaultGradingScale(), nil
+	}
+
+	return r.GradingScaleRepository.GradingScale(schoolID, gradingScaleID)
+}
+
+// checkSubjectScore checks that subjectScore is a valid score of subject. The
+// score of a subject with components is set to the sum of the component
+// scores.
+func checkSubjectScore(subject *class.Subject, subjectScore *student.SubjectScore) error {
+	if !subjectScore.Scored() {
+		// The student repository checks that the subject has no score.
+		return nil
+	}
+
+	if len(subject.Components) == 0 {
+		if len(subjectScore.Components) > 0 {
+			return fmt.Errorf("%w: subject %s has no components", db.ErrorInvalidRequest, subject.Name)
+		}
+	} else {
+		if len(subjectScore.Components) != len(subject.Components) {
+			return fmt.Errorf("%w: %d component scores are required for subject %s", db.ErrorInvalidRequest, len(subject.Components), subject.Name)
+		}
+
+		var totalScore int
+		componentScores := make(map[string]*student.ComponentScore, len(subjectScore.Components))
+		for _, componentScore := range subjectScore.Components {
+			component, found := subject.Component(componentScore.Name)
+			if !found {
+				return fmt.Errorf("%w: component %s does not exist for subject %s", db.ErrorInvalidRequest, componentScore.Name, subject.Name)
+			}
+
+			if componentScores[component.Name] != nil {
+				return fmt.Errorf("%w: more than one score for component %s of subject %s", db.ErrorInvalidRequest, component.Name, subject.Name)
+			}
+			componentScores[component.Name] = componentScore
+
+			if componentScore.Score > component.MaxScore || componentScore.Score < 0 {
+				return fmt.Errorf("%w: invalid student score (%d) for component %s of subject %s (maximum score is %d)",
+					db.ErrorInvalidRequest, componentScore.Score, component.Name, subject.Name, component.MaxScore)
+			}
+
+			totalScore += componentScore.Score
+		}
+
+		if subjectScore.Score != 0 && subjectScore.Score != totalScore {
+			return fmt.Errorf("%w: the score of subject %s must be the sum of its component scores (%d)", db.ErrorInvalidRequest, subject.Name, totalScore)
+		}
+
+		// Keep component scores in the order of the subject components.
+		subjectScore.Components = subjectScore.Components[:0]
+		for _, component := range subject.Components {
+			subjectScore.Components = append(subjectScore.Components, componentScores[component.Name])
+		}
+		subjectScore.Score = totalScore
+	}
+
+	if subjectScore.Score > subject.MaxScore || subjectScore.Score < 0 {
+		return fmt.Errorf("%w: invalid student score (%d) for subject %s (maximum score is %d)",
+			db.ErrorInvalidRequest, subjectScore.Score, subject.Name, subject.MaxScore)
+	}
+
+	return nil
+}
+
 type studentSubjectScore struct {
-	studentID string
-	score     int
+	studentID  string
+	score      int
+	components []*student.ComponentScore
 }
 
 type subjectScoreInfo struct {
+	// studentScores are the scores of the students who were scored in the
+	// subject. Only these students are ranked in the subject.
 	studentScores []*studentSubjectScore
-	maxScore      int
+	// unscored are the subject scores of the students who were not scored,
+	// e.g because they were absent, mapped to their student IDs.
+	unscored map[string]*student.SubjectScore
+	maxScore int
+	weight   float64
 }
 
 type studentReport struct {
 	studentID string
 	report    *student.Report
+	// rankScore is the score the student is ranked by, see
+	// class.ReportSettings.RankingBasis.
+	rankScore float64
+	// percentage is rankScore as a percentage, the student is graded by it.
+	percentage float64
+}
+
+// round2 rounds x to 2 decimal places.
+func round2(x float64) float64 {
+	return math.Round(x*100) / 100
+}
+
+// percentage returns x as a percentage of total, 0 if total is 0.
+func percentage(x, total float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return x / total * 100
+}
+
+// rate returns count as a percentage of total rounded to 2 decimal places.
+func rate(count, total int) float64 {
+	return round2(percentage(float64(count), float64(total)))
+}
+
+// describe returns the mean, the median and the standard deviation of values
+// rounded to 2 decimal places, 0 if values is empty.
+func describe(values []float64) (mean, median, stdDev float64) {
+	if len(values) == 0 {
+		return 0, 0, 0
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	var sum float64
+	for _, value := range sorted {
+		sum += value
+	}
+	mean = sum / float64(len(sorted))
+
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		median = (sorted[middle-1] + sorted[middle]) / 2
+	} else {
+		median = sorted[middle]
+	}
+
+	var squaredDiffs float64
+	for _, value := range sorted {
+		squaredDiffs += (value - mean) * (value - mean)
+	}
+	stdDev = math.Sqrt(squaredDiffs / float64(len(sorted)))
+
+	return round2(mean), round2(median), round2(stdDev)
+}
+
+// rankPositions returns the positions of scores sorted from the highest to
+// the lowest score with rankingMode, and whether each position is shared with
+// another score.
+func rankPositions(rankingMode string, scores []float64) ([]int, []bool) {
+	positions := make([]int, len(scores))
+	shared := make([]bool, len(scores))
+	for i, score := range scores {
+		switch {
+		case i == 0:
+			positions[i] = 1
+		case score == scores[i-1]:
+			positions[i] = positions[i-1]
+			shared[i], shared[i-1] = true, true
+		case rankingMode == class.RankingDense:
+			positions[i] = positions[i-1] + 1
+		default:
+			positions[i] = i + 1
+		}
+	}
+
+	return positions, shared
+}
+
+// percentileRanks returns the percentile ranks of scores sorted from the
+// highest to the lowest score: the percentage of scores below each score plus
+// half of the scores equal to it.
+func percentileRanks(scores []float64) []float64 {
+	ranks := make([]float64, len(scores))
+	for start := 0; start < len(scores); {
+		end := start + 1
+		for end < len(scores) && scores[end] == scores[start] {
+			end++
+		}
+
+		below := len(scores) - end
+		equal := end - start
+		rank := round2((float64(below) + float64(equal)/2) / float64(len(scores)) * 100)
+		for i := start; i < end; i++ {
+			ranks[i] = rank
+		}
+		start = end
+	}
+
+	return ranks
 }
 
-// computeClassReport generates a report for a class. studentsInfo is a map of
-// students to their subject scores.
-func (r *Resolver) computeClassReport(classID string, classSubjects []*class.Subject, studentsInfo map[string][]*student.SubjectScore) {
-	var totalMaxSubjectsScore int
-	subjectScoreMap := make(map[string]*subjectScoreInfo, len(classSubjects))
-	for _, subjectInfo := range classSubjects {
-		totalMaxSubjectsScore += subjectInfo.MaxScore
+// histogram counts percentages in numBuckets buckets of the same width from
+// 0 to 100. The last bucket includes 100.
+func histogram(percentages []float64, numBuckets int) []*model.DistributionBucket {
+	width := 100 / float64(numBuckets)
+	buckets := make([]*model.DistributionBucket, 0, numBuckets)
+	for i := 0; i < numBuckets; i++ {
+		buckets = append(buckets, &model.DistributionBucket{
+			MinPercentage: round2(float64(i) * width),
+			MaxPercentage: round2(float64(i+1) * width),
+		})
+	}
+
+	for _, percentage := range percentages {
+		index := int(percentage / width)
+		if index >= numBuckets {
+			index = numBuckets - 1
+		} else if index < 0 {
+			index = 0
+		}
+		buckets[index].Count++
+	}
+
+	return buckets
+}
+
+// computeClassReport generates a report for classInfo graded with
+// gradingScale, saves it as the next report version of the class, computed by
+// generatedBy, and returns the version. studentNames is a map of students to
+// their names and studentsInfo is a map of students to their subject scores.
+// Totals, percentages and positions only count the subjects each student was
+// scored in. onProgress is called with the percentage of the computation done
+// after every step.
+func (r *Resolver) computeClassReport(schoolID, generatedBy string, classInfo *class.Class, gradingScale *grading.GradingScale,
+	studentNames map[string]string, studentsInfo map[string][]*student.SubjectScore, onProgress func(progress int)) (*history.ReportVersion, error) {
+	// ranksBefore reports whether the student with studentID and score is
+	// listed before the student with otherStudentID and otherScore. Students
+	// with the same score are listed by name and then by ID so that reports
+	// do not depend on the order students are retrieved in.
+	ranksBefore := func(studentID string, score float64, otherStudentID string, otherScore float64) bool {
+		if score != otherScore {
+			return score > otherScore
+		}
+		if studentNames[studentID] != studentNames[otherStudentID] {
+			return studentNames[studentID] < studentNames[otherStudentID]
+		}
+		return studentID < otherStudentID
+	}
+
+	subjectScoreMap := make(map[string]*subjectScoreInfo, len(classInfo.Subjects))
+	for _, subjectInfo := range classInfo.Subjects {
 		subjectScoreMap[subjectInfo.Name] = &subjectScoreInfo{
+			unscored: make(map[string]*student.SubjectScore),
 			maxScore: subjectInfo.MaxScore,
+			weight:   subjectInfo.EffectiveWeight(),
 		}
 	}
 
@@ -61,108 +350,345 @@ func (r *Resolver) computeClassReport(classID string, classSubjects []*class.Sub
 
 	// Compute max scores and subject scores for all students.
 	for studentID, subjects := range studentsInfo {
-		var totalScore int
+		var totalScore, totalMaxScore, scoredSubjects int
+		var totalPercentage, totalWeightedPercentage, totalWeight float64
 		for _, subject := range subjects {
+			subjectInfo := subjectScoreMap[subject.Name]
+			if !subject.Scored() {
+				subjectInfo.unscored[studentID] = subject
+				continue
+			}
+
+			subjectPercentage := percentage(float64(subject.Score), float64(subjectInfo.maxScore))
 			totalScore += subject.Score
+			totalMaxScore += subjectInfo.maxScore
+			totalPercentage += subjectPercentage
+			totalWeightedPercentage += subjectPercentage * subjectInfo.weight
+			totalWeight += subjectInfo.weight
+			scoredSubjects++
 
 			// Group all the scores across all students for this subject.
-			subjectScoreMap[subject.Name].studentScores = append(subjectScoreMap[subject.Name].studentScores, &studentSubjectScore{
-				studentID: studentID,
-				score:     subject.Score,
+			subjectInfo.studentScores = append(subjectInfo.studentScores, &studentSubjectScore{
+				studentID:  studentID,
+				score:      subject.Score,
+				components: subject.Components,
 			})
 		}
 
+		var averagePercentage, weightedPercentage float64
+		if scoredSubjects > 0 {
+			averagePercentage = round2(totalPercentage / float64(scoredSubjects))
+			weightedPercentage = round2(totalWeightedPercentage / totalWeight)
+		}
+
 		report := &student.Report{
 			Class: &student.StudentClassReport{
 				TotalScore:           totalScore,
-				TotalScorePercentage: fmt.Sprintf("%.1f", float64(totalScore)/float64(totalMaxSubjectsScore)*100),
+				TotalScorePercentage: fmt.Sprintf("%.1f", percentage(float64(totalScore), float64(totalMaxScore))),
+				AveragePercentage:    averagePercentage,
+				WeightedPercentage:   weightedPercentage,
 			},
 		}
 
-		studentReportMap[studentID] = report
-		studentReports = append(studentReports, &studentReport{
+		record := &studentReport{
 			studentID: studentID,
 			report:    report,
-		})
+		}
+		switch classInfo.RankingBasis {
+		case class.RankingBasisAverage:
+			record.rankScore = report.Class.AveragePercentage
+			record.percentage = report.Class.AveragePercentage
+		case class.RankingBasisWeighted:
+			record.rankScore = report.Class.WeightedPercentage
+			record.percentage = report.Class.WeightedPercentage
+		default:
+			record.rankScore = float64(totalScore)
+			record.percentage = percentage(float64(totalScore), float64(totalMaxScore))
+		}
+
+		studentReportMap[studentID] = report
+		studentReports = append(studentReports, record)
 	}
 
+	onProgress(30)
 	nowUnix := time.Now().Unix()
 
-	// Set student subject position an grade them.
-	for subjectName, subject := range subjectScoreMap {
+	// Set student subject position an grade them. Subjects are added to
+	// student reports in the order of the class subjects.
+	subjectsStatistics := make([]*class.SubjectStatistics, 0, len(classInfo.Subjects))
+	for _, subjectInfo := range classInfo.Subjects {
+		subject := subjectScoreMap[subjectInfo.Name]
+
 		// Sort according to highest subject scores.
-		sort.SliceStable(subject.studentScores, func(i, j int) bool {
-			return subject.studentScores[i].score > subject.studentScores[j].score
+		sort.Slice(subject.studentScores, func(i, j int) bool {
+			a, b := subject.studentScores[i], subject.studentScores[j]
+			return ranksBefore(a.studentID, float64(a.score), b.studentID, float64(b.score))
 		})
 
+		scores := make([]float64, 0, len(subject.studentScores))
+		for _, report := range subject.studentScores {
+			scores = append(scores, float64(report.score))
+		}
+		positions, shared := rankPositions(classInfo.RankingMode, scores)
+		subjectPercentileRanks := percentileRanks(scores)
+
+		subjectStatistics := &class.SubjectStatistics{
+			Name:          subjectInfo.Name,
+			TotalStudents: len(subject.studentScores),
+		}
+		var totalSubjectScore, passedStudents int
+
 		// Set student position and grade them.
 		for positionIndex, report := range subject.studentScores {
-			subjectScorePercentage := float64(report.score) / float64(subject.maxScore) * 100
+			subjectPercentage := percentage(float64(report.score), float64(subject.maxScore))
+			totalSubjectScore += report.score
+			if gradingScale.Passed(subjectPercentage) {
+				passedStudents++
+			}
+
+			band := gradingScale.Grade(subjectPercentage)
 			studentReportMap[report.studentID].Subjects = append(studentReportMap[report.studentID].Subjects, &student.SubjectReport{
 				SubjectScore: &student.SubjectScore{
-					Name:  subjectName,
-					Score: report.score,
+					Name:       subjectInfo.Name,
+					Score:      report.score,
+					Components: report.components,
 				},
-				Grade:    gradeTotalScorePercentage(fmt.Sprint(subjectScorePercentage)),
-				Position: positionIndex + 1,
+				Grade:          band.Label,
+				GradePoint:     band.GradePoint,
+				Remark:         band.Remark,
+				Position:       positions[positionIndex],
+				PositionShared: shared[positionIndex],
+				PercentileRank: subjectPercentileRanks[positionIndex],
+			})
+		}
+
+		if len(subject.studentScores) > 0 {
+			// Students are sorted by score and then by name, the lowest score
+			// is picked like the highest score.
+			highest := subject.studentScores[0]
+			lowest := subject.studentScores[len(subject.studentScores)-1]
+			for _, report := range subject.studentScores {
+				if report.score == lowest.score {
+					lowest = report
+					break
+				}
+			}
+
+			averageScore := float64(totalSubjectScore) / float64(len(subject.studentScores))
+			subjectStatistics.AverageScore = round2(averageScore)
+			subjectStatistics.AveragePercentage = round2(percentage(averageScore, float64(subject.maxScore)))
+			subjectStatistics.HighestScore = highest.score
+			subjectStatistics.HighestScoreStudentID = highest.studentID
+			subjectStatistics.HighestScoreStudentName = studentNames[highest.studentID]
+			subjectStatistics.LowestScore = lowest.score
+			subjectStatistics.LowestScoreStudentID = lowest.studentID
+			subjectStatistics.LowestScoreStudentName = studentNames[lowest.studentID]
+			subjectStatistics.PassRate = rate(passedStudents, len(subject.studentScores))
+		}
+		subjectsStatistics = append(subjectsStatistics, subjectStatistics)
+
+		// Students who were not scored keep their status without a grade or
+		// a position.
+		for studentID, subjectScore := range subject.unscored {
+			studentReportMap[studentID].Subjects = append(studentReportMap[studentID].Subjects, &student.SubjectReport{
+				SubjectScore: subjectScore,
 			})
 		}
 	}
 
-	// Sort according to highest total scores.
-	sort.SliceStable(studentReports, func(i, j int) bool {
-		return studentReports[i].report.Class.TotalScore > studentReports[j].report.Class.TotalScore
+	onProgress(50)
+
+	// Sort according to highest rank scores.
+	sort.Slice(studentReports, func(i, j int) bool {
+		a, b := studentReports[i], studentReports[j]
+		return ranksBefore(a.studentID, a.rankScore, b.studentID, b.rankScore)
 	})
 
+	rankScores := make([]float64, 0, len(studentReports))
+	for _, record := range studentReports {
+		rankScores = append(rankScores, record.rankScore)
+	}
+	positions, shared := rankPositions(classInfo.RankingMode, rankScores)
+	studentPercentileRanks := percentileRanks(rankScores)
+
 	classReport := &class.ClassReport{
 		TotalStudents: len(studentReports),
+		Subjects:      subjectsStatistics,
 	}
 
+	var passedStudents int
+	studentPercentages := make([]float64, 0, len(studentReports))
+	gradeCounts := make(map[*grading.GradeBand]int, len(gradingScale.Bands))
+
 	// Set student position and grade them.
 	for positionIndex, record := range studentReports {
 		report := record.report.Class
-		studentPosition := positionIndex + 1
 
-		if report.TotalScore > classReport.HighestStudentScore {
+		if positionIndex == 0 || report.TotalScore > classReport.HighestStudentScore {
 			classReport.HighestStudentScore = report.TotalScore
-		} else if classReport.LowestStudentScore == 0 || report.TotalScore < classReport.LowestStudentScore {
+			classReport.HighestStudentScoreAsPercentage = report.TotalScorePercentage
+		}
+		if positionIndex == 0 || report.TotalScore < classReport.LowestStudentScore {
 			classReport.LowestStudentScore = report.TotalScore
+			classReport.LowestStudentScoreAsPercentage = report.TotalScorePercentage
 		}
 
-		studentReportMap[record.studentID].Class.Position = studentPosition
-		studentReportMap[record.studentID].Class.Grade = gradeTotalScorePercentage(report.TotalScorePercentage)
-		studentReportMap[record.studentID].GeneratedAt = fmt.Sprint(nowUnix)
+		// The GPA is weighted with the subject weights, e.g credit units.
+		var totalGradePoints, totalWeight float64
+		for _, subject := range record.report.Subjects {
+			if !subject.Scored() {
+				continue
+			}
+			weight := subjectScoreMap[subject.Name].weight
+			totalGradePoints += subject.GradePoint * weight
+			totalWeight += weight
+		}
+
+		band := gradingScale.Grade(record.percentage)
+		gradeCounts[band]++
+		studentPercentages = append(studentPercentages, record.percentage)
+		if gradingScale.Passed(record.percentage) {
+			passedStudents++
+		}
+
+		report.Position = positions[positionIndex]
+		report.PositionShared = shared[positionIndex]
+		report.PercentileRank = studentPercentileRanks[positionIndex]
+		report.Percentage = round2(record.percentage)
+		report.Grade = band.Label
+		report.Remark = band.Remark
+		if totalWeight > 0 {
+			report.GPA = round2(totalGradePoints / totalWeight)
+		}
+		record.report.GeneratedAt = nowUnix
 	}
 
-	classReport.HighestStudentScoreAsPercentage = fmt.Sprintf("%.1f", float64(classReport.HighestStudentScore)/float64(totalMaxSubjectsScore)*100)
-	classReport.LowestStudentScoreAsPercentage = fmt.Sprintf("%1.f", float64(classReport.LowestStudentScore)/float64(totalMaxSubjectsScore)*100)
-	classReport.GeneratedAt = fmt.Sprint(nowUnix)
+	classReport.MeanPercentage, classReport.MedianPercentage, classReport.StandardDeviation = describe(studentPercentages)
+	classReport.PassRate = rate(passedStudents, len(studentReports))
+	for _, band := range gradingScale.Bands {
+		classReport.GradeDistribution = append(classReport.GradeDistribution, &class.GradeCount{
+			Grade: band.Label,
+			Count: gradeCounts[band],
+		})
+	}
+	classReport.GeneratedAt = nowUnix
+	onProgress(70)
+
+	versionStudents := make([]*history.StudentReport, 0, len(studentReports))
+	for _, record := range studentReports {
+		versionStudents = append(versionStudents, &history.StudentReport{
+			StudentID:   record.studentID,
+			StudentName: studentNames[record.studentID],
+			Report:      record.report,
+		})
+	}
 
-	err := r.ClassRepository.SaveClassReport(classID, classReport)
+	onProgress(90)
+
+	// The report version is only saved with the reports, if the students did
+	// not change while they were computed.
+	return r.ReportStore.CreateReports(schoolID, classInfo.ID, generatedBy, classInfo.StudentsRevision, classReport, versionStudents)
+}
+
+// checkSubjectScores checks that subjectScores are valid scores of the
+// subjects of classInfo.
+func checkSubjectScores(classInfo *class.Class, subjectScores []*student.SubjectScore) error {
+	for _, subject := range subjectScores {
+		classSubject, found := classInfo.Subject(subject.Name)
+		if !found {
+			return fmt.Errorf("%w: subject name %s does not exist, check spelling as subject names are case sensitive",
+				db.ErrorInvalidRequest, subject.Name)
+		}
+
+		if err := checkSubjectScore(classSubject, subject); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// studentsClass returns the class that match classID if the admin of ctx can
+// change its students. Teachers can only change the students of their own
+// classes.
+func (r *Resolver) studentsClass(ctx context.Context, classID string) (*class.Class, error) {
+	if !reqCanAccessClass(ctx, classID) {
+		return nil, &customerror.ErrorForbidden{}
+	}
+
+	classInfo, err := r.ClassRepository.Class(reqSchoolID(ctx), classID)
 	if err != nil {
-		log.Printf("SERVER ERROR: ClassRepo.SaveClassReport %v", err.Error())
+		return nil, handleError(err)
 	}
 
-	err = r.StudentRepository.SaveStudentReports(studentReportMap)
+	if reqRole(ctx) == admin.RoleTeacher && classInfo.TeacherID != reqAdminID(ctx) {
+		return nil, &customerror.ErrorForbidden{}
+	}
+
+	return classInfo, nil
+}
+
+// publishStudentUpdate sends update of studentID to the classUpdated
+// subscriptions of classID.
+func (r *Resolver) publishStudentUpdate(classID string, update model.ClassUpdate, studentID, studentName string) {
+	r.classEvents.publish(classID, &model.ClassUpdatedEvent{
+		ClassID:     classID,
+		Update:      update,
+		StudentID:   studentID,
+		StudentName: studentName,
+	})
+}
+
+// RecoverReports repairs the classes whose current report does not match the
+// reports of all their students, e.g because an older server failed after
+// saving the class report. Classes that cannot be repaired are logged and
+// left as they are.
+func (r *Resolver) RecoverReports() error {
+	classes, err := r.ReportStore.IncompleteReports()
 	if err != nil {
-		log.Printf("SERVER ERROR: StudentRepo.SaveStudentReports %v", err.Error())
-	}
-}
-
-func gradeTotalScorePercentage(totalStudentScorePercentageStr string) string {
-	totalStudentScorePercentage, _ := strconv.ParseFloat(totalStudentScorePercentageStr, 64)
-	switch {
-	case totalStudentScorePercentage > 69:
-		return "Excellent"
-	case totalStudentScorePercentage > 59:
-		return "Good"
-	case totalStudentScorePercentage > 49:
-		return "Fair"
-	case totalStudentScorePercentage > 44:
-		return "Pass"
-	case totalStudentScorePercentage > 40:
-		return "Pass"
-	default:
-		return "Fail"
+		return fmt.Errorf("ReportStore.IncompleteReports error: %w", err)
 	}
+
+	for _, classInfo := range classes {
+		err = r.recoverReport(classInfo)
+		if err != nil {
+			log.Printf("SERVER ERROR: failed to repair the report of class %s: %v", classInfo.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// recoverReport saves the reports of the current report version of classInfo
+// again. Classes reported before reports were versioned are computed again by
+// a job instead. Classes that are computing are repaired by their job.
+func (r *Resolver) recoverReport(classInfo *class.Class) error {
+	if classInfo.State != class.StateReported {
+		return nil
+	}
+
+	if classInfo.CurrentReportVersion == 0 {
+		_, err := r.queueComputeClassReport(classInfo, "")
+		if err != nil {
+			return err
+		}
+
+		log.Printf("Queued a job to compute the half-written report of class %s again", classInfo.ID)
+		return nil
+	}
+
+	reportVersion, err := r.ReportVersionRepository.ReportVersion(classInfo.SchoolID, classInfo.ID, classInfo.CurrentReportVersion)
+	if err != nil {
+		return err
+	}
+
+	// Fails if the students of the class changed since the version was
+	// computed.
+	err = r.ReportStore.RestoreReports(classInfo.SchoolID, class.StateReported, reportVersion)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Repaired the half-written report of class %s with report version %d", classInfo.ID, reportVersion.Version)
+	return nil
 }
@@ -0,0 +1,402 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/ukane-philemon/scomp/internal/class"
+	"github.com/ukane-philemon/scomp/internal/db"
+	"github.com/ukane-philemon/scomp/internal/grading"
+	"github.com/ukane-philemon/scomp/internal/memory"
+	"github.com/ukane-philemon/scomp/internal/student"
+)
+
+func TestRankPositions(t *testing.T) {
+	tests := []struct {
+		name          string
+		rankingMode   string
+		scores        []float64
+		wantPositions []int
+		wantShared    []bool
+	}{
+		{
+			name:          "no scores",
+			rankingMode:   class.RankingCompetition,
+			scores:        []float64{},
+			wantPositions: []int{},
+			wantShared:    []bool{},
+		},
+		{
+			name:          "no ties",
+			rankingMode:   class.RankingCompetition,
+			scores:        []float64{90, 80, 70},
+			wantPositions: []int{1, 2, 3},
+			wantShared:    []bool{false, false, false},
+		},
+		{
+			name:          "competition skips positions after a tie",
+			rankingMode:   class.RankingCompetition,
+			scores:        []float64{90, 80, 80, 70},
+			wantPositions: []int{1, 2, 2, 4},
+			wantShared:    []bool{false, true, true, false},
+		},
+		{
+			name:          "dense does not skip positions after a tie",
+			rankingMode:   class.RankingDense,
+			scores:        []float64{90, 80, 80, 70},
+			wantPositions: []int{1, 2, 2, 3},
+			wantShared:    []bool{false, true, true, false},
+		},
+		{
+			name:          "tie for first",
+			rankingMode:   class.RankingCompetition,
+			scores:        []float64{90, 90, 90, 50},
+			wantPositions: []int{1, 1, 1, 4},
+			wantShared:    []bool{true, true, true, false},
+		},
+		{
+			name:          "empty mode is competition",
+			rankingMode:   "",
+			scores:        []float64{60, 60, 40},
+			wantPositions: []int{1, 1, 3},
+			wantShared:    []bool{true, true, false},
+		},
+	}
+
+	for _, test := range tests {
+		positions, shared := rankPositions(test.rankingMode, test.scores)
+		if !reflect.DeepEqual(positions, test.wantPositions) {
+			t.Errorf("%s: expected positions %v, got %v", test.name, test.wantPositions, positions)
+		}
+
+		if !reflect.DeepEqual(shared, test.wantShared) {
+			t.Errorf("%s: expected shared %v, got %v", test.name, test.wantShared, shared)
+		}
+	}
+}
+
+func TestPercentileRanks(t *testing.T) {
+	tests := []struct {
+		name   string
+		scores []float64
+		want   []float64
+	}{
+		{name: "no scores", scores: []float64{}, want: []float64{}},
+		{name: "single score", scores: []float64{70}, want: []float64{50}},
+		{name: "no ties", scores: []float64{90, 80, 70, 60}, want: []float64{87.5, 62.5, 37.5, 12.5}},
+		{name: "ties", scores: []float64{90, 80, 80, 70}, want: []float64{87.5, 50, 50, 12.5}},
+		{name: "all equal", scores: []float64{50, 50, 50}, want: []float64{50, 50, 50}},
+		{name: "rounded", scores: []float64{3, 2, 1}, want: []float64{83.33, 50, 16.67}},
+	}
+
+	for _, test := range tests {
+		if got := percentileRanks(test.scores); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.want, got)
+		}
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	tests := []struct {
+		name        string
+		percentages []float64
+		numBuckets  int
+		wantRanges  [][2]float64
+		wantCounts  []int
+	}{
+		{
+			name:        "quarters",
+			percentages: []float64{0, 24.99, 25, 50, 99.99, 100},
+			numBuckets:  4,
+			wantRanges:  [][2]float64{{0, 25}, {25, 50}, {50, 75}, {75, 100}},
+			wantCounts:  []int{2, 1, 1, 2},
+		},
+		{
+			name:        "single bucket",
+			percentages: []float64{0, 50, 100},
+			numBuckets:  1,
+			wantRanges:  [][2]float64{{0, 100}},
+			wantCounts:  []int{3},
+		},
+		{
+			name:        "rounded ranges",
+			percentages: []float64{33.33, 33.34, 66.67},
+			numBuckets:  3,
+			wantRanges:  [][2]float64{{0, 33.33}, {33.33, 66.67}, {66.67, 100}},
+			wantCounts:  []int{1, 1, 1},
+		},
+		{
+			name:        "no percentages",
+			percentages: nil,
+			numBuckets:  2,
+			wantRanges:  [][2]float64{{0, 50}, {50, 100}},
+			wantCounts:  []int{0, 0},
+		},
+	}
+
+	for _, test := range tests {
+		buckets := histogram(test.percentages, test.numBuckets)
+		if len(buckets) != test.numBuckets {
+			t.Errorf("%s: expected %d buckets, got %d", test.name, test.numBuckets, len(buckets))
+			continue
+		}
+
+		for i, bucket := range buckets {
+			if bucket.MinPercentage != test.wantRanges[i][0] || bucket.MaxPercentage != test.wantRanges[i][1] {
+				t.Errorf("%s: expected bucket %d to be %v, got [%g %g]", test.name, i, test.wantRanges[i], bucket.MinPercentage, bucket.MaxPercentage)
+			}
+
+			if bucket.Count != test.wantCounts[i] {
+				t.Errorf("%s: expected %d percentages in bucket %d, got %d", test.name, test.wantCounts[i], i, bucket.Count)
+			}
+		}
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	tests := []struct {
+		name       string
+		values     []float64
+		wantMean   float64
+		wantMedian float64
+		wantStdDev float64
+	}{
+		{name: "no values", values: nil},
+		{name: "single value", values: []float64{42}, wantMean: 42, wantMedian: 42},
+		{name: "odd count", values: []float64{9, 1, 5}, wantMean: 5, wantMedian: 5, wantStdDev: 3.27},
+		{name: "even count", values: []float64{2, 4, 4, 4, 5, 5, 7, 9}, wantMean: 5, wantMedian: 4.5, wantStdDev: 2},
+		{name: "rounded", values: []float64{1, 2}, wantMean: 1.5, wantMedian: 1.5, wantStdDev: 0.5},
+	}
+
+	for _, test := range tests {
+		values := append([]float64(nil), test.values...)
+		mean, median, stdDev := describe(values)
+		if mean != test.wantMean || median != test.wantMedian || stdDev != test.wantStdDev {
+			t.Errorf("%s: expected (%g, %g, %g), got (%g, %g, %g)", test.name,
+				test.wantMean, test.wantMedian, test.wantStdDev, mean, median, stdDev)
+		}
+
+		// The values are sorted in a copy.
+		if !reflect.DeepEqual(values, test.values) && len(test.values) > 0 {
+			t.Errorf("%s: describe changed its argument", test.name)
+		}
+	}
+}
+
+// computingClass moves classID to class.StateComputing, like a queued report
+// job, and returns the class.
+func computingClass(t *testing.T, r *Resolver, classID string) *class.Class {
+	t.Helper()
+
+	err := r.ClassRepository.Transition("school", classID, class.StateOpen, class.StateComputing)
+	if err != nil {
+		t.Fatalf("Transition error: %v", err)
+	}
+
+	classInfo, err := r.ClassRepository.Class("school", classID)
+	if err != nil {
+		t.Fatalf("Class error: %v", err)
+	}
+	return classInfo
+}
+
+func TestComputeClassReportRankingBasis(t *testing.T) {
+	// Ada only scores in the heaviest subject, Bola scores 20 in every subject.
+	subjects := make([]*class.Subject, 10)
+	adaScores := make([]*student.SubjectScore, len(subjects))
+	bolaScores := make([]*student.SubjectScore, len(subjects))
+	for i := range subjects {
+		subjects[i] = &class.Subject{Name: fmt.Sprintf("Subject %d", i+1), MaxScore: 100, Weight: 1}
+		adaScores[i] = &student.SubjectScore{Name: subjects[i].Name}
+		bolaScores[i] = &student.SubjectScore{Name: subjects[i].Name, Score: 20}
+	}
+	subjects[0].Weight = 9
+	adaScores[0].Score = 100
+
+	tests := []struct {
+		rankingBasis string
+		wantFirst    string
+	}{
+		{rankingBasis: class.RankingBasisTotal, wantFirst: "Bola"},
+		{rankingBasis: class.RankingBasisAverage, wantFirst: "Bola"},
+		{rankingBasis: class.RankingBasisWeighted, wantFirst: "Ada"},
+	}
+
+	for _, test := range tests {
+		store := memory.New()
+		r := &Resolver{
+			ClassRepository:   memory.NewClassRepository(store),
+			StudentRepository: memory.NewStudentRepository(store),
+			ReportStore:       memory.NewReportStore(store),
+		}
+
+		classID, err := r.ClassRepository.Create("school", "JSS 1", "teacher", class.ReportSettings{RankingBasis: test.rankingBasis}, subjects)
+		if err != nil {
+			t.Fatalf("Create error: %v", err)
+		}
+
+		studentNames := make(map[string]string)
+		studentsInfo := make(map[string][]*student.SubjectScore)
+		for name, scores := range map[string][]*student.SubjectScore{"Ada": adaScores, "Bola": bolaScores} {
+			studentID, err := r.StudentRepository.Create("school", classID, name, subjects, scores)
+			if err != nil {
+				t.Fatalf("Create error: %v", err)
+			}
+			studentNames[studentID] = name
+			studentsInfo[studentID] = scores
+		}
+
+		classInfo := computingClass(t, r, classID)
+		_, err = r.computeClassReport("school", "teacher", classInfo, grading.DefaultGradingScale(), studentNames, studentsInfo, func(int) {})
+		if err != nil {
+			t.Fatalf("computeClassReport error: %v", err)
+		}
+
+		students, err := r.StudentRepository.Students("school", classID)
+		if err != nil {
+			t.Fatalf("Students error: %v", err)
+		}
+
+		for _, studentInfo := range students {
+			report := studentInfo.Report.Class
+			if (report.Position == 1) != (studentInfo.Name == test.wantFirst) {
+				t.Errorf("%s: expected %s first, %s is at position %d", test.rankingBasis, test.wantFirst, studentInfo.Name, report.Position)
+			}
+
+			if studentInfo.Name == "Ada" && (report.AveragePercentage != 10 || report.WeightedPercentage != 50) {
+				t.Errorf("%s: expected Ada to have an average of 10 and a weighted average of 50, got %g and %g",
+					test.rankingBasis, report.AveragePercentage, report.WeightedPercentage)
+			}
+		}
+	}
+}
+
+func TestComputeClassReportUnscored(t *testing.T) {
+	store := memory.New()
+	r := &Resolver{
+		ClassRepository:   memory.NewClassRepository(store),
+		StudentRepository: memory.NewStudentRepository(store),
+		ReportStore:       memory.NewReportStore(store),
+	}
+
+	subjects := []*class.Subject{{Name: "Maths", MaxScore: 100, Weight: 1}, {Name: "English", MaxScore: 100, Weight: 1}}
+	classID, err := r.ClassRepository.Create("school", "JSS 1", "teacher", class.ReportSettings{}, subjects)
+	if err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+
+	studentNames := make(map[string]string)
+	studentsInfo := make(map[string][]*student.SubjectScore)
+	for name, scores := range map[string][]*student.SubjectScore{
+		"Ada":  {{Name: "Maths", Score: 80}, {Name: "English", Status: student.ScoreStatusAbsent}},
+		"Bola": {{Name: "Maths", Score: 60}, {Name: "English", Score: 60}},
+	} {
+		studentID, err := r.StudentRepository.Create("school", classID, name, subjects, scores)
+		if err != nil {
+			t.Fatalf("Create error: %v", err)
+		}
+		studentNames[studentID] = name
+		studentsInfo[studentID] = scores
+	}
+
+	classInfo := computingClass(t, r, classID)
+	_, err = r.computeClassReport("school", "teacher", classInfo, grading.DefaultGradingScale(), studentNames, studentsInfo, func(int) {})
+	if err != nil {
+		t.Fatalf("computeClassReport error: %v", err)
+	}
+
+	students, err := r.StudentRepository.Students("school", classID)
+	if err != nil {
+		t.Fatalf("Students error: %v", err)
+	}
+
+	for _, studentInfo := range students {
+		if studentInfo.Name != "Ada" {
+			continue
+		}
+
+		report := studentInfo.Report.Class
+		if report.TotalScorePercentage != "80.0" || report.AveragePercentage != 80 {
+			t.Errorf("expected Ada to have 80%% of the scored subjects, got %s%% and an average of %g",
+				report.TotalScorePercentage, report.AveragePercentage)
+		}
+
+		for _, subject := range studentInfo.Report.Subjects {
+			if subject.Name == "English" && (subject.Status != student.ScoreStatusAbsent || subject.Grade != "" || subject.Position != 0) {
+				t.Errorf("expected English to be absent without a grade or position, got %+v", subject)
+			}
+		}
+	}
+}
+
+func TestCheckSubjectScore(t *testing.T) {
+	maths := &class.Subject{Name: "Maths", MaxScore: 100, Components: []*class.SubjectComponent{
+		{Name: "Test", MaxScore: 40},
+		{Name: "Exam", MaxScore: 60},
+	}}
+	english := &class.Subject{Name: "English", MaxScore: 100}
+
+	tests := []struct {
+		name           string
+		subject        *class.Subject
+		score          *student.SubjectScore
+		wantScore      int
+		wantComponents []string
+		wantErr        bool
+	}{
+		{name: "no components", subject: english, score: &student.SubjectScore{Score: 70}, wantScore: 70},
+		{name: "score above max", subject: english, score: &student.SubjectScore{Score: 101}, wantErr: true},
+		{name: "negative score", subject: english, score: &student.SubjectScore{Score: -1}, wantErr: true},
+		{name: "unexpected components", subject: english, score: &student.SubjectScore{Components: []*student.ComponentScore{{Name: "Test", Score: 10}}}, wantErr: true},
+		{
+			name:           "score is the sum of the components",
+			subject:        maths,
+			score:          &student.SubjectScore{Components: []*student.ComponentScore{{Name: "Exam", Score: 50}, {Name: "Test", Score: 30}}},
+			wantScore:      80,
+			wantComponents: []string{"Test", "Exam"},
+		},
+		{
+			name:           "matching score",
+			subject:        maths,
+			score:          &student.SubjectScore{Score: 80, Components: []*student.ComponentScore{{Name: "Test", Score: 30}, {Name: "Exam", Score: 50}}},
+			wantScore:      80,
+			wantComponents: []string{"Test", "Exam"},
+		},
+		{name: "score is not the sum", subject: maths, score: &student.SubjectScore{Score: 70, Components: []*student.ComponentScore{{Name: "Test", Score: 30}, {Name: "Exam", Score: 50}}}, wantErr: true},
+		{name: "missing component", subject: maths, score: &student.SubjectScore{Components: []*student.ComponentScore{{Name: "Test", Score: 30}}}, wantErr: true},
+		{name: "unknown component", subject: maths, score: &student.SubjectScore{Components: []*student.ComponentScore{{Name: "Test", Score: 30}, {Name: "Quiz", Score: 50}}}, wantErr: true},
+		{name: "duplicate component", subject: maths, score: &student.SubjectScore{Components: []*student.ComponentScore{{Name: "Test", Score: 30}, {Name: "Test", Score: 30}}}, wantErr: true},
+		{name: "component above max", subject: maths, score: &student.SubjectScore{Components: []*student.ComponentScore{{Name: "Test", Score: 41}, {Name: "Exam", Score: 50}}}, wantErr: true},
+		{name: "negative component", subject: maths, score: &student.SubjectScore{Components: []*student.ComponentScore{{Name: "Test", Score: -1}, {Name: "Exam", Score: 50}}}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		err := checkSubjectScore(test.subject, test.score)
+		if test.wantErr {
+			if !errors.Is(err, db.ErrorInvalidRequest) {
+				t.Errorf("%s: expected db.ErrorInvalidRequest, got %v", test.name, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+
+		if test.score.Score != test.wantScore {
+			t.Errorf("%s: expected score %d, got %d", test.name, test.wantScore, test.score.Score)
+		}
+
+		var componentNames []string
+		for _, component := range test.score.Components {
+			componentNames = append(componentNames, component.Name)
+		}
+
+		if !reflect.DeepEqual(componentNames, test.wantComponents) {
+			t.Errorf("%s: expected components %v, got %v", test.name, test.wantComponents, componentNames)
+		}
+	}
+}
@@ -8,50 +8,452 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
+	"strings"
+	"time"
 
 	"github.com/ukane-philemon/scomp/graph/model"
+	"github.com/ukane-philemon/scomp/internal/admin"
+	"github.com/ukane-philemon/scomp/internal/auth"
 	"github.com/ukane-philemon/scomp/internal/class"
 	"github.com/ukane-philemon/scomp/internal/db"
 	customerror "github.com/ukane-philemon/scomp/internal/errors"
+	"github.com/ukane-philemon/scomp/internal/grading"
+	"github.com/ukane-philemon/scomp/internal/history"
+	"github.com/ukane-philemon/scomp/internal/job"
+	"github.com/ukane-philemon/scomp/internal/school"
+	"github.com/ukane-philemon/scomp/internal/session"
 	"github.com/ukane-philemon/scomp/internal/student"
+	"github.com/ukane-philemon/scomp/internal/totp"
 )
 
+// GradingScale is the resolver for the gradingScale field.
+func (r *classResolver) GradingScale(ctx context.Context, obj *class.Class) (*grading.GradingScale, error) {
+	gradingScale, err := r.gradingScale(obj.SchoolID, obj.GradingScaleID)
+	if err != nil {
+		return nil, handleError(err)
+	}
+
+	return gradingScale, nil
+}
+
+// RankingMode is the resolver for the rankingMode field.
+func (r *classResolver) RankingMode(ctx context.Context, obj *class.Class) (model.RankingMode, error) {
+	if obj.RankingMode == "" {
+		return model.RankingModeCompetition, nil
+	}
+
+	return model.RankingMode(strings.ToUpper(obj.RankingMode)), nil
+}
+
+// RankingBasis is the resolver for the rankingBasis field.
+func (r *classResolver) RankingBasis(ctx context.Context, obj *class.Class) (model.RankingBasis, error) {
+	if obj.RankingBasis == "" {
+		return model.RankingBasisTotal, nil
+	}
+
+	return model.RankingBasis(strings.ToUpper(obj.RankingBasis)), nil
+}
+
+// State is the resolver for the state field.
+func (r *classResolver) State(ctx context.Context, obj *class.Class) (model.ClassState, error) {
+	return model.ClassState(strings.ToUpper(obj.State)), nil
+}
+
 // CreateAdminAccount is the resolver for the createAdminAccount field.
-func (r *mutationResolver) CreateAdminAccount(ctx context.Context, username string, password string) (string, error) {
-	adminID, err := r.AdminRepository.CreateAccount(username, password)
+func (r *mutationResolver) CreateAdminAccount(ctx context.Context, username string, password string, invitationCode string) (string, error) {
+	err := r.PasswordPolicy.Validate(password)
+	if err != nil {
+		return "", handleError(err)
+	}
+
+	invitation, err := r.InvitationRepository.Redeem(invitationCode)
 	if err != nil {
 		return "", handleError(err)
 	}
 
+	adminID, err := r.AdminRepository.CreateAccount(invitation.SchoolID, username, password, invitation.Role)
+	if err != nil {
+		// Allow the invitation to be used again.
+		if releaseErr := r.InvitationRepository.Release(invitation.ID); releaseErr != nil {
+			log.Printf("SERVER ERROR: InvitationRepository.Release %v", releaseErr)
+		}
+		return "", handleError(err)
+	}
+
 	return adminID, nil
 }
 
+// CreateInvitation is the resolver for the createInvitation field.
+func (r *mutationResolver) CreateInvitation(ctx context.Context, role model.Role, validForHours *int) (*model.Invitation, error) {
+	validFor := defaultInvitationValidity
+	if validForHours != nil {
+		validFor = time.Duration(*validForHours) * time.Hour
+	}
+
+	invitation, code, err := r.InvitationRepository.Create(reqSchoolID(ctx), reqAdminID(ctx), adminRole(role), validFor)
+	if err != nil {
+		return nil, handleError(err)
+	}
+
+	return &model.Invitation{
+		ID:        invitation.ID,
+		Code:      code,
+		Role:      role,
+		ExpiresAt: int(invitation.ExpiresAt),
+	}, nil
+}
+
+// RevokeInvitation is the resolver for the revokeInvitation field.
+func (r *mutationResolver) RevokeInvitation(ctx context.Context, invitationID string) (bool, error) {
+	err := r.InvitationRepository.Revoke(reqSchoolID(ctx), invitationID)
+	if err != nil {
+		return false, handleError(err)
+	}
+
+	return true, nil
+}
+
+// SetAdminRole is the resolver for the setAdminRole field.
+func (r *mutationResolver) SetAdminRole(ctx context.Context, adminID string, role model.Role) (bool, error) {
+	if adminID == reqAdminID(ctx) {
+		return false, fmt.Errorf("%w: you cannot change your own role", db.ErrorInvalidRequest)
+	}
+
+	err := r.AdminRepository.SetRole(reqSchoolID(ctx), adminID, adminRole(role))
+	if err != nil {
+		return false, handleError(err)
+	}
+
+	return true, nil
+}
+
+// CreateAPIKey is the resolver for the createAPIKey field.
+func (r *mutationResolver) CreateAPIKey(ctx context.Context, name string, scope model.APIKeyScope, classIDs []string, validForDays *int) (*model.NewAPIKey, error) {
+	validFor := defaultAPIKeyValidity
+	if validForDays != nil {
+		validFor = time.Duration(*validForDays) * 24 * time.Hour
+	}
+
+	for _, classID := range classIDs {
+		classExists, err := r.ClassRepository.Exists(reqSchoolID(ctx), classID)
+		if err != nil {
+			return nil, handleError(err)
+		}
+
+		if !classExists {
+			return nil, fmt.Errorf("%w: class %s does not exist", db.ErrorInvalidRequest, classID)
+		}
+	}
+
+	apiKey, key, err := r.APIKeyRepository.Create(reqSchoolID(ctx), reqAdminID(ctx), name, strings.ToLower(string(scope)), classIDs, validFor)
+	if err != nil {
+		return nil, handleError(err)
+	}
+
+	return &model.NewAPIKey{
+		Key:    key,
+		APIKey: modelAPIKey(apiKey),
+	}, nil
+}
+
+// RevokeAPIKey is the resolver for the revokeAPIKey field.
+func (r *mutationResolver) RevokeAPIKey(ctx context.Context, apiKeyID string) (bool, error) {
+	err := r.APIKeyRepository.Revoke(reqSchoolID(ctx), apiKeyID)
+	if err != nil {
+		return false, handleError(err)
+	}
+
+	return true, nil
+}
+
+// ChangePassword is the resolver for the changePassword field.
+func (r *mutationResolver) ChangePassword(ctx context.Context, currentPassword string, newPassword string) (bool, error) {
+	err := r.PasswordPolicy.Validate(newPassword)
+	if err != nil {
+		return false, handleError(err)
+	}
+
+	err = r.AdminRepository.ChangePassword(reqAdminID(ctx), currentPassword, newPassword)
+	if err != nil {
+		return false, handleError(err)
+	}
+
+	// End all sessions, including those of whoever knew the old password.
+	_, err = r.SessionRepository.RevokeAll(reqAdminID(ctx))
+	if err != nil {
+		return false, handleError(err)
+	}
+
+	return true, nil
+}
+
+// CreatePasswordReset is the resolver for the createPasswordReset field.
+func (r *mutationResolver) CreatePasswordReset(ctx context.Context, adminID string) (*model.PasswordReset, error) {
+	resetToken, expiresAt, err := r.AdminRepository.CreatePasswordReset(reqSchoolID(ctx), adminID)
+	if err != nil {
+		return nil, handleError(err)
+	}
+
+	return &model.PasswordReset{
+		ResetToken: resetToken,
+		ExpiresAt:  int(expiresAt),
+	}, nil
+}
+
+// ResetPassword is the resolver for the resetPassword field.
+func (r *mutationResolver) ResetPassword(ctx context.Context, resetToken string, newPassword string) (bool, error) {
+	err := r.PasswordPolicy.Validate(newPassword)
+	if err != nil {
+		return false, handleError(err)
+	}
+
+	adminID, err := r.AdminRepository.ResetPassword(resetToken, newPassword)
+	if err != nil {
+		return false, handleError(err)
+	}
+
+	_, err = r.SessionRepository.RevokeAll(adminID)
+	if err != nil {
+		return false, handleError(err)
+	}
+
+	return true, nil
+}
+
 // Login is the resolver for the login field.
 func (r *mutationResolver) Login(ctx context.Context, username string, password string) (*model.AuthenticatedAdmin, error) {
-	adminID, err := r.AdminRepository.LoginAccount(username, password)
+	adminInfo, err := r.AdminRepository.LoginAccount(username, password)
 	if err != nil {
 		return nil, handleError(err)
 	}
 
-	authToken, err := r.AuthenticationRepository.GenerateToken(adminID)
+	// The session is only created after the second factor is verified.
+	if adminInfo.TOTPEnabled {
+		challengeToken, err := r.AuthenticationRepository.GenerateChallengeToken(adminInfo.ID, adminInfo.TwoFactorNonce())
+		if err != nil {
+			return nil, handleError(err)
+		}
+
+		return &model.AuthenticatedAdmin{
+			ID:       adminInfo.ID,
+			Username: adminInfo.Username,
+			SchoolID: adminInfo.SchoolID,
+			Role:     modelRole(adminInfo.Role),
+			TwoFactorChallenge: &model.TwoFactorChallenge{
+				ChallengeToken: challengeToken,
+				ExpiresAt:      int(time.Now().Add(auth.ChallengeExpiry).Unix()),
+			},
+		}, nil
+	}
+
+	sessionInfo, refreshToken, err := r.SessionRepository.Create(adminInfo.ID, reqClientInfo(ctx))
 	if err != nil {
 		return nil, handleError(err)
 	}
 
-	return &model.AuthenticatedAdmin{
-		ID:        adminID,
-		Username:  username,
-		AuthToken: authToken,
+	return r.authenticatedAdmin(adminInfo, sessionInfo.ID, refreshToken)
+}
+
+// VerifyTwoFactor is the resolver for the verifyTwoFactor field.
+func (r *mutationResolver) VerifyTwoFactor(ctx context.Context, challengeToken string, code string) (*model.AuthenticatedAdmin, error) {
+	errInvalidChallenge := fmt.Errorf("%w: two-factor challenge is invalid or has expired, login again", db.ErrorInvalidRequest)
+	adminID, nonce, ok := r.AuthenticationRepository.IsValidChallenge(challengeToken)
+	if !ok {
+		return nil, errInvalidChallenge
+	}
+
+	// A challenge cannot be used again once a two-factor code of the admin
+	// was accepted.
+	adminInfo, err := r.AdminRepository.Admin(adminID)
+	if err != nil {
+		return nil, handleError(err)
+	}
+
+	if adminInfo.TwoFactorNonce() != nonce {
+		return nil, errInvalidChallenge
+	}
+
+	adminInfo, err = r.AdminRepository.VerifyTwoFactor(adminID, code)
+	if err != nil {
+		return nil, handleError(err)
+	}
+
+	sessionInfo, refreshToken, err := r.SessionRepository.Create(adminInfo.ID, reqClientInfo(ctx))
+	if err != nil {
+		return nil, handleError(err)
+	}
+
+	return r.authenticatedAdmin(adminInfo, sessionInfo.ID, refreshToken)
+}
+
+// EnrollTotp is the resolver for the enrollTOTP field.
+func (r *mutationResolver) EnrollTotp(ctx context.Context) (*model.TOTPEnrollment, error) {
+	adminInfo, err := r.AdminRepository.Admin(reqAdminID(ctx))
+	if err != nil {
+		return nil, handleError(err)
+	}
+
+	secret, err := r.AdminRepository.EnrollTOTP(adminInfo.ID)
+	if err != nil {
+		return nil, handleError(err)
+	}
+
+	return &model.TOTPEnrollment{
+		Secret:     secret,
+		OtpauthURI: totp.URI(totpIssuer, adminInfo.Username, secret),
 	}, nil
 }
 
+// ConfirmTotp is the resolver for the confirmTOTP field.
+func (r *mutationResolver) ConfirmTotp(ctx context.Context, code string) ([]string, error) {
+	recoveryCodes, err := r.AdminRepository.ConfirmTOTP(reqAdminID(ctx), code)
+	if err != nil {
+		return nil, handleError(err)
+	}
+
+	return recoveryCodes, nil
+}
+
+// DisableTotp is the resolver for the disableTOTP field.
+func (r *mutationResolver) DisableTotp(ctx context.Context, code string) (bool, error) {
+	err := r.AdminRepository.DisableTOTP(reqAdminID(ctx), code)
+	if err != nil {
+		return false, handleError(err)
+	}
+
+	return true, nil
+}
+
+// ResetTwoFactor is the resolver for the resetTwoFactor field.
+func (r *mutationResolver) ResetTwoFactor(ctx context.Context, adminID string) (bool, error) {
+	err := r.AdminRepository.ResetTOTP(reqSchoolID(ctx), adminID)
+	if err != nil {
+		return false, handleError(err)
+	}
+
+	return true, nil
+}
+
+// RefreshToken is the resolver for the refreshToken field.
+func (r *mutationResolver) RefreshToken(ctx context.Context, refreshToken string) (*model.AuthenticatedAdmin, error) {
+	sessionInfo, newRefreshToken, err := r.SessionRepository.Refresh(refreshToken)
+	if err != nil {
+		return nil, handleError(err)
+	}
+
+	adminInfo, err := r.AdminRepository.Admin(sessionInfo.AdminID)
+	if err != nil {
+		return nil, handleError(err)
+	}
+
+	return r.authenticatedAdmin(adminInfo, sessionInfo.ID, newRefreshToken)
+}
+
+// Logout is the resolver for the logout field.
+func (r *mutationResolver) Logout(ctx context.Context) (bool, error) {
+	err := r.SessionRepository.Revoke(reqAdminID(ctx), reqSessionID(ctx))
+	if err != nil {
+		return false, handleError(err)
+	}
+
+	return true, nil
+}
+
+// LogoutAllSessions is the resolver for the logoutAllSessions field.
+func (r *mutationResolver) LogoutAllSessions(ctx context.Context) (bool, error) {
+	_, err := r.SessionRepository.RevokeAll(reqAdminID(ctx))
+	if err != nil {
+		return false, handleError(err)
+	}
+
+	return true, nil
+}
+
+// CreateGradingScale is the resolver for the createGradingScale field.
+func (r *mutationResolver) CreateGradingScale(ctx context.Context, name string, bands []*grading.GradeBand) (*grading.GradingScale, error) {
+	gradingScale, err := r.GradingScaleRepository.Create(reqSchoolID(ctx), name, bands)
+	if err != nil {
+		return nil, handleError(err)
+	}
+
+	return gradingScale, nil
+}
+
+// UpdateGradingScale is the resolver for the updateGradingScale field.
+func (r *mutationResolver) UpdateGradingScale(ctx context.Context, gradingScaleID string, name string, bands []*grading.GradeBand) (*grading.GradingScale, error) {
+	gradingScale, err := r.GradingScaleRepository.Update(reqSchoolID(ctx), gradingScaleID, name, bands)
+	if err != nil {
+		return nil, handleError(err)
+	}
+
+	return gradingScale, nil
+}
+
+// DeleteGradingScale is the resolver for the deleteGradingScale field.
+func (r *mutationResolver) DeleteGradingScale(ctx context.Context, gradingScaleID string) (bool, error) {
+	classes, err := r.ClassRepository.Classes(reqSchoolID(ctx), nil)
+	if err != nil {
+		return false, handleError(err)
+	}
+
+	for _, class := range classes {
+		if class.GradingScaleID == gradingScaleID {
+			return false, fmt.Errorf("%w: grading scale is used by class %s", db.ErrorInvalidRequest, class.Name)
+		}
+	}
+
+	err = r.GradingScaleRepository.Delete(reqSchoolID(ctx), gradingScaleID)
+	if err != nil {
+		return false, handleError(err)
+	}
+
+	return true, nil
+}
+
 // CreateClass is the resolver for the createClass field.
-func (r *mutationResolver) CreateClass(ctx context.Context, className string, subjects []*class.Subject) (string, error) {
-	if !reqAuthenticated(ctx) {
-		return "", &customerror.ErrorUnauthorized{}
+func (r *mutationResolver) CreateClass(ctx context.Context, className string, subjects []*class.Subject, teacherID *string, gradingScaleID *string, rankingMode *model.RankingMode, rankingBasis *model.RankingBasis) (string, error) {
+	// An API key restricted to some classes cannot access new classes.
+	if apiKey := reqAPIKey(ctx); apiKey != nil && len(apiKey.ClassIDs) > 0 {
+		return "", &customerror.ErrorForbidden{}
+	}
+
+	var classTeacherID string
+	if reqRole(ctx) == admin.RoleTeacher {
+		if teacherID != nil && *teacherID != reqAdminID(ctx) {
+			return "", &customerror.ErrorForbidden{}
+		}
+		classTeacherID = reqAdminID(ctx)
+	} else if teacherID != nil && *teacherID != "" {
+		teacher, err := r.AdminRepository.Admin(*teacherID)
+		if err != nil {
+			return "", handleError(err)
+		}
+
+		if teacher.SchoolID != reqSchoolID(ctx) || teacher.Role != admin.RoleTeacher {
+			return "", fmt.Errorf("%w: admin %s is not a teacher", db.ErrorInvalidRequest, *teacherID)
+		}
+		classTeacherID = teacher.ID
+	}
+
+	var settings class.ReportSettings
+	if gradingScaleID != nil && *gradingScaleID != "" && *gradingScaleID != grading.DefaultGradingScaleID {
+		gradingScale, err := r.GradingScaleRepository.GradingScale(reqSchoolID(ctx), *gradingScaleID)
+		if err != nil {
+			return "", handleError(err)
+		}
+		settings.GradingScaleID = gradingScale.ID
+	}
+
+	if rankingMode != nil {
+		settings.RankingMode = strings.ToLower(string(*rankingMode))
+	}
+
+	if rankingBasis != nil {
+		settings.RankingBasis = strings.ToLower(string(*rankingBasis))
 	}
 
-	classID, err := r.ClassRepository.Create(className, subjects)
+	classID, err := r.ClassRepository.Create(reqSchoolID(ctx), className, classTeacherID, settings, subjects)
 	if err != nil {
 		return "", handleError(err)
 	}
@@ -61,96 +463,286 @@ func (r *mutationResolver) CreateClass(ctx context.Context, className string, su
 
 // AddStudentRecord is the resolver for the addStudentRecord field.
 func (r *mutationResolver) AddStudentRecord(ctx context.Context, classID string, studentName string, subjectScores []*student.SubjectScore) (string, error) {
-	if !reqAuthenticated(ctx) {
-		return "", &customerror.ErrorUnauthorized{}
+	// Ensure classID is valid.
+	classInfo, err := r.studentsClass(ctx, classID)
+	if err != nil {
+		return "", err
 	}
 
-	// Ensure classID is valid.
-	class, err := r.ClassRepository.Class(classID)
+	err = class.CheckCanChangeStudents(classInfo.State)
+	if err != nil {
+		return "", err
+	}
+
+	err = checkSubjectScores(classInfo, subjectScores)
+	if err != nil {
+		return "", err
+	}
+
+	// Create student, the class must still be open.
+	studentID, err := r.StudentRepository.Create(reqSchoolID(ctx), classID, studentName, classInfo.Subjects, subjectScores)
 	if err != nil {
 		return "", handleError(err)
 	}
 
-	if len(subjectScores) != len(class.Subjects) {
-		return "", fmt.Errorf("%w: %d class subjects are required to save a student's record", db.ErrorInvalidRequest, len(class.Subjects))
+	r.publishStudentUpdate(classID, model.ClassUpdateStudentAdded, studentID, studentName)
+
+	return studentID, nil
+}
+
+// UpdateStudentScores is the resolver for the updateStudentScores field.
+func (r *mutationResolver) UpdateStudentScores(ctx context.Context, classID string, studentID string, subjectScores []*student.SubjectScore) (bool, error) {
+	classInfo, err := r.studentsClass(ctx, classID)
+	if err != nil {
+		return false, err
 	}
 
-	if class.Report != nil {
-		return "", fmt.Errorf("%w: class already has a report, new students cannot be added", db.ErrorInvalidRequest)
+	err = class.CheckCanChangeStudents(classInfo.State)
+	if err != nil {
+		return false, err
 	}
 
-	subjectMaxScores := make(map[string]int, len(class.Subjects))
-	for _, subject := range class.Subjects {
-		subjectMaxScores[subject.Name] = subject.MaxScore
+	studentInfo, err := r.StudentRepository.Student(reqSchoolID(ctx), classID, studentID)
+	if err != nil {
+		return false, handleError(err)
 	}
 
-	for _, subject := range subjectScores {
-		maxSubjectScore, found := subjectMaxScores[subject.Name]
-		if !found {
-			return "", fmt.Errorf("%w: subject name %s does not exist, check spelling as subject names are case sensitive",
-				db.ErrorInvalidRequest, subject.Name)
-		}
+	err = checkSubjectScores(classInfo, subjectScores)
+	if err != nil {
+		return false, err
+	}
 
-		if subject.Score > maxSubjectScore || subject.Score < 0 {
-			return "", fmt.Errorf("%w: invalid student score (%d) for subject %s (maximum score is %d)",
-				db.ErrorInvalidRequest, subject.Score, subject.Name, maxSubjectScore)
-		}
+	err = r.StudentRepository.UpdateScores(reqSchoolID(ctx), classID, studentID, classInfo.Subjects, subjectScores)
+	if err != nil {
+		return false, handleError(err)
+	}
+
+	r.publishStudentUpdate(classID, model.ClassUpdateStudentUpdated, studentID, studentInfo.Name)
+
+	return true, nil
+}
+
+// RenameStudent is the resolver for the renameStudent field.
+func (r *mutationResolver) RenameStudent(ctx context.Context, classID string, studentID string, studentName string) (bool, error) {
+	classInfo, err := r.studentsClass(ctx, classID)
+	if err != nil {
+		return false, err
 	}
 
-	// Create student.
-	studentID, err := r.StudentRepository.Create(classID, studentName, subjectScores)
+	err = class.CheckCanChangeStudents(classInfo.State)
 	if err != nil {
-		return "", err
+		return false, err
 	}
 
-	return studentID, nil
+	err = r.StudentRepository.Rename(reqSchoolID(ctx), classID, studentID, studentName)
+	if err != nil {
+		return false, handleError(err)
+	}
+
+	// Reports list students by name.
+	r.publishStudentUpdate(classID, model.ClassUpdateStudentRenamed, studentID, studentName)
+
+	return true, nil
 }
 
-// ComputeClassReport is the resolver for the computeClassReport field.
-func (r *mutationResolver) ComputeClassReport(ctx context.Context, classID string) (string, error) {
-	if !reqAuthenticated(ctx) {
-		return "", &customerror.ErrorUnauthorized{}
+// RemoveStudent is the resolver for the removeStudent field.
+func (r *mutationResolver) RemoveStudent(ctx context.Context, classID string, studentID string) (bool, error) {
+	classInfo, err := r.studentsClass(ctx, classID)
+	if err != nil {
+		return false, err
 	}
 
-	class, err := r.ClassRepository.Class(classID)
+	err = class.CheckCanChangeStudents(classInfo.State)
 	if err != nil {
-		return "", handleError(err)
+		return false, err
 	}
 
-	// Retrieve student record for this class.
-	studentScores, err := r.StudentRepository.StudentScores(classID)
+	studentInfo, err := r.StudentRepository.Student(reqSchoolID(ctx), classID, studentID)
 	if err != nil {
-		return "", handleError(err)
+		return false, handleError(err)
+	}
+
+	err = r.StudentRepository.Remove(reqSchoolID(ctx), classID, studentID)
+	if err != nil {
+		return false, handleError(err)
+	}
+
+	r.publishStudentUpdate(classID, model.ClassUpdateStudentRemoved, studentID, studentInfo.Name)
+
+	return true, nil
+}
+
+// MoveStudentToClass is the resolver for the moveStudentToClass field.
+func (r *mutationResolver) MoveStudentToClass(ctx context.Context, classID string, studentID string, toClassID string) (bool, error) {
+	if classID == toClassID {
+		return false, fmt.Errorf("%w: student is already in class %s", db.ErrorInvalidRequest, toClassID)
 	}
 
-	const minStudentScores = 2
-	if len(studentScores) < minStudentScores {
-		return "", fmt.Errorf("add at least %d students to this class before generating a report", minStudentScores)
+	classInfo, err := r.studentsClass(ctx, classID)
+	if err != nil {
+		return false, err
+	}
+
+	toClassInfo, err := r.studentsClass(ctx, toClassID)
+	if err != nil {
+		return false, err
+	}
+
+	err = class.CheckCanChangeStudents(classInfo.State)
+	if err != nil {
+		return false, err
+	}
+
+	err = class.CheckCanChangeStudents(toClassInfo.State)
+	if err != nil {
+		return false, err
+	}
+
+	studentInfo, err := r.StudentRepository.Student(reqSchoolID(ctx), classID, studentID)
+	if err != nil {
+		return false, handleError(err)
+	}
+
+	studentScores, err := r.StudentRepository.StudentScores(reqSchoolID(ctx), classID)
+	if err != nil {
+		return false, handleError(err)
+	}
+
+	// The scores of the student are checked against the subjects of the new
+	// class.
+	subjectScores := studentScores[studentID]
+	err = checkSubjectScores(toClassInfo, subjectScores)
+	if err != nil {
+		return false, err
+	}
+
+	err = r.StudentRepository.Move(reqSchoolID(ctx), classID, studentID, toClassID, toClassInfo.Subjects, subjectScores)
+	if err != nil {
+		return false, handleError(err)
+	}
+
+	r.publishStudentUpdate(classID, model.ClassUpdateStudentRemoved, studentID, studentInfo.Name)
+	r.publishStudentUpdate(toClassID, model.ClassUpdateStudentAdded, studentID, studentInfo.Name)
+
+	return true, nil
+}
+
+// ComputeClassReport is the resolver for the computeClassReport field.
+func (r *mutationResolver) ComputeClassReport(ctx context.Context, classID string) (*job.Job, error) {
+	if !reqCanAccessClass(ctx, classID) {
+		return nil, &customerror.ErrorForbidden{}
+	}
+
+	classInfo, err := r.ClassRepository.Class(reqSchoolID(ctx), classID)
+	if err != nil {
+		return nil, handleError(err)
+	}
+
+	// Check that the class has enough students now, the job computes the
+	// report with the students of the class when it runs.
+	_, _, err = r.reportStudents(reqSchoolID(ctx), classID)
+	if err != nil {
+		return nil, handleError(err)
 	}
 
 	// Compute asynchronously as this task may take some time.
-	r.wg.Add(1)
-	go func() {
-		defer r.wg.Done()
-		r.computeClassReport(classID, class.Subjects, studentScores)
-	}()
+	reportJob, err := r.queueComputeClassReport(classInfo, reqAdminID(ctx))
+	if err != nil {
+		return nil, handleError(err)
+	}
+
+	r.publishReportStatus(reportJob, &model.ReportStatusEvent{Status: model.ReportStatusQueued})
+	r.notifyJobQueued()
+
+	return reportJob, nil
+}
+
+// SetCurrentReportVersion is the resolver for the setCurrentReportVersion field.
+func (r *mutationResolver) SetCurrentReportVersion(ctx context.Context, classID string, version int) (bool, error) {
+	if !reqCanAccessClass(ctx, classID) {
+		return false, &customerror.ErrorForbidden{}
+	}
+
+	classInfo, err := r.ClassRepository.Class(reqSchoolID(ctx), classID)
+	if err != nil {
+		return false, handleError(err)
+	}
+
+	if classInfo.State == class.StateComputing {
+		return false, fmt.Errorf("%w: class report is being computed, try again once it is done", db.ErrorInvalidRequest)
+	}
+
+	if classInfo.ReportStale {
+		return false, fmt.Errorf("%w: students of the class changed since its report was computed, compute the report again", db.ErrorInvalidRequest)
+	}
+
+	if classInfo.CurrentReportVersion == version {
+		return false, fmt.Errorf("%w: report version %d is already the current report of the class", db.ErrorInvalidRequest, version)
+	}
+
+	reportVersion, err := r.ReportVersionRepository.ReportVersion(reqSchoolID(ctx), classID, version)
+	if err != nil {
+		return false, handleError(err)
+	}
+
+	if reportVersion.StudentsRevision != classInfo.StudentsRevision {
+		return false, fmt.Errorf("%w: the students of the class changed since report version %d was computed, compute the report again", db.ErrorInvalidRequest, version)
+	}
+
+	// Report versions of other students cannot be restored, versions saved
+	// before students revisions were recorded all have revision 0.
+	students, err := r.StudentRepository.Students(reqSchoolID(ctx), classID)
+	if err != nil {
+		return false, handleError(err)
+	}
+
+	versionStudents := make(map[string]bool, len(reportVersion.Students))
+	for _, studentReport := range reportVersion.Students {
+		versionStudents[studentReport.StudentID] = true
+	}
+
+	sameStudents := len(students) == len(versionStudents)
+	for _, studentInfo := range students {
+		sameStudents = sameStudents && versionStudents[studentInfo.ID]
+	}
+
+	if !sameStudents {
+		return false, fmt.Errorf("%w: report version %d was computed with other students, compute the report again", db.ErrorInvalidRequest, version)
+	}
+
+	// Fails if the report of the class started computing or its students
+	// changed in between.
+	err = r.ReportStore.RestoreReports(reqSchoolID(ctx), classInfo.State, reportVersion)
+	if err != nil {
+		return false, handleError(err)
+	}
 
-	return "Class report is being generated, check back in a few minutes", nil
+	return true, nil
+}
+
+// School is the resolver for the school field.
+func (r *queryResolver) School(ctx context.Context) (*school.School, error) {
+	school, err := r.SchoolRepository.School(reqSchoolID(ctx))
+	if err != nil {
+		return nil, handleError(err)
+	}
+
+	return school, nil
 }
 
 // ClassInfo is the resolver for the classInfo field.
 func (r *queryResolver) ClassInfo(ctx context.Context, classID string) (*model.CompleteClassInfo, error) {
-	if !reqAuthenticated(ctx) {
-		return nil, &customerror.ErrorUnauthorized{}
+	if !reqCanAccessClass(ctx, classID) {
+		return nil, &customerror.ErrorForbidden{}
 	}
 
-	class, err := r.ClassRepository.Class(classID)
+	class, err := r.ClassRepository.Class(reqSchoolID(ctx), classID)
 	if err != nil {
 		return nil, handleError(err)
 	}
 
 	// Retrieve student record for this class.
-	classStudents, err := r.StudentRepository.Students(classID)
+	classStudents, err := r.StudentRepository.Students(reqSchoolID(ctx), classID)
 	if err != nil {
 		return nil, handleError(err)
 	}
@@ -161,21 +753,80 @@ func (r *queryResolver) ClassInfo(ctx context.Context, classID string) (*model.C
 	}, nil
 }
 
-// Classes is the resolver for the classes field.
-func (r *queryResolver) Classes(ctx context.Context, hasReport *bool) ([]*model.CompleteClassInfo, error) {
-	if !reqAuthenticated(ctx) {
-		return nil, &customerror.ErrorUnauthorized{}
+// ClassDistribution is the resolver for the classDistribution field.
+func (r *queryResolver) ClassDistribution(ctx context.Context, classID string, subject *string, buckets int) (*model.ScoreDistribution, error) {
+	if !reqCanAccessClass(ctx, classID) {
+		return nil, &customerror.ErrorForbidden{}
 	}
 
-	classes, err := r.ClassRepository.Classes(hasReport)
+	if buckets < 1 || buckets > maxDistributionBuckets {
+		return nil, fmt.Errorf("%w: buckets must be between 1 and %d", db.ErrorInvalidRequest, maxDistributionBuckets)
+	}
+
+	class, err := r.ClassRepository.Class(reqSchoolID(ctx), classID)
+	if err != nil {
+		return nil, handleError(err)
+	}
+
+	if class.Report == nil {
+		return nil, fmt.Errorf("%w: class has no report yet", db.ErrorInvalidRequest)
+	}
+
+	var maxScore int
+	if subject != nil {
+		classSubject, found := class.Subject(*subject)
+		if !found {
+			return nil, fmt.Errorf("%w: subject name %s does not exist, check spelling as subject names are case sensitive",
+				db.ErrorInvalidRequest, *subject)
+		}
+		maxScore = classSubject.MaxScore
+	}
+
+	classStudents, err := r.StudentRepository.Students(reqSchoolID(ctx), classID)
+	if err != nil {
+		return nil, handleError(err)
+	}
+
+	var percentages []float64
+	for _, student := range classStudents {
+		if student.Report == nil || student.Report.Class == nil {
+			continue
+		}
+
+		if subject == nil {
+			percentages = append(percentages, student.Report.Class.Percentage)
+			continue
+		}
+
+		for _, subjectReport := range student.Report.Subjects {
+			if subjectReport.Name == *subject && subjectReport.Scored() {
+				percentages = append(percentages, percentage(float64(subjectReport.Score), float64(maxScore)))
+			}
+		}
+	}
+
+	return &model.ScoreDistribution{
+		Subject:       subject,
+		TotalStudents: len(percentages),
+		Buckets:       histogram(percentages, buckets),
+	}, nil
+}
+
+// Classes is the resolver for the classes field.
+func (r *queryResolver) Classes(ctx context.Context, hasReport *bool) ([]*model.CompleteClassInfo, error) {
+	classes, err := r.ClassRepository.Classes(reqSchoolID(ctx), hasReport)
 	if err != nil {
 		return nil, handleError(err)
 	}
 
 	var completeClassInfo []*model.CompleteClassInfo
 	for _, class := range classes {
+		if !reqCanAccessClass(ctx, class.ID) {
+			continue
+		}
+
 		// Retrieve student record for this class.
-		classStudents, err := r.StudentRepository.Students(class.ID)
+		classStudents, err := r.StudentRepository.Students(reqSchoolID(ctx), class.ID)
 		if err != nil {
 			return nil, handleError(err)
 		}
@@ -189,13 +840,83 @@ func (r *queryResolver) Classes(ctx context.Context, hasReport *bool) ([]*model.
 	return completeClassInfo, err
 }
 
+// ReportHistory is the resolver for the reportHistory field.
+func (r *queryResolver) ReportHistory(ctx context.Context, classID string) ([]*history.ReportVersion, error) {
+	if !reqCanAccessClass(ctx, classID) {
+		return nil, &customerror.ErrorForbidden{}
+	}
+
+	classExists, err := r.ClassRepository.Exists(reqSchoolID(ctx), classID)
+	if err != nil {
+		return nil, handleError(err)
+	}
+
+	if !classExists {
+		return nil, errors.New("class does not exist")
+	}
+
+	reportVersions, err := r.ReportVersionRepository.ReportVersions(reqSchoolID(ctx), classID)
+	if err != nil {
+		return nil, handleError(err)
+	}
+
+	return reportVersions, nil
+}
+
+// ReportVersion is the resolver for the reportVersion field.
+func (r *queryResolver) ReportVersion(ctx context.Context, classID string, version int) (*history.ReportVersion, error) {
+	if !reqCanAccessClass(ctx, classID) {
+		return nil, &customerror.ErrorForbidden{}
+	}
+
+	reportVersion, err := r.ReportVersionRepository.ReportVersion(reqSchoolID(ctx), classID, version)
+	if err != nil {
+		return nil, handleError(err)
+	}
+
+	return reportVersion, nil
+}
+
+// ReportJob is the resolver for the reportJob field.
+func (r *queryResolver) ReportJob(ctx context.Context, jobID string) (*job.Job, error) {
+	reportJob, err := r.JobRepository.Job(reqSchoolID(ctx), jobID)
+	if err != nil {
+		return nil, handleError(err)
+	}
+
+	if !reqCanAccessClass(ctx, reportJob.ClassID) {
+		return nil, &customerror.ErrorForbidden{}
+	}
+
+	return reportJob, nil
+}
+
+// ReportDiff is the resolver for the reportDiff field.
+func (r *queryResolver) ReportDiff(ctx context.Context, classID string, fromVersion int, toVersion int) (*history.ReportDiff, error) {
+	if !reqCanAccessClass(ctx, classID) {
+		return nil, &customerror.ErrorForbidden{}
+	}
+
+	from, err := r.ReportVersionRepository.ReportVersion(reqSchoolID(ctx), classID, fromVersion)
+	if err != nil {
+		return nil, handleError(err)
+	}
+
+	to, err := r.ReportVersionRepository.ReportVersion(reqSchoolID(ctx), classID, toVersion)
+	if err != nil {
+		return nil, handleError(err)
+	}
+
+	return history.Diff(from, to), nil
+}
+
 // Student is the resolver for the student field.
 func (r *queryResolver) Student(ctx context.Context, classID string, studentID string) (*student.Student, error) {
-	if !reqAuthenticated(ctx) {
-		return nil, &customerror.ErrorUnauthorized{}
+	if !reqCanAccessClass(ctx, classID) {
+		return nil, &customerror.ErrorForbidden{}
 	}
 
-	student, err := r.StudentRepository.Student(classID, studentID)
+	student, err := r.StudentRepository.Student(reqSchoolID(ctx), classID, studentID)
 	if err != nil {
 		return nil, handleError(err)
 	}
@@ -205,11 +926,11 @@ func (r *queryResolver) Student(ctx context.Context, classID string, studentID s
 
 // Students is the resolver for the students field.
 func (r *queryResolver) Students(ctx context.Context, classID string) ([]*student.Student, error) {
-	if !reqAuthenticated(ctx) {
-		return nil, &customerror.ErrorUnauthorized{}
+	if !reqCanAccessClass(ctx, classID) {
+		return nil, &customerror.ErrorForbidden{}
 	}
 
-	classExists, err := r.ClassRepository.Exists(classID)
+	classExists, err := r.ClassRepository.Exists(reqSchoolID(ctx), classID)
 	if err != nil {
 		return nil, handleError(err)
 	}
@@ -219,7 +940,7 @@ func (r *queryResolver) Students(ctx context.Context, classID string) ([]*studen
 	}
 
 	// Retrieve student record for this class.
-	classStudents, err := r.StudentRepository.Students(classID)
+	classStudents, err := r.StudentRepository.Students(reqSchoolID(ctx), classID)
 	if err != nil {
 		return nil, handleError(err)
 	}
@@ -227,11 +948,129 @@ func (r *queryResolver) Students(ctx context.Context, classID string) ([]*studen
 	return classStudents, nil
 }
 
+// Sessions is the resolver for the sessions field.
+func (r *queryResolver) Sessions(ctx context.Context) ([]*session.Session, error) {
+	sessions, err := r.SessionRepository.Sessions(reqAdminID(ctx))
+	if err != nil {
+		return nil, handleError(err)
+	}
+
+	return sessions, nil
+}
+
+// GradingScales is the resolver for the gradingScales field.
+func (r *queryResolver) GradingScales(ctx context.Context) ([]*grading.GradingScale, error) {
+	gradingScales, err := r.GradingScaleRepository.GradingScales(reqSchoolID(ctx))
+	if err != nil {
+		return nil, handleError(err)
+	}
+
+	return gradingScales, nil
+}
+
+// APIKeys is the resolver for the apiKeys field.
+func (r *queryResolver) APIKeys(ctx context.Context) ([]*model.APIKey, error) {
+	apiKeys, err := r.APIKeyRepository.APIKeys(reqSchoolID(ctx))
+	if err != nil {
+		return nil, handleError(err)
+	}
+
+	modelAPIKeys := make([]*model.APIKey, 0, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		modelAPIKeys = append(modelAPIKeys, modelAPIKey(apiKey))
+	}
+
+	return modelAPIKeys, nil
+}
+
+// State is the resolver for the state field.
+func (r *reportJobResolver) State(ctx context.Context, obj *job.Job) (model.JobState, error) {
+	return model.JobState(strings.ToUpper(obj.State)), nil
+}
+
+// Current is the resolver for the current field.
+func (r *sessionResolver) Current(ctx context.Context, obj *session.Session) (bool, error) {
+	return obj.ID == reqSessionID(ctx), nil
+}
+
+// Status is the resolver for the status field.
+func (r *subjectReportResolver) Status(ctx context.Context, obj *student.SubjectReport) (model.ScoreStatus, error) {
+	return modelScoreStatus(obj.Status), nil
+}
+
+// ReportStatus is the resolver for the reportStatus field.
+func (r *subscriptionResolver) ReportStatus(ctx context.Context, classID string) (<-chan *model.ReportStatusEvent, error) {
+	if !reqCanAccessClass(ctx, classID) {
+		return nil, &customerror.ErrorForbidden{}
+	}
+
+	classExists, err := r.ClassRepository.Exists(reqSchoolID(ctx), classID)
+	if err != nil {
+		return nil, handleError(err)
+	}
+
+	if !classExists {
+		return nil, errors.New("class does not exist")
+	}
+
+	return r.reportEvents.subscribe(ctx, classID), nil
+}
+
+// ClassUpdated is the resolver for the classUpdated field.
+func (r *subscriptionResolver) ClassUpdated(ctx context.Context, classID string) (<-chan *model.ClassUpdatedEvent, error) {
+	if !reqCanAccessClass(ctx, classID) {
+		return nil, &customerror.ErrorForbidden{}
+	}
+
+	classExists, err := r.ClassRepository.Exists(reqSchoolID(ctx), classID)
+	if err != nil {
+		return nil, handleError(err)
+	}
+
+	if !classExists {
+		return nil, errors.New("class does not exist")
+	}
+
+	return r.classEvents.subscribe(ctx, classID), nil
+}
+
+// Status is the resolver for the status field.
+func (r *subjectScoreResolver) Status(ctx context.Context, obj *student.SubjectScore, data *model.ScoreStatus) error {
+	if data != nil {
+		obj.Status = scoreStatus(*data)
+	}
+	return nil
+}
+
+// Class returns ClassResolver implementation.
+func (r *Resolver) Class() ClassResolver { return &classResolver{r} }
+
 // Mutation returns MutationResolver implementation.
 func (r *Resolver) Mutation() MutationResolver { return &mutationResolver{r} }
 
 // Query returns QueryResolver implementation.
 func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
 
+// ReportJob returns ReportJobResolver implementation.
+func (r *Resolver) ReportJob() ReportJobResolver { return &reportJobResolver{r} }
+
+// Session returns SessionResolver implementation.
+func (r *Resolver) Session() SessionResolver { return &sessionResolver{r} }
+
+// SubjectReport returns SubjectReportResolver implementation.
+func (r *Resolver) SubjectReport() SubjectReportResolver { return &subjectReportResolver{r} }
+
+// Subscription returns SubscriptionResolver implementation.
+func (r *Resolver) Subscription() SubscriptionResolver { return &subscriptionResolver{r} }
+
+// SubjectScore returns SubjectScoreResolver implementation.
+func (r *Resolver) SubjectScore() SubjectScoreResolver { return &subjectScoreResolver{r} }
+
+type classResolver struct{ *Resolver }
 type mutationResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
+type reportJobResolver struct{ *Resolver }
+type sessionResolver struct{ *Resolver }
+type subjectReportResolver struct{ *Resolver }
+type subscriptionResolver struct{ *Resolver }
+type subjectScoreResolver struct{ *Resolver }
@@ -0,0 +1,253 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ukane-philemon/scomp/internal/admin"
+	"github.com/ukane-philemon/scomp/internal/class"
+	"github.com/ukane-philemon/scomp/internal/db"
+	"github.com/ukane-philemon/scomp/internal/job"
+	"github.com/ukane-philemon/scomp/internal/memory"
+	"github.com/ukane-philemon/scomp/internal/student"
+)
+
+const testSchoolID = "school"
+
+// newTestResolver returns a Resolver backed by the in-memory storage and the
+// context of a request by the owner of testSchoolID.
+func newTestResolver() (*Resolver, context.Context) {
+	store := memory.New()
+	r := &Resolver{
+		SchoolRepository:        memory.NewSchoolRepository(store),
+		GradingScaleRepository:  memory.NewGradingScaleRepository(store),
+		AdminRepository:         memory.NewAdminRepository(store),
+		InvitationRepository:    memory.NewInvitationRepository(store),
+		APIKeyRepository:        memory.NewAPIKeyRepository(store),
+		ClassRepository:         memory.NewClassRepository(store),
+		StudentRepository:       memory.NewStudentRepository(store),
+		ReportVersionRepository: memory.NewReportVersionRepository(store),
+		JobRepository:           memory.NewJobRepository(store),
+		ReportStore:             memory.NewReportStore(store),
+		SessionRepository:       memory.NewSessionRepository(store),
+	}
+
+	ctx := context.WithValue(context.Background(), adminCtxKey, "owner")
+	ctx = context.WithValue(ctx, roleCtxKey, admin.RoleOwner)
+	ctx = context.WithValue(ctx, schoolCtxKey, testSchoolID)
+	return r, ctx
+}
+
+// scores returns the scores of the "Maths" subject of the classes created by
+// newTestClass.
+func scores(score int) []*student.SubjectScore {
+	return []*student.SubjectScore{{Name: "Maths", Score: score}}
+}
+
+// newTestClass creates a class with a "Maths" subject and a student for each
+// score, and returns the ID of the class and of its students.
+func newTestClass(t *testing.T, r *Resolver, ctx context.Context, studentScores ...int) (string, []string) {
+	t.Helper()
+
+	classID, err := r.Mutation().CreateClass(ctx, "JSS 1", []*class.Subject{{Name: "Maths", MaxScore: 100, Weight: 1}}, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("CreateClass error: %v", err)
+	}
+
+	studentIDs := make([]string, 0, len(studentScores))
+	for i, score := range studentScores {
+		studentID, err := r.Mutation().AddStudentRecord(ctx, classID, string(rune('A'+i))+" Student", scores(score))
+		if err != nil {
+			t.Fatalf("AddStudentRecord error: %v", err)
+		}
+		studentIDs = append(studentIDs, studentID)
+	}
+
+	return classID, studentIDs
+}
+
+// computeReport queues the report of classID and runs its job, and returns
+// the saved report version.
+func computeReport(t *testing.T, r *Resolver, ctx context.Context, classID string) int {
+	t.Helper()
+
+	if _, err := r.Mutation().ComputeClassReport(ctx, classID); err != nil {
+		t.Fatalf("ComputeClassReport error: %v", err)
+	}
+
+	reportJob, err := r.JobRepository.Claim(time.Now())
+	if err != nil || reportJob == nil {
+		t.Fatalf("expected a queued job, got %v, %v", reportJob, err)
+	}
+
+	r.runJob(reportJob)
+
+	reportJob, err = r.JobRepository.Job(testSchoolID, reportJob.ID)
+	if err != nil {
+		t.Fatalf("Job error: %v", err)
+	}
+
+	if reportJob.State != job.StateSucceeded {
+		t.Fatalf("expected job to succeed, got %s: %s", reportJob.State, reportJob.Error)
+	}
+	return reportJob.ReportVersion
+}
+
+// currentClass returns the class that match classID.
+func currentClass(t *testing.T, r *Resolver, classID string) *class.Class {
+	t.Helper()
+
+	classInfo, err := r.ClassRepository.Class(testSchoolID, classID)
+	if err != nil {
+		t.Fatalf("Class error: %v", err)
+	}
+	return classInfo
+}
+
+// expectInvalidRequest fails t if err is not a db.ErrorInvalidRequest.
+func expectInvalidRequest(t *testing.T, action string, err error) {
+	t.Helper()
+
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("%s: expected db.ErrorInvalidRequest, got %v", action, err)
+	}
+}
+
+func TestComputeClassReport(t *testing.T) {
+	r, ctx := newTestResolver()
+	classID, studentIDs := newTestClass(t, r, ctx, 90, 60, 75)
+
+	version := computeReport(t, r, ctx, classID)
+	if version != 1 {
+		t.Fatalf("expected report version 1, got %d", version)
+	}
+
+	info := currentClass(t, r, classID)
+	if info.State != class.StateReported || info.CurrentReportVersion != 1 || info.ReportStale {
+		t.Fatalf("expected a reported class with report version 1, got %s %d stale %v", info.State, info.CurrentReportVersion, info.ReportStale)
+	}
+
+	reportVersion, err := r.ReportVersionRepository.ReportVersion(testSchoolID, classID, 1)
+	if err != nil {
+		t.Fatalf("ReportVersion error: %v", err)
+	}
+
+	if len(reportVersion.Students) != len(studentIDs) {
+		t.Fatalf("expected %d student reports, got %d", len(studentIDs), len(reportVersion.Students))
+	}
+
+	wantPositions := map[string]int{studentIDs[0]: 1, studentIDs[1]: 3, studentIDs[2]: 2}
+	for _, studentReport := range reportVersion.Students {
+		if position := studentReport.Report.Class.Position; position != wantPositions[studentReport.StudentID] {
+			t.Errorf("expected position %d for student %s, got %d", wantPositions[studentReport.StudentID], studentReport.StudentName, position)
+		}
+	}
+}
+
+func TestComputeClassReportRejections(t *testing.T) {
+	r, ctx := newTestResolver()
+
+	classID, studentIDs := newTestClass(t, r, ctx, 90)
+	_, err := r.Mutation().ComputeClassReport(ctx, classID)
+	expectInvalidRequest(t, "compute with too few students", err)
+
+	_, err = r.Mutation().AddStudentRecord(ctx, classID, "B Student", scores(50))
+	if err != nil {
+		t.Fatalf("AddStudentRecord error: %v", err)
+	}
+
+	_, err = r.Mutation().ComputeClassReport(ctx, classID)
+	if err != nil {
+		t.Fatalf("ComputeClassReport error: %v", err)
+	}
+
+	// The job has not run, the class is computing.
+	_, err = r.Mutation().ComputeClassReport(ctx, classID)
+	expectInvalidRequest(t, "compute twice", err)
+
+	_, err = r.Mutation().AddStudentRecord(ctx, classID, "C Student", scores(70))
+	expectInvalidRequest(t, "add student while computing", err)
+
+	_, err = r.Mutation().UpdateStudentScores(ctx, classID, studentIDs[0], scores(10))
+	expectInvalidRequest(t, "update scores while computing", err)
+
+	_, err = r.Mutation().RemoveStudent(ctx, classID, studentIDs[0])
+	expectInvalidRequest(t, "remove student while computing", err)
+
+	_, err = r.Mutation().SetCurrentReportVersion(ctx, classID, 1)
+	expectInvalidRequest(t, "restore while computing", err)
+}
+
+func TestAddStudentToReportedClass(t *testing.T) {
+	r, ctx := newTestResolver()
+	classID, _ := newTestClass(t, r, ctx, 90, 60)
+	computeReport(t, r, ctx, classID)
+
+	_, err := r.Mutation().AddStudentRecord(ctx, classID, "C Student", scores(70))
+	if err != nil {
+		t.Fatalf("AddStudentRecord error: %v", err)
+	}
+
+	if info := currentClass(t, r, classID); info.State != class.StateOpen || !info.ReportStale {
+		t.Fatalf("expected the class to be open with a stale report, got %s stale %v", info.State, info.ReportStale)
+	}
+}
+
+func TestSetCurrentReportVersion(t *testing.T) {
+	r, ctx := newTestResolver()
+	classID, _ := newTestClass(t, r, ctx, 90, 60)
+	computeReport(t, r, ctx, classID)
+
+	version := computeReport(t, r, ctx, classID)
+	if version != 2 {
+		t.Fatalf("expected report version 2, got %d", version)
+	}
+
+	_, err := r.Mutation().SetCurrentReportVersion(ctx, classID, 2)
+	expectInvalidRequest(t, "restore current version", err)
+
+	_, err = r.Mutation().SetCurrentReportVersion(ctx, classID, 3)
+	if err == nil {
+		t.Fatal("restored a report version that does not exist")
+	}
+
+	ok, err := r.Mutation().SetCurrentReportVersion(ctx, classID, 1)
+	if err != nil || !ok {
+		t.Fatalf("SetCurrentReportVersion error: %v", err)
+	}
+
+	if info := currentClass(t, r, classID); info.State != class.StateReported || info.CurrentReportVersion != 1 {
+		t.Fatalf("expected report version 1 to be current, got %s %d", info.State, info.CurrentReportVersion)
+	}
+}
+
+func TestSetCurrentReportVersionAfterStudentsChanged(t *testing.T) {
+	r, ctx := newTestResolver()
+	classID, studentIDs := newTestClass(t, r, ctx, 90, 60)
+	computeReport(t, r, ctx, classID)
+
+	_, err := r.Mutation().UpdateStudentScores(ctx, classID, studentIDs[1], scores(95))
+	if err != nil {
+		t.Fatalf("UpdateStudentScores error: %v", err)
+	}
+
+	info := currentClass(t, r, classID)
+	if info.State != class.StateOpen || !info.ReportStale {
+		t.Fatalf("expected the class to be open with a stale report, got %s stale %v", info.State, info.ReportStale)
+	}
+
+	_, err = r.Mutation().SetCurrentReportVersion(ctx, classID, 1)
+	expectInvalidRequest(t, "restore stale report", err)
+
+	computeReport(t, r, ctx, classID)
+
+	// Version 1 has the same students but was computed with other scores.
+	_, err = r.Mutation().SetCurrentReportVersion(ctx, classID, 1)
+	expectInvalidRequest(t, "restore report of old scores", err)
+
+	if info := currentClass(t, r, classID); info.CurrentReportVersion != 2 {
+		t.Fatalf("expected report version 2 to stay current, got %d", info.CurrentReportVersion)
+	}
+}
@@ -3,7 +3,10 @@ package graph
 import (
 	"errors"
 	"log"
+	"strings"
 
+	"github.com/ukane-philemon/scomp/graph/model"
+	"github.com/ukane-philemon/scomp/internal/admin"
 	"github.com/ukane-philemon/scomp/internal/db"
 	customerror "github.com/ukane-philemon/scomp/internal/errors"
 )
@@ -18,3 +21,43 @@ func handleError(err error) error {
 	log.Printf("SERVER ERROR: %v", err.Error())
 	return &customerror.ErrorUnknown{}
 }
+
+// adminRole converts a GraphQL role to an admin role.
+func adminRole(role model.Role) string {
+	return strings.ToLower(string(role))
+}
+
+// modelRole converts an admin role to a GraphQL role.
+func modelRole(role string) model.Role {
+	return model.Role(strings.ToUpper(role))
+}
+
+// scoreStatus converts a GraphQL score status to a student score status.
+func scoreStatus(status model.ScoreStatus) string {
+	if status == model.ScoreStatusScored {
+		return ""
+	}
+	return strings.ToLower(string(status))
+}
+
+// modelScoreStatus converts a student score status to a GraphQL score status.
+func modelScoreStatus(status string) model.ScoreStatus {
+	if status == "" {
+		return model.ScoreStatusScored
+	}
+	return model.ScoreStatus(strings.ToUpper(status))
+}
+
+// modelAPIKey converts an API key to a GraphQL API key.
+func modelAPIKey(apiKey *admin.APIKey) *model.APIKey {
+	return &model.APIKey{
+		ID:         apiKey.ID,
+		Name:       apiKey.Name,
+		Scope:      model.APIKeyScope(strings.ToUpper(apiKey.Scope)),
+		ClassIDs:   apiKey.ClassIDs,
+		CreatedBy:  apiKey.CreatedBy,
+		CreatedAt:  int(apiKey.CreatedAt),
+		ExpiresAt:  int(apiKey.ExpiresAt),
+		LastUsedAt: int(apiKey.LastUsedAt),
+	}
+}
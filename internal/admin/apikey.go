@@ -0,0 +1,240 @@
+package admin
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ukane-philemon/scomp/internal/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MaxAPIKeyValidity is the maximum lifetime of an API key.
+const MaxAPIKeyValidity = 365 * 24 * time.Hour
+
+// apiKeyPrefix is the prefix of API keys, it makes leaked keys easy to spot.
+const apiKeyPrefix = "scomp_"
+
+// API key scopes.
+const (
+	// APIKeyScopeRead keys can read class and student records.
+	APIKeyScopeRead = "read"
+	// APIKeyScopeWrite keys can also create classes, add student records and
+	// compute class reports.
+	APIKeyScopeWrite = "write"
+)
+
+const (
+	hashedSecretKey = "hashedSecret"
+	createdAtKey    = "createdAt"
+	lastUsedAtKey   = "lastUsedAt"
+	revokedAtKey    = "revokedAt"
+)
+
+// APIKey is a credential of SchoolID used by other systems to access the API
+// without an admin login.
+type APIKey struct {
+	ID           string `json:"_id" bson:"_id"`
+	SchoolID     string `json:"schoolID" bson:"schoolID"`
+	Name         string `json:"name" bson:"name"`
+	HashedSecret string `json:"-" bson:"hashedSecret"`
+	Scope        string `json:"scope" bson:"scope"`
+	// ClassIDs are the classes the key can access. A key without ClassIDs
+	// can access all the classes of the school.
+	ClassIDs   []string `json:"classIDs" bson:"classIDs"`
+	CreatedBy  string   `json:"createdBy" bson:"createdBy"`
+	CreatedAt  int64    `json:"createdAt" bson:"createdAt"`
+	ExpiresAt  int64    `json:"expiresAt" bson:"expiresAt"`
+	LastUsedAt int64    `json:"lastUsedAt" bson:"lastUsedAt"` // 0 until used
+	RevokedAt  int64    `json:"revokedAt" bson:"revokedAt"`   // 0 until revoked
+}
+
+// NewAPIKey returns a new *APIKey of schoolID with scope that expires after
+// validFor, and the key. Set classIDs to restrict the key to some classes.
+func NewAPIKey(schoolID, createdBy, name, scope string, classIDs []string, validFor time.Duration) (*APIKey, string, error) {
+	if schoolID == "" {
+		return nil, "", fmt.Errorf("%w: missing schoolID", db.ErrorInvalidRequest)
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", fmt.Errorf("%w: missing API key name", db.ErrorInvalidRequest)
+	}
+
+	if scope != APIKeyScopeRead && scope != APIKeyScopeWrite {
+		return nil, "", fmt.Errorf("%w: invalid API key scope %s", db.ErrorInvalidRequest, scope)
+	}
+
+	if validFor <= 0 || validFor > MaxAPIKeyValidity {
+		return nil, "", fmt.Errorf("%w: API keys must be valid for at most %d days", db.ErrorInvalidRequest, MaxAPIKeyValidity/(24*time.Hour))
+	}
+
+	secretBytes := make([]byte, 32)
+	_, err := rand.Read(secretBytes)
+	if err != nil {
+		return nil, "", fmt.Errorf("rand.Read error: %w", err)
+	}
+
+	if classIDs == nil {
+		classIDs = []string{}
+	}
+
+	apiKeyID := primitive.NewObjectID().Hex()
+	secret := base64.RawURLEncoding.EncodeToString(secretBytes)
+	now := time.Now()
+	return &APIKey{
+		ID:           apiKeyID,
+		SchoolID:     schoolID,
+		Name:         name,
+		HashedSecret: hashAPIKeySecret(secret),
+		Scope:        scope,
+		ClassIDs:     classIDs,
+		CreatedBy:    createdBy,
+		CreatedAt:    now.Unix(),
+		ExpiresAt:    now.Add(validFor).Unix(),
+	}, apiKeyPrefix + apiKeyID + "." + secret, nil
+}
+
+// ParseAPIKey returns the ID and the hashed secret of key.
+func ParseAPIKey(key string) (string, string, error) {
+	apiKeyID, secret, found := strings.Cut(strings.TrimPrefix(key, apiKeyPrefix), ".")
+	if !found || apiKeyID == "" || secret == "" {
+		return "", "", fmt.Errorf("%w: invalid API key", db.ErrorInvalidRequest)
+	}
+
+	return apiKeyID, hashAPIKeySecret(secret), nil
+}
+
+func hashAPIKeySecret(secret string) string {
+	hash := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(hash[:])
+}
+
+// Role returns the admin role whose permissions the key has. A key never has
+// more permissions than creatorRole, the current role of the admin that
+// created it, so that keys of a demoted admin are demoted too.
+func (k *APIKey) Role(creatorRole string) string {
+	role := RoleViewer
+	if k.Scope == APIKeyScopeWrite {
+		role = RoleAdmin
+	}
+
+	if !HasRole(creatorRole, role) {
+		return creatorRole
+	}
+	return role
+}
+
+// CanAccessClass checks that the key can access classID.
+func (k *APIKey) CanAccessClass(classID string) bool {
+	if len(k.ClassIDs) == 0 {
+		return true
+	}
+
+	for _, id := range k.ClassIDs {
+		if id == classID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MongoAPIKeyRepository implements APIKeyRepository.
+type MongoAPIKeyRepository struct {
+	ctx              context.Context
+	apiKeyCollection *mongo.Collection
+}
+
+// NewAPIKeyRepository creates a new instance of *MongoAPIKeyRepository. The
+// collection indexes are created by db.MigrateMongoDB.
+func NewAPIKeyRepository(ctx context.Context, db *mongo.Database) APIKeyRepository {
+	return &MongoAPIKeyRepository{
+		ctx:              ctx,
+		apiKeyCollection: db.Collection("apiKeys"),
+	}
+}
+
+// Create implements APIKeyRepository.
+func (kr *MongoAPIKeyRepository) Create(schoolID, createdBy, name, scope string, classIDs []string, validFor time.Duration) (*APIKey, string, error) {
+	apiKey, key, err := NewAPIKey(schoolID, createdBy, name, scope, classIDs, validFor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	_, err = kr.apiKeyCollection.InsertOne(kr.ctx, apiKey)
+	if err != nil {
+		return nil, "", fmt.Errorf("apiKeyCollection.InsertOne error: %w", err)
+	}
+
+	return apiKey, key, nil
+}
+
+// Authenticate implements APIKeyRepository.
+func (kr *MongoAPIKeyRepository) Authenticate(key string) (*APIKey, error) {
+	apiKeyID, hashedSecret, err := ParseAPIKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+	filter := bson.M{
+		idKey:           apiKeyID,
+		hashedSecretKey: hashedSecret,
+		revokedAtKey:    0,
+		expiresAtKey:    bson.M{"$gt": now},
+	}
+
+	var apiKey *APIKey
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = kr.apiKeyCollection.FindOneAndUpdate(kr.ctx, filter, bson.M{"$set": bson.M{lastUsedAtKey: now}}, opts).Decode(&apiKey)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("%w: API key is invalid, expired or has been revoked", db.ErrorInvalidRequest)
+		}
+		return nil, fmt.Errorf("apiKeyCollection.FindOneAndUpdate error: %w", err)
+	}
+
+	return apiKey, nil
+}
+
+// APIKeys implements APIKeyRepository.
+func (kr *MongoAPIKeyRepository) APIKeys(schoolID string) ([]*APIKey, error) {
+	filter := bson.M{
+		schoolIDKey:  schoolID,
+		revokedAtKey: 0,
+		expiresAtKey: bson.M{"$gt": time.Now().Unix()},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: createdAtKey, Value: -1}})
+	cur, err := kr.apiKeyCollection.Find(kr.ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("apiKeyCollection.Find error: %w", err)
+	}
+
+	var apiKeys []*APIKey
+	return apiKeys, cur.All(kr.ctx, &apiKeys)
+}
+
+// Revoke implements APIKeyRepository.
+func (kr *MongoAPIKeyRepository) Revoke(schoolID, apiKeyID string) error {
+	filter := bson.M{idKey: apiKeyID, schoolIDKey: schoolID, revokedAtKey: 0}
+	res, err := kr.apiKeyCollection.UpdateOne(kr.ctx, filter, bson.M{"$set": bson.M{revokedAtKey: time.Now().Unix()}})
+	if err != nil {
+		return fmt.Errorf("apiKeyCollection.UpdateOne error: %w", err)
+	}
+
+	if res.ModifiedCount == 0 {
+		return fmt.Errorf("%w: no active API key found with ID %s", db.ErrorInvalidRequest, apiKeyID)
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package admin
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ukane-philemon/scomp/internal/db"
+)
+
+func TestNewAPIKey(t *testing.T) {
+	apiKey, key, err := NewAPIKey("school", "admin", " Results portal ", APIKeyScopeRead, nil, time.Hour)
+	if err != nil {
+		t.Fatalf("NewAPIKey error: %v", err)
+	}
+
+	if apiKey.Name != "Results portal" || apiKey.ClassIDs == nil {
+		t.Fatalf("expected a trimmed name and no class restriction, got %q %v", apiKey.Name, apiKey.ClassIDs)
+	}
+
+	apiKeyID, hashedSecret, err := ParseAPIKey(key)
+	if err != nil {
+		t.Fatalf("ParseAPIKey error: %v", err)
+	}
+
+	if apiKeyID != apiKey.ID || hashedSecret != apiKey.HashedSecret {
+		t.Fatalf("expected key %s, got %s", apiKey.ID, apiKeyID)
+	}
+
+	tests := []struct {
+		name, schoolID, keyName, scope string
+		validFor                       time.Duration
+	}{
+		{name: "missing school", keyName: "key", scope: APIKeyScopeRead, validFor: time.Hour},
+		{name: "missing name", schoolID: "school", keyName: " ", scope: APIKeyScopeRead, validFor: time.Hour},
+		{name: "unknown scope", schoolID: "school", keyName: "key", scope: "admin", validFor: time.Hour},
+		{name: "no validity", schoolID: "school", keyName: "key", scope: APIKeyScopeRead},
+		{name: "too long", schoolID: "school", keyName: "key", scope: APIKeyScopeRead, validFor: MaxAPIKeyValidity + time.Hour},
+	}
+
+	for _, test := range tests {
+		_, _, err := NewAPIKey(test.schoolID, "admin", test.keyName, test.scope, nil, test.validFor)
+		if !errors.Is(err, db.ErrorInvalidRequest) {
+			t.Errorf("%s: expected db.ErrorInvalidRequest, got %v", test.name, err)
+		}
+	}
+}
+
+func TestParseAPIKey(t *testing.T) {
+	for _, key := range []string{"", "scomp_", "scomp_id", "scomp_id.", "scomp_.secret"} {
+		_, _, err := ParseAPIKey(key)
+		if !errors.Is(err, db.ErrorInvalidRequest) {
+			t.Errorf("expected db.ErrorInvalidRequest for %q, got %v", key, err)
+		}
+	}
+}
+
+func TestAPIKeyRole(t *testing.T) {
+	tests := []struct {
+		scope       string
+		creatorRole string
+		want        string
+	}{
+		{scope: APIKeyScopeRead, creatorRole: RoleOwner, want: RoleViewer},
+		{scope: APIKeyScopeWrite, creatorRole: RoleOwner, want: RoleAdmin},
+		{scope: APIKeyScopeWrite, creatorRole: RoleAdmin, want: RoleAdmin},
+		{scope: APIKeyScopeWrite, creatorRole: RoleTeacher, want: RoleTeacher},
+		{scope: APIKeyScopeWrite, creatorRole: RoleViewer, want: RoleViewer},
+		{scope: APIKeyScopeRead, creatorRole: RoleViewer, want: RoleViewer},
+	}
+
+	for _, test := range tests {
+		apiKey := &APIKey{Scope: test.scope}
+		if got := apiKey.Role(test.creatorRole); got != test.want {
+			t.Errorf("%s key of %s: expected role %s, got %s", test.scope, test.creatorRole, test.want, got)
+		}
+	}
+}
+
+func TestCanAccessClass(t *testing.T) {
+	tests := []struct {
+		name     string
+		classIDs []string
+		classID  string
+		want     bool
+	}{
+		{name: "unrestricted", classID: "class 1", want: true},
+		{name: "allowed class", classIDs: []string{"class 1", "class 2"}, classID: "class 2", want: true},
+		{name: "other class", classIDs: []string{"class 1"}, classID: "class 2"},
+	}
+
+	for _, test := range tests {
+		apiKey := &APIKey{ClassIDs: test.classIDs}
+		if got := apiKey.CanAccessClass(test.classID); got != test.want {
+			t.Errorf("%s: expected %v, got %v", test.name, test.want, got)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/ukane-philemon/scomp/internal/db"
+	"github.com/ukane-philemon/scomp/internal/totp"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -14,13 +15,78 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
-const usernameKey = "username"
+const (
+	idKey                  = "_id"
+	usernameKey            = "username"
+	hashedPasswordKey      = "hashedPassword"
+	roleKey                = "role"
+	schoolIDKey            = "schoolID"
+	failedLoginAttemptsKey = "failedLoginAttempts"
+	lockedUntilKey         = "lockedUntil"
+	hashedResetTokenKey    = "hashedResetToken"
+	resetTokenExpiresAtKey = "resetTokenExpiresAt"
+	totpSecretKey          = "totpSecret"
+	totpEnabledKey         = "totpEnabled"
+	totpLastCounterKey     = "totpLastCounter"
+	hashedRecoveryCodesKey = "hashedRecoveryCodes"
+)
+
+// Admin roles from the most to the least privileged. An admin can do
+// everything the roles below theirs can do.
+const (
+	// RoleOwner can manage accounts and change the role of other admins.
+	RoleOwner = "owner"
+	// RoleAdmin can create accounts and compute class reports.
+	RoleAdmin = "admin"
+	// RoleTeacher can create classes and add student records to their own
+	// classes.
+	RoleTeacher = "teacher"
+	// RoleViewer can only read class and student records.
+	RoleViewer = "viewer"
+)
+
+// roleRanks maps a role to its privilege rank.
+var roleRanks = map[string]int{
+	RoleOwner:   4,
+	RoleAdmin:   3,
+	RoleTeacher: 2,
+	RoleViewer:  1,
+}
+
+// ValidRole checks that role is a known admin role.
+func ValidRole(role string) bool {
+	_, found := roleRanks[role]
+	return found
+}
+
+// HasRole checks that role is at least as privileged as requiredRole.
+func HasRole(role, requiredRole string) bool {
+	return ValidRole(role) && roleRanks[role] >= roleRanks[requiredRole]
+}
 
 type Admin struct {
 	ID             string `json:"_id" bson:"_id"`
+	SchoolID       string `json:"schoolID" bson:"schoolID"`
 	Username       string `json:"username" bson:"username"`
 	HashedPassword string `json:"hashedPassword" bson:"hashedPassword"`
+	Role           string `json:"role" bson:"role"`
 	CreatedAt      int64  `json:"createdAt" bson:"createdAt"`
+	// FailedLoginAttempts is the number of consecutive failed login attempts
+	// since the last successful login or lockout.
+	FailedLoginAttempts int   `json:"failedLoginAttempts" bson:"failedLoginAttempts"`
+	LockedUntil         int64 `json:"lockedUntil" bson:"lockedUntil"` // 0 if never locked
+	// HashedResetToken is the hash of the current password reset token, empty
+	// if there is none.
+	HashedResetToken    string `json:"-" bson:"hashedResetToken"`
+	ResetTokenExpiresAt int64  `json:"-" bson:"resetTokenExpiresAt"`
+	// TOTPSecret is the TOTP secret of the admin, empty if the admin has not
+	// enrolled. Two-factor authentication is only required once TOTPEnabled.
+	TOTPSecret  string `json:"-" bson:"totpSecret"`
+	TOTPEnabled bool   `json:"totpEnabled" bson:"totpEnabled"`
+	// TOTPLastCounter is the counter of the last accepted TOTP code, codes
+	// can only be used once.
+	TOTPLastCounter     int64    `json:"-" bson:"totpLastCounter"`
+	HashedRecoveryCodes []string `json:"-" bson:"hashedRecoveryCodes"`
 }
 
 // AdminRepository implements Repository.
@@ -29,35 +95,25 @@ type AdminRepository struct {
 	adminCollection *mongo.Collection
 }
 
-// NewRepository creates a new instance of *AdminRepo.
-func NewRepository(ctx context.Context, db *mongo.Database) (Repository, error) {
-	adminCollectionIndex := mongo.IndexModel{
-		Keys: bson.D{{
-			Key:   usernameKey,
-			Value: 1,
-		}},
-		Options: options.Index().SetUnique(true),
-	}
-
-	// Create a unique index on the admin collection.
-	adminCollection := db.Collection("admin")
-	_, err := adminCollection.Indexes().CreateOne(ctx, adminCollectionIndex)
-	if err != nil {
-		return nil, err
-	}
-
+// NewRepository creates a new instance of *AdminRepo. The collection indexes
+// are created by db.MigrateMongoDB.
+func NewRepository(ctx context.Context, db *mongo.Database) Repository {
 	return &AdminRepository{
 		ctx:             ctx,
-		adminCollection: adminCollection,
-	}, nil
+		adminCollection: db.Collection("admin"),
+	}
 }
 
 // CreateAccount implements Repository.
-func (ar *AdminRepository) CreateAccount(username, password string) (string, error) {
-	if username == "" || password == "" {
+func (ar *AdminRepository) CreateAccount(schoolID, username, password, role string) (string, error) {
+	if schoolID == "" || username == "" || password == "" {
 		return "", fmt.Errorf("%w: missing username or password", db.ErrorInvalidRequest)
 	}
 
+	if !ValidRole(role) {
+		return "", fmt.Errorf("%w: invalid role %s", db.ErrorInvalidRequest, role)
+	}
+
 	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return "", fmt.Errorf("bcrypt.GenerateFromPassword error: %w", err)
@@ -65,8 +121,10 @@ func (ar *AdminRepository) CreateAccount(username, password string) (string, err
 
 	adminInfo := &Admin{
 		ID:             primitive.NewObjectID().Hex(),
+		SchoolID:       schoolID,
 		Username:       username,
 		HashedPassword: string(passwordHash),
+		Role:           role,
 		CreatedAt:      time.Now().Unix(),
 	}
 
@@ -82,20 +140,348 @@ func (ar *AdminRepository) CreateAccount(username, password string) (string, err
 }
 
 // LoginAccount implements Repository.
-func (a *AdminRepository) LoginAccount(username, password string) (string, error) {
+func (a *AdminRepository) LoginAccount(username, password string) (*Admin, error) {
 	var admin *Admin
 	err := a.adminCollection.FindOne(a.ctx, bson.M{usernameKey: username}).Decode(&admin)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return "", fmt.Errorf("%w: username or password is incorrect", db.ErrorInvalidRequest)
+			return nil, ErrorIncorrectLogin
 		}
-		return "", fmt.Errorf("adminCollection.FindOne error: %w", err)
+		return nil, fmt.Errorf("adminCollection.FindOne error: %w", err)
+	}
+
+	now := time.Now()
+	if admin.Locked(now) {
+		return nil, ErrorIncorrectLogin
 	}
 
 	err = bcrypt.CompareHashAndPassword([]byte(admin.HashedPassword), []byte(password))
 	if err != nil {
-		return "", fmt.Errorf("%w: username or password is incorrect", db.ErrorInvalidRequest)
+		if err = a.recordFailedLogin(admin.ID, now); err != nil {
+			return nil, err
+		}
+		return nil, ErrorIncorrectLogin
+	}
+
+	if admin.FailedLoginAttempts > 0 {
+		_, err = a.adminCollection.UpdateOne(a.ctx, bson.M{idKey: admin.ID}, bson.M{"$set": bson.M{failedLoginAttemptsKey: 0}})
+		if err != nil {
+			return nil, fmt.Errorf("adminCollection.UpdateOne error: %w", err)
+		}
+		admin.FailedLoginAttempts = 0
+	}
+
+	return admin, nil
+}
+
+// Admin returns the admin that match adminID.
+// Implements Repository.
+func (a *AdminRepository) Admin(adminID string) (*Admin, error) {
+	var admin *Admin
+	err := a.adminCollection.FindOne(a.ctx, bson.M{idKey: adminID}).Decode(&admin)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("%w: admin not found", db.ErrorInvalidRequest)
+		}
+		return nil, fmt.Errorf("adminCollection.FindOne error: %w", err)
+	}
+
+	return admin, nil
+}
+
+// HasAccounts checks if at least one admin account exists.
+// Implements Repository.
+func (a *AdminRepository) HasAccounts() (bool, error) {
+	nAdmin, err := a.adminCollection.CountDocuments(a.ctx, bson.M{}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, fmt.Errorf("adminCollection.CountDocuments error: %w", err)
+	}
+
+	return nAdmin > 0, nil
+}
+
+// SetRole changes the role of the admin of schoolID that match adminID.
+// Implements Repository.
+func (a *AdminRepository) SetRole(schoolID, adminID, role string) error {
+	if !ValidRole(role) {
+		return fmt.Errorf("%w: invalid role %s", db.ErrorInvalidRequest, role)
+	}
+
+	res, err := a.adminCollection.UpdateOne(a.ctx, bson.M{idKey: adminID, schoolIDKey: schoolID}, bson.M{"$set": bson.M{roleKey: role}})
+	if err != nil {
+		return fmt.Errorf("adminCollection.UpdateOne error: %w", err)
+	}
+
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("%w: admin not found", db.ErrorInvalidRequest)
+	}
+
+	return nil
+}
+
+// ChangePassword changes the password of adminID if currentPassword is
+// correct.
+// Implements Repository.
+func (a *AdminRepository) ChangePassword(adminID, currentPassword, newPassword string) error {
+	admin, err := a.Admin(adminID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if err = admin.CheckLocked(now); err != nil {
+		return err
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(admin.HashedPassword), []byte(currentPassword))
+	if err != nil {
+		if err = a.recordFailedLogin(admin.ID, now); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: current password is incorrect", db.ErrorInvalidRequest)
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("bcrypt.GenerateFromPassword error: %w", err)
+	}
+
+	// Fail if the password was changed since it was checked.
+	filter := bson.M{idKey: adminID, hashedPasswordKey: admin.HashedPassword}
+	res, err := a.adminCollection.UpdateOne(a.ctx, filter, bson.M{"$set": bson.M{hashedPasswordKey: string(passwordHash)}})
+	if err != nil {
+		return fmt.Errorf("adminCollection.UpdateOne error: %w", err)
+	}
+
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("%w: current password is incorrect", db.ErrorInvalidRequest)
+	}
+
+	return nil
+}
+
+// CreatePasswordReset creates a one-time password reset token for the admin
+// of schoolID that match adminID and returns the token and its expiry.
+// Implements Repository.
+func (a *AdminRepository) CreatePasswordReset(schoolID, adminID string) (string, int64, error) {
+	resetToken, hashedResetToken, err := NewPasswordResetToken()
+	if err != nil {
+		return "", 0, err
+	}
+
+	expiresAt := time.Now().Add(PasswordResetTokenExpiry).Unix()
+	update := bson.M{"$set": bson.M{hashedResetTokenKey: hashedResetToken, resetTokenExpiresAtKey: expiresAt}}
+	res, err := a.adminCollection.UpdateOne(a.ctx, bson.M{idKey: adminID, schoolIDKey: schoolID}, update)
+	if err != nil {
+		return "", 0, fmt.Errorf("adminCollection.UpdateOne error: %w", err)
+	}
+
+	if res.MatchedCount == 0 {
+		return "", 0, fmt.Errorf("%w: admin not found", db.ErrorInvalidRequest)
+	}
+
+	return resetToken, expiresAt, nil
+}
+
+// ResetPassword changes the password of the admin of the unexpired
+// resetToken, unlocks their account and returns their ID.
+// Implements Repository.
+func (a *AdminRepository) ResetPassword(resetToken, newPassword string) (string, error) {
+	if resetToken == "" {
+		return "", fmt.Errorf("%w: missing reset token", db.ErrorInvalidRequest)
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("bcrypt.GenerateFromPassword error: %w", err)
+	}
+
+	filter := bson.M{
+		hashedResetTokenKey:    HashPasswordResetToken(resetToken),
+		resetTokenExpiresAtKey: bson.M{"$gt": time.Now().Unix()},
+	}
+	update := bson.M{"$set": bson.M{
+		hashedPasswordKey:      string(passwordHash),
+		hashedResetTokenKey:    "",
+		resetTokenExpiresAtKey: 0,
+		failedLoginAttemptsKey: 0,
+		lockedUntilKey:         0,
+	}}
+
+	var admin *Admin
+	err = a.adminCollection.FindOneAndUpdate(a.ctx, filter, update).Decode(&admin)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return "", fmt.Errorf("%w: reset token is invalid or has expired", db.ErrorInvalidRequest)
+		}
+		return "", fmt.Errorf("adminCollection.FindOneAndUpdate error: %w", err)
 	}
 
 	return admin.ID, nil
 }
+
+// EnrollTOTP generates a new TOTP secret for adminID and returns it.
+// Implements Repository.
+func (a *AdminRepository) EnrollTOTP(adminID string) (string, error) {
+	secret, err := totp.NewSecret()
+	if err != nil {
+		return "", err
+	}
+
+	filter := bson.M{idKey: adminID, totpEnabledKey: false}
+	res, err := a.adminCollection.UpdateOne(a.ctx, filter, bson.M{"$set": bson.M{totpSecretKey: secret}})
+	if err != nil {
+		return "", fmt.Errorf("adminCollection.UpdateOne error: %w", err)
+	}
+
+	if res.MatchedCount == 0 {
+		return "", fmt.Errorf("%w: two-factor authentication is already enabled", db.ErrorInvalidRequest)
+	}
+
+	return secret, nil
+}
+
+// ConfirmTOTP enables two-factor authentication for adminID if code is a
+// valid code of the enrolled secret and returns new recovery codes.
+// Implements Repository.
+func (a *AdminRepository) ConfirmTOTP(adminID, code string) ([]string, error) {
+	admin, err := a.Admin(adminID)
+	if err != nil {
+		return nil, err
+	}
+
+	counter, err := admin.ConfirmTOTPCode(code)
+	if err != nil {
+		return nil, err
+	}
+
+	recoveryCodes, hashedRecoveryCodes, err := NewRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	// Fail if the admin enrolled again since the secret was read.
+	filter := bson.M{idKey: adminID, totpSecretKey: admin.TOTPSecret, totpEnabledKey: false}
+	update := bson.M{"$set": bson.M{
+		totpEnabledKey:         true,
+		totpLastCounterKey:     counter,
+		hashedRecoveryCodesKey: hashedRecoveryCodes,
+	}}
+	res, err := a.adminCollection.UpdateOne(a.ctx, filter, update)
+	if err != nil {
+		return nil, fmt.Errorf("adminCollection.UpdateOne error: %w", err)
+	}
+
+	if res.MatchedCount == 0 {
+		return nil, fmt.Errorf("%w: two-factor enrollment has changed, please enroll again", db.ErrorInvalidRequest)
+	}
+
+	return recoveryCodes, nil
+}
+
+// VerifyTwoFactor checks that code is an unused TOTP code or recovery code of
+// adminID and returns the admin.
+// Implements Repository.
+func (a *AdminRepository) VerifyTwoFactor(adminID, code string) (*Admin, error) {
+	admin, err := a.Admin(adminID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if err = admin.CheckLocked(now); err != nil {
+		return nil, err
+	}
+
+	match, ok := admin.MatchTwoFactorCode(code, now)
+	if !ok {
+		if err = a.recordFailedLogin(admin.ID, now); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: two-factor code is invalid or has already been used", db.ErrorInvalidRequest)
+	}
+
+	// Mark the code as used. The filter fails if it was used concurrently.
+	filter := bson.M{idKey: adminID, totpEnabledKey: true}
+	set := bson.M{failedLoginAttemptsKey: 0}
+	update := bson.M{"$set": set}
+	if match.HashedRecoveryCode != "" {
+		filter[hashedRecoveryCodesKey] = match.HashedRecoveryCode
+		update["$pull"] = bson.M{hashedRecoveryCodesKey: match.HashedRecoveryCode}
+	} else {
+		filter[totpLastCounterKey] = bson.M{"$lt": match.Counter}
+		set[totpLastCounterKey] = match.Counter
+	}
+
+	res, err := a.adminCollection.UpdateOne(a.ctx, filter, update)
+	if err != nil {
+		return nil, fmt.Errorf("adminCollection.UpdateOne error: %w", err)
+	}
+
+	if res.MatchedCount == 0 {
+		return nil, fmt.Errorf("%w: two-factor code is invalid or has already been used", db.ErrorInvalidRequest)
+	}
+
+	admin.FailedLoginAttempts = 0
+	return admin, nil
+}
+
+// DisableTOTP disables two-factor authentication for adminID if code is an
+// unused TOTP code or recovery code.
+// Implements Repository.
+func (a *AdminRepository) DisableTOTP(adminID, code string) error {
+	_, err := a.VerifyTwoFactor(adminID, code)
+	if err != nil {
+		return err
+	}
+
+	_, err = a.adminCollection.UpdateOne(a.ctx, bson.M{idKey: adminID}, disableTOTPUpdate())
+	if err != nil {
+		return fmt.Errorf("adminCollection.UpdateOne error: %w", err)
+	}
+
+	return nil
+}
+
+// ResetTOTP disables two-factor authentication for the admin of schoolID that
+// match adminID.
+// Implements Repository.
+func (a *AdminRepository) ResetTOTP(schoolID, adminID string) error {
+	res, err := a.adminCollection.UpdateOne(a.ctx, bson.M{idKey: adminID, schoolIDKey: schoolID}, disableTOTPUpdate())
+	if err != nil {
+		return fmt.Errorf("adminCollection.UpdateOne error: %w", err)
+	}
+
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("%w: admin not found", db.ErrorInvalidRequest)
+	}
+
+	return nil
+}
+
+// recordFailedLogin counts a failed login attempt of adminID and locks the
+// account if there have been too many.
+func (a *AdminRepository) recordFailedLogin(adminID string, now time.Time) error {
+	// Both fields are set from their current values.
+	attempts := bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$" + failedLoginAttemptsKey, 0}}, 1}}
+	lock := bson.M{"$gte": bson.A{attempts, MaxFailedLoginAttempts}}
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{
+		failedLoginAttemptsKey: bson.M{"$cond": bson.A{lock, 0, attempts}},
+		lockedUntilKey:         bson.M{"$cond": bson.A{lock, now.Add(LockoutDuration).Unix(), bson.M{"$ifNull": bson.A{"$" + lockedUntilKey, 0}}}},
+	}}}}
+	_, err := a.adminCollection.UpdateOne(a.ctx, bson.M{idKey: adminID}, update)
+	if err != nil {
+		return fmt.Errorf("adminCollection.UpdateOne error: %w", err)
+	}
+
+	return nil
+}
+
+func disableTOTPUpdate() bson.M {
+	return bson.M{"$set": bson.M{
+		totpSecretKey:          "",
+		totpEnabledKey:         false,
+		totpLastCounterKey:     0,
+		hashedRecoveryCodesKey: bson.A{},
+	}}
+}
@@ -0,0 +1,158 @@
+package admin
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ukane-philemon/scomp/internal/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MaxInvitationValidity is the maximum lifetime of an invitation.
+const MaxInvitationValidity = 30 * 24 * time.Hour
+
+const (
+	hashedCodeKey = "hashedCode"
+	expiresAtKey  = "expiresAt"
+	redeemedAtKey = "redeemedAt"
+)
+
+// Invitation is a single-use invitation to create an admin account of SchoolID
+// with Role.
+type Invitation struct {
+	ID         string `json:"_id" bson:"_id"`
+	SchoolID   string `json:"schoolID" bson:"schoolID"`
+	HashedCode string `json:"-" bson:"hashedCode"`
+	Role       string `json:"role" bson:"role"`
+	CreatedBy  string `json:"createdBy" bson:"createdBy"`
+	CreatedAt  int64  `json:"createdAt" bson:"createdAt"`
+	ExpiresAt  int64  `json:"expiresAt" bson:"expiresAt"`
+	RedeemedAt int64  `json:"redeemedAt" bson:"redeemedAt"` // 0 until redeemed
+}
+
+// NewInvitation returns a new *Invitation to join schoolID with role that
+// expires after validFor, and its code.
+func NewInvitation(schoolID, createdBy, role string, validFor time.Duration) (*Invitation, string, error) {
+	if schoolID == "" {
+		return nil, "", fmt.Errorf("%w: missing schoolID", db.ErrorInvalidRequest)
+	}
+
+	if !ValidRole(role) {
+		return nil, "", fmt.Errorf("%w: invalid role %s", db.ErrorInvalidRequest, role)
+	}
+
+	if validFor <= 0 || validFor > MaxInvitationValidity {
+		return nil, "", fmt.Errorf("%w: invitations must be valid for at most %s", db.ErrorInvalidRequest, MaxInvitationValidity)
+	}
+
+	codeBytes := make([]byte, 16)
+	_, err := rand.Read(codeBytes)
+	if err != nil {
+		return nil, "", fmt.Errorf("rand.Read error: %w", err)
+	}
+
+	code := base64.RawURLEncoding.EncodeToString(codeBytes)
+	now := time.Now()
+	return &Invitation{
+		ID:         primitive.NewObjectID().Hex(),
+		SchoolID:   schoolID,
+		HashedCode: HashInvitationCode(code),
+		Role:       role,
+		CreatedBy:  createdBy,
+		CreatedAt:  now.Unix(),
+		ExpiresAt:  now.Add(validFor).Unix(),
+	}, code, nil
+}
+
+// HashInvitationCode returns the hash of an invitation code. Only the hash of
+// a code is stored.
+func HashInvitationCode(code string) string {
+	hash := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(hash[:])
+}
+
+// MongoInvitationRepository implements InvitationRepository.
+type MongoInvitationRepository struct {
+	ctx                  context.Context
+	invitationCollection *mongo.Collection
+}
+
+// NewInvitationRepository creates a new instance of
+// *MongoInvitationRepository. The collection indexes are created by
+// db.MigrateMongoDB.
+func NewInvitationRepository(ctx context.Context, db *mongo.Database) InvitationRepository {
+	return &MongoInvitationRepository{
+		ctx:                  ctx,
+		invitationCollection: db.Collection("invitations"),
+	}
+}
+
+// Create implements InvitationRepository.
+func (ir *MongoInvitationRepository) Create(schoolID, createdBy, role string, validFor time.Duration) (*Invitation, string, error) {
+	invitation, code, err := NewInvitation(schoolID, createdBy, role, validFor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	_, err = ir.invitationCollection.InsertOne(ir.ctx, invitation)
+	if err != nil {
+		return nil, "", fmt.Errorf("invitationCollection.InsertOne error: %w", err)
+	}
+
+	return invitation, code, nil
+}
+
+// Redeem implements InvitationRepository.
+func (ir *MongoInvitationRepository) Redeem(code string) (*Invitation, error) {
+	now := time.Now().Unix()
+	filter := bson.M{
+		hashedCodeKey: HashInvitationCode(code),
+		redeemedAtKey: 0,
+		expiresAtKey:  bson.M{"$gt": now},
+	}
+
+	var invitation *Invitation
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := ir.invitationCollection.FindOneAndUpdate(ir.ctx, filter, bson.M{"$set": bson.M{redeemedAtKey: now}}, opts).Decode(&invitation)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("%w: invitation code is invalid, expired or has already been used", db.ErrorInvalidRequest)
+		}
+		return nil, fmt.Errorf("invitationCollection.FindOneAndUpdate error: %w", err)
+	}
+
+	return invitation, nil
+}
+
+// Release implements InvitationRepository.
+func (ir *MongoInvitationRepository) Release(invitationID string) error {
+	_, err := ir.invitationCollection.UpdateOne(ir.ctx, bson.M{idKey: invitationID}, bson.M{"$set": bson.M{redeemedAtKey: 0}})
+	if err != nil {
+		return fmt.Errorf("invitationCollection.UpdateOne error: %w", err)
+	}
+
+	return nil
+}
+
+// Revoke implements InvitationRepository.
+func (ir *MongoInvitationRepository) Revoke(schoolID, invitationID string) error {
+	res, err := ir.invitationCollection.DeleteOne(ir.ctx, bson.M{idKey: invitationID, schoolIDKey: schoolID, redeemedAtKey: 0})
+	if err != nil {
+		return fmt.Errorf("invitationCollection.DeleteOne error: %w", err)
+	}
+
+	if res.DeletedCount == 0 {
+		return fmt.Errorf("%w: no unused invitation found with ID %s", db.ErrorInvalidRequest, invitationID)
+	}
+
+	return nil
+}
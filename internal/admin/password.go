@@ -0,0 +1,146 @@
+package admin
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ukane-philemon/scomp/internal/db"
+)
+
+const (
+	// MaxPasswordLength is the maximum length of a password in bytes, longer
+	// passwords cannot be hashed with bcrypt.
+	MaxPasswordLength = 72
+	// MaxFailedLoginAttempts is the number of consecutive failed login
+	// attempts after which an account is locked.
+	MaxFailedLoginAttempts = 5
+	// LockoutDuration is how long an account is locked for after
+	// MaxFailedLoginAttempts consecutive failed login attempts.
+	LockoutDuration = 15 * time.Minute
+	// PasswordResetTokenExpiry is the lifetime of a password reset token.
+	PasswordResetTokenExpiry = 24 * time.Hour
+)
+
+// PasswordPolicy are the rules a new password must satisfy.
+type PasswordPolicy struct {
+	MinLength        int
+	RequireUppercase bool
+	RequireLowercase bool
+	RequireDigit     bool
+	RequireSymbol    bool
+}
+
+// DefaultPasswordPolicy returns the password policy used if none is
+// configured.
+func DefaultPasswordPolicy() *PasswordPolicy {
+	return &PasswordPolicy{
+		MinLength:        8,
+		RequireUppercase: true,
+		RequireLowercase: true,
+		RequireDigit:     true,
+	}
+}
+
+// Validate checks that password satisfies the policy.
+func (p *PasswordPolicy) Validate(password string) error {
+	if len(password) > MaxPasswordLength {
+		return fmt.Errorf("%w: password must not be longer than %d characters", db.ErrorInvalidRequest, MaxPasswordLength)
+	}
+
+	var hasUppercase, hasLowercase, hasDigit, hasSymbol bool
+	for _, c := range password {
+		switch {
+		case unicode.IsUpper(c):
+			hasUppercase = true
+		case unicode.IsLower(c):
+			hasLowercase = true
+		case unicode.IsDigit(c):
+			hasDigit = true
+		case unicode.IsPunct(c) || unicode.IsSymbol(c):
+			hasSymbol = true
+		}
+	}
+
+	if len([]rune(password)) < p.MinLength || (p.RequireUppercase && !hasUppercase) || (p.RequireLowercase && !hasLowercase) ||
+		(p.RequireDigit && !hasDigit) || (p.RequireSymbol && !hasSymbol) {
+		return fmt.Errorf("%w: %s", db.ErrorInvalidRequest, p)
+	}
+
+	return nil
+}
+
+// String describes the policy.
+func (p *PasswordPolicy) String() string {
+	var requirements []string
+	if p.RequireUppercase {
+		requirements = append(requirements, "an uppercase letter")
+	}
+	if p.RequireLowercase {
+		requirements = append(requirements, "a lowercase letter")
+	}
+	if p.RequireDigit {
+		requirements = append(requirements, "a digit")
+	}
+	if p.RequireSymbol {
+		requirements = append(requirements, "a symbol")
+	}
+
+	description := fmt.Sprintf("password must have at least %d characters", p.MinLength)
+	switch len(requirements) {
+	case 0:
+		return description
+	case 1:
+		return description + " and " + requirements[0]
+	default:
+		last := len(requirements) - 1
+		return description + ", " + strings.Join(requirements[:last], ", ") + " and " + requirements[last]
+	}
+}
+
+// ErrorIncorrectLogin is returned for an unknown username, an incorrect
+// password and a locked account alike, so that failed logins do not reveal
+// which usernames exist.
+var ErrorIncorrectLogin = fmt.Errorf("%w: username or password is incorrect", db.ErrorInvalidRequest)
+
+// Locked checks if the account is locked at now because of too many failed
+// login attempts.
+func (a *Admin) Locked(now time.Time) bool {
+	return a.LockedUntil > now.Unix()
+}
+
+// CheckLocked returns db.ErrorInvalidRequest if the account is locked at now
+// because of too many failed login attempts. Logins return
+// ErrorIncorrectLogin instead.
+func (a *Admin) CheckLocked(now time.Time) error {
+	if a.Locked(now) {
+		return fmt.Errorf("%w: account is locked after too many failed login attempts, try again in %s",
+			db.ErrorInvalidRequest, time.Unix(a.LockedUntil, 0).Sub(now).Round(time.Second))
+	}
+	return nil
+}
+
+// NewPasswordResetToken generates a new password reset token and returns the
+// token and the hash to be stored.
+func NewPasswordResetToken() (string, string, error) {
+	tokenBytes := make([]byte, 32)
+	_, err := rand.Read(tokenBytes)
+	if err != nil {
+		return "", "", fmt.Errorf("rand.Read error: %w", err)
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(tokenBytes)
+	return token, HashPasswordResetToken(token), nil
+}
+
+// HashPasswordResetToken returns the hash of a password reset token as
+// stored in the database.
+func HashPasswordResetToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
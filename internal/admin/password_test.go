@@ -0,0 +1,73 @@
+package admin
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ukane-philemon/scomp/internal/db"
+)
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	strict := &PasswordPolicy{
+		MinLength:        10,
+		RequireUppercase: true,
+		RequireLowercase: true,
+		RequireDigit:     true,
+		RequireSymbol:    true,
+	}
+
+	tests := []struct {
+		name     string
+		policy   *PasswordPolicy
+		password string
+		wantErr  bool
+	}{
+		{name: "default policy", policy: DefaultPasswordPolicy(), password: "Passw0rd"},
+		{name: "too short", policy: DefaultPasswordPolicy(), password: "Pa55wd", wantErr: true},
+		{name: "no uppercase", policy: DefaultPasswordPolicy(), password: "passw0rd", wantErr: true},
+		{name: "no lowercase", policy: DefaultPasswordPolicy(), password: "PASSW0RD", wantErr: true},
+		{name: "no digit", policy: DefaultPasswordPolicy(), password: "Password", wantErr: true},
+		{name: "symbol", policy: strict, password: "Passw0rd!!"},
+		{name: "no symbol", policy: strict, password: "Passw0rd12", wantErr: true},
+		{name: "length counts characters", policy: &PasswordPolicy{MinLength: 4}, password: "ééé", wantErr: true},
+		{name: "no requirements", policy: &PasswordPolicy{}, password: ""},
+		{name: "too long", policy: &PasswordPolicy{}, password: strings.Repeat("a", MaxPasswordLength+1), wantErr: true},
+		{name: "max length", policy: &PasswordPolicy{}, password: strings.Repeat("a", MaxPasswordLength)},
+	}
+
+	for _, test := range tests {
+		err := test.policy.Validate(test.password)
+		if test.wantErr && !errors.Is(err, db.ErrorInvalidRequest) {
+			t.Errorf("%s: expected db.ErrorInvalidRequest, got %v", test.name, err)
+		} else if !test.wantErr && err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+		}
+	}
+}
+
+func TestPasswordPolicyString(t *testing.T) {
+	tests := []struct {
+		policy *PasswordPolicy
+		want   string
+	}{
+		{
+			policy: &PasswordPolicy{MinLength: 6},
+			want:   "password must have at least 6 characters",
+		},
+		{
+			policy: &PasswordPolicy{MinLength: 6, RequireDigit: true},
+			want:   "password must have at least 6 characters and a digit",
+		},
+		{
+			policy: DefaultPasswordPolicy(),
+			want:   "password must have at least 8 characters, an uppercase letter, a lowercase letter and a digit",
+		},
+	}
+
+	for _, test := range tests {
+		if got := test.policy.String(); got != test.want {
+			t.Errorf("expected %q, got %q", test.want, got)
+		}
+	}
+}
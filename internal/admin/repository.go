@@ -1,8 +1,81 @@
 package admin
 
+import "time"
+
 type Repository interface {
-	// CreateAccount creates a new admin and returns their id.
-	CreateAccount(username, password string) (string, error)
-	// LoginAccount authenticate and admin and returns their id.
-	LoginAccount(username, password string) (string, error)
+	// CreateAccount creates a new admin of schoolID with role and returns their
+	// id. Usernames are unique across all schools.
+	CreateAccount(schoolID, username, password, role string) (string, error)
+	// LoginAccount authenticate and admin and returns their information. The
+	// account is locked for LockoutDuration after MaxFailedLoginAttempts
+	// consecutive failed attempts. Returns ErrorIncorrectLogin for an unknown
+	// username, an incorrect password or a locked account.
+	LoginAccount(username, password string) (*Admin, error)
+	// Admin returns the admin that match adminID.
+	Admin(adminID string) (*Admin, error)
+	// HasAccounts checks if at least one admin account exists.
+	HasAccounts() (bool, error)
+	// SetRole changes the role of the admin of schoolID that match adminID.
+	SetRole(schoolID, adminID, role string) error
+	// ChangePassword changes the password of adminID if currentPassword is
+	// correct. Incorrect passwords count towards the account lockout.
+	ChangePassword(adminID, currentPassword, newPassword string) error
+	// CreatePasswordReset creates a one-time password reset token for the
+	// admin of schoolID that match adminID and returns the token and its
+	// expiry. Any previous reset token of the admin can no longer be used.
+	CreatePasswordReset(schoolID, adminID string) (string, int64, error)
+	// ResetPassword changes the password of the admin of the unexpired
+	// resetToken, unlocks their account and returns their ID. A reset token
+	// can only be used once.
+	ResetPassword(resetToken, newPassword string) (string, error)
+	// EnrollTOTP generates a new TOTP secret for adminID and returns it.
+	// Two-factor authentication is enabled once the secret is confirmed with
+	// ConfirmTOTP. Returns db.ErrorInvalidRequest if two-factor
+	// authentication is already enabled.
+	EnrollTOTP(adminID string) (string, error)
+	// ConfirmTOTP enables two-factor authentication for adminID if code is a
+	// valid code of the enrolled secret and returns new recovery codes.
+	ConfirmTOTP(adminID, code string) ([]string, error)
+	// VerifyTwoFactor checks that code is an unused TOTP code or recovery
+	// code of adminID and returns the admin. Failed attempts count towards
+	// the account lockout.
+	VerifyTwoFactor(adminID, code string) (*Admin, error)
+	// DisableTOTP disables two-factor authentication for adminID if code is
+	// an unused TOTP code or recovery code.
+	DisableTOTP(adminID, code string) error
+	// ResetTOTP disables two-factor authentication for the admin of schoolID
+	// that match adminID, e.g when they lost their authenticator and recovery
+	// codes.
+	ResetTOTP(schoolID, adminID string) error
+}
+
+type InvitationRepository interface {
+	// Create creates a new invitation to join schoolID with role that expires
+	// after validFor and returns the invitation and its code.
+	Create(schoolID, createdBy, role string, validFor time.Duration) (*Invitation, string, error)
+	// Redeem marks the unexpired invitation that match code as redeemed and
+	// returns it. An invitation can only be redeemed once.
+	Redeem(code string) (*Invitation, error)
+	// Release marks a redeemed invitation as not redeemed, e.g when creating
+	// the account of the invitation failed.
+	Release(invitationID string) error
+	// Revoke deletes the invitation of schoolID that match invitationID if it
+	// has not been redeemed.
+	Revoke(schoolID, invitationID string) error
+}
+
+type APIKeyRepository interface {
+	// Create creates a new API key of schoolID with scope that expires after
+	// validFor and returns the API key and the key. Set classIDs to restrict
+	// the key to some classes.
+	Create(schoolID, createdBy, name, scope string, classIDs []string, validFor time.Duration) (*APIKey, string, error)
+	// Authenticate returns the unexpired and unrevoked API key that match key
+	// and records that it was used. Returns db.ErrorInvalidRequest if key is
+	// not valid.
+	Authenticate(key string) (*APIKey, error)
+	// APIKeys returns the active API keys of schoolID, most recently created
+	// first.
+	APIKeys(schoolID string) ([]*APIKey, error)
+	// Revoke revokes the active API key of schoolID that match apiKeyID.
+	Revoke(schoolID, apiKeyID string) error
 }
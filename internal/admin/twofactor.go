@@ -0,0 +1,112 @@
+package admin
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ukane-philemon/scomp/internal/db"
+	"github.com/ukane-philemon/scomp/internal/totp"
+)
+
+// numRecoveryCodes is the number of recovery codes generated when two-factor
+// authentication is enabled.
+const numRecoveryCodes = 10
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TwoFactorMatch is a two-factor code that matched the codes of an admin.
+type TwoFactorMatch struct {
+	// Counter is the TOTP counter of the code, 0 if a recovery code matched.
+	Counter int64
+	// HashedRecoveryCode is the hash of the recovery code that matched, empty
+	// if a TOTP code matched. A recovery code can only be used once.
+	HashedRecoveryCode string
+}
+
+// NewRecoveryCodes generates new recovery codes and returns the codes and the
+// hashes to be stored.
+func NewRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, numRecoveryCodes)
+	hashes := make([]string, 0, numRecoveryCodes)
+	for i := 0; i < numRecoveryCodes; i++ {
+		codeBytes := make([]byte, 5)
+		_, err := rand.Read(codeBytes)
+		if err != nil {
+			return nil, nil, fmt.Errorf("rand.Read error: %w", err)
+		}
+
+		code := strings.ToLower(recoveryCodeEncoding.EncodeToString(codeBytes))
+		code = code[:4] + "-" + code[4:]
+		codes = append(codes, code)
+		hashes = append(hashes, HashRecoveryCode(code))
+	}
+
+	return codes, hashes, nil
+}
+
+// HashRecoveryCode returns the hash of a recovery code as stored in the
+// database. Recovery codes are not case sensitive and dashes are optional.
+func HashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	hash := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(hash[:])
+}
+
+// MatchTwoFactorCode checks that code is an unused TOTP code or recovery code
+// of the admin at now. A TOTP code that is not newer than the last accepted
+// code is rejected.
+func (a *Admin) MatchTwoFactorCode(code string, now time.Time) (*TwoFactorMatch, bool) {
+	if !a.TOTPEnabled {
+		return nil, false
+	}
+
+	if counter, ok := totp.Validate(a.TOTPSecret, strings.TrimSpace(code), now); ok {
+		if counter <= a.TOTPLastCounter {
+			return nil, false
+		}
+		return &TwoFactorMatch{Counter: counter}, true
+	}
+
+	hashedCode := HashRecoveryCode(code)
+	for _, hashedRecoveryCode := range a.HashedRecoveryCodes {
+		if hashedRecoveryCode == hashedCode {
+			return &TwoFactorMatch{HashedRecoveryCode: hashedCode}, true
+		}
+	}
+
+	return nil, false
+}
+
+// TwoFactorNonce returns a value that changes whenever a two-factor code of
+// the admin is accepted or two-factor authentication is enrolled again.
+// Two-factor challenges are bound to it so that a challenge cannot be used
+// again after a successful verification.
+func (a *Admin) TwoFactorNonce() string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s:%d:%s", a.TOTPSecret, a.TOTPLastCounter, strings.Join(a.HashedRecoveryCodes, ","))
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// ConfirmTOTPCode checks that code is a valid code of the enrolled but not yet
+// enabled TOTP secret of the admin and returns its counter.
+func (a *Admin) ConfirmTOTPCode(code string) (int64, error) {
+	if a.TOTPEnabled {
+		return 0, fmt.Errorf("%w: two-factor authentication is already enabled", db.ErrorInvalidRequest)
+	}
+
+	if a.TOTPSecret == "" {
+		return 0, fmt.Errorf("%w: enroll for two-factor authentication first", db.ErrorInvalidRequest)
+	}
+
+	counter, ok := totp.Validate(a.TOTPSecret, strings.TrimSpace(code), time.Now())
+	if !ok {
+		return 0, fmt.Errorf("%w: two-factor code is invalid", db.ErrorInvalidRequest)
+	}
+
+	return counter, nil
+}
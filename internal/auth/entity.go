@@ -2,7 +2,11 @@ package auth
 
 import (
 	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
+	"log"
+	"sort"
 	"time"
 
 	"github.com/cristalhq/jwt/v4"
@@ -11,55 +15,169 @@ import (
 const (
 	jwtIssuer = "SCOMP"
 
-	JWTExpiry        = 24 * time.Hour
+	// JWTExpiry is the lifetime of auth tokens. Auth tokens are short lived,
+	// refresh tokens are used to get new auth tokens.
+	JWTExpiry        = 15 * time.Minute
 	jwtAudienceAdmin = "admin"
-	jwtAlg           = jwt.HS256
+
+	// ChallengeExpiry is the lifetime of two-factor challenge tokens.
+	ChallengeExpiry = 5 * time.Minute
+	// jwtAudienceTwoFactor is the audience of two-factor challenge tokens, so
+	// they cannot be used as auth tokens.
+	jwtAudienceTwoFactor = "two-factor"
 )
 
+// Claims are the claims of a valid auth token.
+type Claims struct {
+	AdminID   string
+	SessionID string
+	Role      string
+	SchoolID  string
+}
+
+// tokenClaims are the JWT claims of auth tokens.
+type tokenClaims struct {
+	jwt.RegisteredClaims
+	Role     string `json:"role"`
+	SchoolID string `json:"school"`
+}
+
 // AuthRepository implements Repository.
 type AuthRepository struct {
-	aud      string
-	builder  *jwt.Builder
-	verifier jwt.Verifier
+	aud     string
+	builder *jwt.Builder
+	// keys is a map of key ID to keys used to verify tokens.
+	keys map[string]*key
 }
 
-// NewRepository returns a new  instance of *AuthRepository.
-func NewRepository() (Repository, error) {
-	jwtSecret := make([]byte, 32)
-	_, err := rand.Read(jwtSecret)
-	if err != nil {
-		return nil, fmt.Errorf("rand.Read error: %w", err)
+// NewRepository returns a new  instance of *AuthRepository. If cfg is nil, a
+// random HS256 key is generated and tokens will not be valid after a restart.
+func NewRepository(cfg *KeysConfig) (Repository, error) {
+	if cfg == nil {
+		jwtSecret := make([]byte, 32)
+		_, err := rand.Read(jwtSecret)
+		if err != nil {
+			return nil, fmt.Errorf("rand.Read error: %w", err)
+		}
+
+		log.Println("WARNING: no JWT keys configured, using a random key. Auth tokens will be invalid after a restart...")
+		cfg = NewSecretKeysConfig(base64.StdEncoding.EncodeToString(jwtSecret))
 	}
 
-	signer, err := jwt.NewSignerHS(jwtAlg, jwtSecret)
-	if err != nil {
-		return nil, fmt.Errorf("jwt.NewSignerHS error: %w", err)
+	if len(cfg.Keys) == 0 {
+		return nil, fmt.Errorf("no JWT keys configured")
 	}
 
-	verifier, err := jwt.NewVerifierHS(jwtAlg, jwtSecret[:])
-	if err != nil {
-		return nil, fmt.Errorf("jwt.NewVerifierHS error: %w", err)
+	keys := make(map[string]*key, len(cfg.Keys))
+	for _, keyCfg := range cfg.Keys {
+		k, err := parseKey(keyCfg)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, found := keys[k.id]; found {
+			return nil, fmt.Errorf("duplicate JWT key id %s", k.id)
+		}
+
+		keys[k.id] = k
+	}
+
+	signingKey, found := keys[cfg.SigningKeyID]
+	if !found {
+		return nil, fmt.Errorf("signing key %q is not configured", cfg.SigningKeyID)
+	}
+
+	if signingKey.signer == nil {
+		return nil, fmt.Errorf("signing key %q has no private key", cfg.SigningKeyID)
 	}
 
 	return &AuthRepository{
-		aud:      jwtAudienceAdmin,
-		builder:  jwt.NewBuilder(signer),
-		verifier: verifier,
+		aud:     jwtAudienceAdmin,
+		builder: jwt.NewBuilder(signingKey.signer, jwt.WithKeyID(signingKey.id)),
+		keys:    keys,
 	}, nil
 }
 
-// GenerateToken generates a new auth token for uniqueID.
+// GenerateToken generates a new auth token with the provided claims. All the
+// claims are required.
+// Implements Repository.
+func (ar *AuthRepository) GenerateToken(claims *Claims) (string, error) {
+	if claims == nil || claims.AdminID == "" || claims.SessionID == "" || claims.Role == "" || claims.SchoolID == "" {
+		return "", fmt.Errorf("missing required token claim(s)")
+	}
+
+	jwtClaims := &tokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        claims.SessionID,
+			Subject:   claims.AdminID,
+			Audience:  jwt.Audience{jwtAudienceAdmin},
+			Issuer:    jwtIssuer,
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(JWTExpiry)),
+		},
+		Role:     claims.Role,
+		SchoolID: claims.SchoolID,
+	}
+
+	token, err := ar.builder.Build(jwtClaims)
+	if err != nil {
+		return "", fmt.Errorf("m.builder.Build error: %w", err)
+	}
+
+	return token.String(), nil
+}
+
+// IsValid checks the token is valid and return it's claims. The token is
+// verified with the key that match the key ID in its header.
+// Implements Repository.
+func (ar *AuthRepository) IsValid(jwtToken string) (*Claims, bool) {
+	token, err := jwt.ParseNoVerify([]byte(jwtToken))
+	if err != nil {
+		return nil, false
+	}
+
+	k, found := ar.keys[token.Header().KeyID]
+	if !found || k.verifier.Verify(token) != nil {
+		return nil, false
+	}
+
+	jwtClaims := new(tokenClaims)
+	err = token.DecodeClaims(jwtClaims)
+	if err != nil || !(jwtClaims.IsIssuer(jwtIssuer) && jwtClaims.IsValidAt(time.Now())) || !jwtClaims.IsForAudience(ar.aud) {
+		return nil, false
+	}
+
+	if jwtClaims.Subject == "" || jwtClaims.ID == "" || jwtClaims.Role == "" || jwtClaims.SchoolID == "" {
+		return nil, false
+	}
+
+	return &Claims{
+		AdminID:   jwtClaims.Subject,
+		SessionID: jwtClaims.ID,
+		Role:      jwtClaims.Role,
+		SchoolID:  jwtClaims.SchoolID,
+	}, true
+}
+
+// GenerateChallengeToken generates a new two-factor challenge token for
+// adminID. nonce is the ID of the token, the challenge is only valid while
+// the nonce of the admin is unchanged.
 // Implements Repository.
-func (ar *AuthRepository) GenerateToken(uniqueID string) (string, error) {
-	claims := &jwt.RegisteredClaims{
-		ID:        uniqueID,
-		Audience:  jwt.Audience{jwtAudienceAdmin},
+func (ar *AuthRepository) GenerateChallengeToken(adminID, nonce string) (string, error) {
+	if adminID == "" || nonce == "" {
+		return "", fmt.Errorf("missing required token claim(s)")
+	}
+
+	jwtClaims := &jwt.RegisteredClaims{
+		ID:        nonce,
+		Subject:   adminID,
+		Audience:  jwt.Audience{jwtAudienceTwoFactor},
 		Issuer:    jwtIssuer,
 		IssuedAt:  jwt.NewNumericDate(time.Now()),
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(JWTExpiry)),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(ChallengeExpiry)),
 	}
 
-	token, err := ar.builder.Build(claims)
+	token, err := ar.builder.Build(jwtClaims)
 	if err != nil {
 		return "", fmt.Errorf("m.builder.Build error: %w", err)
 	}
@@ -67,14 +185,52 @@ func (ar *AuthRepository) GenerateToken(uniqueID string) (string, error) {
 	return token.String(), nil
 }
 
-// IsValid checks the token is valid and return it's uniqueID.
+// IsValidChallenge checks the two-factor challenge token is valid and returns
+// the admin ID and the nonce it was issued for.
 // Implements Repository.
-func (ar *AuthRepository) IsValid(jwtToken string) (string, bool) {
+func (ar *AuthRepository) IsValidChallenge(jwtToken string) (string, string, bool) {
+	token, err := jwt.ParseNoVerify([]byte(jwtToken))
+	if err != nil {
+		return "", "", false
+	}
+
+	k, found := ar.keys[token.Header().KeyID]
+	if !found || k.verifier.Verify(token) != nil {
+		return "", "", false
+	}
+
 	jwtClaims := new(jwt.RegisteredClaims)
-	err := jwt.ParseClaims([]byte(jwtToken), ar.verifier, jwtClaims)
-	if err != nil || !(jwtClaims.IsIssuer(jwtIssuer) && jwtClaims.IsValidAt(time.Now())) || !jwtClaims.IsForAudience(ar.aud) {
-		return "", false
+	err = token.DecodeClaims(jwtClaims)
+	if err != nil || !(jwtClaims.IsIssuer(jwtIssuer) && jwtClaims.IsValidAt(time.Now())) || !jwtClaims.IsForAudience(jwtAudienceTwoFactor) {
+		return "", "", false
+	}
+
+	if jwtClaims.Subject == "" || jwtClaims.ID == "" {
+		return "", "", false
+	}
+
+	return jwtClaims.Subject, jwtClaims.ID, true
+}
+
+// JWKS returns the JSON Web Key Set of the public keys that can be used to
+// verify tokens. HMAC keys are never included.
+// Implements Repository.
+func (ar *AuthRepository) JWKS() ([]byte, error) {
+	jwks := struct {
+		Keys []*jwk `json:"keys"`
+	}{
+		Keys: make([]*jwk, 0, len(ar.keys)),
 	}
 
-	return jwtClaims.ID, true
+	for _, k := range ar.keys {
+		if publicJWK := k.toJWK(); publicJWK != nil {
+			jwks.Keys = append(jwks.Keys, publicJWK)
+		}
+	}
+
+	sort.Slice(jwks.Keys, func(i, j int) bool {
+		return jwks.Keys[i].KeyID < jwks.Keys[j].KeyID
+	})
+
+	return json.Marshal(jwks)
 }
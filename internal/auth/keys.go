@@ -0,0 +1,280 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/cristalhq/jwt/v4"
+)
+
+// defaultKeyID is the key ID used for a key provided without an ID.
+const defaultKeyID = "default"
+
+// KeyConfig is the configuration for a single JWT key. HMAC keys (HS256,
+// HS384, HS512) require Secret. Asymmetric keys (EdDSA, ES256, ES384, ES512)
+// require PrivateKeyFile to sign tokens or PublicKeyFile to only verify them.
+type KeyConfig struct {
+	ID        string `json:"id"`
+	Algorithm string `json:"algorithm"`
+	// Secret is the base64 encoded HMAC secret.
+	Secret string `json:"secret,omitempty"`
+	// PrivateKeyFile is the path to a PEM encoded PKCS #8 private key.
+	PrivateKeyFile string `json:"privateKeyFile,omitempty"`
+	// PublicKeyFile is the path to a PEM encoded PKIX public key.
+	PublicKeyFile string `json:"publicKeyFile,omitempty"`
+}
+
+// KeysConfig is the configuration for the keys used to sign and verify JWTs.
+// All keys are used for verification, so old keys can be kept while rotating
+// to a new signing key.
+type KeysConfig struct {
+	// SigningKeyID is the ID of the key used to sign new tokens.
+	SigningKeyID string       `json:"signingKeyID"`
+	Keys         []*KeyConfig `json:"keys"`
+}
+
+// LoadKeysFile reads a JSON encoded *KeysConfig from path. Relative key file
+// paths are resolved against the directory of path.
+func LoadKeysFile(path string) (*KeysConfig, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("os.ReadFile error: %w", err)
+	}
+
+	cfg := new(KeysConfig)
+	err = json.Unmarshal(b, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("json.Unmarshal error: %w", err)
+	}
+
+	dir := filepath.Dir(path)
+	for _, key := range cfg.Keys {
+		if key.PrivateKeyFile != "" && !filepath.IsAbs(key.PrivateKeyFile) {
+			key.PrivateKeyFile = filepath.Join(dir, key.PrivateKeyFile)
+		}
+		if key.PublicKeyFile != "" && !filepath.IsAbs(key.PublicKeyFile) {
+			key.PublicKeyFile = filepath.Join(dir, key.PublicKeyFile)
+		}
+	}
+
+	return cfg, nil
+}
+
+// NewSecretKeysConfig returns a *KeysConfig with a single HS256 key for the
+// base64 encoded secret.
+func NewSecretKeysConfig(secret string) *KeysConfig {
+	return &KeysConfig{
+		SigningKeyID: defaultKeyID,
+		Keys: []*KeyConfig{{
+			ID:        defaultKeyID,
+			Algorithm: jwt.HS256.String(),
+			Secret:    secret,
+		}},
+	}
+}
+
+// key is a parsed KeyConfig.
+type key struct {
+	id        string
+	alg       jwt.Algorithm
+	signer    jwt.Signer // nil for verification only keys
+	verifier  jwt.Verifier
+	publicKey crypto.PublicKey // nil for HMAC keys
+}
+
+func parseKey(cfg *KeyConfig) (*key, error) {
+	if cfg.ID == "" {
+		return nil, errors.New("missing key id")
+	}
+
+	k := &key{
+		id:  cfg.ID,
+		alg: jwt.Algorithm(cfg.Algorithm),
+	}
+
+	var err error
+	switch k.alg {
+	case jwt.HS256, jwt.HS384, jwt.HS512:
+		secret, err := base64.StdEncoding.DecodeString(cfg.Secret)
+		if err != nil {
+			return nil, fmt.Errorf("key %s has an invalid base64 secret: %w", cfg.ID, err)
+		}
+
+		const minSecretLength = 32
+		if len(secret) < minSecretLength {
+			return nil, fmt.Errorf("key %s secret must be at least %d bytes", cfg.ID, minSecretLength)
+		}
+
+		k.signer, err = jwt.NewSignerHS(k.alg, secret)
+		if err != nil {
+			return nil, fmt.Errorf("jwt.NewSignerHS error: %w", err)
+		}
+
+		k.verifier, err = jwt.NewVerifierHS(k.alg, secret)
+		if err != nil {
+			return nil, fmt.Errorf("jwt.NewVerifierHS error: %w", err)
+		}
+
+	case jwt.EdDSA, jwt.ES256, jwt.ES384, jwt.ES512:
+		var privateKey crypto.Signer
+		if cfg.PrivateKeyFile != "" {
+			privateKey, err = readPrivateKey(cfg.PrivateKeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("key %s: %w", cfg.ID, err)
+			}
+			k.publicKey = privateKey.Public()
+		} else if cfg.PublicKeyFile != "" {
+			k.publicKey, err = readPublicKey(cfg.PublicKeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("key %s: %w", cfg.ID, err)
+			}
+		} else {
+			return nil, fmt.Errorf("key %s is missing a private or public key file", cfg.ID)
+		}
+
+		err = k.setAsymmetricAlgs(privateKey)
+		if err != nil {
+			return nil, fmt.Errorf("key %s: %w", cfg.ID, err)
+		}
+
+	default:
+		return nil, fmt.Errorf("key %s has an unsupported algorithm %q", cfg.ID, cfg.Algorithm)
+	}
+
+	return k, nil
+}
+
+// setAsymmetricAlgs sets the signer and verifier of an asymmetric key.
+// privateKey may be nil for verification only keys.
+func (k *key) setAsymmetricAlgs(privateKey crypto.Signer) (err error) {
+	if k.alg == jwt.EdDSA {
+		publicKey, ok := k.publicKey.(ed25519.PublicKey)
+		if !ok {
+			return errors.New("EdDSA requires an Ed25519 key")
+		}
+
+		k.verifier, err = jwt.NewVerifierEdDSA(publicKey)
+		if err != nil || privateKey == nil {
+			return err
+		}
+
+		k.signer, err = jwt.NewSignerEdDSA(privateKey.(ed25519.PrivateKey))
+		return err
+	}
+
+	publicKey, ok := k.publicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("%s requires an ECDSA key", k.alg)
+	}
+
+	k.verifier, err = jwt.NewVerifierES(k.alg, publicKey)
+	if err != nil || privateKey == nil {
+		return err
+	}
+
+	k.signer, err = jwt.NewSignerES(k.alg, privateKey.(*ecdsa.PrivateKey))
+	return err
+}
+
+func readPEMBlock(path string) (*pem.Block, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("os.ReadFile error: %w", err)
+	}
+
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %s", path)
+	}
+
+	return block, nil
+}
+
+func readPrivateKey(path string) (crypto.Signer, error) {
+	block, err := readPEMBlock(path)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("x509.ParsePKCS8PrivateKey error: %w", err)
+	}
+
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", privateKey)
+	}
+
+	return signer, nil
+}
+
+func readPublicKey(path string) (crypto.PublicKey, error) {
+	block, err := readPEMBlock(path)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("x509.ParsePKIXPublicKey error: %w", err)
+	}
+
+	return publicKey, nil
+}
+
+// jwk is a JSON Web Key as defined in RFC 7517.
+type jwk struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	Y         string `json:"y,omitempty"`
+}
+
+// toJWK returns the public JWK of k. Returns nil for HMAC keys.
+func (k *key) toJWK() *jwk {
+	b64 := base64.RawURLEncoding.EncodeToString
+	switch publicKey := k.publicKey.(type) {
+	case ed25519.PublicKey:
+		return &jwk{
+			KeyType:   "OKP",
+			KeyID:     k.id,
+			Algorithm: k.alg.String(),
+			Use:       "sig",
+			Curve:     "Ed25519",
+			X:         b64(publicKey),
+		}
+	case *ecdsa.PublicKey:
+		ecdhKey, err := publicKey.ECDH()
+		if err != nil {
+			return nil
+		}
+
+		// Uncompressed point encoding is 0x04 || X || Y.
+		point := ecdhKey.Bytes()[1:]
+		size := len(point) / 2
+		return &jwk{
+			KeyType:   "EC",
+			KeyID:     k.id,
+			Algorithm: k.alg.String(),
+			Use:       "sig",
+			Curve:     publicKey.Curve.Params().Name,
+			X:         b64(point[:size]),
+			Y:         b64(point[size:]),
+		}
+	default:
+		return nil
+	}
+}
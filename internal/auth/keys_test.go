@@ -0,0 +1,381 @@
+package auth
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cristalhq/jwt/v4"
+)
+
+// testSecret returns a base64 encoded HMAC secret of n bytes.
+func testSecret(n int) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{'s'}, n))
+}
+
+// writeKeyFiles writes the PEM encoded private and public keys of privateKey
+// to dir and returns the paths of the files.
+func writeKeyFiles(t *testing.T, dir, name string, privateKey crypto.Signer) (string, string) {
+	t.Helper()
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatalf("x509.MarshalPKCS8PrivateKey error: %v", err)
+	}
+
+	publicDER, err := x509.MarshalPKIXPublicKey(privateKey.Public())
+	if err != nil {
+		t.Fatalf("x509.MarshalPKIXPublicKey error: %v", err)
+	}
+
+	privatePath, publicPath := filepath.Join(dir, name+".pem"), filepath.Join(dir, name+".pub.pem")
+	for path, block := range map[string]*pem.Block{
+		privatePath: {Type: "PRIVATE KEY", Bytes: privateDER},
+		publicPath:  {Type: "PUBLIC KEY", Bytes: publicDER},
+	} {
+		err = os.WriteFile(path, pem.EncodeToMemory(block), 0o600)
+		if err != nil {
+			t.Fatalf("os.WriteFile error: %v", err)
+		}
+	}
+
+	return privatePath, publicPath
+}
+
+// testClaims returns the claims of the test tokens.
+func testClaims() *Claims {
+	return &Claims{AdminID: "admin", SessionID: "session", Role: "teacher", SchoolID: "school"}
+}
+
+// tamper changes a character of the signature of token.
+func tamper(token string) string {
+	b := []byte(token)
+	i := len(b) - 5
+	if b[i] == 'A' {
+		b[i] = 'B'
+	} else {
+		b[i] = 'A'
+	}
+	return string(b)
+}
+
+func TestParseKey(t *testing.T) {
+	dir := t.TempDir()
+	_, edPrivateKey, _ := ed25519.GenerateKey(rand.Reader)
+	edPrivatePath, edPublicPath := writeKeyFiles(t, dir, "ed", edPrivateKey)
+	ecPrivateKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ecPrivatePath, _ := writeKeyFiles(t, dir, "ec", ecPrivateKey)
+
+	tests := []struct {
+		name       string
+		cfg        *KeyConfig
+		wantErr    bool
+		wantSigner bool
+	}{
+		{name: "HS256", cfg: &KeyConfig{ID: "k", Algorithm: "HS256", Secret: testSecret(32)}, wantSigner: true},
+		{name: "short secret", cfg: &KeyConfig{ID: "k", Algorithm: "HS256", Secret: testSecret(31)}, wantErr: true},
+		{name: "invalid secret", cfg: &KeyConfig{ID: "k", Algorithm: "HS512", Secret: "not base64!"}, wantErr: true},
+		{name: "missing id", cfg: &KeyConfig{Algorithm: "HS256", Secret: testSecret(32)}, wantErr: true},
+		{name: "unsupported algorithm", cfg: &KeyConfig{ID: "k", Algorithm: "RS256", Secret: testSecret(32)}, wantErr: true},
+		{name: "EdDSA private key", cfg: &KeyConfig{ID: "k", Algorithm: "EdDSA", PrivateKeyFile: edPrivatePath}, wantSigner: true},
+		{name: "EdDSA public key", cfg: &KeyConfig{ID: "k", Algorithm: "EdDSA", PublicKeyFile: edPublicPath}},
+		{name: "ES256 private key", cfg: &KeyConfig{ID: "k", Algorithm: "ES256", PrivateKeyFile: ecPrivatePath}, wantSigner: true},
+		{name: "algorithm of another key type", cfg: &KeyConfig{ID: "k", Algorithm: "ES256", PrivateKeyFile: edPrivatePath}, wantErr: true},
+		{name: "missing key file", cfg: &KeyConfig{ID: "k", Algorithm: "EdDSA"}, wantErr: true},
+		{name: "unknown key file", cfg: &KeyConfig{ID: "k", Algorithm: "EdDSA", PrivateKeyFile: filepath.Join(dir, "unknown.pem")}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		k, err := parseKey(test.cfg)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", test.name)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+
+		if (k.signer != nil) != test.wantSigner {
+			t.Errorf("%s: expected a signer: %v", test.name, test.wantSigner)
+		}
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	dir := t.TempDir()
+	_, edPrivateKey, _ := ed25519.GenerateKey(rand.Reader)
+	edPrivatePath, _ := writeKeyFiles(t, dir, "ed", edPrivateKey)
+
+	oldKey := &KeyConfig{ID: "old", Algorithm: "HS256", Secret: testSecret(32)}
+	newKey := &KeyConfig{ID: "new", Algorithm: "EdDSA", PrivateKeyFile: edPrivatePath}
+
+	oldRepo, err := NewRepository(&KeysConfig{SigningKeyID: "old", Keys: []*KeyConfig{oldKey}})
+	if err != nil {
+		t.Fatalf("NewRepository error: %v", err)
+	}
+
+	rotatedRepo, err := NewRepository(&KeysConfig{SigningKeyID: "new", Keys: []*KeyConfig{oldKey, newKey}})
+	if err != nil {
+		t.Fatalf("NewRepository error: %v", err)
+	}
+
+	oldToken, err := oldRepo.GenerateToken(testClaims())
+	if err != nil {
+		t.Fatalf("GenerateToken error: %v", err)
+	}
+
+	newToken, err := rotatedRepo.GenerateToken(testClaims())
+	if err != nil {
+		t.Fatalf("GenerateToken error: %v", err)
+	}
+
+	parsed, err := jwt.ParseNoVerify([]byte(newToken))
+	if err != nil {
+		t.Fatalf("jwt.ParseNoVerify error: %v", err)
+	}
+
+	if parsed.Header().KeyID != "new" || parsed.Header().Algorithm != jwt.EdDSA {
+		t.Fatalf("expected a token signed by key new, got %s %s", parsed.Header().KeyID, parsed.Header().Algorithm)
+	}
+
+	tests := []struct {
+		name   string
+		repo   Repository
+		token  string
+		wantOK bool
+	}{
+		{name: "old token before rotation", repo: oldRepo, token: oldToken, wantOK: true},
+		{name: "old token after rotation", repo: rotatedRepo, token: oldToken, wantOK: true},
+		{name: "new token after rotation", repo: rotatedRepo, token: newToken, wantOK: true},
+		{name: "new token with an unknown key id", repo: oldRepo, token: newToken},
+		{name: "tampered token", repo: rotatedRepo, token: tamper(newToken)},
+		{name: "not a token", repo: rotatedRepo, token: "token"},
+	}
+
+	for _, test := range tests {
+		claims, ok := test.repo.IsValid(test.token)
+		if ok != test.wantOK || (ok && (claims.AdminID != "admin" || claims.SessionID != "session" || claims.Role != "teacher" || claims.SchoolID != "school")) {
+			t.Errorf("%s: expected %v, got %+v %v", test.name, test.wantOK, claims, ok)
+		}
+	}
+
+	// A key with the same ID but another secret does not verify the token.
+	otherRepo, err := NewRepository(NewSecretKeysConfig(testSecret(48)))
+	if err != nil {
+		t.Fatalf("NewRepository error: %v", err)
+	}
+
+	defaultRepo, err := NewRepository(NewSecretKeysConfig(testSecret(32)))
+	if err != nil {
+		t.Fatalf("NewRepository error: %v", err)
+	}
+
+	token, err := defaultRepo.GenerateToken(testClaims())
+	if err != nil {
+		t.Fatalf("GenerateToken error: %v", err)
+	}
+
+	if _, ok := otherRepo.IsValid(token); ok {
+		t.Fatal("token verified with another secret")
+	}
+}
+
+func TestNewRepositoryErrors(t *testing.T) {
+	dir := t.TempDir()
+	_, edPrivateKey, _ := ed25519.GenerateKey(rand.Reader)
+	_, edPublicPath := writeKeyFiles(t, dir, "ed", edPrivateKey)
+
+	hsKey := &KeyConfig{ID: "hs", Algorithm: "HS256", Secret: testSecret(32)}
+	tests := []struct {
+		name string
+		cfg  *KeysConfig
+	}{
+		{name: "no keys", cfg: &KeysConfig{SigningKeyID: "hs"}},
+		{name: "duplicate key id", cfg: &KeysConfig{SigningKeyID: "hs", Keys: []*KeyConfig{hsKey, hsKey}}},
+		{name: "unknown signing key", cfg: &KeysConfig{SigningKeyID: "other", Keys: []*KeyConfig{hsKey}}},
+		{
+			name: "signing key without private key",
+			cfg:  &KeysConfig{SigningKeyID: "ed", Keys: []*KeyConfig{hsKey, {ID: "ed", Algorithm: "EdDSA", PublicKeyFile: edPublicPath}}},
+		},
+	}
+
+	for _, test := range tests {
+		if _, err := NewRepository(test.cfg); err == nil {
+			t.Errorf("%s: expected an error", test.name)
+		}
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	dir := t.TempDir()
+	_, edPrivateKey, _ := ed25519.GenerateKey(rand.Reader)
+	edPrivatePath, _ := writeKeyFiles(t, dir, "ed", edPrivateKey)
+	ecPrivateKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, ecPublicPath := writeKeyFiles(t, dir, "ec", ecPrivateKey)
+
+	// Key files are relative to the keys file.
+	keysFile := filepath.Join(dir, "keys.json")
+	err := os.WriteFile(keysFile, []byte(`{
+		"signingKeyID": "ed",
+		"keys": [
+			{"id": "hs", "algorithm": "HS256", "secret": "`+testSecret(32)+`"},
+			{"id": "ed", "algorithm": "EdDSA", "privateKeyFile": "ed.pem"},
+			{"id": "ec", "algorithm": "ES256", "publicKeyFile": "`+filepath.Base(ecPublicPath)+`"}
+		]
+	}`), 0o600)
+	if err != nil {
+		t.Fatalf("os.WriteFile error: %v", err)
+	}
+
+	cfg, err := LoadKeysFile(keysFile)
+	if err != nil {
+		t.Fatalf("LoadKeysFile error: %v", err)
+	}
+
+	if cfg.Keys[1].PrivateKeyFile != edPrivatePath {
+		t.Fatalf("expected key file %s, got %s", edPrivatePath, cfg.Keys[1].PrivateKeyFile)
+	}
+
+	repo, err := NewRepository(cfg)
+	if err != nil {
+		t.Fatalf("NewRepository error: %v", err)
+	}
+
+	b, err := repo.JWKS()
+	if err != nil {
+		t.Fatalf("JWKS error: %v", err)
+	}
+
+	if strings.Contains(string(b), testSecret(32)) {
+		t.Fatal("JWKS contains the HMAC secret")
+	}
+
+	var jwks struct {
+		Keys []*jwk `json:"keys"`
+	}
+	err = json.Unmarshal(b, &jwks)
+	if err != nil {
+		t.Fatalf("json.Unmarshal error: %v", err)
+	}
+
+	// Keys are sorted by ID and HMAC keys are left out.
+	if len(jwks.Keys) != 2 || jwks.Keys[0].KeyID != "ec" || jwks.Keys[1].KeyID != "ed" {
+		t.Fatalf("expected the ec and ed keys, got %s", b)
+	}
+
+	ecJWK := jwks.Keys[0]
+	if ecJWK.KeyType != "EC" || ecJWK.Algorithm != "ES256" || ecJWK.Curve != "P-256" {
+		t.Fatalf("unexpected EC key %+v", ecJWK)
+	}
+
+	x, y := make([]byte, 32), make([]byte, 32)
+	ecPrivateKey.X.FillBytes(x)
+	ecPrivateKey.Y.FillBytes(y)
+	if ecJWK.X != base64.RawURLEncoding.EncodeToString(x) || ecJWK.Y != base64.RawURLEncoding.EncodeToString(y) {
+		t.Fatal("EC key coordinates do not match the public key")
+	}
+
+	// Tokens can be verified with only the published key.
+	edJWK := jwks.Keys[1]
+	if edJWK.KeyType != "OKP" || edJWK.Curve != "Ed25519" {
+		t.Fatalf("unexpected Ed25519 key %+v", edJWK)
+	}
+
+	publicKey, err := base64.RawURLEncoding.DecodeString(edJWK.X)
+	if err != nil {
+		t.Fatalf("failed to decode the Ed25519 key: %v", err)
+	}
+
+	verifier, err := jwt.NewVerifierEdDSA(ed25519.PublicKey(publicKey))
+	if err != nil {
+		t.Fatalf("jwt.NewVerifierEdDSA error: %v", err)
+	}
+
+	token, err := repo.GenerateToken(testClaims())
+	if err != nil {
+		t.Fatalf("GenerateToken error: %v", err)
+	}
+
+	_, err = jwt.Parse([]byte(token), verifier)
+	if err != nil {
+		t.Fatalf("token was not verified with the published key: %v", err)
+	}
+}
+
+func TestGenerateTokenRequiresClaims(t *testing.T) {
+	repo, err := NewRepository(NewSecretKeysConfig(testSecret(32)))
+	if err != nil {
+		t.Fatalf("NewRepository error: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		claims *Claims
+	}{
+		{name: "no claims"},
+		{name: "missing admin", claims: &Claims{SessionID: "session", Role: "teacher", SchoolID: "school"}},
+		{name: "missing session", claims: &Claims{AdminID: "admin", Role: "teacher", SchoolID: "school"}},
+		{name: "missing role", claims: &Claims{AdminID: "admin", SessionID: "session", SchoolID: "school"}},
+		{name: "missing school", claims: &Claims{AdminID: "admin", SessionID: "session", Role: "teacher"}},
+	}
+
+	for _, test := range tests {
+		if _, err := repo.GenerateToken(test.claims); err == nil {
+			t.Errorf("%s: expected an error", test.name)
+		}
+	}
+}
+
+func TestChallengeToken(t *testing.T) {
+	repo, err := NewRepository(NewSecretKeysConfig(testSecret(32)))
+	if err != nil {
+		t.Fatalf("NewRepository error: %v", err)
+	}
+
+	if _, err := repo.GenerateChallengeToken("admin", ""); err == nil {
+		t.Fatal("generated a challenge token without a nonce")
+	}
+
+	challengeToken, err := repo.GenerateChallengeToken("admin", "nonce")
+	if err != nil {
+		t.Fatalf("GenerateChallengeToken error: %v", err)
+	}
+
+	adminID, nonce, ok := repo.IsValidChallenge(challengeToken)
+	if !ok || adminID != "admin" || nonce != "nonce" {
+		t.Fatalf("expected a valid challenge of admin with its nonce, got %q %q %v", adminID, nonce, ok)
+	}
+
+	// Challenge and auth tokens cannot be used in place of each other.
+	if _, ok := repo.IsValid(challengeToken); ok {
+		t.Fatal("challenge token accepted as an auth token")
+	}
+
+	authToken, err := repo.GenerateToken(testClaims())
+	if err != nil {
+		t.Fatalf("GenerateToken error: %v", err)
+	}
+
+	if _, _, ok := repo.IsValidChallenge(authToken); ok {
+		t.Fatal("auth token accepted as a challenge token")
+	}
+
+	if _, _, ok := repo.IsValidChallenge(tamper(challengeToken)); ok {
+		t.Fatal("tampered challenge token accepted")
+	}
+}
@@ -1,8 +1,17 @@
 package auth
 
 type Repository interface {
-	// GenerateToken generates a new auth token for uniqueID.
-	GenerateToken(uniqueID string) (string, error)
-	// IsValid checks the token is valid and return it's uniqueID.
-	IsValid(token string) (string, bool)
+	// GenerateToken generates a new auth token with the provided claims.
+	GenerateToken(claims *Claims) (string, error)
+	// IsValid checks the token is valid and return it's claims.
+	IsValid(token string) (*Claims, bool)
+	// GenerateChallengeToken generates a new two-factor challenge token for
+	// the admin that passed the first login step, bound to nonce.
+	GenerateChallengeToken(adminID, nonce string) (string, error)
+	// IsValidChallenge checks the two-factor challenge token is valid and
+	// returns the admin ID and the nonce it was issued for.
+	IsValidChallenge(token string) (string, string, bool)
+	// JWKS returns the JSON Web Key Set of the public keys that can be used to
+	// verify tokens.
+	JWKS() ([]byte, error)
 }
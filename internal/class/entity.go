@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/ukane-philemon/scomp/internal/db"
@@ -14,23 +15,142 @@ import (
 )
 
 const (
-	idKey     = "_id"
-	reportKey = "report"
-	nameKey   = "name"
+	idKey       = "_id"
+	schoolIDKey = "schoolID"
+	reportKey   = "report"
+	stateKey    = "state"
 )
 
+// Class states. A class is open for new students until its report is
+// computed, computing while a job computes its report and reported once the
+// report is saved. Reported classes go back to computing when their report is
+// computed again, and are open again with a stale report when their students
+// change.
+const (
+	StateOpen      = "open"
+	StateComputing = "computing"
+	StateReported  = "reported"
+)
+
+// Ranking modes decide the positions of students with the same score.
+const (
+	// RankingCompetition skips the positions after a tie, e.g 1, 2, 2, 4.
+	RankingCompetition = "competition"
+	// RankingDense does not skip positions after a tie, e.g 1, 2, 2, 3.
+	RankingDense = "dense"
+)
+
+// Ranking bases decide the score students are ranked and graded by.
+const (
+	// RankingBasisTotal ranks students by the sum of their subject scores.
+	// Subjects with a higher max score count more.
+	RankingBasisTotal = "total"
+	// RankingBasisAverage ranks students by the average percentage of their
+	// subject scores. Every subject counts the same.
+	RankingBasisAverage = "average"
+	// RankingBasisWeighted ranks students by the average percentage of their
+	// subject scores weighted with the subject weights.
+	RankingBasisWeighted = "weighted"
+)
+
+// maxSubjectWeight is the maximum weight of a subject.
+const maxSubjectWeight = 100
+
 type Class struct {
-	ID            string       `json:"_id" bson:"_id"`
-	Name          string       `json:"name" bson:"name"`
-	Subjects      []*Subject   `json:"subjects" bson:"subjects"`
-	Report        *ClassReport `json:"report" bson:"report"` // nil until a report is generated
-	CreatedAt     string       `json:"createdAt" bson:"createdAt"`
-	LastUpdatedAt string       `json:"lastUpdatedAt" bson:"lastUpdatedAt"`
+	ID             string `json:"_id" bson:"_id"`
+	SchoolID       string `json:"schoolID" bson:"schoolID"`
+	Name           string `json:"name" bson:"name"`
+	TeacherID      string `json:"teacherID" bson:"teacherID"` // empty if no teacher is assigned
+	ReportSettings `bson:",inline"`
+	Subjects       []*Subject   `json:"subjects" bson:"subjects"`
+	Report         *ClassReport `json:"report" bson:"report"` // nil until a report is generated
+	// State is StateOpen, StateComputing or StateReported.
+	State string `json:"state" bson:"state"`
+	// ReportStale is true if the students of the class changed after Report
+	// was computed, until the report is computed again.
+	ReportStale bool `json:"reportStale" bson:"reportStale"`
+	// StudentsRevision counts the changes of the students of the class. A
+	// report is only saved if the students did not change while it was
+	// computed.
+	StudentsRevision int `json:"studentsRevision" bson:"studentsRevision"`
+	// CurrentReportVersion is the version of Report, 0 if no report is
+	// generated or Report was generated before reports were versioned.
+	CurrentReportVersion int   `json:"currentReportVersion" bson:"currentReportVersion"`
+	CreatedAt            int64 `json:"createdAt" bson:"createdAt"`
+	LastUpdatedAt        int64 `json:"lastUpdatedAt" bson:"lastUpdatedAt"`
+}
+
+// ReportSettings decide how the reports of a class are computed.
+type ReportSettings struct {
+	// GradingScaleID is the grading scale of the class reports, empty for the
+	// default grading scale.
+	GradingScaleID string `json:"gradingScaleID" bson:"gradingScaleID"`
+	// RankingMode is RankingCompetition or RankingDense.
+	RankingMode string `json:"rankingMode" bson:"rankingMode"`
+	// RankingBasis is RankingBasisTotal, RankingBasisAverage or
+	// RankingBasisWeighted.
+	RankingBasis string `json:"rankingBasis" bson:"rankingBasis"`
 }
 
 type Subject struct {
 	Name     string `json:"name" bson:"name"`
 	MaxScore int    `json:"maxScore" bson:"maxScore"`
+	// Weight is the weight, e.g credit units, of the subject in weighted
+	// averages. 0 for subjects created before weights were added, see
+	// EffectiveWeight.
+	Weight float64 `json:"weight" bson:"weight"`
+	// Optional subjects, e.g electives, are only scored for the students who
+	// take them.
+	Optional bool `json:"optional" bson:"optional"`
+	// Components are the assessments, e.g tests and exams, the subject score
+	// is the sum of. Empty if the subject is scored as a whole.
+	Components []*SubjectComponent `json:"components,omitempty" bson:"components,omitempty"`
+}
+
+// SubjectComponent is an assessment of a subject.
+type SubjectComponent struct {
+	Name     string `json:"name" bson:"name"`
+	MaxScore int    `json:"maxScore" bson:"maxScore"`
+}
+
+// Component returns the component of the subject that match name.
+func (s *Subject) Component(name string) (*SubjectComponent, bool) {
+	for _, component := range s.Components {
+		if component.Name == name {
+			return component, true
+		}
+	}
+	return nil, false
+}
+
+// Subject returns the subject of the class that match name.
+func (c *Class) Subject(name string) (*Subject, bool) {
+	for _, subject := range c.Subjects {
+		if subject.Name == name {
+			return subject, true
+		}
+	}
+	return nil, false
+}
+
+// EffectiveWeight returns the weight of the subject, 1 if the subject was
+// created before weights were added. New subjects always have a weight.
+func (s *Subject) EffectiveWeight() float64 {
+	if s.Weight == 0 {
+		return 1
+	}
+	return s.Weight
+}
+
+// CheckCanChangeStudents returns db.ErrorInvalidRequest if students cannot be
+// added to, changed in or removed from a class in state, i.e if its report is
+// being computed. Changing the students of a reported class makes its report
+// stale.
+func CheckCanChangeStudents(state string) error {
+	if state == StateComputing {
+		return fmt.Errorf("%w: class report is being computed, students cannot be changed", db.ErrorInvalidRequest)
+	}
+	return nil
 }
 
 type ClassReport struct {
@@ -39,67 +159,188 @@ type ClassReport struct {
 	HighestStudentScoreAsPercentage string `json:"highestStudentScoreAsPercentage" bson:"highestStudentScoreAsPercentage"`
 	LowestStudentScore              int    `json:"lowestStudentScore" bson:"lowestStudentScore"`
 	LowestStudentScoreAsPercentage  string `json:"lowestStudentScoreAsPercentage" bson:"lowestStudentScoreAsPercentage"`
-	GeneratedAt                     string `json:"generatedAt" bson:"generatedAt"`
+	// MeanPercentage, MedianPercentage and StandardDeviation describe the
+	// percentages students are graded by, see ReportSettings.RankingBasis.
+	MeanPercentage    float64 `json:"meanPercentage" bson:"meanPercentage"`
+	MedianPercentage  float64 `json:"medianPercentage" bson:"medianPercentage"`
+	StandardDeviation float64 `json:"standardDeviation" bson:"standardDeviation"`
+	// PassRate is the percentage of students whose grade is not the lowest
+	// grade of the grading scale.
+	PassRate float64 `json:"passRate" bson:"passRate"`
+	// GradeDistribution is the number of students with each grade of the
+	// grading scale, from the highest to the lowest grade.
+	GradeDistribution []*GradeCount `json:"gradeDistribution" bson:"gradeDistribution"`
+	// Subjects are the statistics of the class subjects.
+	Subjects    []*SubjectStatistics `json:"subjects" bson:"subjects"`
+	GeneratedAt int64                `json:"generatedAt" bson:"generatedAt"`
 }
 
-type ClassRepository struct {
-	ctx             context.Context
-	classCollection *mongo.Collection
+// GradeCount is the number of students with a grade.
+type GradeCount struct {
+	Grade string `json:"grade" bson:"grade"`
+	Count int    `json:"count" bson:"count"`
 }
 
-// NewRepository creates a new instance of *ClassRepository.
-func NewRepository(ctx context.Context, db *mongo.Database) (Repository, error) {
-	classCollectionIndex := mongo.IndexModel{
-		Keys: bson.D{{
-			Key:   nameKey,
-			Value: 1,
-		}},
-		Options: options.Index().SetUnique(true),
-	}
+// SubjectStatistics are the statistics of the scores of the students who were
+// scored in a subject.
+type SubjectStatistics struct {
+	Name              string  `json:"name" bson:"name"`
+	TotalStudents     int     `json:"totalStudents" bson:"totalStudents"`
+	AverageScore      float64 `json:"averageScore" bson:"averageScore"`
+	AveragePercentage float64 `json:"averagePercentage" bson:"averagePercentage"`
+	// HighestScore is the score of HighestScoreStudentID. Students with the
+	// same score are picked by name, like positions.
+	HighestScore            int    `json:"highestScore" bson:"highestScore"`
+	HighestScoreStudentID   string `json:"highestScoreStudentID" bson:"highestScoreStudentID"`
+	HighestScoreStudentName string `json:"highestScoreStudentName" bson:"highestScoreStudentName"`
+	// LowestScore is the score of LowestScoreStudentID.
+	LowestScore            int    `json:"lowestScore" bson:"lowestScore"`
+	LowestScoreStudentID   string `json:"lowestScoreStudentID" bson:"lowestScoreStudentID"`
+	LowestScoreStudentName string `json:"lowestScoreStudentName" bson:"lowestScoreStudentName"`
+	// PassRate is the percentage of students whose subject grade is not the
+	// lowest grade of the grading scale.
+	PassRate float64 `json:"passRate" bson:"passRate"`
+}
 
-	// Create a unique index on the class collection.
-	classCollection := db.Collection("classes")
-	_, err := classCollection.Indexes().CreateOne(ctx, classCollectionIndex)
-	if err != nil {
-		return nil, err
+// NewClass returns a new *Class of schoolID. Empty settings get their default
+// value, competition ranking by total score, and subjects without a weight get
+// a weight of 1.
+func NewClass(schoolID, className, teacherID string, settings ReportSettings, subjects []*Subject) (*Class, error) {
+	if schoolID == "" || className == "" {
+		return nil, fmt.Errorf("%w: missing schoolID or class name", db.ErrorInvalidRequest)
 	}
 
-	return &ClassRepository{
-		ctx:             ctx,
-		classCollection: classCollection,
-	}, nil
-}
+	switch settings.RankingMode {
+	case "":
+		settings.RankingMode = RankingCompetition
+	case RankingCompetition, RankingDense:
+	default:
+		return nil, fmt.Errorf("%w: invalid ranking mode %s", db.ErrorInvalidRequest, settings.RankingMode)
+	}
 
-// Create creates a new class in the database. Returns
-// db.ErrorInvalidRequest is the provided class name matches any record in the
-// database.
-// Implements Repository.
-func (cr *ClassRepository) Create(className string, subjects []*Subject) (string, error) {
-	if className == "" {
-		return "", fmt.Errorf("%w: missing class name", db.ErrorInvalidRequest)
+	switch settings.RankingBasis {
+	case "":
+		settings.RankingBasis = RankingBasisTotal
+	case RankingBasisTotal, RankingBasisAverage, RankingBasisWeighted:
+	default:
+		return nil, fmt.Errorf("%w: invalid ranking basis %s", db.ErrorInvalidRequest, settings.RankingBasis)
 	}
 
-	if len(subjects) != db.RequiredClassSubjects {
-		return "", fmt.Errorf("%w: %d class subjects are required to create a class", db.ErrorInvalidRequest, db.RequiredClassSubjects)
+	if len(subjects) < db.MinClassSubjects || len(subjects) > db.MaxClassSubjects {
+		return nil, fmt.Errorf("%w: a class must have between %d and %d subjects", db.ErrorInvalidRequest, db.MinClassSubjects, db.MaxClassSubjects)
 	}
 
+	classSubjects := make([]*Subject, 0, len(subjects))
 	for index, subject := range subjects {
 		if subject.Name == "" {
-			return "", fmt.Errorf("%w: subject %d is missing subject name", db.ErrorInvalidRequest, index+1)
+			return nil, fmt.Errorf("%w: subject %d is missing subject name", db.ErrorInvalidRequest, index+1)
+		}
+
+		for _, s := range classSubjects {
+			if strings.EqualFold(s.Name, subject.Name) {
+				return nil, fmt.Errorf("%w: subject %s is listed more than once", db.ErrorInvalidRequest, subject.Name)
+			}
 		}
 
 		if subject.MaxScore < 1 {
-			return "", fmt.Errorf("%w: subject %s has an invalid max score %d", db.ErrorInvalidRequest, subject.Name, subject.MaxScore)
+			return nil, fmt.Errorf("%w: subject %s has an invalid max score %d", db.ErrorInvalidRequest, subject.Name, subject.MaxScore)
 		}
+
+		if subject.Weight <= 0 || subject.Weight > maxSubjectWeight {
+			return nil, fmt.Errorf("%w: subject %s has an invalid weight %g, weights must be greater than 0 and at most %d", db.ErrorInvalidRequest, subject.Name, subject.Weight, maxSubjectWeight)
+		}
+
+		components, err := validateSubjectComponents(subject)
+		if err != nil {
+			return nil, err
+		}
+
+		classSubjects = append(classSubjects, &Subject{
+			Name:       subject.Name,
+			MaxScore:   subject.MaxScore,
+			Weight:     subject.Weight,
+			Optional:   subject.Optional,
+			Components: components,
+		})
 	}
 
 	nowUnix := time.Now().Unix()
-	classInfo := &Class{
-		ID:            primitive.NewObjectID().Hex(),
-		Name:          className,
-		Subjects:      subjects,
-		CreatedAt:     fmt.Sprint(nowUnix),
-		LastUpdatedAt: fmt.Sprint(nowUnix),
+	return &Class{
+		ID:             primitive.NewObjectID().Hex(),
+		SchoolID:       schoolID,
+		Name:           className,
+		TeacherID:      teacherID,
+		ReportSettings: settings,
+		Subjects:       classSubjects,
+		State:          StateOpen,
+		CreatedAt:      nowUnix,
+		LastUpdatedAt:  nowUnix,
+	}, nil
+}
+
+// validateSubjectComponents checks that the components of subject have unique
+// names and add up to the max score of the subject, and returns a copy of the
+// components.
+func validateSubjectComponents(subject *Subject) ([]*SubjectComponent, error) {
+	if len(subject.Components) == 0 {
+		return nil, nil
+	}
+
+	var totalMaxScore int
+	components := make([]*SubjectComponent, 0, len(subject.Components))
+	for index, component := range subject.Components {
+		if component.Name == "" {
+			return nil, fmt.Errorf("%w: component %d of subject %s is missing component name", db.ErrorInvalidRequest, index+1, subject.Name)
+		}
+
+		if component.MaxScore < 1 {
+			return nil, fmt.Errorf("%w: component %s of subject %s has an invalid max score %d", db.ErrorInvalidRequest,
+				component.Name, subject.Name, component.MaxScore)
+		}
+
+		for _, c := range components {
+			if c.Name == component.Name {
+				return nil, fmt.Errorf("%w: subject %s has more than one %s component", db.ErrorInvalidRequest, subject.Name, component.Name)
+			}
+		}
+
+		totalMaxScore += component.MaxScore
+		components = append(components, &SubjectComponent{
+			Name:     component.Name,
+			MaxScore: component.MaxScore,
+		})
+	}
+
+	if totalMaxScore != subject.MaxScore {
+		return nil, fmt.Errorf("%w: the max scores of the components of subject %s add up to %d instead of %d", db.ErrorInvalidRequest,
+			subject.Name, totalMaxScore, subject.MaxScore)
+	}
+
+	return components, nil
+}
+
+type ClassRepository struct {
+	ctx             context.Context
+	classCollection *mongo.Collection
+}
+
+// NewRepository creates a new instance of *ClassRepository. The collection
+// indexes are created by db.MigrateMongoDB.
+func NewRepository(ctx context.Context, db *mongo.Database) Repository {
+	return &ClassRepository{
+		ctx:             ctx,
+		classCollection: db.Collection("classes"),
+	}
+}
+
+// Create creates a new class in the database. Returns
+// db.ErrorInvalidRequest is the provided class name matches any class of the
+// school.
+// Implements Repository.
+func (cr *ClassRepository) Create(schoolID, className, teacherID string, settings ReportSettings, subjects []*Subject) (string, error) {
+	classInfo, err := NewClass(schoolID, className, teacherID, settings, subjects)
+	if err != nil {
+		return "", err
 	}
 
 	res, err := cr.classCollection.InsertOne(cr.ctx, classInfo)
@@ -115,8 +356,8 @@ func (cr *ClassRepository) Create(className string, subjects []*Subject) (string
 
 // Class returns information for the class that match the provided classID.
 // Implements Repository.
-func (cr *ClassRepository) Class(classID string) (*Class, error) {
-	classFilter, err := classFilter(classID)
+func (cr *ClassRepository) Class(schoolID, classID string) (*Class, error) {
+	classFilter, err := classFilter(schoolID, classID)
 	if err != nil {
 		return nil, err
 	}
@@ -133,15 +374,19 @@ func (cr *ClassRepository) Class(classID string) (*Class, error) {
 	return cInfo, nil
 }
 
-// Classes returns information for all the classes in the database.
+// Classes returns information for all the classes of the school.
 // Implements Repository.
-func (cr *ClassRepository) Classes(hasReport *bool) ([]*Class, error) {
-	filter := bson.M{}
+func (cr *ClassRepository) Classes(schoolID string, hasReport *bool) ([]*Class, error) {
+	if schoolID == "" {
+		return nil, fmt.Errorf("%w: missing schoolID", db.ErrorInvalidRequest)
+	}
+
+	filter := bson.M{schoolIDKey: schoolID}
 	if hasReport != nil {
 		if *hasReport {
-			filter = bson.M{reportKey: bson.M{"$exists": true, "$ne": nil}}
+			filter[reportKey] = bson.M{"$exists": true, "$ne": nil}
 		} else {
-			filter = bson.M{reportKey: nil}
+			filter[reportKey] = nil
 		}
 	}
 
@@ -156,8 +401,8 @@ func (cr *ClassRepository) Classes(hasReport *bool) ([]*Class, error) {
 
 // Exists checks if classID exists.
 // Implements Repository.
-func (cr *ClassRepository) Exists(classID string) (bool, error) {
-	classFilter, err := classFilter(classID)
+func (cr *ClassRepository) Exists(schoolID, classID string) (bool, error) {
+	classFilter, err := classFilter(schoolID, classID)
 	if err != nil {
 		return false, err
 	}
@@ -170,31 +415,32 @@ func (cr *ClassRepository) Exists(classID string) (bool, error) {
 	return nClass > 0, nil
 }
 
-// SaveClassReport saves a newly generated class report for the class that match
-// the provided classID.
+// Transition changes the state of the class that match classID from from to
+// to. Returns db.ErrorInvalidRequest if the class is not in the from state.
 // Implements Repository.
-func (cr *ClassRepository) SaveClassReport(classID string, report *ClassReport) error {
-	classFilter, err := classFilter(classID)
+func (cr *ClassRepository) Transition(schoolID, classID, from, to string) error {
+	classFilter, err := classFilter(schoolID, classID)
 	if err != nil {
 		return err
 	}
 
-	res, err := cr.classCollection.UpdateOne(cr.ctx, classFilter, bson.M{"$set": bson.M{reportKey: report}}, options.Update().SetUpsert(false))
+	classFilter[stateKey] = from
+	res, err := cr.classCollection.UpdateOne(cr.ctx, classFilter, bson.M{"$set": bson.M{stateKey: to}})
 	if err != nil {
 		return fmt.Errorf("classCollection.UpdateOne error: %w", err)
 	}
 
-	if res.ModifiedCount == 0 {
-		return fmt.Errorf("%w: report for class with ID %s was not updated", db.ErrorInvalidRequest, classID)
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("%w: class with ID %s is not %s", db.ErrorInvalidRequest, classID, from)
 	}
 
 	return nil
 }
 
-func classFilter(classID string) (bson.M, error) {
-	if classID == "" {
-		return nil, fmt.Errorf("%w: missing classID", db.ErrorInvalidRequest)
+func classFilter(schoolID, classID string) (bson.M, error) {
+	if schoolID == "" || classID == "" {
+		return nil, fmt.Errorf("%w: missing schoolID or classID", db.ErrorInvalidRequest)
 	}
 
-	return bson.M{idKey: classID}, nil
+	return bson.M{idKey: classID, schoolIDKey: schoolID}, nil
 }
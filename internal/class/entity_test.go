@@ -0,0 +1,118 @@
+package class
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ukane-philemon/scomp/internal/db"
+)
+
+// testSubjects returns 3 subjects with weight.
+func testSubjects(weight float64) []*Subject {
+	subjects := make([]*Subject, 3)
+	for i := range subjects {
+		subjects[i] = &Subject{Name: fmt.Sprintf("Subject %d", i+1), MaxScore: 100, Weight: weight}
+	}
+	return subjects
+}
+
+func TestNewClass(t *testing.T) {
+	classInfo, err := NewClass("school", "JSS 1", "teacher", ReportSettings{}, testSubjects(1))
+	if err != nil {
+		t.Fatalf("NewClass error: %v", err)
+	}
+
+	if classInfo.RankingMode != RankingCompetition || classInfo.RankingBasis != RankingBasisTotal {
+		t.Fatalf("expected the default report settings, got %+v", classInfo.ReportSettings)
+	}
+
+	classInfo, err = NewClass("school", "JSS 1", "teacher", ReportSettings{RankingBasis: RankingBasisWeighted}, testSubjects(2.5))
+	if err != nil {
+		t.Fatalf("NewClass error: %v", err)
+	}
+
+	if classInfo.RankingBasis != RankingBasisWeighted || classInfo.Subjects[0].Weight != 2.5 {
+		t.Fatalf("expected weighted ranking with a weight of 2.5, got %s with %g", classInfo.RankingBasis, classInfo.Subjects[0].Weight)
+	}
+
+	tests := []struct {
+		name     string
+		settings ReportSettings
+		subjects []*Subject
+	}{
+		{name: "unknown ranking mode", settings: ReportSettings{RankingMode: "olympic"}, subjects: testSubjects(1)},
+		{name: "unknown ranking basis", settings: ReportSettings{RankingBasis: "median"}, subjects: testSubjects(1)},
+		{name: "zero weight", subjects: testSubjects(0)},
+		{name: "negative weight", subjects: testSubjects(-1)},
+		{name: "weight too high", subjects: testSubjects(maxSubjectWeight + 1)},
+		{name: "no subjects"},
+		{name: "too many subjects", subjects: make([]*Subject, db.MaxClassSubjects+1)},
+		{name: "duplicate subject", subjects: append(testSubjects(1), &Subject{Name: "SUBJECT 1", MaxScore: 100, Weight: 1})},
+	}
+
+	for _, test := range tests {
+		_, err := NewClass("school", "JSS 1", "teacher", test.settings, test.subjects)
+		if !errors.Is(err, db.ErrorInvalidRequest) {
+			t.Errorf("%s: expected db.ErrorInvalidRequest, got %v", test.name, err)
+		}
+	}
+}
+
+func TestEffectiveWeight(t *testing.T) {
+	// Subjects created before weights were added have no weight.
+	if weight := (&Subject{}).EffectiveWeight(); weight != 1 {
+		t.Fatalf("expected a subject without a weight to have a weight of 1, got %g", weight)
+	}
+
+	if weight := (&Subject{Weight: 2.5}).EffectiveWeight(); weight != 2.5 {
+		t.Fatalf("expected a weight of 2.5, got %g", weight)
+	}
+}
+
+func TestNewClassComponents(t *testing.T) {
+	components := func(maxScores ...int) []*SubjectComponent {
+		subjectComponents := make([]*SubjectComponent, len(maxScores))
+		for i, maxScore := range maxScores {
+			subjectComponents[i] = &SubjectComponent{Name: fmt.Sprintf("Component %d", i+1), MaxScore: maxScore}
+		}
+		return subjectComponents
+	}
+
+	tests := []struct {
+		name       string
+		components []*SubjectComponent
+		wantErr    bool
+	}{
+		{name: "no components"},
+		{name: "components add up to the max score", components: components(20, 20, 60)},
+		{name: "components add up to less", components: components(20, 20, 50), wantErr: true},
+		{name: "components add up to more", components: components(50, 60), wantErr: true},
+		{name: "missing component name", components: []*SubjectComponent{{MaxScore: 100}}, wantErr: true},
+		{name: "invalid component max score", components: components(100, 0), wantErr: true},
+		{name: "duplicate component", components: []*SubjectComponent{{Name: "Test", MaxScore: 50}, {Name: "Test", MaxScore: 50}}, wantErr: true},
+	}
+
+	for _, test := range tests {
+		subjects := testSubjects(1)
+		subjects[0].Components = test.components
+
+		classInfo, err := NewClass("school", "JSS 1", "teacher", ReportSettings{}, subjects)
+		if test.wantErr {
+			if !errors.Is(err, db.ErrorInvalidRequest) {
+				t.Errorf("%s: expected db.ErrorInvalidRequest, got %v", test.name, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+
+		subject, found := classInfo.Subject("Subject 1")
+		if !found || len(subject.Components) != len(test.components) {
+			t.Errorf("%s: expected %d components", test.name, len(test.components))
+		}
+	}
+}
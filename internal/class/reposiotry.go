@@ -1,18 +1,20 @@
 package class
 
+// Repository is the class store. Every method is scoped to the school that
+// match schoolID, classes of other schools are never returned or modified.
 type Repository interface {
-	// Create creates a new class in the database. Returns
-	// db.ErrorInvalidRequest is the provided class name matches any record in
-	// the database.
-	Create(className string, subjects []*Subject) (string, error)
+	// Create creates a new class taught by teacherID whose reports are
+	// computed with settings in the database. Returns db.ErrorInvalidRequest
+	// is the provided class name matches any class of the school.
+	Create(schoolID, className, teacherID string, settings ReportSettings, subjects []*Subject) (string, error)
 	// Class returns information for the class that match the provided classID.
-	Class(classID string) (*Class, error)
-	// Classes returns information for all the classes in the database. Set
+	Class(schoolID, classID string) (*Class, error)
+	// Classes returns information for all the classes of the school. Set
 	// hasReport to filter classes by report.
-	Classes(hasReport *bool) ([]*Class, error)
+	Classes(schoolID string, hasReport *bool) ([]*Class, error)
 	// Exists checks if classID exists.
-	Exists(classID string) (bool, error)
-	// SaveClassReport saves a newly generated class report for the class that
-	// match the provided classID.
-	SaveClassReport(classID string, report *ClassReport) error
+	Exists(schoolID, classID string) (bool, error)
+	// Transition changes the state of classID from from to to if the class is
+	// still in the from state. Returns db.ErrorInvalidRequest otherwise.
+	Transition(schoolID, classID, from, to string) error
 }
@@ -0,0 +1,410 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// schemaMigrationsCollection stores a document for every applied migration.
+const schemaMigrationsCollection = "schema_migrations"
+
+// MongoDB error codes returned when dropping an index of a collection that
+// does not exist or an index that does not exist.
+const (
+	namespaceNotFoundCode = 26
+	indexNotFoundCode     = 27
+)
+
+// MigrationInfo describes a schema migration.
+type MigrationInfo struct {
+	Version     int
+	Description string
+}
+
+// appliedMigration is the record stored for an applied migration.
+type appliedMigration struct {
+	Version     int    `bson:"_id"`
+	Description string `bson:"description"`
+	AppliedAt   int64  `bson:"appliedAt"`
+}
+
+// mongoMigration is a MongoDB up-migration. Migrations must be idempotent
+// because MongoDB cannot apply a migration and record it atomically.
+type mongoMigration struct {
+	description string
+	up          func(ctx context.Context, mdb *mongo.Database) error
+}
+
+// mongoMigrations are the MongoDB migrations applied in order. The version of
+// a migration is its index + 1. Migrations use literal collection and field
+// names so that they keep working when the entities change. Never edit or
+// reorder an existing migration, append a new one instead.
+var mongoMigrations = []*mongoMigration{
+	{
+		description: "create unique indexes",
+		up: func(ctx context.Context, mdb *mongo.Database) error {
+			indexes := map[string]bson.D{
+				"admin":    {{Key: "username", Value: 1}},
+				"classes":  {{Key: "name", Value: 1}},
+				"students": {{Key: "name", Value: 1}, {Key: "classID", Value: 1}},
+			}
+
+			for collection, keys := range indexes {
+				_, err := mdb.Collection(collection).Indexes().CreateOne(ctx, mongo.IndexModel{
+					Keys:    keys,
+					Options: options.Index().SetUnique(true),
+				})
+				if err != nil {
+					return fmt.Errorf("failed to create %s index: %w", collection, err)
+				}
+			}
+
+			return nil
+		},
+	},
+	{
+		description: "store timestamps as int64 instead of string",
+		up: func(ctx context.Context, mdb *mongo.Database) error {
+			fields := map[string][]string{
+				"classes":  {"createdAt", "lastUpdatedAt", "report.generatedAt"},
+				"students": {"createdAt", "report.generatedAt"},
+			}
+
+			for collection, collectionFields := range fields {
+				for _, field := range collectionFields {
+					toLong := bson.M{"$convert": bson.M{"input": "$" + field, "to": "long", "onError": int64(0), "onNull": int64(0)}}
+					_, err := mdb.Collection(collection).UpdateMany(ctx,
+						bson.M{field: bson.M{"$type": "string"}},
+						mongo.Pipeline{{{Key: "$set", Value: bson.M{field: toLong}}}})
+					if err != nil {
+						return fmt.Errorf("failed to convert %s.%s: %w", collection, field, err)
+					}
+				}
+			}
+
+			return nil
+		},
+	},
+	{
+		description: "create sessions index",
+		up: func(ctx context.Context, mdb *mongo.Database) error {
+			_, err := mdb.Collection("sessions").Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys: bson.D{{Key: "adminID", Value: 1}},
+			})
+			if err != nil {
+				return fmt.Errorf("failed to create sessions index: %w", err)
+			}
+			return nil
+		},
+	},
+	{
+		description: "add admin roles and class teachers",
+		up: func(ctx context.Context, mdb *mongo.Database) error {
+			// Existing admins could do everything, keep it that way.
+			_, err := mdb.Collection("admin").UpdateMany(ctx,
+				bson.M{"role": bson.M{"$exists": false}},
+				bson.M{"$set": bson.M{"role": "owner"}})
+			if err != nil {
+				return fmt.Errorf("failed to set admin roles: %w", err)
+			}
+
+			_, err = mdb.Collection("classes").UpdateMany(ctx,
+				bson.M{"teacherID": bson.M{"$exists": false}},
+				bson.M{"$set": bson.M{"teacherID": ""}})
+			if err != nil {
+				return fmt.Errorf("failed to set class teachers: %w", err)
+			}
+
+			return nil
+		},
+	},
+	{
+		description: "create invitations index",
+		up: func(ctx context.Context, mdb *mongo.Database) error {
+			_, err := mdb.Collection("invitations").Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "hashedCode", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to create invitations index: %w", err)
+			}
+			return nil
+		},
+	},
+	{
+		description: "add schools",
+		up: func(ctx context.Context, mdb *mongo.Database) error {
+			_, err := mdb.Collection("schools").Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "name", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to create schools index: %w", err)
+			}
+
+			// Move existing records to a default school.
+			collections := []string{"admin", "classes", "students", "invitations"}
+			noSchool := bson.M{"schoolID": bson.M{"$exists": false}}
+			var hasData bool
+			for _, collection := range collections {
+				n, err := mdb.Collection(collection).CountDocuments(ctx, noSchool, options.Count().SetLimit(1))
+				if err != nil {
+					return fmt.Errorf("failed to count %s: %w", collection, err)
+				}
+				hasData = hasData || n > 0
+			}
+
+			if hasData {
+				defaultSchool := bson.M{"_id": primitive.NewObjectID().Hex(), "name": "Default school", "createdAt": time.Now().Unix()}
+				opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+				err = mdb.Collection("schools").FindOneAndUpdate(ctx, bson.M{"name": defaultSchool["name"]},
+					bson.M{"$setOnInsert": defaultSchool}, opts).Decode(&defaultSchool)
+				if err != nil {
+					return fmt.Errorf("failed to create default school: %w", err)
+				}
+
+				for _, collection := range collections {
+					_, err = mdb.Collection(collection).UpdateMany(ctx, noSchool, bson.M{"$set": bson.M{"schoolID": defaultSchool["_id"]}})
+					if err != nil {
+						return fmt.Errorf("failed to set %s school: %w", collection, err)
+					}
+				}
+			}
+
+			// Class names are unique per school.
+			classIndexes := mdb.Collection("classes").Indexes()
+			_, err = classIndexes.DropOne(ctx, "name_1")
+			var cmdErr mongo.CommandError
+			if err != nil && !(errors.As(err, &cmdErr) && (cmdErr.Code == namespaceNotFoundCode || cmdErr.Code == indexNotFoundCode)) {
+				return fmt.Errorf("failed to drop classes index: %w", err)
+			}
+
+			_, err = classIndexes.CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "schoolID", Value: 1}, {Key: "name", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to create classes index: %w", err)
+			}
+
+			return nil
+		},
+	},
+	{
+		description: "create password reset index",
+		up: func(ctx context.Context, mdb *mongo.Database) error {
+			_, err := mdb.Collection("admin").Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "hashedResetToken", Value: 1}},
+				Options: options.Index().SetSparse(true),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to create admin index: %w", err)
+			}
+			return nil
+		},
+	},
+	{
+		description: "add two-factor authentication",
+		up: func(ctx context.Context, mdb *mongo.Database) error {
+			// Two-factor codes are matched with these fields, they must exist.
+			_, err := mdb.Collection("admin").UpdateMany(ctx,
+				bson.M{"totpEnabled": bson.M{"$exists": false}},
+				bson.M{"$set": bson.M{"totpSecret": "", "totpEnabled": false, "totpLastCounter": int64(0), "hashedRecoveryCodes": bson.A{}}})
+			if err != nil {
+				return fmt.Errorf("failed to set admin two-factor fields: %w", err)
+			}
+			return nil
+		},
+	},
+	{
+		description: "create API keys index",
+		up: func(ctx context.Context, mdb *mongo.Database) error {
+			_, err := mdb.Collection("apiKeys").Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys: bson.D{{Key: "schoolID", Value: 1}, {Key: "createdAt", Value: -1}},
+			})
+			if err != nil {
+				return fmt.Errorf("failed to create API keys index: %w", err)
+			}
+			return nil
+		},
+	},
+	{
+		description: "create grading scales index",
+		up: func(ctx context.Context, mdb *mongo.Database) error {
+			_, err := mdb.Collection("gradingScales").Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "schoolID", Value: 1}, {Key: "name", Value: 1}},
+				Options: options.Index().SetUnique(true),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to create grading scales index: %w", err)
+			}
+			return nil
+		},
+	},
+	{
+		description: "set class ranking modes",
+		up: func(ctx context.Context, mdb *mongo.Database) error {
+			_, err := mdb.Collection("classes").UpdateMany(ctx,
+				bson.M{"rankingMode": bson.M{"$exists": false}},
+				bson.M{"$set": bson.M{"rankingMode": "competition"}})
+			if err != nil {
+				return fmt.Errorf("failed to set class ranking modes: %w", err)
+			}
+			return nil
+		},
+	},
+	{
+		description: "set class ranking bases",
+		up: func(ctx context.Context, mdb *mongo.Database) error {
+			_, err := mdb.Collection("classes").UpdateMany(ctx,
+				bson.M{"rankingBasis": bson.M{"$exists": false}},
+				bson.M{"$set": bson.M{"rankingBasis": "total"}})
+			if err != nil {
+				return fmt.Errorf("failed to set class ranking bases: %w", err)
+			}
+			return nil
+		},
+	},
+	{
+		description: "create report versions index",
+		up: func(ctx context.Context, mdb *mongo.Database) error {
+			_, err := mdb.Collection("reportVersions").Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "schoolID", Value: 1}, {Key: "classID", Value: 1}, {Key: "version", Value: -1}},
+				Options: options.Index().SetUnique(true),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to create report versions index: %w", err)
+			}
+			return nil
+		},
+	},
+	{
+		description: "create jobs index",
+		up: func(ctx context.Context, mdb *mongo.Database) error {
+			_, err := mdb.Collection("jobs").Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys: bson.D{{Key: "state", Value: 1}, {Key: "runAt", Value: 1}},
+			})
+			if err != nil {
+				return fmt.Errorf("failed to create jobs index: %w", err)
+			}
+			return nil
+		},
+	},
+	{
+		description: "set class states",
+		up: func(ctx context.Context, mdb *mongo.Database) error {
+			// Classes with a queued or running job are computing their
+			// report.
+			classIDs, err := mdb.Collection("jobs").Distinct(ctx, "classID", bson.M{"state": bson.M{"$in": bson.A{"queued", "running"}}})
+			if err != nil {
+				return fmt.Errorf("failed to find classes with active jobs: %w", err)
+			}
+
+			states := []struct {
+				state  string
+				filter bson.M
+			}{
+				{"computing", bson.M{"_id": bson.M{"$in": classIDs}}},
+				{"reported", bson.M{"report": bson.M{"$ne": nil}}},
+				{"open", bson.M{}},
+			}
+			for _, s := range states {
+				s.filter["state"] = bson.M{"$exists": false}
+				_, err = mdb.Collection("classes").UpdateMany(ctx, s.filter, bson.M{"$set": bson.M{"state": s.state}})
+				if err != nil {
+					return fmt.Errorf("failed to set %s class states: %w", s.state, err)
+				}
+			}
+			return nil
+		},
+	},
+	{
+		description: "set class and report version students revisions",
+		up: func(ctx context.Context, mdb *mongo.Database) error {
+			_, err := mdb.Collection("classes").UpdateMany(ctx,
+				bson.M{"studentsRevision": bson.M{"$exists": false}},
+				bson.M{"$set": bson.M{"studentsRevision": 0, "reportStale": false}})
+			if err != nil {
+				return fmt.Errorf("failed to set class students revisions: %w", err)
+			}
+
+			_, err = mdb.Collection("reportVersions").UpdateMany(ctx,
+				bson.M{"studentsRevision": bson.M{"$exists": false}},
+				bson.M{"$set": bson.M{"studentsRevision": 0}})
+			if err != nil {
+				return fmt.Errorf("failed to set report version students revisions: %w", err)
+			}
+			return nil
+		},
+	},
+}
+
+// MongoDBSchemaVersion returns the schema version of the database and the
+// latest schema version known to this binary.
+func MongoDBSchemaVersion(ctx context.Context, mdb *mongo.Database) (current int, latest int, err error) {
+	var lastApplied *appliedMigration
+	opts := options.FindOne().SetSort(bson.D{{Key: "_id", Value: -1}})
+	err = mdb.Collection(schemaMigrationsCollection).FindOne(ctx, bson.M{}, opts).Decode(&lastApplied)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, 0, fmt.Errorf("schema_migrations.FindOne error: %w", err)
+	}
+
+	if lastApplied != nil {
+		current = lastApplied.Version
+	}
+
+	return current, len(mongoMigrations), nil
+}
+
+// MigrateMongoDB applies all pending migrations to mdb and returns them. If
+// dryRun is true, the pending migrations are returned without being applied.
+// An error is returned if the database schema is newer than this binary.
+func MigrateMongoDB(ctx context.Context, mdb *mongo.Database, dryRun bool) ([]*MigrationInfo, error) {
+	currentVersion, latestVersion, err := MongoDBSchemaVersion(ctx, mdb)
+	if err != nil {
+		return nil, err
+	}
+
+	if currentVersion > latestVersion {
+		return nil, fmt.Errorf("database schema version %d is newer than the latest known version %d, please upgrade SCOMP", currentVersion, latestVersion)
+	}
+
+	var pending []*MigrationInfo
+	for index := currentVersion; index < latestVersion; index++ {
+		migration := mongoMigrations[index]
+		info := &MigrationInfo{
+			Version:     index + 1,
+			Description: migration.description,
+		}
+
+		if !dryRun {
+			err = migration.up(ctx, mdb)
+			if err != nil {
+				return nil, fmt.Errorf("migration %d (%s) failed: %w", info.Version, info.Description, err)
+			}
+
+			_, err = mdb.Collection(schemaMigrationsCollection).InsertOne(ctx, &appliedMigration{
+				Version:     info.Version,
+				Description: info.Description,
+				AppliedAt:   time.Now().Unix(),
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to record migration %d: %w", info.Version, err)
+			}
+
+			log.Printf("Applied database migration %d (%s)...", info.Version, info.Description)
+		}
+
+		pending = append(pending, info)
+	}
+
+	return pending, nil
+}
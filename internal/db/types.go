@@ -4,8 +4,12 @@ import (
 	"errors"
 )
 
-// RequiredClassSubjects is the number of required student subjects.
-const RequiredClassSubjects = 10
+// MinClassSubjects and MaxClassSubjects are the minimum and maximum number of
+// subjects of a class.
+const (
+	MinClassSubjects = 1
+	MaxClassSubjects = 30
+)
 
 // ErrorInvalidRequest is a user facing error returned by repositories.
 var ErrorInvalidRequest = errors.New("invalid request")
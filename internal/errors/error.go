@@ -7,6 +7,14 @@ func (eu *ErrorUnauthorized) Error() string {
 	return "not authorized"
 }
 
+// ErrorForbidden is the error for authenticated requests that are not
+// permitted for the admin's role.
+type ErrorForbidden struct{}
+
+func (ef *ErrorForbidden) Error() string {
+	return "you do not have permission to perform this action"
+}
+
 // ErrorUnknown is a generic error sent for server related errors.
 type ErrorUnknown struct{}
 
@@ -0,0 +1,267 @@
+package grading
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ukane-philemon/scomp/internal/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DefaultGradingScaleID is the ID of the grading scale used by classes
+// created without a grading scale.
+const DefaultGradingScaleID = "default"
+
+const (
+	idKey            = "_id"
+	schoolIDKey      = "schoolID"
+	nameKey          = "name"
+	bandsKey         = "bands"
+	lastUpdatedAtKey = "lastUpdatedAt"
+)
+
+// GradingScale maps score percentages to grades.
+type GradingScale struct {
+	ID       string `json:"_id" bson:"_id"`
+	SchoolID string `json:"schoolID" bson:"schoolID"`
+	Name     string `json:"name" bson:"name"`
+	// Bands are sorted from the highest to the lowest MinPercentage. The
+	// lowest band always starts at 0.
+	Bands         []*GradeBand `json:"bands" bson:"bands"`
+	CreatedAt     int64        `json:"createdAt" bson:"createdAt"`
+	LastUpdatedAt int64        `json:"lastUpdatedAt" bson:"lastUpdatedAt"`
+}
+
+// GradeBand is the grade of the percentages from MinPercentage up to the
+// MinPercentage of the next band.
+type GradeBand struct {
+	MinPercentage float64 `json:"minPercentage" bson:"minPercentage"`
+	Label         string  `json:"label" bson:"label"`
+	GradePoint    float64 `json:"gradePoint" bson:"gradePoint"`
+	Remark        string  `json:"remark" bson:"remark"`
+}
+
+// DefaultGradingScale returns the grading scale used by classes created
+// without a grading scale. It matches the grades SCOMP used before grading
+// scales were configurable.
+func DefaultGradingScale() *GradingScale {
+	return &GradingScale{
+		ID:   DefaultGradingScaleID,
+		Name: "Default",
+		Bands: []*GradeBand{
+			{MinPercentage: 70, Label: "Excellent"},
+			{MinPercentage: 60, Label: "Good"},
+			{MinPercentage: 50, Label: "Fair"},
+			{MinPercentage: 41, Label: "Pass"},
+			{MinPercentage: 0, Label: "Fail"},
+		},
+	}
+}
+
+// NewGradingScale returns a new *GradingScale of schoolID.
+func NewGradingScale(schoolID, name string, bands []*GradeBand) (*GradingScale, error) {
+	if schoolID == "" {
+		return nil, fmt.Errorf("%w: missing schoolID", db.ErrorInvalidRequest)
+	}
+
+	name, bands, err := ValidateGradingScale(name, bands)
+	if err != nil {
+		return nil, err
+	}
+
+	nowUnix := time.Now().Unix()
+	return &GradingScale{
+		ID:            primitive.NewObjectID().Hex(),
+		SchoolID:      schoolID,
+		Name:          name,
+		Bands:         bands,
+		CreatedAt:     nowUnix,
+		LastUpdatedAt: nowUnix,
+	}, nil
+}
+
+// ValidateGradingScale checks name and bands and returns the trimmed name and
+// the bands sorted from the highest to the lowest MinPercentage.
+func ValidateGradingScale(name string, bands []*GradeBand) (string, []*GradeBand, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil, fmt.Errorf("%w: missing grading scale name", db.ErrorInvalidRequest)
+	}
+
+	if len(bands) == 0 {
+		return "", nil, fmt.Errorf("%w: a grading scale must have at least one band", db.ErrorInvalidRequest)
+	}
+
+	sortedBands := make([]*GradeBand, 0, len(bands))
+	for _, band := range bands {
+		if band == nil || strings.TrimSpace(band.Label) == "" {
+			return "", nil, fmt.Errorf("%w: every band must have a label", db.ErrorInvalidRequest)
+		}
+
+		if band.MinPercentage < 0 || band.MinPercentage > 100 {
+			return "", nil, fmt.Errorf("%w: band %s has an invalid min percentage %g", db.ErrorInvalidRequest, band.Label, band.MinPercentage)
+		}
+
+		if band.GradePoint < 0 {
+			return "", nil, fmt.Errorf("%w: band %s has an invalid grade point %g", db.ErrorInvalidRequest, band.Label, band.GradePoint)
+		}
+
+		sortedBands = append(sortedBands, &GradeBand{
+			MinPercentage: band.MinPercentage,
+			Label:         strings.TrimSpace(band.Label),
+			GradePoint:    band.GradePoint,
+			Remark:        strings.TrimSpace(band.Remark),
+		})
+	}
+
+	sort.Slice(sortedBands, func(i, j int) bool {
+		return sortedBands[i].MinPercentage > sortedBands[j].MinPercentage
+	})
+
+	for i := 1; i < len(sortedBands); i++ {
+		if sortedBands[i].MinPercentage == sortedBands[i-1].MinPercentage {
+			return "", nil, fmt.Errorf("%w: bands %s and %s have the same min percentage", db.ErrorInvalidRequest,
+				sortedBands[i-1].Label, sortedBands[i].Label)
+		}
+	}
+
+	if sortedBands[len(sortedBands)-1].MinPercentage != 0 {
+		return "", nil, fmt.Errorf("%w: the lowest band must have a min percentage of 0", db.ErrorInvalidRequest)
+	}
+
+	return name, sortedBands, nil
+}
+
+// Grade returns the band of percentage.
+func (s *GradingScale) Grade(percentage float64) *GradeBand {
+	for _, band := range s.Bands {
+		if percentage >= band.MinPercentage {
+			return band
+		}
+	}
+
+	// Unreachable for valid scales, the lowest band starts at 0.
+	return s.Bands[len(s.Bands)-1]
+}
+
+// Passed checks that percentage is a pass, i.e its grade is not the lowest
+// grade of the scale. Every percentage is a pass on a scale with one band.
+func (s *GradingScale) Passed(percentage float64) bool {
+	return len(s.Bands) == 1 || percentage >= s.Bands[len(s.Bands)-2].MinPercentage
+}
+
+// GradingScaleRepository implements Repository.
+type GradingScaleRepository struct {
+	ctx                    context.Context
+	gradingScaleCollection *mongo.Collection
+}
+
+// NewRepository creates a new instance of *GradingScaleRepository. The
+// collection indexes are created by db.MigrateMongoDB.
+func NewRepository(ctx context.Context, db *mongo.Database) Repository {
+	return &GradingScaleRepository{
+		ctx:                    ctx,
+		gradingScaleCollection: db.Collection("gradingScales"),
+	}
+}
+
+// Create creates a new grading scale. Returns db.ErrorInvalidRequest if name
+// is already used by another grading scale of the school.
+// Implements Repository.
+func (gr *GradingScaleRepository) Create(schoolID, name string, bands []*GradeBand) (*GradingScale, error) {
+	gradingScale, err := NewGradingScale(schoolID, name, bands)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = gr.gradingScaleCollection.InsertOne(gr.ctx, gradingScale)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf("%w: grading scale name %s already exists", db.ErrorInvalidRequest, gradingScale.Name)
+		}
+		return nil, fmt.Errorf("gradingScaleCollection.InsertOne error: %w", err)
+	}
+
+	return gradingScale, nil
+}
+
+// GradingScale returns the grading scale that match gradingScaleID.
+// Implements Repository.
+func (gr *GradingScaleRepository) GradingScale(schoolID, gradingScaleID string) (*GradingScale, error) {
+	var gradingScale *GradingScale
+	err := gr.gradingScaleCollection.FindOne(gr.ctx, bson.M{idKey: gradingScaleID, schoolIDKey: schoolID}).Decode(&gradingScale)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("%w: no record found for grading scale with ID %s", db.ErrorInvalidRequest, gradingScaleID)
+		}
+		return nil, fmt.Errorf("gradingScaleCollection.FindOne error: %w", err)
+	}
+
+	return gradingScale, nil
+}
+
+// GradingScales returns all the grading scales of the school, sorted by name.
+// Implements Repository.
+func (gr *GradingScaleRepository) GradingScales(schoolID string) ([]*GradingScale, error) {
+	opts := options.Find().SetSort(bson.D{{Key: nameKey, Value: 1}})
+	cur, err := gr.gradingScaleCollection.Find(gr.ctx, bson.M{schoolIDKey: schoolID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("gradingScaleCollection.Find error: %w", err)
+	}
+
+	var gradingScales []*GradingScale
+	return gradingScales, cur.All(gr.ctx, &gradingScales)
+}
+
+// Update replaces the name and bands of the grading scale that match
+// gradingScaleID and returns the updated grading scale.
+// Implements Repository.
+func (gr *GradingScaleRepository) Update(schoolID, gradingScaleID, name string, bands []*GradeBand) (*GradingScale, error) {
+	name, bands, err := ValidateGradingScale(name, bands)
+	if err != nil {
+		return nil, err
+	}
+
+	update := bson.M{"$set": bson.M{
+		nameKey:          name,
+		bandsKey:         bands,
+		lastUpdatedAtKey: time.Now().Unix(),
+	}}
+
+	var gradingScale *GradingScale
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = gr.gradingScaleCollection.FindOneAndUpdate(gr.ctx, bson.M{idKey: gradingScaleID, schoolIDKey: schoolID}, update, opts).Decode(&gradingScale)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("%w: no record found for grading scale with ID %s", db.ErrorInvalidRequest, gradingScaleID)
+		}
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf("%w: grading scale name %s already exists", db.ErrorInvalidRequest, name)
+		}
+		return nil, fmt.Errorf("gradingScaleCollection.FindOneAndUpdate error: %w", err)
+	}
+
+	return gradingScale, nil
+}
+
+// Delete deletes the grading scale that match gradingScaleID.
+// Implements Repository.
+func (gr *GradingScaleRepository) Delete(schoolID, gradingScaleID string) error {
+	res, err := gr.gradingScaleCollection.DeleteOne(gr.ctx, bson.M{idKey: gradingScaleID, schoolIDKey: schoolID})
+	if err != nil {
+		return fmt.Errorf("gradingScaleCollection.DeleteOne error: %w", err)
+	}
+
+	if res.DeletedCount == 0 {
+		return fmt.Errorf("%w: no record found for grading scale with ID %s", db.ErrorInvalidRequest, gradingScaleID)
+	}
+
+	return nil
+}
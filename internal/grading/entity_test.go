@@ -0,0 +1,150 @@
+package grading
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ukane-philemon/scomp/internal/db"
+)
+
+func TestGrade(t *testing.T) {
+	scale := DefaultGradingScale()
+	tests := []struct {
+		percentage float64
+		grade      string
+		passed     bool
+	}{
+		{percentage: 100, grade: "Excellent", passed: true},
+		{percentage: 70, grade: "Excellent", passed: true},
+		{percentage: 69.99, grade: "Good", passed: true},
+		{percentage: 60, grade: "Good", passed: true},
+		{percentage: 50, grade: "Fair", passed: true},
+		{percentage: 41, grade: "Pass", passed: true},
+		{percentage: 40.99, grade: "Fail", passed: false},
+		{percentage: 0, grade: "Fail", passed: false},
+	}
+
+	for _, test := range tests {
+		if grade := scale.Grade(test.percentage).Label; grade != test.grade {
+			t.Errorf("expected grade %s for %g%%, got %s", test.grade, test.percentage, grade)
+		}
+
+		if passed := scale.Passed(test.percentage); passed != test.passed {
+			t.Errorf("expected Passed(%g) to be %v", test.percentage, test.passed)
+		}
+	}
+}
+
+func TestPassedSingleBand(t *testing.T) {
+	scale := &GradingScale{Bands: []*GradeBand{{MinPercentage: 0, Label: "Done"}}}
+	if !scale.Passed(0) {
+		t.Fatal("every percentage must pass on a scale with one band")
+	}
+}
+
+func TestValidateGradingScale(t *testing.T) {
+	tests := []struct {
+		name      string
+		scaleName string
+		bands     []*GradeBand
+		wantErr   bool
+		// wantLabels are the labels of the returned bands, in order.
+		wantLabels []string
+	}{
+		{
+			name:       "sorts bands",
+			scaleName:  " WAEC ",
+			bands:      []*GradeBand{{MinPercentage: 0, Label: "F9"}, {MinPercentage: 75, Label: " A1 "}, {MinPercentage: 40, Label: "E8"}},
+			wantLabels: []string{"A1", "E8", "F9"},
+		},
+		{
+			name:       "single band",
+			scaleName:  "Pass/Fail",
+			bands:      []*GradeBand{{MinPercentage: 0, Label: "Done"}},
+			wantLabels: []string{"Done"},
+		},
+		{
+			name:      "missing name",
+			scaleName: "  ",
+			bands:     []*GradeBand{{MinPercentage: 0, Label: "F"}},
+			wantErr:   true,
+		},
+		{
+			name:      "no bands",
+			scaleName: "Empty",
+			wantErr:   true,
+		},
+		{
+			name:      "missing label",
+			scaleName: "Scale",
+			bands:     []*GradeBand{{MinPercentage: 0, Label: " "}},
+			wantErr:   true,
+		},
+		{
+			name:      "nil band",
+			scaleName: "Scale",
+			bands:     []*GradeBand{nil},
+			wantErr:   true,
+		},
+		{
+			name:      "percentage above 100",
+			scaleName: "Scale",
+			bands:     []*GradeBand{{MinPercentage: 101, Label: "A"}, {MinPercentage: 0, Label: "F"}},
+			wantErr:   true,
+		},
+		{
+			name:      "negative percentage",
+			scaleName: "Scale",
+			bands:     []*GradeBand{{MinPercentage: -1, Label: "F"}},
+			wantErr:   true,
+		},
+		{
+			name:      "negative grade point",
+			scaleName: "Scale",
+			bands:     []*GradeBand{{MinPercentage: 0, Label: "F", GradePoint: -1}},
+			wantErr:   true,
+		},
+		{
+			name:      "duplicate percentage",
+			scaleName: "Scale",
+			bands:     []*GradeBand{{MinPercentage: 50, Label: "A"}, {MinPercentage: 50, Label: "B"}, {MinPercentage: 0, Label: "F"}},
+			wantErr:   true,
+		},
+		{
+			name:      "lowest band above 0",
+			scaleName: "Scale",
+			bands:     []*GradeBand{{MinPercentage: 50, Label: "A"}, {MinPercentage: 10, Label: "F"}},
+			wantErr:   true,
+		},
+	}
+
+	for _, test := range tests {
+		name, bands, err := ValidateGradingScale(test.scaleName, test.bands)
+		if test.wantErr {
+			if !errors.Is(err, db.ErrorInvalidRequest) {
+				t.Errorf("%s: expected db.ErrorInvalidRequest, got %v", test.name, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+
+		if name == "" || name[0] == ' ' || name[len(name)-1] == ' ' {
+			t.Errorf("%s: name %q was not trimmed", test.name, name)
+		}
+
+		if len(bands) != len(test.wantLabels) {
+			t.Errorf("%s: expected %d bands, got %d", test.name, len(test.wantLabels), len(bands))
+			continue
+		}
+
+		for i, band := range bands {
+			if band.Label != test.wantLabels[i] {
+				t.Errorf("%s: expected band %d to be %s, got %s", test.name, i, test.wantLabels[i], band.Label)
+			}
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package grading
+
+// Repository is the grading scale store. Every method is scoped to the school
+// that match schoolID.
+type Repository interface {
+	// Create creates a new grading scale. Returns db.ErrorInvalidRequest if
+	// name is already used by another grading scale of the school.
+	Create(schoolID, name string, bands []*GradeBand) (*GradingScale, error)
+	// GradingScale returns the grading scale that match gradingScaleID.
+	GradingScale(schoolID, gradingScaleID string) (*GradingScale, error)
+	// GradingScales returns all the grading scales of the school, sorted by
+	// name.
+	GradingScales(schoolID string) ([]*GradingScale, error)
+	// Update replaces the name and bands of the grading scale that match
+	// gradingScaleID and returns the updated grading scale.
+	Update(schoolID, gradingScaleID, name string, bands []*GradeBand) (*GradingScale, error)
+	// Delete deletes the grading scale that match gradingScaleID.
+	Delete(schoolID, gradingScaleID string) error
+}
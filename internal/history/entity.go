@@ -0,0 +1,206 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ukane-philemon/scomp/internal/class"
+	"github.com/ukane-philemon/scomp/internal/db"
+	"github.com/ukane-philemon/scomp/internal/student"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	schoolIDKey = "schoolID"
+	classIDKey  = "classID"
+	versionKey  = "version"
+)
+
+// ReportVersion is an immutable snapshot of the reports of a class saved
+// every time the reports of the class are computed. The versions of a class
+// are numbered from 1.
+type ReportVersion struct {
+	ID       string `json:"_id" bson:"_id"`
+	SchoolID string `json:"schoolID" bson:"schoolID"`
+	ClassID  string `json:"classID" bson:"classID"`
+	Version  int    `json:"version" bson:"version"`
+	// GeneratedBy is the ID of the admin that computed the reports. For API
+	// keys, it is the admin that created the key.
+	GeneratedBy string `json:"generatedBy" bson:"generatedBy"`
+	// StudentsRevision is the students revision of the class the reports were
+	// computed with, see class.Class.StudentsRevision. The reports can only be
+	// restored if the students of the class did not change since.
+	StudentsRevision int                `json:"studentsRevision" bson:"studentsRevision"`
+	ClassReport      *class.ClassReport `json:"classReport" bson:"classReport"`
+	// Students are sorted by class position.
+	Students    []*StudentReport `json:"students" bson:"students"`
+	GeneratedAt int64            `json:"generatedAt" bson:"generatedAt"`
+}
+
+// StudentReport is the report of a student in a ReportVersion.
+type StudentReport struct {
+	StudentID   string          `json:"studentID" bson:"studentID"`
+	StudentName string          `json:"studentName" bson:"studentName"`
+	Report      *student.Report `json:"report" bson:"report"`
+}
+
+// ReportDiff lists the students whose class position or grade differ between
+// two versions of the reports of a class.
+type ReportDiff struct {
+	FromVersion int
+	ToVersion   int
+	// Changes are sorted by the class positions of ToVersion, followed by the
+	// students that are only in FromVersion.
+	Changes []*StudentChange
+}
+
+// StudentChange is the change of the class report of a student between two
+// report versions. The position, grade and percentage of a version the
+// student is not in are zero values.
+type StudentChange struct {
+	StudentID      string
+	StudentName    string
+	FromPosition   int
+	ToPosition     int
+	FromGrade      string
+	ToGrade        string
+	FromPercentage float64
+	ToPercentage   float64
+}
+
+// NewReportVersion returns version of the reports of classID computed with
+// the students of studentsRevision.
+func NewReportVersion(schoolID, classID, generatedBy string, version, studentsRevision int, classReport *class.ClassReport, students []*StudentReport) (*ReportVersion, error) {
+	if schoolID == "" || classID == "" {
+		return nil, fmt.Errorf("%w: missing schoolID or classID", db.ErrorInvalidRequest)
+	}
+
+	if version < 1 {
+		return nil, fmt.Errorf("%w: invalid report version %d", db.ErrorInvalidRequest, version)
+	}
+
+	if classReport == nil || len(students) == 0 {
+		return nil, fmt.Errorf("%w: missing class or student reports", db.ErrorInvalidRequest)
+	}
+
+	for _, studentReport := range students {
+		if studentReport == nil || studentReport.StudentID == "" || studentReport.Report == nil {
+			return nil, fmt.Errorf("%w: invalid student report", db.ErrorInvalidRequest)
+		}
+	}
+
+	return &ReportVersion{
+		ID:               primitive.NewObjectID().Hex(),
+		SchoolID:         schoolID,
+		ClassID:          classID,
+		Version:          version,
+		GeneratedBy:      generatedBy,
+		StudentsRevision: studentsRevision,
+		ClassReport:      classReport,
+		Students:         students,
+		GeneratedAt:      classReport.GeneratedAt,
+	}, nil
+}
+
+// Diff returns the students whose class position or grade differ between the
+// versions from and to, including students that are only in one of them.
+func Diff(from, to *ReportVersion) *ReportDiff {
+	fromStudents := make(map[string]*StudentReport, len(from.Students))
+	for _, studentReport := range from.Students {
+		fromStudents[studentReport.StudentID] = studentReport
+	}
+
+	diff := &ReportDiff{
+		FromVersion: from.Version,
+		ToVersion:   to.Version,
+		Changes:     []*StudentChange{},
+	}
+
+	toStudents := make(map[string]bool, len(to.Students))
+	for _, studentReport := range to.Students {
+		toStudents[studentReport.StudentID] = true
+		change := newStudentChange(fromStudents[studentReport.StudentID], studentReport)
+		if change.FromPosition != change.ToPosition || change.FromGrade != change.ToGrade {
+			diff.Changes = append(diff.Changes, change)
+		}
+	}
+
+	for _, studentReport := range from.Students {
+		if !toStudents[studentReport.StudentID] {
+			diff.Changes = append(diff.Changes, newStudentChange(studentReport, nil))
+		}
+	}
+
+	return diff
+}
+
+// newStudentChange returns the change from the report from to the report to
+// of a student. One of from or to can be nil.
+func newStudentChange(from, to *StudentReport) *StudentChange {
+	change := new(StudentChange)
+	if from != nil {
+		change.StudentID, change.StudentName = from.StudentID, from.StudentName
+		change.FromPosition, change.FromGrade, change.FromPercentage = from.result()
+	}
+	if to != nil {
+		change.StudentID, change.StudentName = to.StudentID, to.StudentName
+		change.ToPosition, change.ToGrade, change.ToPercentage = to.result()
+	}
+	return change
+}
+
+// result returns the class position, grade and percentage of the student.
+func (r *StudentReport) result() (int, string, float64) {
+	if r.Report == nil || r.Report.Class == nil {
+		return 0, "", 0
+	}
+	return r.Report.Class.Position, r.Report.Class.Grade, r.Report.Class.Percentage
+}
+
+// ReportVersionRepository implements Repository.
+type ReportVersionRepository struct {
+	ctx                     context.Context
+	reportVersionCollection *mongo.Collection
+}
+
+// NewRepository creates a new instance of *ReportVersionRepository. The
+// collection indexes are created by db.MigrateMongoDB.
+func NewRepository(ctx context.Context, db *mongo.Database) Repository {
+	return &ReportVersionRepository{
+		ctx:                     ctx,
+		reportVersionCollection: db.Collection("reportVersions"),
+	}
+}
+
+// ReportVersion returns version of the reports of classID.
+// Implements Repository.
+func (vr *ReportVersionRepository) ReportVersion(schoolID, classID string, version int) (*ReportVersion, error) {
+	var reportVersion *ReportVersion
+	err := vr.reportVersionCollection.FindOne(vr.ctx, bson.M{schoolIDKey: schoolID, classIDKey: classID, versionKey: version}).Decode(&reportVersion)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("%w: no report version %d found for class with ID %s", db.ErrorInvalidRequest, version, classID)
+		}
+		return nil, fmt.Errorf("reportVersionCollection.FindOne error: %w", err)
+	}
+
+	return reportVersion, nil
+}
+
+// ReportVersions returns all the versions of the reports of classID, from the
+// newest to the oldest.
+// Implements Repository.
+func (vr *ReportVersionRepository) ReportVersions(schoolID, classID string) ([]*ReportVersion, error) {
+	opts := options.Find().SetSort(bson.D{{Key: versionKey, Value: -1}})
+	cur, err := vr.reportVersionCollection.Find(vr.ctx, bson.M{schoolIDKey: schoolID, classIDKey: classID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("reportVersionCollection.Find error: %w", err)
+	}
+
+	var reportVersions []*ReportVersion
+	return reportVersions, cur.All(vr.ctx, &reportVersions)
+}
@@ -0,0 +1,85 @@
+package history
+
+import (
+	"testing"
+
+	"github.com/ukane-philemon/scomp/internal/student"
+)
+
+func newStudentReport(studentID string, position int, grade string) *StudentReport {
+	return &StudentReport{
+		StudentID:   studentID,
+		StudentName: "Student " + studentID,
+		Report: &student.Report{
+			Class: &student.StudentClassReport{Position: position, Grade: grade},
+		},
+	}
+}
+
+func TestDiff(t *testing.T) {
+	from := &ReportVersion{
+		Version: 1,
+		Students: []*StudentReport{
+			newStudentReport("same", 1, "A"),
+			newStudentReport("moved", 2, "B"),
+			newStudentReport("regraded", 3, "C"),
+			newStudentReport("removed", 4, "D"),
+		},
+	}
+	to := &ReportVersion{
+		Version: 2,
+		Students: []*StudentReport{
+			newStudentReport("same", 1, "A"),
+			newStudentReport("added", 2, "A"),
+			newStudentReport("moved", 3, "B"),
+			newStudentReport("regraded", 3, "B"),
+		},
+	}
+
+	want := map[string]StudentChange{
+		"added":    {ToPosition: 2, ToGrade: "A"},
+		"moved":    {FromPosition: 2, FromGrade: "B", ToPosition: 3, ToGrade: "B"},
+		"regraded": {FromPosition: 3, FromGrade: "C", ToPosition: 3, ToGrade: "B"},
+		"removed":  {FromPosition: 4, FromGrade: "D"},
+	}
+
+	diff := Diff(from, to)
+	if diff.FromVersion != 1 || diff.ToVersion != 2 {
+		t.Fatalf("expected versions 1 to 2, got %d to %d", diff.FromVersion, diff.ToVersion)
+	}
+
+	if len(diff.Changes) != len(want) {
+		t.Fatalf("expected %d changes, got %d", len(want), len(diff.Changes))
+	}
+
+	for _, change := range diff.Changes {
+		wantChange, found := want[change.StudentID]
+		if !found {
+			t.Errorf("unexpected change of student %s", change.StudentID)
+			continue
+		}
+
+		if change.StudentName != "Student "+change.StudentID {
+			t.Errorf("expected the name of student %s, got %s", change.StudentID, change.StudentName)
+		}
+
+		if change.FromPosition != wantChange.FromPosition || change.FromGrade != wantChange.FromGrade ||
+			change.ToPosition != wantChange.ToPosition || change.ToGrade != wantChange.ToGrade {
+			t.Errorf("student %s: expected %d %s to %d %s, got %d %s to %d %s", change.StudentID,
+				wantChange.FromPosition, wantChange.FromGrade, wantChange.ToPosition, wantChange.ToGrade,
+				change.FromPosition, change.FromGrade, change.ToPosition, change.ToGrade)
+		}
+	}
+}
+
+func TestDiffSameVersion(t *testing.T) {
+	version := &ReportVersion{
+		Version:  1,
+		Students: []*StudentReport{newStudentReport("a", 1, "A"), newStudentReport("b", 2, "B")},
+	}
+
+	diff := Diff(version, version)
+	if diff.Changes == nil || len(diff.Changes) != 0 {
+		t.Fatalf("expected no changes, got %v", diff.Changes)
+	}
+}
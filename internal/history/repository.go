@@ -0,0 +1,12 @@
+package history
+
+// Repository is the report version store. Report versions are created with
+// the reports they snapshot by report.Store and are never updated or deleted.
+// Every method is scoped to the school that match schoolID.
+type Repository interface {
+	// ReportVersion returns version of the reports of classID.
+	ReportVersion(schoolID, classID string, version int) (*ReportVersion, error)
+	// ReportVersions returns all the versions of the reports of classID, from
+	// the newest to the oldest.
+	ReportVersions(schoolID, classID string) ([]*ReportVersion, error)
+}
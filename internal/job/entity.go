@@ -0,0 +1,250 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ukane-philemon/scomp/internal/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Job kinds.
+const (
+	// KindComputeClassReport jobs compute the reports of a class and save
+	// them as a new report version.
+	KindComputeClassReport = "computeClassReport"
+)
+
+// Job states. Jobs are queued, then running until they succeed, fail or are
+// queued again to retry a failed attempt.
+const (
+	StateQueued    = "queued"
+	StateRunning   = "running"
+	StateSucceeded = "succeeded"
+	StateFailed    = "failed"
+)
+
+const (
+	// DefaultMaxAttempts is the number of times a job is attempted before it
+	// fails.
+	DefaultMaxAttempts = 5
+	// LeaseDuration is how long a claimed job is reserved for its worker. The
+	// job is claimed again by another worker if it is still running after
+	// the lease, e.g because the server stopped.
+	LeaseDuration = 5 * time.Minute
+	// RetryBaseDelay is the delay before the first retry of a job, the delay
+	// doubles with every attempt up to MaxRetryDelay.
+	RetryBaseDelay = 10 * time.Second
+	// MaxRetryDelay is the maximum delay before a job is retried.
+	MaxRetryDelay = 5 * time.Minute
+)
+
+const (
+	idKey             = "_id"
+	schoolIDKey       = "schoolID"
+	stateKey          = "state"
+	attemptsKey       = "attempts"
+	errorKey          = "error"
+	reportVersionKey  = "reportVersion"
+	runAtKey          = "runAt"
+	leaseExpiresAtKey = "leaseExpiresAt"
+	startedAtKey      = "startedAt"
+	finishedAtKey     = "finishedAt"
+)
+
+// Job is a task of a class run in the background by a worker.
+type Job struct {
+	ID          string `json:"_id" bson:"_id"`
+	SchoolID    string `json:"schoolID" bson:"schoolID"`
+	ClassID     string `json:"classID" bson:"classID"`
+	Kind        string `json:"kind" bson:"kind"`
+	State       string `json:"state" bson:"state"`
+	RequestedBy string `json:"requestedBy" bson:"requestedBy"`
+	// Attempts is the number of times the job was claimed by a worker.
+	Attempts    int `json:"attempts" bson:"attempts"`
+	MaxAttempts int `json:"maxAttempts" bson:"maxAttempts"`
+	// Error is the error of the last failed attempt, empty if no attempt
+	// failed.
+	Error string `json:"error" bson:"error"`
+	// ReportVersion is the report version saved by the job, 0 until the job
+	// succeeds.
+	ReportVersion int `json:"reportVersion" bson:"reportVersion"`
+	// RunAt is when a queued job is due.
+	RunAt          int64 `json:"runAt" bson:"runAt"`
+	LeaseExpiresAt int64 `json:"leaseExpiresAt" bson:"leaseExpiresAt"` // 0 unless running
+	CreatedAt      int64 `json:"createdAt" bson:"createdAt"`
+	StartedAt      int64 `json:"startedAt" bson:"startedAt"`   // start of the last attempt
+	FinishedAt     int64 `json:"finishedAt" bson:"finishedAt"` // 0 until succeeded or failed
+}
+
+// NewJob returns a new queued *Job of kind for classID that is due now.
+func NewJob(schoolID, classID, kind, requestedBy string) (*Job, error) {
+	if schoolID == "" || classID == "" {
+		return nil, fmt.Errorf("%w: missing schoolID or classID", db.ErrorInvalidRequest)
+	}
+
+	if kind != KindComputeClassReport {
+		return nil, fmt.Errorf("%w: unknown job kind %s", db.ErrorInvalidRequest, kind)
+	}
+
+	nowUnix := time.Now().Unix()
+	return &Job{
+		ID:          primitive.NewObjectID().Hex(),
+		SchoolID:    schoolID,
+		ClassID:     classID,
+		Kind:        kind,
+		State:       StateQueued,
+		RequestedBy: requestedBy,
+		MaxAttempts: DefaultMaxAttempts,
+		RunAt:       nowUnix,
+		CreatedAt:   nowUnix,
+	}, nil
+}
+
+// RetryDelay returns how long to wait before retrying a job after its
+// attempt failed.
+func RetryDelay(attempt int) time.Duration {
+	delay := RetryBaseDelay
+	for i := 1; i < attempt && delay < MaxRetryDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > MaxRetryDelay {
+		return MaxRetryDelay
+	}
+	return delay
+}
+
+// JobRepository implements Repository.
+type JobRepository struct {
+	ctx           context.Context
+	jobCollection *mongo.Collection
+}
+
+// NewRepository creates a new instance of *JobRepository. The collection
+// indexes are created by db.MigrateMongoDB.
+func NewRepository(ctx context.Context, db *mongo.Database) Repository {
+	return &JobRepository{
+		ctx:           ctx,
+		jobCollection: db.Collection("jobs"),
+	}
+}
+
+// Create queues a new job of kind for classID requested by requestedBy.
+// Implements Repository.
+func (jr *JobRepository) Create(schoolID, classID, kind, requestedBy string) (*Job, error) {
+	newJob, err := NewJob(schoolID, classID, kind, requestedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = jr.jobCollection.InsertOne(jr.ctx, newJob)
+	if err != nil {
+		return nil, fmt.Errorf("jobCollection.InsertOne error: %w", err)
+	}
+
+	return newJob, nil
+}
+
+// Job returns the job that match jobID.
+// Implements Repository.
+func (jr *JobRepository) Job(schoolID, jobID string) (*Job, error) {
+	var jobInfo *Job
+	err := jr.jobCollection.FindOne(jr.ctx, bson.M{idKey: jobID, schoolIDKey: schoolID}).Decode(&jobInfo)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("%w: no record found for job with ID %s", db.ErrorInvalidRequest, jobID)
+		}
+		return nil, fmt.Errorf("jobCollection.FindOne error: %w", err)
+	}
+
+	return jobInfo, nil
+}
+
+// Claim marks the next due job as running until now + LeaseDuration, counts
+// the attempt and returns the job. Returns nil if no job is due.
+// Implements Repository.
+func (jr *JobRepository) Claim(now time.Time) (*Job, error) {
+	nowUnix := now.Unix()
+	filter := bson.M{"$or": bson.A{
+		bson.M{stateKey: StateQueued, runAtKey: bson.M{"$lte": nowUnix}},
+		bson.M{stateKey: StateRunning, leaseExpiresAtKey: bson.M{"$lte": nowUnix}},
+	}}
+	update := bson.M{
+		"$set": bson.M{
+			stateKey:          StateRunning,
+			leaseExpiresAtKey: now.Add(LeaseDuration).Unix(),
+			startedAtKey:      nowUnix,
+		},
+		"$inc": bson.M{attemptsKey: 1},
+	}
+
+	var jobInfo *Job
+	opts := options.FindOneAndUpdate().SetSort(bson.D{{Key: runAtKey, Value: 1}}).SetReturnDocument(options.After)
+	err := jr.jobCollection.FindOneAndUpdate(jr.ctx, filter, update, opts).Decode(&jobInfo)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("jobCollection.FindOneAndUpdate error: %w", err)
+	}
+
+	return jobInfo, nil
+}
+
+// Succeed marks the running job that match jobID as succeeded with the report
+// version it saved.
+// Implements Repository.
+func (jr *JobRepository) Succeed(schoolID, jobID string, attempt, reportVersion int) error {
+	return jr.finishAttempt(schoolID, jobID, attempt, bson.M{
+		stateKey:          StateSucceeded,
+		errorKey:          "",
+		reportVersionKey:  reportVersion,
+		leaseExpiresAtKey: 0,
+		finishedAtKey:     time.Now().Unix(),
+	})
+}
+
+// Retry queues the running job that match jobID to run again at runAt after
+// its attempt failed with errMsg.
+// Implements Repository.
+func (jr *JobRepository) Retry(schoolID, jobID string, attempt int, errMsg string, runAt time.Time) error {
+	return jr.finishAttempt(schoolID, jobID, attempt, bson.M{
+		stateKey:          StateQueued,
+		errorKey:          errMsg,
+		runAtKey:          runAt.Unix(),
+		leaseExpiresAtKey: 0,
+	})
+}
+
+// Fail marks the running job that match jobID as failed with errMsg.
+// Implements Repository.
+func (jr *JobRepository) Fail(schoolID, jobID string, attempt int, errMsg string) error {
+	return jr.finishAttempt(schoolID, jobID, attempt, bson.M{
+		stateKey:          StateFailed,
+		errorKey:          errMsg,
+		leaseExpiresAtKey: 0,
+		finishedAtKey:     time.Now().Unix(),
+	})
+}
+
+// finishAttempt sets the fields of the job that match jobID if it is running
+// attempt.
+func (jr *JobRepository) finishAttempt(schoolID, jobID string, attempt int, fields bson.M) error {
+	filter := bson.M{idKey: jobID, schoolIDKey: schoolID, stateKey: StateRunning, attemptsKey: attempt}
+	res, err := jr.jobCollection.UpdateOne(jr.ctx, filter, bson.M{"$set": fields})
+	if err != nil {
+		return fmt.Errorf("jobCollection.UpdateOne error: %w", err)
+	}
+
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("%w: job with ID %s is not running attempt %d", db.ErrorInvalidRequest, jobID, attempt)
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package job
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ukane-philemon/scomp/internal/db"
+)
+
+func TestNewJob(t *testing.T) {
+	newJob, err := NewJob("school", "class", KindComputeClassReport, "teacher")
+	if err != nil {
+		t.Fatalf("NewJob error: %v", err)
+	}
+
+	if newJob.State != StateQueued || newJob.MaxAttempts != DefaultMaxAttempts || newJob.RunAt == 0 {
+		t.Fatalf("expected a queued job that is due now, got %+v", newJob)
+	}
+
+	tests := []struct {
+		name     string
+		schoolID string
+		classID  string
+		kind     string
+	}{
+		{name: "missing school", classID: "class", kind: KindComputeClassReport},
+		{name: "missing class", schoolID: "school", kind: KindComputeClassReport},
+		{name: "unknown kind", schoolID: "school", classID: "class", kind: "unknown"},
+	}
+
+	for _, test := range tests {
+		_, err := NewJob(test.schoolID, test.classID, test.kind, "teacher")
+		if !errors.Is(err, db.ErrorInvalidRequest) {
+			t.Errorf("%s: expected db.ErrorInvalidRequest, got %v", test.name, err)
+		}
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempt   int
+		wantDelay time.Duration
+	}{
+		{attempt: 1, wantDelay: RetryBaseDelay},
+		{attempt: 2, wantDelay: 2 * RetryBaseDelay},
+		{attempt: 3, wantDelay: 4 * RetryBaseDelay},
+		{attempt: 5, wantDelay: 16 * RetryBaseDelay},
+		{attempt: 6, wantDelay: MaxRetryDelay},
+		{attempt: 100, wantDelay: MaxRetryDelay},
+	}
+
+	for _, test := range tests {
+		if delay := RetryDelay(test.attempt); delay != test.wantDelay {
+			t.Errorf("expected a delay of %v after attempt %d, got %v", test.wantDelay, test.attempt, delay)
+		}
+	}
+}
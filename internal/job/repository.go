@@ -0,0 +1,30 @@
+package job
+
+import "time"
+
+// Repository is the job store. Jobs are claimed by workers of every school,
+// the other methods are scoped to the school that match schoolID.
+//
+// Succeed, Retry and Fail finish attempt, the attempt counted by the Claim
+// that returned the job, and return db.ErrorInvalidRequest if the job is not
+// running that attempt anymore, e.g because its lease expired and another
+// worker claimed it again.
+type Repository interface {
+	// Create queues a new job of kind for classID requested by requestedBy.
+	Create(schoolID, classID, kind, requestedBy string) (*Job, error)
+	// Job returns the job that match jobID.
+	Job(schoolID, jobID string) (*Job, error)
+	// Claim marks the next due job as running until now + LeaseDuration,
+	// counts the attempt and returns the job. A job is due if it is queued
+	// and its RunAt has passed, or if it is running and its lease has expired
+	// because its worker stopped. Returns nil if no job is due.
+	Claim(now time.Time) (*Job, error)
+	// Succeed marks the running job that match jobID as succeeded with the
+	// report version it saved.
+	Succeed(schoolID, jobID string, attempt, reportVersion int) error
+	// Retry queues the running job that match jobID to run again at runAt
+	// after its attempt failed with errMsg.
+	Retry(schoolID, jobID string, attempt int, errMsg string, runAt time.Time) error
+	// Fail marks the running job that match jobID as failed with errMsg.
+	Fail(schoolID, jobID string, attempt int, errMsg string) error
+}
@@ -0,0 +1,378 @@
+package memory
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ukane-philemon/scomp/internal/admin"
+	"github.com/ukane-philemon/scomp/internal/db"
+	"github.com/ukane-philemon/scomp/internal/totp"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// AdminRepository implements admin.Repository.
+type AdminRepository struct {
+	store *Store
+}
+
+// NewAdminRepository creates a new instance of *AdminRepository.
+func NewAdminRepository(store *Store) admin.Repository {
+	return &AdminRepository{
+		store: store,
+	}
+}
+
+// CreateAccount implements admin.Repository.
+func (ar *AdminRepository) CreateAccount(schoolID, username, password, role string) (string, error) {
+	if schoolID == "" || username == "" || password == "" {
+		return "", fmt.Errorf("%w: missing username or password", db.ErrorInvalidRequest)
+	}
+
+	if !admin.ValidRole(role) {
+		return "", fmt.Errorf("%w: invalid role %s", db.ErrorInvalidRequest, role)
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("bcrypt.GenerateFromPassword error: %w", err)
+	}
+
+	ar.store.mtx.Lock()
+	defer ar.store.mtx.Unlock()
+
+	for _, a := range ar.store.admins {
+		if a.Username == username {
+			return "", fmt.Errorf("%w: please try another username", db.ErrorInvalidRequest)
+		}
+	}
+
+	adminInfo := &admin.Admin{
+		ID:             primitive.NewObjectID().Hex(),
+		SchoolID:       schoolID,
+		Username:       username,
+		HashedPassword: string(passwordHash),
+		Role:           role,
+		CreatedAt:      time.Now().Unix(),
+	}
+
+	ar.store.admins[adminInfo.ID] = adminInfo
+
+	return adminInfo.ID, nil
+}
+
+// LoginAccount implements admin.Repository.
+func (ar *AdminRepository) LoginAccount(username, password string) (*admin.Admin, error) {
+	ar.store.mtx.RLock()
+	var adminInfo *admin.Admin
+	var err error
+	for _, a := range ar.store.admins {
+		if a.Username == username {
+			adminInfo, err = clone(a)
+			break
+		}
+	}
+	ar.store.mtx.RUnlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if adminInfo == nil {
+		return nil, admin.ErrorIncorrectLogin
+	}
+
+	now := time.Now()
+	if adminInfo.Locked(now) {
+		return nil, admin.ErrorIncorrectLogin
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(adminInfo.HashedPassword), []byte(password))
+
+	ar.store.mtx.Lock()
+	defer ar.store.mtx.Unlock()
+
+	storedAdmin, found := ar.store.admins[adminInfo.ID]
+	if !found {
+		return nil, admin.ErrorIncorrectLogin
+	}
+
+	if err != nil {
+		recordFailedLogin(storedAdmin, now)
+		return nil, admin.ErrorIncorrectLogin
+	}
+
+	storedAdmin.FailedLoginAttempts = 0
+	adminInfo.FailedLoginAttempts = 0
+	return adminInfo, nil
+}
+
+// Admin implements admin.Repository.
+func (ar *AdminRepository) Admin(adminID string) (*admin.Admin, error) {
+	ar.store.mtx.RLock()
+	defer ar.store.mtx.RUnlock()
+
+	adminInfo, found := ar.store.admins[adminID]
+	if !found {
+		return nil, fmt.Errorf("%w: admin not found", db.ErrorInvalidRequest)
+	}
+
+	return clone(adminInfo)
+}
+
+// HasAccounts implements admin.Repository.
+func (ar *AdminRepository) HasAccounts() (bool, error) {
+	ar.store.mtx.RLock()
+	defer ar.store.mtx.RUnlock()
+
+	return len(ar.store.admins) > 0, nil
+}
+
+// SetRole implements admin.Repository.
+func (ar *AdminRepository) SetRole(schoolID, adminID, role string) error {
+	if !admin.ValidRole(role) {
+		return fmt.Errorf("%w: invalid role %s", db.ErrorInvalidRequest, role)
+	}
+
+	ar.store.mtx.Lock()
+	defer ar.store.mtx.Unlock()
+
+	adminInfo, found := ar.store.admins[adminID]
+	if !found || adminInfo.SchoolID != schoolID {
+		return fmt.Errorf("%w: admin not found", db.ErrorInvalidRequest)
+	}
+
+	adminInfo.Role = role
+	return nil
+}
+
+// ChangePassword implements admin.Repository.
+func (ar *AdminRepository) ChangePassword(adminID, currentPassword, newPassword string) error {
+	adminInfo, err := ar.Admin(adminID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if err = adminInfo.CheckLocked(now); err != nil {
+		return err
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(adminInfo.HashedPassword), []byte(currentPassword))
+	if err != nil {
+		ar.store.mtx.Lock()
+		defer ar.store.mtx.Unlock()
+
+		if storedAdmin, found := ar.store.admins[adminID]; found {
+			recordFailedLogin(storedAdmin, now)
+		}
+		return fmt.Errorf("%w: current password is incorrect", db.ErrorInvalidRequest)
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("bcrypt.GenerateFromPassword error: %w", err)
+	}
+
+	ar.store.mtx.Lock()
+	defer ar.store.mtx.Unlock()
+
+	// Fail if the password was changed since it was checked.
+	storedAdmin, found := ar.store.admins[adminID]
+	if !found || storedAdmin.HashedPassword != adminInfo.HashedPassword {
+		return fmt.Errorf("%w: current password is incorrect", db.ErrorInvalidRequest)
+	}
+
+	storedAdmin.HashedPassword = string(passwordHash)
+	return nil
+}
+
+// CreatePasswordReset implements admin.Repository.
+func (ar *AdminRepository) CreatePasswordReset(schoolID, adminID string) (string, int64, error) {
+	resetToken, hashedResetToken, err := admin.NewPasswordResetToken()
+	if err != nil {
+		return "", 0, err
+	}
+
+	ar.store.mtx.Lock()
+	defer ar.store.mtx.Unlock()
+
+	adminInfo, found := ar.store.admins[adminID]
+	if !found || adminInfo.SchoolID != schoolID {
+		return "", 0, fmt.Errorf("%w: admin not found", db.ErrorInvalidRequest)
+	}
+
+	adminInfo.HashedResetToken = hashedResetToken
+	adminInfo.ResetTokenExpiresAt = time.Now().Add(admin.PasswordResetTokenExpiry).Unix()
+	return resetToken, adminInfo.ResetTokenExpiresAt, nil
+}
+
+// ResetPassword implements admin.Repository.
+func (ar *AdminRepository) ResetPassword(resetToken, newPassword string) (string, error) {
+	if resetToken == "" {
+		return "", fmt.Errorf("%w: missing reset token", db.ErrorInvalidRequest)
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("bcrypt.GenerateFromPassword error: %w", err)
+	}
+
+	hashedResetToken := admin.HashPasswordResetToken(resetToken)
+	now := time.Now().Unix()
+
+	ar.store.mtx.Lock()
+	defer ar.store.mtx.Unlock()
+
+	for _, adminInfo := range ar.store.admins {
+		if adminInfo.HashedResetToken != hashedResetToken || adminInfo.ResetTokenExpiresAt <= now {
+			continue
+		}
+
+		adminInfo.HashedPassword = string(passwordHash)
+		adminInfo.HashedResetToken = ""
+		adminInfo.ResetTokenExpiresAt = 0
+		adminInfo.FailedLoginAttempts = 0
+		adminInfo.LockedUntil = 0
+		return adminInfo.ID, nil
+	}
+
+	return "", fmt.Errorf("%w: reset token is invalid or has expired", db.ErrorInvalidRequest)
+}
+
+// EnrollTOTP implements admin.Repository.
+func (ar *AdminRepository) EnrollTOTP(adminID string) (string, error) {
+	secret, err := totp.NewSecret()
+	if err != nil {
+		return "", err
+	}
+
+	ar.store.mtx.Lock()
+	defer ar.store.mtx.Unlock()
+
+	adminInfo, found := ar.store.admins[adminID]
+	if !found {
+		return "", fmt.Errorf("%w: admin not found", db.ErrorInvalidRequest)
+	}
+
+	if adminInfo.TOTPEnabled {
+		return "", fmt.Errorf("%w: two-factor authentication is already enabled", db.ErrorInvalidRequest)
+	}
+
+	adminInfo.TOTPSecret = secret
+	return secret, nil
+}
+
+// ConfirmTOTP implements admin.Repository.
+func (ar *AdminRepository) ConfirmTOTP(adminID, code string) ([]string, error) {
+	recoveryCodes, hashedRecoveryCodes, err := admin.NewRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	ar.store.mtx.Lock()
+	defer ar.store.mtx.Unlock()
+
+	adminInfo, found := ar.store.admins[adminID]
+	if !found {
+		return nil, fmt.Errorf("%w: admin not found", db.ErrorInvalidRequest)
+	}
+
+	counter, err := adminInfo.ConfirmTOTPCode(code)
+	if err != nil {
+		return nil, err
+	}
+
+	adminInfo.TOTPEnabled = true
+	adminInfo.TOTPLastCounter = counter
+	adminInfo.HashedRecoveryCodes = hashedRecoveryCodes
+	return recoveryCodes, nil
+}
+
+// VerifyTwoFactor implements admin.Repository.
+func (ar *AdminRepository) VerifyTwoFactor(adminID, code string) (*admin.Admin, error) {
+	ar.store.mtx.Lock()
+	defer ar.store.mtx.Unlock()
+
+	adminInfo, found := ar.store.admins[adminID]
+	if !found {
+		return nil, fmt.Errorf("%w: admin not found", db.ErrorInvalidRequest)
+	}
+
+	now := time.Now()
+	if err := adminInfo.CheckLocked(now); err != nil {
+		return nil, err
+	}
+
+	match, ok := adminInfo.MatchTwoFactorCode(code, now)
+	if !ok {
+		recordFailedLogin(adminInfo, now)
+		return nil, fmt.Errorf("%w: two-factor code is invalid or has already been used", db.ErrorInvalidRequest)
+	}
+
+	// Mark the code as used.
+	if match.HashedRecoveryCode != "" {
+		hashedRecoveryCodes := make([]string, 0, len(adminInfo.HashedRecoveryCodes))
+		for _, hashedRecoveryCode := range adminInfo.HashedRecoveryCodes {
+			if hashedRecoveryCode != match.HashedRecoveryCode {
+				hashedRecoveryCodes = append(hashedRecoveryCodes, hashedRecoveryCode)
+			}
+		}
+		adminInfo.HashedRecoveryCodes = hashedRecoveryCodes
+	} else {
+		adminInfo.TOTPLastCounter = match.Counter
+	}
+
+	adminInfo.FailedLoginAttempts = 0
+	return clone(adminInfo)
+}
+
+// DisableTOTP implements admin.Repository.
+func (ar *AdminRepository) DisableTOTP(adminID, code string) error {
+	_, err := ar.VerifyTwoFactor(adminID, code)
+	if err != nil {
+		return err
+	}
+
+	ar.store.mtx.Lock()
+	defer ar.store.mtx.Unlock()
+
+	if adminInfo, found := ar.store.admins[adminID]; found {
+		disableTOTP(adminInfo)
+	}
+
+	return nil
+}
+
+// ResetTOTP implements admin.Repository.
+func (ar *AdminRepository) ResetTOTP(schoolID, adminID string) error {
+	ar.store.mtx.Lock()
+	defer ar.store.mtx.Unlock()
+
+	adminInfo, found := ar.store.admins[adminID]
+	if !found || adminInfo.SchoolID != schoolID {
+		return fmt.Errorf("%w: admin not found", db.ErrorInvalidRequest)
+	}
+
+	disableTOTP(adminInfo)
+	return nil
+}
+
+// recordFailedLogin counts a failed login attempt of adminInfo and locks the
+// account if there have been too many. The caller must hold the store lock.
+func recordFailedLogin(adminInfo *admin.Admin, now time.Time) {
+	adminInfo.FailedLoginAttempts++
+	if adminInfo.FailedLoginAttempts >= admin.MaxFailedLoginAttempts {
+		adminInfo.FailedLoginAttempts = 0
+		adminInfo.LockedUntil = now.Add(admin.LockoutDuration).Unix()
+	}
+}
+
+// disableTOTP clears the two-factor authentication settings of adminInfo. The
+// caller must hold the store lock.
+func disableTOTP(adminInfo *admin.Admin) {
+	adminInfo.TOTPSecret = ""
+	adminInfo.TOTPEnabled = false
+	adminInfo.TOTPLastCounter = 0
+	adminInfo.HashedRecoveryCodes = nil
+}
@@ -0,0 +1,112 @@
+package memory
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ukane-philemon/scomp/internal/admin"
+	"github.com/ukane-philemon/scomp/internal/db"
+)
+
+func TestCreateAccount(t *testing.T) {
+	ar := NewAdminRepository(New())
+
+	hasAccounts, err := ar.HasAccounts()
+	if err != nil || hasAccounts {
+		t.Fatalf("expected no accounts, got %v %v", hasAccounts, err)
+	}
+
+	adminID, err := ar.CreateAccount(testSchoolID, "admin", "password", admin.RoleOwner)
+	if err != nil {
+		t.Fatalf("CreateAccount error: %v", err)
+	}
+
+	if adminID == "" {
+		t.Fatal("CreateAccount returned an empty ID")
+	}
+
+	hasAccounts, err = ar.HasAccounts()
+	if err != nil || !hasAccounts {
+		t.Fatalf("expected accounts, got %v %v", hasAccounts, err)
+	}
+
+	tests := []struct {
+		name, username, password, role string
+	}{
+		{name: "duplicate username", username: "admin", password: "another password", role: admin.RoleAdmin},
+		{name: "missing username", password: "password", role: admin.RoleAdmin},
+		{name: "unknown role", username: "teacher", password: "password", role: "principal"},
+	}
+
+	for _, test := range tests {
+		_, err := ar.CreateAccount(testSchoolID, test.username, test.password, test.role)
+		if !errors.Is(err, db.ErrorInvalidRequest) {
+			t.Errorf("%s: expected db.ErrorInvalidRequest, got %v", test.name, err)
+		}
+	}
+}
+
+func TestLoginAccount(t *testing.T) {
+	ar := NewAdminRepository(New())
+
+	adminID, err := ar.CreateAccount(testSchoolID, "admin", "password", admin.RoleTeacher)
+	if err != nil {
+		t.Fatalf("CreateAccount error: %v", err)
+	}
+
+	adminInfo, err := ar.LoginAccount("admin", "password")
+	if err != nil {
+		t.Fatalf("LoginAccount error: %v", err)
+	}
+
+	if adminInfo.ID != adminID || adminInfo.Role != admin.RoleTeacher {
+		t.Fatalf("expected admin %s with role %s, got %s with role %s", adminID, admin.RoleTeacher, adminInfo.ID, adminInfo.Role)
+	}
+
+	tests := []struct {
+		username, password string
+	}{
+		{username: "admin", password: "wrong password"},
+		{username: "unknown", password: "password"},
+	}
+
+	for _, test := range tests {
+		_, err := ar.LoginAccount(test.username, test.password)
+		if !errors.Is(err, db.ErrorInvalidRequest) {
+			t.Errorf("expected db.ErrorInvalidRequest for %s/%s, got %v", test.username, test.password, err)
+		}
+	}
+}
+
+func TestSetRole(t *testing.T) {
+	ar := NewAdminRepository(New())
+
+	adminID, err := ar.CreateAccount(testSchoolID, "admin", "password", admin.RoleViewer)
+	if err != nil {
+		t.Fatalf("CreateAccount error: %v", err)
+	}
+
+	err = ar.SetRole(testSchoolID, adminID, admin.RoleAdmin)
+	if err != nil {
+		t.Fatalf("SetRole error: %v", err)
+	}
+
+	adminInfo, err := ar.Admin(adminID)
+	if err != nil {
+		t.Fatalf("Admin error: %v", err)
+	}
+
+	if adminInfo.Role != admin.RoleAdmin {
+		t.Fatalf("expected role %s, got %s", admin.RoleAdmin, adminInfo.Role)
+	}
+
+	err = ar.SetRole(testSchoolID, adminID, "principal")
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for an unknown role, got %v", err)
+	}
+
+	err = ar.SetRole(testSchoolID, "unknown", admin.RoleAdmin)
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for an unknown admin, got %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+package memory
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ukane-philemon/scomp/internal/admin"
+	"github.com/ukane-philemon/scomp/internal/db"
+)
+
+// APIKeyRepository implements admin.APIKeyRepository.
+type APIKeyRepository struct {
+	store *Store
+}
+
+// NewAPIKeyRepository creates a new instance of *APIKeyRepository.
+func NewAPIKeyRepository(store *Store) admin.APIKeyRepository {
+	return &APIKeyRepository{
+		store: store,
+	}
+}
+
+// Create implements admin.APIKeyRepository.
+func (kr *APIKeyRepository) Create(schoolID, createdBy, name, scope string, classIDs []string, validFor time.Duration) (*admin.APIKey, string, error) {
+	apiKey, key, err := admin.NewAPIKey(schoolID, createdBy, name, scope, classIDs, validFor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	storedAPIKey, err := clone(apiKey)
+	if err != nil {
+		return nil, "", err
+	}
+
+	kr.store.mtx.Lock()
+	kr.store.apiKeys[apiKey.ID] = storedAPIKey
+	kr.store.mtx.Unlock()
+
+	return apiKey, key, nil
+}
+
+// Authenticate implements admin.APIKeyRepository.
+func (kr *APIKeyRepository) Authenticate(key string) (*admin.APIKey, error) {
+	apiKeyID, hashedSecret, err := admin.ParseAPIKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	kr.store.mtx.Lock()
+	defer kr.store.mtx.Unlock()
+
+	apiKey, found := kr.store.apiKeys[apiKeyID]
+	if !found || apiKey.HashedSecret != hashedSecret || !isActiveAPIKey(apiKey, now) {
+		return nil, fmt.Errorf("%w: API key is invalid, expired or has been revoked", db.ErrorInvalidRequest)
+	}
+
+	apiKey.LastUsedAt = now.Unix()
+	return clone(apiKey)
+}
+
+// APIKeys implements admin.APIKeyRepository.
+func (kr *APIKeyRepository) APIKeys(schoolID string) ([]*admin.APIKey, error) {
+	kr.store.mtx.RLock()
+	defer kr.store.mtx.RUnlock()
+
+	now := time.Now()
+	var apiKeys []*admin.APIKey
+	for _, apiKey := range kr.store.apiKeys {
+		if apiKey.SchoolID != schoolID || !isActiveAPIKey(apiKey, now) {
+			continue
+		}
+
+		apiKeyCopy, err := clone(apiKey)
+		if err != nil {
+			return nil, err
+		}
+		apiKeys = append(apiKeys, apiKeyCopy)
+	}
+
+	sort.Slice(apiKeys, func(i, j int) bool {
+		return apiKeys[i].CreatedAt > apiKeys[j].CreatedAt
+	})
+
+	return apiKeys, nil
+}
+
+// Revoke implements admin.APIKeyRepository.
+func (kr *APIKeyRepository) Revoke(schoolID, apiKeyID string) error {
+	kr.store.mtx.Lock()
+	defer kr.store.mtx.Unlock()
+
+	apiKey, found := kr.store.apiKeys[apiKeyID]
+	if !found || apiKey.SchoolID != schoolID || apiKey.RevokedAt != 0 {
+		return fmt.Errorf("%w: no active API key found with ID %s", db.ErrorInvalidRequest, apiKeyID)
+	}
+
+	apiKey.RevokedAt = time.Now().Unix()
+	return nil
+}
+
+func isActiveAPIKey(apiKey *admin.APIKey, now time.Time) bool {
+	return apiKey.RevokedAt == 0 && apiKey.ExpiresAt > now.Unix()
+}
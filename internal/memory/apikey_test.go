@@ -0,0 +1,101 @@
+package memory
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ukane-philemon/scomp/internal/admin"
+	"github.com/ukane-philemon/scomp/internal/db"
+)
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	store := New()
+	kr := NewAPIKeyRepository(store)
+
+	apiKey, key, err := kr.Create(testSchoolID, "admin", "Results portal", admin.APIKeyScopeWrite, []string{"class"}, time.Hour)
+	if err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+
+	authenticated, err := kr.Authenticate(key)
+	if err != nil {
+		t.Fatalf("Authenticate error: %v", err)
+	}
+
+	if authenticated.ID != apiKey.ID || authenticated.CreatedBy != "admin" || authenticated.LastUsedAt == 0 {
+		t.Fatalf("expected used API key %s, got %+v", apiKey.ID, authenticated)
+	}
+
+	apiKeyID, _, _ := admin.ParseAPIKey(key)
+	tests := []struct {
+		name string
+		key  string
+	}{
+		{name: "malformed key", key: "key"},
+		{name: "wrong secret", key: "scomp_" + apiKeyID + ".secret"},
+		{name: "unknown key", key: "scomp_unknown.secret"},
+	}
+
+	for _, test := range tests {
+		_, err := kr.Authenticate(test.key)
+		if !errors.Is(err, db.ErrorInvalidRequest) {
+			t.Errorf("%s: expected db.ErrorInvalidRequest, got %v", test.name, err)
+		}
+	}
+
+	// Expired keys are refused.
+	store.apiKeys[apiKey.ID].ExpiresAt = time.Now().Add(-time.Second).Unix()
+	_, err = kr.Authenticate(key)
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for an expired key, got %v", err)
+	}
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	kr := NewAPIKeyRepository(New())
+
+	apiKey, key, err := kr.Create(testSchoolID, "admin", "Results portal", admin.APIKeyScopeRead, nil, time.Hour)
+	if err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+
+	_, _, err = kr.Create("other school", "admin", "Other portal", admin.APIKeyScopeRead, nil, time.Hour)
+	if err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+
+	apiKeys, err := kr.APIKeys(testSchoolID)
+	if err != nil {
+		t.Fatalf("APIKeys error: %v", err)
+	}
+
+	if len(apiKeys) != 1 || apiKeys[0].ID != apiKey.ID {
+		t.Fatalf("expected the API key of the school, got %d keys", len(apiKeys))
+	}
+
+	err = kr.Revoke("other school", apiKey.ID)
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest revoking a key of another school, got %v", err)
+	}
+
+	err = kr.Revoke(testSchoolID, apiKey.ID)
+	if err != nil {
+		t.Fatalf("Revoke error: %v", err)
+	}
+
+	_, err = kr.Authenticate(key)
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for a revoked key, got %v", err)
+	}
+
+	apiKeys, err = kr.APIKeys(testSchoolID)
+	if err != nil || len(apiKeys) != 0 {
+		t.Fatalf("expected no active API keys, got %d, %v", len(apiKeys), err)
+	}
+
+	err = kr.Revoke(testSchoolID, apiKey.ID)
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest revoking a revoked key, got %v", err)
+	}
+}
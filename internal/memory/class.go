@@ -0,0 +1,128 @@
+package memory
+
+import (
+	"fmt"
+
+	"github.com/ukane-philemon/scomp/internal/class"
+	"github.com/ukane-philemon/scomp/internal/db"
+)
+
+// ClassRepository implements class.Repository.
+type ClassRepository struct {
+	store *Store
+}
+
+// NewClassRepository creates a new instance of *ClassRepository.
+func NewClassRepository(store *Store) class.Repository {
+	return &ClassRepository{
+		store: store,
+	}
+}
+
+// Create creates a new class in the store. Returns db.ErrorInvalidRequest is
+// the provided class name matches any class of the school.
+// Implements class.Repository.
+func (cr *ClassRepository) Create(schoolID, className, teacherID string, settings class.ReportSettings, subjects []*class.Subject) (string, error) {
+	newClass, err := class.NewClass(schoolID, className, teacherID, settings, subjects)
+	if err != nil {
+		return "", err
+	}
+
+	classInfo, err := clone(newClass)
+	if err != nil {
+		return "", err
+	}
+
+	cr.store.mtx.Lock()
+	defer cr.store.mtx.Unlock()
+
+	for _, c := range cr.store.classes {
+		if c.SchoolID == schoolID && c.Name == className {
+			return "", fmt.Errorf("%w: class name %s already exists", db.ErrorInvalidRequest, className)
+		}
+	}
+
+	cr.store.classes[classInfo.ID] = classInfo
+
+	return classInfo.ID, nil
+}
+
+// Class returns information for the class that match the provided classID.
+// Implements class.Repository.
+func (cr *ClassRepository) Class(schoolID, classID string) (*class.Class, error) {
+	if schoolID == "" || classID == "" {
+		return nil, fmt.Errorf("%w: missing schoolID or classID", db.ErrorInvalidRequest)
+	}
+
+	cr.store.mtx.RLock()
+	defer cr.store.mtx.RUnlock()
+
+	classInfo, found := cr.store.classes[classID]
+	if !found || classInfo.SchoolID != schoolID {
+		return nil, fmt.Errorf("%w: no record found for class with ID %s", db.ErrorInvalidRequest, classID)
+	}
+
+	return clone(classInfo)
+}
+
+// Classes returns information for all the classes of the school.
+// Implements class.Repository.
+func (cr *ClassRepository) Classes(schoolID string, hasReport *bool) ([]*class.Class, error) {
+	if schoolID == "" {
+		return nil, fmt.Errorf("%w: missing schoolID", db.ErrorInvalidRequest)
+	}
+
+	cr.store.mtx.RLock()
+	defer cr.store.mtx.RUnlock()
+
+	var classes []*class.Class
+	for _, classInfo := range cr.store.classes {
+		if classInfo.SchoolID != schoolID || (hasReport != nil && *hasReport != (classInfo.Report != nil)) {
+			continue
+		}
+
+		c, err := clone(classInfo)
+		if err != nil {
+			return nil, err
+		}
+
+		classes = append(classes, c)
+	}
+
+	return classes, nil
+}
+
+// Exists checks if classID exists.
+// Implements class.Repository.
+func (cr *ClassRepository) Exists(schoolID, classID string) (bool, error) {
+	if schoolID == "" || classID == "" {
+		return false, fmt.Errorf("%w: missing schoolID or classID", db.ErrorInvalidRequest)
+	}
+
+	cr.store.mtx.RLock()
+	defer cr.store.mtx.RUnlock()
+
+	classInfo, found := cr.store.classes[classID]
+	return found && classInfo.SchoolID == schoolID, nil
+}
+
+// Transition changes the state of the class that match classID from from to
+// to.
+// Implements class.Repository.
+func (cr *ClassRepository) Transition(schoolID, classID, from, to string) error {
+	if schoolID == "" || classID == "" {
+		return fmt.Errorf("%w: missing schoolID or classID", db.ErrorInvalidRequest)
+	}
+
+	cr.store.mtx.Lock()
+	defer cr.store.mtx.Unlock()
+
+	classInfo, found := cr.store.classes[classID]
+	if !found || classInfo.SchoolID != schoolID || classInfo.State != from {
+		return fmt.Errorf("%w: class with ID %s is not %s", db.ErrorInvalidRequest, classID, from)
+	}
+
+	classInfo.State = to
+
+	return nil
+}
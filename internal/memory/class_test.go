@@ -0,0 +1,133 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ukane-philemon/scomp/internal/class"
+	"github.com/ukane-philemon/scomp/internal/db"
+)
+
+// testSchoolID is the school of the records created by tests.
+const testSchoolID = "school"
+
+// testSubjects returns the subjects of the classes created by tests.
+func testSubjects() []*class.Subject {
+	subjects := make([]*class.Subject, 3)
+	for i := range subjects {
+		subjects[i] = &class.Subject{Name: fmt.Sprintf("Subject %d", i+1), MaxScore: 100, Weight: 1}
+	}
+	return subjects
+}
+
+// newTestClass creates a class named className and returns its ID.
+func newTestClass(t *testing.T, cr class.Repository, className string) string {
+	t.Helper()
+
+	classID, err := cr.Create(testSchoolID, className, "teacher", class.ReportSettings{}, testSubjects())
+	if err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+	return classID
+}
+
+func TestCreateClass(t *testing.T) {
+	cr := NewClassRepository(New())
+	classID := newTestClass(t, cr, "JSS 1")
+
+	classInfo, err := cr.Class(testSchoolID, classID)
+	if err != nil {
+		t.Fatalf("Class error: %v", err)
+	}
+
+	if classInfo.Name != "JSS 1" || len(classInfo.Subjects) != len(testSubjects()) {
+		t.Fatalf("expected class JSS 1 with %d subjects, got %s with %d subjects", len(testSubjects()), classInfo.Name, len(classInfo.Subjects))
+	}
+
+	tests := []struct {
+		name      string
+		className string
+		subjects  []*class.Subject
+	}{
+		{name: "duplicate name", className: "JSS 1", subjects: testSubjects()},
+		{name: "missing name", className: "", subjects: testSubjects()},
+		{name: "missing subjects", className: "JSS 2"},
+		{name: "invalid max score", className: "JSS 2", subjects: append(testSubjects(), &class.Subject{Name: "Art", Weight: 1})},
+		{name: "duplicate subject", className: "JSS 2", subjects: append(testSubjects(), &class.Subject{Name: "subject 1", MaxScore: 100, Weight: 1})},
+	}
+
+	for _, test := range tests {
+		_, err := cr.Create(testSchoolID, test.className, "teacher", class.ReportSettings{}, test.subjects)
+		if !errors.Is(err, db.ErrorInvalidRequest) {
+			t.Errorf("%s: expected db.ErrorInvalidRequest, got %v", test.name, err)
+		}
+	}
+}
+
+func TestClassIsCopied(t *testing.T) {
+	cr := NewClassRepository(New())
+
+	subjects := testSubjects()
+	classID, err := cr.Create(testSchoolID, "JSS 1", "teacher", class.ReportSettings{}, subjects)
+	if err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+
+	// Changing the arguments or the returned classes must not change the
+	// stored class.
+	subjects[0].Name = "Changed"
+	classInfo, err := cr.Class(testSchoolID, classID)
+	if err != nil {
+		t.Fatalf("Class error: %v", err)
+	}
+
+	if classInfo.Subjects[0].Name != "Subject 1" {
+		t.Fatalf("stored class changed with the arguments of Create")
+	}
+
+	classInfo.Name = "Changed"
+	classInfo.Subjects[0].Name = "Changed"
+	classInfo, err = cr.Class(testSchoolID, classID)
+	if err != nil {
+		t.Fatalf("Class error: %v", err)
+	}
+
+	if classInfo.Name != "JSS 1" || classInfo.Subjects[0].Name != "Subject 1" {
+		t.Fatalf("stored class changed with the returned class")
+	}
+}
+
+func TestCreateClassRankingMode(t *testing.T) {
+	cr := NewClassRepository(New())
+
+	tests := []struct {
+		className   string
+		rankingMode string
+		want        string
+	}{
+		{className: "JSS 1", want: class.RankingCompetition},
+		{className: "JSS 2", rankingMode: class.RankingDense, want: class.RankingDense},
+	}
+
+	for _, test := range tests {
+		classID, err := cr.Create(testSchoolID, test.className, "teacher", class.ReportSettings{RankingMode: test.rankingMode}, testSubjects())
+		if err != nil {
+			t.Fatalf("Create error: %v", err)
+		}
+
+		classInfo, err := cr.Class(testSchoolID, classID)
+		if err != nil {
+			t.Fatalf("Class error: %v", err)
+		}
+
+		if classInfo.RankingMode != test.want {
+			t.Errorf("%s: expected ranking mode %s, got %s", test.className, test.want, classInfo.RankingMode)
+		}
+	}
+
+	_, err := cr.Create(testSchoolID, "JSS 3", "teacher", class.ReportSettings{RankingMode: "olympic"}, testSubjects())
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for an unknown ranking mode, got %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+package memory
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ukane-philemon/scomp/internal/db"
+	"github.com/ukane-philemon/scomp/internal/grading"
+)
+
+// GradingScaleRepository implements grading.Repository.
+type GradingScaleRepository struct {
+	store *Store
+}
+
+// NewGradingScaleRepository creates a new instance of
+// *GradingScaleRepository.
+func NewGradingScaleRepository(store *Store) grading.Repository {
+	return &GradingScaleRepository{
+		store: store,
+	}
+}
+
+// Create implements grading.Repository.
+func (gr *GradingScaleRepository) Create(schoolID, name string, bands []*grading.GradeBand) (*grading.GradingScale, error) {
+	gradingScale, err := grading.NewGradingScale(schoolID, name, bands)
+	if err != nil {
+		return nil, err
+	}
+
+	storedGradingScale, err := clone(gradingScale)
+	if err != nil {
+		return nil, err
+	}
+
+	gr.store.mtx.Lock()
+	defer gr.store.mtx.Unlock()
+
+	if gr.nameExists(schoolID, "", gradingScale.Name) {
+		return nil, fmt.Errorf("%w: grading scale name %s already exists", db.ErrorInvalidRequest, gradingScale.Name)
+	}
+
+	gr.store.scales[gradingScale.ID] = storedGradingScale
+
+	return gradingScale, nil
+}
+
+// GradingScale implements grading.Repository.
+func (gr *GradingScaleRepository) GradingScale(schoolID, gradingScaleID string) (*grading.GradingScale, error) {
+	gr.store.mtx.RLock()
+	defer gr.store.mtx.RUnlock()
+
+	gradingScale, found := gr.store.scales[gradingScaleID]
+	if !found || gradingScale.SchoolID != schoolID {
+		return nil, fmt.Errorf("%w: no record found for grading scale with ID %s", db.ErrorInvalidRequest, gradingScaleID)
+	}
+
+	return clone(gradingScale)
+}
+
+// GradingScales implements grading.Repository.
+func (gr *GradingScaleRepository) GradingScales(schoolID string) ([]*grading.GradingScale, error) {
+	gr.store.mtx.RLock()
+	defer gr.store.mtx.RUnlock()
+
+	var gradingScales []*grading.GradingScale
+	for _, gradingScale := range gr.store.scales {
+		if gradingScale.SchoolID != schoolID {
+			continue
+		}
+
+		gradingScaleCopy, err := clone(gradingScale)
+		if err != nil {
+			return nil, err
+		}
+		gradingScales = append(gradingScales, gradingScaleCopy)
+	}
+
+	sort.Slice(gradingScales, func(i, j int) bool {
+		return gradingScales[i].Name < gradingScales[j].Name
+	})
+
+	return gradingScales, nil
+}
+
+// Update implements grading.Repository.
+func (gr *GradingScaleRepository) Update(schoolID, gradingScaleID, name string, bands []*grading.GradeBand) (*grading.GradingScale, error) {
+	name, bands, err := grading.ValidateGradingScale(name, bands)
+	if err != nil {
+		return nil, err
+	}
+
+	gr.store.mtx.Lock()
+	defer gr.store.mtx.Unlock()
+
+	gradingScale, found := gr.store.scales[gradingScaleID]
+	if !found || gradingScale.SchoolID != schoolID {
+		return nil, fmt.Errorf("%w: no record found for grading scale with ID %s", db.ErrorInvalidRequest, gradingScaleID)
+	}
+
+	if gr.nameExists(schoolID, gradingScaleID, name) {
+		return nil, fmt.Errorf("%w: grading scale name %s already exists", db.ErrorInvalidRequest, name)
+	}
+
+	gradingScale.Name = name
+	gradingScale.Bands = bands
+	gradingScale.LastUpdatedAt = time.Now().Unix()
+	return clone(gradingScale)
+}
+
+// Delete implements grading.Repository.
+func (gr *GradingScaleRepository) Delete(schoolID, gradingScaleID string) error {
+	gr.store.mtx.Lock()
+	defer gr.store.mtx.Unlock()
+
+	gradingScale, found := gr.store.scales[gradingScaleID]
+	if !found || gradingScale.SchoolID != schoolID {
+		return fmt.Errorf("%w: no record found for grading scale with ID %s", db.ErrorInvalidRequest, gradingScaleID)
+	}
+
+	delete(gr.store.scales, gradingScaleID)
+	return nil
+}
+
+// nameExists checks if a grading scale of schoolID other than exceptID is
+// named name. The caller must hold the store lock.
+func (gr *GradingScaleRepository) nameExists(schoolID, exceptID, name string) bool {
+	for _, gradingScale := range gr.store.scales {
+		if gradingScale.SchoolID == schoolID && gradingScale.ID != exceptID && gradingScale.Name == name {
+			return true
+		}
+	}
+	return false
+}
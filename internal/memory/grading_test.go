@@ -0,0 +1,82 @@
+package memory
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ukane-philemon/scomp/internal/db"
+	"github.com/ukane-philemon/scomp/internal/grading"
+)
+
+// testBands returns the bands of the grading scales created by tests.
+func testBands() []*grading.GradeBand {
+	return []*grading.GradeBand{{MinPercentage: 50, Label: "Pass"}, {MinPercentage: 0, Label: "Fail"}}
+}
+
+func TestGradingScales(t *testing.T) {
+	gr := NewGradingScaleRepository(New())
+
+	gradingScale, err := gr.Create(testSchoolID, "Pass/Fail", testBands())
+	if err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+
+	other, err := gr.Create(testSchoolID, "Other", testBands())
+	if err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+
+	// Names are unique per school.
+	_, err = gr.Create(testSchoolID, "Pass/Fail", testBands())
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for a duplicate name, got %v", err)
+	}
+
+	_, err = gr.Create("other school", "Pass/Fail", testBands())
+	if err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+
+	gradingScales, err := gr.GradingScales(testSchoolID)
+	if err != nil {
+		t.Fatalf("GradingScales error: %v", err)
+	}
+
+	if len(gradingScales) != 2 || gradingScales[0].ID != other.ID || gradingScales[1].ID != gradingScale.ID {
+		t.Fatalf("expected the grading scales of the school sorted by name, got %d", len(gradingScales))
+	}
+
+	_, err = gr.Update(testSchoolID, gradingScale.ID, "Other", testBands())
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest renaming to a used name, got %v", err)
+	}
+
+	updated, err := gr.Update(testSchoolID, gradingScale.ID, "Pass/Fail", []*grading.GradeBand{{MinPercentage: 0, Label: "Done"}})
+	if err != nil {
+		t.Fatalf("Update error: %v", err)
+	}
+
+	if len(updated.Bands) != 1 || updated.Bands[0].Label != "Done" {
+		t.Fatalf("expected the updated bands, got %d bands", len(updated.Bands))
+	}
+
+	_, err = gr.GradingScale("other school", gradingScale.ID)
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for a grading scale of another school, got %v", err)
+	}
+
+	err = gr.Delete("other school", gradingScale.ID)
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest deleting a grading scale of another school, got %v", err)
+	}
+
+	err = gr.Delete(testSchoolID, gradingScale.ID)
+	if err != nil {
+		t.Fatalf("Delete error: %v", err)
+	}
+
+	_, err = gr.GradingScale(testSchoolID, gradingScale.ID)
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for a deleted grading scale, got %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package memory
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ukane-philemon/scomp/internal/db"
+	"github.com/ukane-philemon/scomp/internal/history"
+)
+
+// ReportVersionRepository implements history.Repository.
+type ReportVersionRepository struct {
+	store *Store
+}
+
+// NewReportVersionRepository creates a new instance of
+// *ReportVersionRepository.
+func NewReportVersionRepository(store *Store) history.Repository {
+	return &ReportVersionRepository{
+		store: store,
+	}
+}
+
+// ReportVersion implements history.Repository.
+func (vr *ReportVersionRepository) ReportVersion(schoolID, classID string, version int) (*history.ReportVersion, error) {
+	vr.store.mtx.RLock()
+	defer vr.store.mtx.RUnlock()
+
+	for _, reportVersion := range vr.store.reportVersions {
+		if reportVersion.SchoolID == schoolID && reportVersion.ClassID == classID && reportVersion.Version == version {
+			return clone(reportVersion)
+		}
+	}
+
+	return nil, fmt.Errorf("%w: no report version %d found for class with ID %s", db.ErrorInvalidRequest, version, classID)
+}
+
+// ReportVersions implements history.Repository.
+func (vr *ReportVersionRepository) ReportVersions(schoolID, classID string) ([]*history.ReportVersion, error) {
+	vr.store.mtx.RLock()
+	defer vr.store.mtx.RUnlock()
+
+	var reportVersions []*history.ReportVersion
+	for _, reportVersion := range vr.store.reportVersions {
+		if reportVersion.SchoolID != schoolID || reportVersion.ClassID != classID {
+			continue
+		}
+
+		reportVersionCopy, err := clone(reportVersion)
+		if err != nil {
+			return nil, err
+		}
+		reportVersions = append(reportVersions, reportVersionCopy)
+	}
+
+	sort.Slice(reportVersions, func(i, j int) bool {
+		return reportVersions[i].Version > reportVersions[j].Version
+	})
+
+	return reportVersions, nil
+}
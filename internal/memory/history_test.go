@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ukane-philemon/scomp/internal/class"
+	"github.com/ukane-philemon/scomp/internal/db"
+)
+
+func TestReportVersions(t *testing.T) {
+	store := New()
+	rs, vr := NewReportStore(store), NewReportVersionRepository(store)
+	classID, studentIDs := newReportClass(t, store, "Ada")
+	otherClassID, otherStudentIDs := newReportClass(t, store, "Bola")
+
+	for version := 1; version <= 2; version++ {
+		if version > 1 {
+			startComputing(t, store, classID, class.StateReported)
+		}
+
+		reportVersion, err := rs.CreateReports(testSchoolID, classID, "teacher", store.classes[classID].StudentsRevision, &class.ClassReport{TotalStudents: version}, testStudentReports(1, studentIDs...))
+		if err != nil {
+			t.Fatalf("CreateReports error: %v", err)
+		}
+
+		if reportVersion.Version != version {
+			t.Fatalf("expected version %d, got %d", version, reportVersion.Version)
+		}
+	}
+
+	// Versions are counted per class.
+	reportVersion, err := rs.CreateReports(testSchoolID, otherClassID, "teacher", store.classes[otherClassID].StudentsRevision, &class.ClassReport{}, testStudentReports(1, otherStudentIDs...))
+	if err != nil {
+		t.Fatalf("CreateReports error: %v", err)
+	}
+
+	if reportVersion.Version != 1 {
+		t.Fatalf("expected the first version of another class to be 1, got %d", reportVersion.Version)
+	}
+
+	reportVersions, err := vr.ReportVersions(testSchoolID, classID)
+	if err != nil {
+		t.Fatalf("ReportVersions error: %v", err)
+	}
+
+	if len(reportVersions) != 2 || reportVersions[0].Version != 2 || reportVersions[1].Version != 1 {
+		t.Fatal("expected the two versions of the class from the newest to the oldest")
+	}
+
+	reportVersion, err = vr.ReportVersion(testSchoolID, classID, 1)
+	if err != nil {
+		t.Fatalf("ReportVersion error: %v", err)
+	}
+
+	if reportVersion.ClassReport.TotalStudents != 1 {
+		t.Fatalf("expected the class report of version 1, got %d students", reportVersion.ClassReport.TotalStudents)
+	}
+
+	_, err = vr.ReportVersion("other school", classID, 1)
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for another school, got %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package memory
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ukane-philemon/scomp/internal/admin"
+	"github.com/ukane-philemon/scomp/internal/db"
+)
+
+// InvitationRepository implements admin.InvitationRepository.
+type InvitationRepository struct {
+	store *Store
+}
+
+// NewInvitationRepository creates a new instance of *InvitationRepository.
+func NewInvitationRepository(store *Store) admin.InvitationRepository {
+	return &InvitationRepository{
+		store: store,
+	}
+}
+
+// Create implements admin.InvitationRepository.
+func (ir *InvitationRepository) Create(schoolID, createdBy, role string, validFor time.Duration) (*admin.Invitation, string, error) {
+	invitation, code, err := admin.NewInvitation(schoolID, createdBy, role, validFor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	storedInvitation, err := clone(invitation)
+	if err != nil {
+		return nil, "", err
+	}
+
+	ir.store.mtx.Lock()
+	ir.store.invitations[invitation.ID] = storedInvitation
+	ir.store.mtx.Unlock()
+
+	return invitation, code, nil
+}
+
+// Redeem implements admin.InvitationRepository.
+func (ir *InvitationRepository) Redeem(code string) (*admin.Invitation, error) {
+	hashedCode := admin.HashInvitationCode(code)
+	now := time.Now().Unix()
+
+	ir.store.mtx.Lock()
+	defer ir.store.mtx.Unlock()
+
+	for _, invitation := range ir.store.invitations {
+		if invitation.HashedCode != hashedCode || invitation.RedeemedAt != 0 || invitation.ExpiresAt <= now {
+			continue
+		}
+
+		invitation.RedeemedAt = now
+		return clone(invitation)
+	}
+
+	return nil, fmt.Errorf("%w: invitation code is invalid, expired or has already been used", db.ErrorInvalidRequest)
+}
+
+// Release implements admin.InvitationRepository.
+func (ir *InvitationRepository) Release(invitationID string) error {
+	ir.store.mtx.Lock()
+	defer ir.store.mtx.Unlock()
+
+	if invitation, found := ir.store.invitations[invitationID]; found {
+		invitation.RedeemedAt = 0
+	}
+
+	return nil
+}
+
+// Revoke implements admin.InvitationRepository.
+func (ir *InvitationRepository) Revoke(schoolID, invitationID string) error {
+	ir.store.mtx.Lock()
+	defer ir.store.mtx.Unlock()
+
+	invitation, found := ir.store.invitations[invitationID]
+	if !found || invitation.SchoolID != schoolID || invitation.RedeemedAt != 0 {
+		return fmt.Errorf("%w: no unused invitation found with ID %s", db.ErrorInvalidRequest, invitationID)
+	}
+
+	delete(ir.store.invitations, invitationID)
+	return nil
+}
@@ -0,0 +1,123 @@
+package memory
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ukane-philemon/scomp/internal/admin"
+	"github.com/ukane-philemon/scomp/internal/db"
+)
+
+func TestRedeemInvitation(t *testing.T) {
+	store := New()
+	ir := NewInvitationRepository(store)
+
+	invitation, code, err := ir.Create(testSchoolID, "owner", admin.RoleTeacher, time.Hour)
+	if err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+
+	if invitation.HashedCode == code {
+		t.Fatal("invitation code is stored in plain text")
+	}
+
+	_, err = ir.Redeem("unknown")
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for an unknown code, got %v", err)
+	}
+
+	redeemed, err := ir.Redeem(code)
+	if err != nil {
+		t.Fatalf("Redeem error: %v", err)
+	}
+
+	if redeemed.ID != invitation.ID || redeemed.Role != admin.RoleTeacher || redeemed.RedeemedAt == 0 {
+		t.Fatalf("expected redeemed invitation %s, got %+v", invitation.ID, redeemed)
+	}
+
+	// An invitation can only be redeemed once.
+	_, err = ir.Redeem(code)
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for a redeemed code, got %v", err)
+	}
+
+	// A redeemed invitation cannot be revoked.
+	err = ir.Revoke(testSchoolID, invitation.ID)
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest revoking a redeemed invitation, got %v", err)
+	}
+
+	// Releasing the invitation makes it redeemable again.
+	err = ir.Release(invitation.ID)
+	if err != nil {
+		t.Fatalf("Release error: %v", err)
+	}
+
+	_, err = ir.Redeem(code)
+	if err != nil {
+		t.Fatalf("Redeem error after Release: %v", err)
+	}
+}
+
+func TestRedeemExpiredInvitation(t *testing.T) {
+	store := New()
+	ir := NewInvitationRepository(store)
+
+	invitation, code, err := ir.Create(testSchoolID, "owner", admin.RoleViewer, time.Hour)
+	if err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+
+	store.invitations[invitation.ID].ExpiresAt = time.Now().Add(-time.Second).Unix()
+
+	_, err = ir.Redeem(code)
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for an expired code, got %v", err)
+	}
+}
+
+func TestRevokeInvitation(t *testing.T) {
+	ir := NewInvitationRepository(New())
+
+	invitation, code, err := ir.Create(testSchoolID, "owner", admin.RoleAdmin, time.Hour)
+	if err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+
+	err = ir.Revoke(testSchoolID, invitation.ID)
+	if err != nil {
+		t.Fatalf("Revoke error: %v", err)
+	}
+
+	_, err = ir.Redeem(code)
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for a revoked code, got %v", err)
+	}
+
+	err = ir.Revoke(testSchoolID, invitation.ID)
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest revoking an unknown invitation, got %v", err)
+	}
+}
+
+func TestCreateInvitationErrors(t *testing.T) {
+	ir := NewInvitationRepository(New())
+
+	tests := []struct {
+		name     string
+		role     string
+		validFor time.Duration
+	}{
+		{name: "unknown role", role: "principal", validFor: time.Hour},
+		{name: "no validity", role: admin.RoleTeacher},
+		{name: "too long", role: admin.RoleTeacher, validFor: admin.MaxInvitationValidity + time.Hour},
+	}
+
+	for _, test := range tests {
+		_, _, err := ir.Create(testSchoolID, "owner", test.role, test.validFor)
+		if !errors.Is(err, db.ErrorInvalidRequest) {
+			t.Errorf("%s: expected db.ErrorInvalidRequest, got %v", test.name, err)
+		}
+	}
+}
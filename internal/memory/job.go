@@ -0,0 +1,127 @@
+package memory
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ukane-philemon/scomp/internal/db"
+	"github.com/ukane-philemon/scomp/internal/job"
+)
+
+// JobRepository implements job.Repository.
+type JobRepository struct {
+	store *Store
+}
+
+// NewJobRepository creates a new instance of *JobRepository.
+func NewJobRepository(store *Store) job.Repository {
+	return &JobRepository{
+		store: store,
+	}
+}
+
+// Create implements job.Repository.
+func (jr *JobRepository) Create(schoolID, classID, kind, requestedBy string) (*job.Job, error) {
+	newJob, err := job.NewJob(schoolID, classID, kind, requestedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	storedJob, err := clone(newJob)
+	if err != nil {
+		return nil, err
+	}
+
+	jr.store.mtx.Lock()
+	defer jr.store.mtx.Unlock()
+
+	jr.store.jobs[newJob.ID] = storedJob
+
+	return newJob, nil
+}
+
+// Job implements job.Repository.
+func (jr *JobRepository) Job(schoolID, jobID string) (*job.Job, error) {
+	jr.store.mtx.RLock()
+	defer jr.store.mtx.RUnlock()
+
+	jobInfo, found := jr.store.jobs[jobID]
+	if !found || jobInfo.SchoolID != schoolID {
+		return nil, fmt.Errorf("%w: no record found for job with ID %s", db.ErrorInvalidRequest, jobID)
+	}
+
+	return clone(jobInfo)
+}
+
+// Claim implements job.Repository.
+func (jr *JobRepository) Claim(now time.Time) (*job.Job, error) {
+	nowUnix := now.Unix()
+
+	jr.store.mtx.Lock()
+	defer jr.store.mtx.Unlock()
+
+	var dueJob *job.Job
+	for _, jobInfo := range jr.store.jobs {
+		due := (jobInfo.State == job.StateQueued && jobInfo.RunAt <= nowUnix) ||
+			(jobInfo.State == job.StateRunning && jobInfo.LeaseExpiresAt <= nowUnix)
+		if due && (dueJob == nil || jobInfo.RunAt < dueJob.RunAt) {
+			dueJob = jobInfo
+		}
+	}
+
+	if dueJob == nil {
+		return nil, nil
+	}
+
+	dueJob.State = job.StateRunning
+	dueJob.Attempts++
+	dueJob.LeaseExpiresAt = now.Add(job.LeaseDuration).Unix()
+	dueJob.StartedAt = nowUnix
+
+	return clone(dueJob)
+}
+
+// Succeed implements job.Repository.
+func (jr *JobRepository) Succeed(schoolID, jobID string, attempt, reportVersion int) error {
+	return jr.finishAttempt(schoolID, jobID, attempt, func(jobInfo *job.Job) {
+		jobInfo.State = job.StateSucceeded
+		jobInfo.Error = ""
+		jobInfo.ReportVersion = reportVersion
+		jobInfo.FinishedAt = time.Now().Unix()
+	})
+}
+
+// Retry implements job.Repository.
+func (jr *JobRepository) Retry(schoolID, jobID string, attempt int, errMsg string, runAt time.Time) error {
+	return jr.finishAttempt(schoolID, jobID, attempt, func(jobInfo *job.Job) {
+		jobInfo.State = job.StateQueued
+		jobInfo.Error = errMsg
+		jobInfo.RunAt = runAt.Unix()
+	})
+}
+
+// Fail implements job.Repository.
+func (jr *JobRepository) Fail(schoolID, jobID string, attempt int, errMsg string) error {
+	return jr.finishAttempt(schoolID, jobID, attempt, func(jobInfo *job.Job) {
+		jobInfo.State = job.StateFailed
+		jobInfo.Error = errMsg
+		jobInfo.FinishedAt = time.Now().Unix()
+	})
+}
+
+// finishAttempt updates the job that match jobID with update if it is running
+// attempt.
+func (jr *JobRepository) finishAttempt(schoolID, jobID string, attempt int, update func(jobInfo *job.Job)) error {
+	jr.store.mtx.Lock()
+	defer jr.store.mtx.Unlock()
+
+	jobInfo, found := jr.store.jobs[jobID]
+	if !found || jobInfo.SchoolID != schoolID || jobInfo.State != job.StateRunning || jobInfo.Attempts != attempt {
+		return fmt.Errorf("%w: job with ID %s is not running attempt %d", db.ErrorInvalidRequest, jobID, attempt)
+	}
+
+	update(jobInfo)
+	jobInfo.LeaseExpiresAt = 0
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package memory
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ukane-philemon/scomp/internal/db"
+	"github.com/ukane-philemon/scomp/internal/job"
+)
+
+// newTestJob queues a job for classID and returns it.
+func newTestJob(t *testing.T, jr job.Repository, classID string) *job.Job {
+	t.Helper()
+
+	newJob, err := jr.Create(testSchoolID, classID, job.KindComputeClassReport, "teacher")
+	if err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+	return newJob
+}
+
+// claim claims the next due job at now and fails the test if it is not
+// jobID.
+func claim(t *testing.T, jr job.Repository, now time.Time, jobID string) *job.Job {
+	t.Helper()
+
+	claimedJob, err := jr.Claim(now)
+	if err != nil {
+		t.Fatalf("Claim error: %v", err)
+	}
+
+	if claimedJob == nil || claimedJob.ID != jobID {
+		t.Fatalf("expected job %s to be claimed, got %+v", jobID, claimedJob)
+	}
+	return claimedJob
+}
+
+// expectNoDueJob fails the test if a job is due at now.
+func expectNoDueJob(t *testing.T, jr job.Repository, now time.Time) {
+	t.Helper()
+
+	claimedJob, err := jr.Claim(now)
+	if err != nil {
+		t.Fatalf("Claim error: %v", err)
+	}
+
+	if claimedJob != nil {
+		t.Fatalf("expected no due job, got %+v", claimedJob)
+	}
+}
+
+func TestClaimLeaseExpiry(t *testing.T) {
+	jr := NewJobRepository(New())
+	newJob := newTestJob(t, jr, "class")
+	now := time.Now()
+
+	claimedJob := claim(t, jr, now, newJob.ID)
+	if claimedJob.State != job.StateRunning || claimedJob.Attempts != 1 {
+		t.Fatalf("expected the first attempt of a running job, got %+v", claimedJob)
+	}
+
+	// The job is reserved for its worker until its lease expires.
+	expectNoDueJob(t, jr, now.Add(job.LeaseDuration-time.Second))
+
+	claimedJob = claim(t, jr, now.Add(job.LeaseDuration), newJob.ID)
+	if claimedJob.Attempts != 2 {
+		t.Fatalf("expected a second attempt after the lease expired, got %d", claimedJob.Attempts)
+	}
+}
+
+func TestRetryJob(t *testing.T) {
+	jr := NewJobRepository(New())
+	newJob := newTestJob(t, jr, "class")
+	now := time.Now()
+
+	claimedJob := claim(t, jr, now, newJob.ID)
+	runAt := now.Add(job.RetryDelay(claimedJob.Attempts))
+	if err := jr.Retry(testSchoolID, newJob.ID, claimedJob.Attempts, "failed", runAt); err != nil {
+		t.Fatalf("Retry error: %v", err)
+	}
+
+	jobInfo, err := jr.Job(testSchoolID, newJob.ID)
+	if err != nil {
+		t.Fatalf("Job error: %v", err)
+	}
+
+	if jobInfo.State != job.StateQueued || jobInfo.Error != "failed" || jobInfo.LeaseExpiresAt != 0 {
+		t.Fatalf("expected a queued job with the error of its attempt, got %+v", jobInfo)
+	}
+
+	// The job is not due before its retry delay.
+	expectNoDueJob(t, jr, runAt.Add(-time.Second))
+	claimedJob = claim(t, jr, runAt, newJob.ID)
+
+	if err := jr.Succeed(testSchoolID, newJob.ID, claimedJob.Attempts, 1); err != nil {
+		t.Fatalf("Succeed error: %v", err)
+	}
+
+	jobInfo, err = jr.Job(testSchoolID, newJob.ID)
+	if err != nil {
+		t.Fatalf("Job error: %v", err)
+	}
+
+	if jobInfo.State != job.StateSucceeded || jobInfo.Error != "" || jobInfo.ReportVersion != 1 || jobInfo.FinishedAt == 0 {
+		t.Fatalf("expected a succeeded job with its report version, got %+v", jobInfo)
+	}
+
+	// Finished jobs are never claimed again.
+	expectNoDueJob(t, jr, now.Add(2*job.LeaseDuration))
+}
+
+func TestFinishAttemptRequiresRunningJob(t *testing.T) {
+	jr := NewJobRepository(New())
+	newJob := newTestJob(t, jr, "class")
+
+	err := jr.Fail(testSchoolID, newJob.ID, 0, "failed")
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for a queued job, got %v", err)
+	}
+
+	claim(t, jr, time.Now(), newJob.ID)
+
+	err = jr.Succeed("other school", newJob.ID, 1, 1)
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for another school, got %v", err)
+	}
+
+	if err := jr.Fail(testSchoolID, newJob.ID, 1, "failed"); err != nil {
+		t.Fatalf("Fail error: %v", err)
+	}
+
+	err = jr.Retry(testSchoolID, newJob.ID, 1, "failed", time.Now())
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for a failed job, got %v", err)
+	}
+}
+
+func TestFinishAttemptRequiresCurrentAttempt(t *testing.T) {
+	jr := NewJobRepository(New())
+	newJob := newTestJob(t, jr, "class")
+	now := time.Now()
+
+	// The lease of the first attempt expires and another worker claims the
+	// job again.
+	firstAttempt := claim(t, jr, now, newJob.ID)
+	secondAttempt := claim(t, jr, now.Add(job.LeaseDuration), newJob.ID)
+
+	err := jr.Fail(testSchoolID, newJob.ID, firstAttempt.Attempts, "failed")
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for an expired attempt, got %v", err)
+	}
+
+	jobInfo, err := jr.Job(testSchoolID, newJob.ID)
+	if err != nil {
+		t.Fatalf("Job error: %v", err)
+	}
+
+	if jobInfo.State != job.StateRunning {
+		t.Fatalf("expected the job to keep running its second attempt, got %s", jobInfo.State)
+	}
+
+	if err := jr.Succeed(testSchoolID, newJob.ID, secondAttempt.Attempts, 1); err != nil {
+		t.Fatalf("Succeed error: %v", err)
+	}
+}
@@ -0,0 +1,177 @@
+package memory
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ukane-philemon/scomp/internal/admin"
+	"github.com/ukane-philemon/scomp/internal/db"
+)
+
+func TestLoginLockout(t *testing.T) {
+	ar := NewAdminRepository(New())
+
+	adminID, err := ar.CreateAccount(testSchoolID, "admin", "password", admin.RoleAdmin)
+	if err != nil {
+		t.Fatalf("CreateAccount error: %v", err)
+	}
+
+	// A successful login resets the failed attempts.
+	for i := 0; i < admin.MaxFailedLoginAttempts-1; i++ {
+		ar.LoginAccount("admin", "wrong password")
+	}
+
+	_, err = ar.LoginAccount("admin", "password")
+	if err != nil {
+		t.Fatalf("LoginAccount error: %v", err)
+	}
+
+	for i := 0; i < admin.MaxFailedLoginAttempts; i++ {
+		_, err = ar.LoginAccount("admin", "wrong password")
+		if !errors.Is(err, db.ErrorInvalidRequest) {
+			t.Fatalf("expected db.ErrorInvalidRequest for a wrong password, got %v", err)
+		}
+	}
+
+	// The correct password is refused while the account is locked, with the
+	// error of an unknown username.
+	_, err = ar.LoginAccount("admin", "password")
+	if !errors.Is(err, admin.ErrorIncorrectLogin) {
+		t.Fatalf("expected admin.ErrorIncorrectLogin for a locked account, got %v", err)
+	}
+
+	_, err = ar.LoginAccount("unknown", "password")
+	if !errors.Is(err, admin.ErrorIncorrectLogin) {
+		t.Fatalf("expected admin.ErrorIncorrectLogin for an unknown username, got %v", err)
+	}
+
+	// Resetting the password unlocks the account.
+	resetToken, _, err := ar.CreatePasswordReset(testSchoolID, adminID)
+	if err != nil {
+		t.Fatalf("CreatePasswordReset error: %v", err)
+	}
+
+	_, err = ar.ResetPassword(resetToken, "new password")
+	if err != nil {
+		t.Fatalf("ResetPassword error: %v", err)
+	}
+
+	_, err = ar.LoginAccount("admin", "new password")
+	if err != nil {
+		t.Fatalf("LoginAccount error after ResetPassword: %v", err)
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	ar := NewAdminRepository(New())
+
+	adminID, err := ar.CreateAccount(testSchoolID, "admin", "password", admin.RoleAdmin)
+	if err != nil {
+		t.Fatalf("CreateAccount error: %v", err)
+	}
+
+	err = ar.ChangePassword(adminID, "wrong password", "new password")
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for a wrong current password, got %v", err)
+	}
+
+	err = ar.ChangePassword(adminID, "password", "new password")
+	if err != nil {
+		t.Fatalf("ChangePassword error: %v", err)
+	}
+
+	_, err = ar.LoginAccount("admin", "password")
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for the old password, got %v", err)
+	}
+
+	_, err = ar.LoginAccount("admin", "new password")
+	if err != nil {
+		t.Fatalf("LoginAccount error: %v", err)
+	}
+}
+
+func TestChangePasswordLockout(t *testing.T) {
+	ar := NewAdminRepository(New())
+
+	adminID, err := ar.CreateAccount(testSchoolID, "admin", "password", admin.RoleAdmin)
+	if err != nil {
+		t.Fatalf("CreateAccount error: %v", err)
+	}
+
+	for i := 0; i < admin.MaxFailedLoginAttempts; i++ {
+		err = ar.ChangePassword(adminID, "wrong password", "new password")
+		if !errors.Is(err, db.ErrorInvalidRequest) {
+			t.Fatalf("expected db.ErrorInvalidRequest for a wrong current password, got %v", err)
+		}
+	}
+
+	// Guessing the current password locks the account.
+	err = ar.ChangePassword(adminID, "password", "new password")
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for a locked account, got %v", err)
+	}
+
+	_, err = ar.LoginAccount("admin", "password")
+	if !errors.Is(err, admin.ErrorIncorrectLogin) {
+		t.Fatalf("expected admin.ErrorIncorrectLogin for a locked account, got %v", err)
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	store := New()
+	ar := NewAdminRepository(store)
+
+	adminID, err := ar.CreateAccount(testSchoolID, "admin", "password", admin.RoleAdmin)
+	if err != nil {
+		t.Fatalf("CreateAccount error: %v", err)
+	}
+
+	_, _, err = ar.CreatePasswordReset("other school", adminID)
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for an admin of another school, got %v", err)
+	}
+
+	oldToken, _, err := ar.CreatePasswordReset(testSchoolID, adminID)
+	if err != nil {
+		t.Fatalf("CreatePasswordReset error: %v", err)
+	}
+
+	resetToken, _, err := ar.CreatePasswordReset(testSchoolID, adminID)
+	if err != nil {
+		t.Fatalf("CreatePasswordReset error: %v", err)
+	}
+
+	// Only the latest reset token can be used.
+	_, err = ar.ResetPassword(oldToken, "new password")
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for a replaced token, got %v", err)
+	}
+
+	resetAdminID, err := ar.ResetPassword(resetToken, "new password")
+	if err != nil {
+		t.Fatalf("ResetPassword error: %v", err)
+	}
+
+	if resetAdminID != adminID {
+		t.Fatalf("expected admin %s, got %s", adminID, resetAdminID)
+	}
+
+	// A reset token can only be used once.
+	_, err = ar.ResetPassword(resetToken, "another password")
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for a used token, got %v", err)
+	}
+
+	// Expired tokens are refused.
+	resetToken, _, err = ar.CreatePasswordReset(testSchoolID, adminID)
+	if err != nil {
+		t.Fatalf("CreatePasswordReset error: %v", err)
+	}
+	store.admins[adminID].ResetTokenExpiresAt = 1
+
+	_, err = ar.ResetPassword(resetToken, "another password")
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for an expired token, got %v", err)
+	}
+}
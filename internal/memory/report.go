@@ -0,0 +1,146 @@
+package memory
+
+import (
+	"fmt"
+
+	"github.com/ukane-philemon/scomp/internal/class"
+	"github.com/ukane-philemon/scomp/internal/db"
+	"github.com/ukane-philemon/scomp/internal/history"
+	"github.com/ukane-philemon/scomp/internal/report"
+)
+
+// ReportStore implements report.Store.
+type ReportStore struct {
+	store *Store
+}
+
+// NewReportStore creates a new instance of *ReportStore.
+func NewReportStore(store *Store) report.Store {
+	return &ReportStore{
+		store: store,
+	}
+}
+
+// CreateReports saves classReport and the reports of students as the next
+// version of the reports of classID and as the current reports of the class
+// and its students, and marks the class as reported. Nothing is saved if the
+// class is not computing, its students changed or any of the students does
+// not exist.
+// Implements report.Store.
+func (rs *ReportStore) CreateReports(schoolID, classID, generatedBy string, studentsRevision int, classReport *class.ClassReport, students []*history.StudentReport) (*history.ReportVersion, error) {
+	rs.store.mtx.Lock()
+	defer rs.store.mtx.Unlock()
+
+	var latestVersion int
+	for _, reportVersion := range rs.store.reportVersions {
+		if reportVersion.SchoolID == schoolID && reportVersion.ClassID == classID && reportVersion.Version > latestVersion {
+			latestVersion = reportVersion.Version
+		}
+	}
+
+	reportVersion, err := history.NewReportVersion(schoolID, classID, generatedBy, latestVersion+1, studentsRevision, classReport, students)
+	if err != nil {
+		return nil, err
+	}
+
+	storedReportVersion, err := clone(reportVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	// The current reports are not shared with the stored version.
+	currentReports, err := clone(reportVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	err = rs.saveReports(schoolID, class.StateComputing, currentReports)
+	if err != nil {
+		return nil, err
+	}
+
+	rs.store.reportVersions[reportVersion.ID] = storedReportVersion
+
+	return reportVersion, nil
+}
+
+// RestoreReports saves the reports of reportVersion as the current reports of
+// its class and the students of the class and marks the class as reported.
+// Nothing is saved if the class is not in the fromState state, its students
+// changed or any of the students does not exist.
+// Implements report.Store.
+func (rs *ReportStore) RestoreReports(schoolID, fromState string, reportVersion *history.ReportVersion) error {
+	if schoolID == "" || reportVersion.ClassID == "" {
+		return fmt.Errorf("%w: missing schoolID or classID", db.ErrorInvalidRequest)
+	}
+
+	reportVersionCopy, err := clone(reportVersion)
+	if err != nil {
+		return err
+	}
+
+	rs.store.mtx.Lock()
+	defer rs.store.mtx.Unlock()
+
+	return rs.saveReports(schoolID, fromState, reportVersionCopy)
+}
+
+// saveReports saves the reports of reportVersion as the current reports of its
+// class and the students of the class if the class is in the fromState state
+// and its students did not change since reportVersion was computed. The
+// reports are stored as they are. The store must be locked.
+func (rs *ReportStore) saveReports(schoolID, fromState string, reportVersion *history.ReportVersion) error {
+	classID := reportVersion.ClassID
+	classInfo, found := rs.store.classes[classID]
+	if !found || classInfo.SchoolID != schoolID || classInfo.State != fromState {
+		return fmt.Errorf("%w: report for class with ID %s was not updated, the class is not %s", db.ErrorInvalidRequest, classID, fromState)
+	}
+
+	if classInfo.StudentsRevision != reportVersion.StudentsRevision {
+		return report.ErrorStudentsChanged
+	}
+
+	for _, studentReport := range reportVersion.Students {
+		if studentInfo, found := rs.store.students[studentReport.StudentID]; !found || studentInfo.SchoolID != schoolID || studentInfo.ClassID != classID {
+			return fmt.Errorf("student with ID %s was not updated", studentReport.StudentID)
+		}
+	}
+
+	classInfo.Report = reportVersion.ClassReport
+	classInfo.CurrentReportVersion = reportVersion.Version
+	classInfo.State = class.StateReported
+	classInfo.ReportStale = false
+	for _, studentReport := range reportVersion.Students {
+		rs.store.students[studentReport.StudentID].Report = studentReport.Report
+	}
+
+	return nil
+}
+
+// IncompleteReports returns the reported classes of every school whose current
+// report does not match the reports of all their students.
+// Implements report.Store.
+func (rs *ReportStore) IncompleteReports() ([]*class.Class, error) {
+	rs.store.mtx.RLock()
+	defer rs.store.mtx.RUnlock()
+
+	incomplete := make(map[string]bool)
+	for _, studentInfo := range rs.store.students {
+		classInfo, found := rs.store.classes[studentInfo.ClassID]
+		if found && classInfo.State == class.StateReported && classInfo.Report != nil && !report.Matches(classInfo.Report, studentInfo.Report) {
+			incomplete[classInfo.ID] = true
+		}
+	}
+
+	var classes []*class.Class
+	for classID := range incomplete {
+		classInfo, err := clone(rs.store.classes[classID])
+		if err != nil {
+			return nil, err
+		}
+
+		classes = append(classes, classInfo)
+	}
+
+	return classes, nil
+}
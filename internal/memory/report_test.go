@@ -0,0 +1,209 @@
+package memory
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ukane-philemon/scomp/internal/class"
+	"github.com/ukane-philemon/scomp/internal/db"
+	"github.com/ukane-philemon/scomp/internal/history"
+	"github.com/ukane-philemon/scomp/internal/report"
+	"github.com/ukane-philemon/scomp/internal/student"
+)
+
+// newReportClass creates a computing class with a student for every name in
+// studentNames and returns the ID of the class and the IDs of the students.
+func newReportClass(t *testing.T, store *Store, studentNames ...string) (string, []string) {
+	t.Helper()
+
+	classID := newTestClass(t, NewClassRepository(store), "JSS "+studentNames[0])
+	sr := NewStudentRepository(store)
+
+	var studentIDs []string
+	for _, name := range studentNames {
+		studentIDs = append(studentIDs, newTestStudent(t, sr, classID, name))
+	}
+
+	startComputing(t, store, classID, class.StateOpen)
+	return classID, studentIDs
+}
+
+// startComputing moves classID from the from state to class.StateComputing.
+func startComputing(t *testing.T, store *Store, classID, from string) {
+	t.Helper()
+
+	err := NewClassRepository(store).Transition(testSchoolID, classID, from, class.StateComputing)
+	if err != nil {
+		t.Fatalf("Transition error: %v", err)
+	}
+}
+
+// testStudentReports returns reports generated at generatedAt for studentIDs,
+// ranked in order.
+func testStudentReports(generatedAt int64, studentIDs ...string) []*history.StudentReport {
+	var reports []*history.StudentReport
+	for i, studentID := range studentIDs {
+		reports = append(reports, &history.StudentReport{
+			StudentID: studentID,
+			Report: &student.Report{
+				Class:       &student.StudentClassReport{Position: i + 1},
+				GeneratedAt: generatedAt,
+			},
+		})
+	}
+	return reports
+}
+
+// expectReports fails the test if the current reports of classID and its
+// students were not generated at generatedAt.
+func expectReports(t *testing.T, store *Store, classID string, generatedAt int64) {
+	t.Helper()
+
+	classInfo, err := NewClassRepository(store).Class(testSchoolID, classID)
+	if err != nil {
+		t.Fatalf("Class error: %v", err)
+	}
+
+	if generatedAt == 0 && classInfo.Report != nil || generatedAt != 0 && (classInfo.Report == nil || classInfo.Report.GeneratedAt != generatedAt) {
+		t.Fatalf("expected the class report generated at %d, got %+v", generatedAt, classInfo.Report)
+	}
+
+	students, err := NewStudentRepository(store).Students(testSchoolID, classID)
+	if err != nil {
+		t.Fatalf("Students error: %v", err)
+	}
+
+	for _, studentInfo := range students {
+		if studentInfo.Report.GeneratedAt != generatedAt {
+			t.Fatalf("expected the report of %s generated at %d, got %d", studentInfo.Name, generatedAt, studentInfo.Report.GeneratedAt)
+		}
+	}
+}
+
+func TestCreateReportsSavesEverythingOrNothing(t *testing.T) {
+	store := New()
+	rs, vr := NewReportStore(store), NewReportVersionRepository(store)
+	classID, studentIDs := newReportClass(t, store, "Ada", "Bola")
+
+	_, err := rs.CreateReports(testSchoolID, classID, "teacher", store.classes[classID].StudentsRevision, &class.ClassReport{GeneratedAt: 1}, testStudentReports(1, studentIDs[0], "missing"))
+	if err == nil {
+		t.Fatal("CreateReports saved the reports of a missing student")
+	}
+
+	expectReports(t, store, classID, 0)
+
+	reportVersions, err := vr.ReportVersions(testSchoolID, classID)
+	if err != nil {
+		t.Fatalf("ReportVersions error: %v", err)
+	}
+
+	if len(reportVersions) != 0 {
+		t.Fatalf("failed CreateReports saved %d report versions", len(reportVersions))
+	}
+
+	reportVersion, err := rs.CreateReports(testSchoolID, classID, "teacher", store.classes[classID].StudentsRevision, &class.ClassReport{GeneratedAt: 2}, testStudentReports(2, studentIDs...))
+	if err != nil {
+		t.Fatalf("CreateReports error: %v", err)
+	}
+
+	if reportVersion.Version != 1 {
+		t.Fatalf("expected report version 1, got %d", reportVersion.Version)
+	}
+
+	expectReports(t, store, classID, 2)
+
+	hasReport := true
+	classes, err := NewClassRepository(store).Classes(testSchoolID, &hasReport)
+	if err != nil {
+		t.Fatalf("Classes error: %v", err)
+	}
+
+	if len(classes) != 1 || classes[0].ID != classID || classes[0].CurrentReportVersion != 1 {
+		t.Fatalf("expected version 1 of class %s to be its current report, got %d classes", classID, len(classes))
+	}
+
+	_, err = rs.CreateReports(testSchoolID, "unknown", "teacher", 0, &class.ClassReport{}, testStudentReports(3, studentIDs...))
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for an unknown class, got %v", err)
+	}
+}
+
+func TestRestoreReports(t *testing.T) {
+	store := New()
+	rs := NewReportStore(store)
+	classID, studentIDs := newReportClass(t, store, "Ada", "Bola")
+
+	firstVersion, err := rs.CreateReports(testSchoolID, classID, "teacher", store.classes[classID].StudentsRevision, &class.ClassReport{GeneratedAt: 1}, testStudentReports(1, studentIDs...))
+	if err != nil {
+		t.Fatalf("CreateReports error: %v", err)
+	}
+
+	startComputing(t, store, classID, class.StateReported)
+	_, err = rs.CreateReports(testSchoolID, classID, "teacher", store.classes[classID].StudentsRevision, &class.ClassReport{GeneratedAt: 2}, testStudentReports(2, studentIDs...))
+	if err != nil {
+		t.Fatalf("CreateReports error: %v", err)
+	}
+
+	// A version whose second student no longer exists is not restored.
+	missingStudentVersion := *firstVersion
+	missingStudentVersion.Students = testStudentReports(1, studentIDs[0], "missing")
+	err = rs.RestoreReports(testSchoolID, class.StateReported, &missingStudentVersion)
+	if err == nil {
+		t.Fatal("RestoreReports saved the reports of a missing student")
+	}
+
+	expectReports(t, store, classID, 2)
+
+	// A class that is not in the expected state is not restored.
+	err = rs.RestoreReports(testSchoolID, class.StateOpen, firstVersion)
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for a class that is not open, got %v", err)
+	}
+
+	expectReports(t, store, classID, 2)
+
+	err = rs.RestoreReports(testSchoolID, class.StateReported, firstVersion)
+	if err != nil {
+		t.Fatalf("RestoreReports error: %v", err)
+	}
+
+	expectReports(t, store, classID, 1)
+}
+
+func TestCreateReportsRequiresComputingClass(t *testing.T) {
+	store := New()
+	rs, cr := NewReportStore(store), NewClassRepository(store)
+	classID, studentIDs := newReportClass(t, store, "Ada")
+
+	_, err := rs.CreateReports(testSchoolID, classID, "teacher", store.classes[classID].StudentsRevision, &class.ClassReport{GeneratedAt: 1}, testStudentReports(1, studentIDs...))
+	if err != nil {
+		t.Fatalf("CreateReports error: %v", err)
+	}
+
+	classInfo, err := cr.Class(testSchoolID, classID)
+	if err != nil {
+		t.Fatalf("Class error: %v", err)
+	}
+
+	if classInfo.State != class.StateReported {
+		t.Fatalf("expected a reported class, got %s", classInfo.State)
+	}
+
+	// The class is not computing anymore.
+	_, err = rs.CreateReports(testSchoolID, classID, "teacher", store.classes[classID].StudentsRevision, &class.ClassReport{GeneratedAt: 2}, testStudentReports(2, studentIDs...))
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for a reported class, got %v", err)
+	}
+
+	expectReports(t, store, classID, 1)
+
+	// Reports computed with students that changed since are not saved.
+	otherClassID, otherStudentIDs := newReportClass(t, store, "Bola")
+	revision := store.classes[otherClassID].StudentsRevision
+	_, err = rs.CreateReports(testSchoolID, otherClassID, "teacher", revision-1, &class.ClassReport{GeneratedAt: 1}, testStudentReports(1, otherStudentIDs...))
+	if !errors.Is(err, report.ErrorStudentsChanged) {
+		t.Fatalf("expected report.ErrorStudentsChanged, got %v", err)
+	}
+
+	expectReports(t, store, otherClassID, 0)
+}
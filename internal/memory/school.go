@@ -0,0 +1,54 @@
+package memory
+
+import (
+	"fmt"
+
+	"github.com/ukane-philemon/scomp/internal/db"
+	"github.com/ukane-philemon/scomp/internal/school"
+)
+
+// SchoolRepository implements school.Repository.
+type SchoolRepository struct {
+	store *Store
+}
+
+// NewSchoolRepository creates a new instance of *SchoolRepository.
+func NewSchoolRepository(store *Store) school.Repository {
+	return &SchoolRepository{
+		store: store,
+	}
+}
+
+// Create implements school.Repository.
+func (sr *SchoolRepository) Create(name string) (string, error) {
+	schoolInfo, err := school.NewSchool(name)
+	if err != nil {
+		return "", err
+	}
+
+	sr.store.mtx.Lock()
+	defer sr.store.mtx.Unlock()
+
+	for _, s := range sr.store.schools {
+		if s.Name == name {
+			return "", fmt.Errorf("%w: school name %s already exists", db.ErrorInvalidRequest, name)
+		}
+	}
+
+	sr.store.schools[schoolInfo.ID] = schoolInfo
+
+	return schoolInfo.ID, nil
+}
+
+// School implements school.Repository.
+func (sr *SchoolRepository) School(schoolID string) (*school.School, error) {
+	sr.store.mtx.RLock()
+	defer sr.store.mtx.RUnlock()
+
+	schoolInfo, found := sr.store.schools[schoolID]
+	if !found {
+		return nil, fmt.Errorf("%w: no record found for school with ID %s", db.ErrorInvalidRequest, schoolID)
+	}
+
+	return clone(schoolInfo)
+}
@@ -0,0 +1,115 @@
+package memory
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ukane-philemon/scomp/internal/admin"
+	"github.com/ukane-philemon/scomp/internal/class"
+	"github.com/ukane-philemon/scomp/internal/db"
+	"github.com/ukane-philemon/scomp/internal/history"
+	"github.com/ukane-philemon/scomp/internal/student"
+)
+
+func TestCreateSchool(t *testing.T) {
+	sr := NewSchoolRepository(New())
+
+	schoolID, err := sr.Create("Scomp High")
+	if err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+
+	schoolInfo, err := sr.School(schoolID)
+	if err != nil {
+		t.Fatalf("School error: %v", err)
+	}
+
+	if schoolInfo.Name != "Scomp High" {
+		t.Fatalf("expected school Scomp High, got %s", schoolInfo.Name)
+	}
+
+	for _, name := range []string{"Scomp High", ""} {
+		_, err = sr.Create(name)
+		if !errors.Is(err, db.ErrorInvalidRequest) {
+			t.Errorf("expected db.ErrorInvalidRequest for school name %q, got %v", name, err)
+		}
+	}
+
+	_, err = sr.School("unknown")
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for an unknown school, got %v", err)
+	}
+}
+
+func TestSchoolIsolation(t *testing.T) {
+	const otherSchoolID = "other school"
+
+	store := New()
+	ar, ir := NewAdminRepository(store), NewInvitationRepository(store)
+	cr, sr := NewClassRepository(store), NewStudentRepository(store)
+
+	classID := newTestClass(t, cr, "JSS 1")
+	studentID := newTestStudent(t, sr, classID, "Ada")
+
+	// Class names are unique per school.
+	_, err := cr.Create(otherSchoolID, "JSS 1", "teacher", class.ReportSettings{}, testSubjects())
+	if err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+
+	_, err = cr.Class(otherSchoolID, classID)
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for a class of another school, got %v", err)
+	}
+
+	if exists, _ := cr.Exists(otherSchoolID, classID); exists {
+		t.Fatal("class exists in another school")
+	}
+
+	classes, err := cr.Classes(otherSchoolID, nil)
+	if err != nil {
+		t.Fatalf("Classes error: %v", err)
+	}
+
+	for _, classInfo := range classes {
+		if classInfo.ID == classID {
+			t.Fatal("Classes returned a class of another school")
+		}
+	}
+
+	_, err = sr.Student(otherSchoolID, classID, studentID)
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for a student of another school, got %v", err)
+	}
+
+	students, err := sr.Students(otherSchoolID, classID)
+	if err != nil || len(students) != 0 {
+		t.Fatalf("expected no students of another school, got %d, %v", len(students), err)
+	}
+
+	_, err = NewReportStore(store).CreateReports(otherSchoolID, classID, "teacher", 0, &class.ClassReport{}, []*history.StudentReport{{StudentID: studentID, Report: new(student.Report)}})
+	if err == nil {
+		t.Fatal("CreateReports saved the reports of a class of another school")
+	}
+
+	adminID, err := ar.CreateAccount(testSchoolID, "admin", "password", admin.RoleViewer)
+	if err != nil {
+		t.Fatalf("CreateAccount error: %v", err)
+	}
+
+	err = ar.SetRole(otherSchoolID, adminID, admin.RoleOwner)
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest changing the role of an admin of another school, got %v", err)
+	}
+
+	invitation, _, err := ir.Create(testSchoolID, adminID, admin.RoleTeacher, time.Hour)
+	if err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+
+	err = ir.Revoke(otherSchoolID, invitation.ID)
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest revoking an invitation of another school, got %v", err)
+	}
+}
@@ -0,0 +1,155 @@
+package memory
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ukane-philemon/scomp/internal/db"
+	"github.com/ukane-philemon/scomp/internal/session"
+)
+
+// SessionRepository implements session.Repository.
+type SessionRepository struct {
+	store *Store
+}
+
+// NewSessionRepository creates a new instance of *SessionRepository.
+func NewSessionRepository(store *Store) session.Repository {
+	return &SessionRepository{
+		store: store,
+	}
+}
+
+// Create implements session.Repository.
+func (sr *SessionRepository) Create(adminID string, client *session.ClientInfo) (*session.Session, string, error) {
+	sessionInfo, refreshToken, err := session.NewSession(adminID, client)
+	if err != nil {
+		return nil, "", err
+	}
+
+	storedSession, err := clone(sessionInfo)
+	if err != nil {
+		return nil, "", err
+	}
+
+	sr.store.mtx.Lock()
+	sr.store.sessions[sessionInfo.ID] = storedSession
+	sr.store.mtx.Unlock()
+
+	return sessionInfo, refreshToken, nil
+}
+
+// Refresh implements session.Repository.
+func (sr *SessionRepository) Refresh(refreshToken string) (*session.Session, string, error) {
+	sessionID, hashedRefreshToken, err := session.ParseRefreshToken(refreshToken)
+	if err != nil {
+		return nil, "", err
+	}
+
+	newRefreshToken, newHashedRefreshToken, err := session.NewRefreshToken(sessionID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	sr.store.mtx.Lock()
+	defer sr.store.mtx.Unlock()
+
+	now := time.Now()
+	sessionInfo, found := sr.store.sessions[sessionID]
+	if !found || !isActive(sessionInfo, now) {
+		return nil, "", fmt.Errorf("%w: refresh token is invalid or has expired, please login again", db.ErrorInvalidRequest)
+	}
+
+	if sessionInfo.HashedRefreshToken != hashedRefreshToken {
+		// Revoke the session if a rotated refresh token was reused, it may
+		// have been stolen.
+		if sessionInfo.PreviousHashedRefreshToken == hashedRefreshToken {
+			sessionInfo.RevokedAt = now.Unix()
+		}
+		return nil, "", fmt.Errorf("%w: refresh token is invalid or has expired, please login again", db.ErrorInvalidRequest)
+	}
+
+	sessionInfo.PreviousHashedRefreshToken = hashedRefreshToken
+	sessionInfo.HashedRefreshToken = newHashedRefreshToken
+	sessionInfo.LastUsedAt = now.Unix()
+	sessionInfo.ExpiresAt = now.Add(session.RefreshTokenExpiry).Unix()
+
+	sessionCopy, err := clone(sessionInfo)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return sessionCopy, newRefreshToken, nil
+}
+
+// IsActive implements session.Repository.
+func (sr *SessionRepository) IsActive(sessionID string) (bool, error) {
+	sr.store.mtx.RLock()
+	defer sr.store.mtx.RUnlock()
+
+	sessionInfo, found := sr.store.sessions[sessionID]
+	return found && isActive(sessionInfo, time.Now()), nil
+}
+
+// Revoke implements session.Repository.
+func (sr *SessionRepository) Revoke(adminID, sessionID string) error {
+	sr.store.mtx.Lock()
+	defer sr.store.mtx.Unlock()
+
+	now := time.Now()
+	sessionInfo, found := sr.store.sessions[sessionID]
+	if !found || sessionInfo.AdminID != adminID || !isActive(sessionInfo, now) {
+		return fmt.Errorf("%w: no active session found with ID %s", db.ErrorInvalidRequest, sessionID)
+	}
+
+	sessionInfo.RevokedAt = now.Unix()
+	return nil
+}
+
+// RevokeAll implements session.Repository.
+func (sr *SessionRepository) RevokeAll(adminID string) (int, error) {
+	sr.store.mtx.Lock()
+	defer sr.store.mtx.Unlock()
+
+	now := time.Now()
+	var nRevoked int
+	for _, sessionInfo := range sr.store.sessions {
+		if sessionInfo.AdminID == adminID && isActive(sessionInfo, now) {
+			sessionInfo.RevokedAt = now.Unix()
+			nRevoked++
+		}
+	}
+
+	return nRevoked, nil
+}
+
+// Sessions implements session.Repository.
+func (sr *SessionRepository) Sessions(adminID string) ([]*session.Session, error) {
+	sr.store.mtx.RLock()
+	defer sr.store.mtx.RUnlock()
+
+	now := time.Now()
+	var sessions []*session.Session
+	for _, sessionInfo := range sr.store.sessions {
+		if sessionInfo.AdminID != adminID || !isActive(sessionInfo, now) {
+			continue
+		}
+
+		sessionCopy, err := clone(sessionInfo)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, sessionCopy)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastUsedAt > sessions[j].LastUsedAt
+	})
+
+	return sessions, nil
+}
+
+func isActive(sessionInfo *session.Session, now time.Time) bool {
+	return sessionInfo.RevokedAt == 0 && sessionInfo.ExpiresAt > now.Unix()
+}
@@ -0,0 +1,131 @@
+package memory
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ukane-philemon/scomp/internal/db"
+	"github.com/ukane-philemon/scomp/internal/session"
+)
+
+// newTestSession creates a session for adminID and returns the session and its
+// refresh token.
+func newTestSession(t *testing.T, sr session.Repository, adminID string) (*session.Session, string) {
+	t.Helper()
+
+	sessionInfo, refreshToken, err := sr.Create(adminID, &session.ClientInfo{UserAgent: "test", IPAddress: "127.0.0.1"})
+	if err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+	return sessionInfo, refreshToken
+}
+
+// expectActive fails t if the activity of sessionID is not active.
+func expectActive(t *testing.T, sr session.Repository, sessionID string, active bool) {
+	t.Helper()
+
+	isActive, err := sr.IsActive(sessionID)
+	if err != nil {
+		t.Fatalf("IsActive error: %v", err)
+	}
+
+	if isActive != active {
+		t.Fatalf("expected session %s to be active: %v", sessionID, active)
+	}
+}
+
+func TestRefreshRotatesToken(t *testing.T) {
+	sr := NewSessionRepository(New())
+	sessionInfo, refreshToken := newTestSession(t, sr, "admin")
+	expectActive(t, sr, sessionInfo.ID, true)
+
+	refreshed, newRefreshToken, err := sr.Refresh(refreshToken)
+	if err != nil {
+		t.Fatalf("Refresh error: %v", err)
+	}
+
+	if refreshed.ID != sessionInfo.ID || newRefreshToken == refreshToken {
+		t.Fatalf("expected a new refresh token for session %s", sessionInfo.ID)
+	}
+
+	// Reusing a rotated refresh token revokes the session.
+	_, _, err = sr.Refresh(refreshToken)
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for a rotated refresh token, got %v", err)
+	}
+
+	expectActive(t, sr, sessionInfo.ID, false)
+
+	_, _, err = sr.Refresh(newRefreshToken)
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for the refresh token of a revoked session, got %v", err)
+	}
+}
+
+func TestRefreshInvalidToken(t *testing.T) {
+	sr := NewSessionRepository(New())
+	sessionInfo, _ := newTestSession(t, sr, "admin")
+
+	for _, refreshToken := range []string{"", "token", sessionInfo.ID + ".secret", "unknown.secret"} {
+		_, _, err := sr.Refresh(refreshToken)
+		if !errors.Is(err, db.ErrorInvalidRequest) {
+			t.Errorf("expected db.ErrorInvalidRequest for refresh token %q, got %v", refreshToken, err)
+		}
+	}
+
+	// A wrong secret is not a reuse of a rotated refresh token.
+	expectActive(t, sr, sessionInfo.ID, true)
+}
+
+func TestRevokeSession(t *testing.T) {
+	sr := NewSessionRepository(New())
+	sessionInfo, refreshToken := newTestSession(t, sr, "admin")
+	otherSession, _ := newTestSession(t, sr, "admin")
+
+	err := sr.Revoke("another admin", sessionInfo.ID)
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for the session of another admin, got %v", err)
+	}
+
+	err = sr.Revoke("admin", sessionInfo.ID)
+	if err != nil {
+		t.Fatalf("Revoke error: %v", err)
+	}
+
+	expectActive(t, sr, sessionInfo.ID, false)
+	expectActive(t, sr, otherSession.ID, true)
+
+	_, _, err = sr.Refresh(refreshToken)
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for the refresh token of a revoked session, got %v", err)
+	}
+
+	err = sr.Revoke("admin", sessionInfo.ID)
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for a revoked session, got %v", err)
+	}
+}
+
+func TestRevokeAllSessions(t *testing.T) {
+	sr := NewSessionRepository(New())
+	newTestSession(t, sr, "admin")
+	newTestSession(t, sr, "admin")
+	otherSession, _ := newTestSession(t, sr, "another admin")
+
+	sessions, err := sr.Sessions("admin")
+	if err != nil || len(sessions) != 2 {
+		t.Fatalf("expected 2 sessions, got %d, %v", len(sessions), err)
+	}
+
+	nRevoked, err := sr.RevokeAll("admin")
+	if err != nil || nRevoked != 2 {
+		t.Fatalf("expected 2 revoked sessions, got %d, %v", nRevoked, err)
+	}
+
+	sessions, err = sr.Sessions("admin")
+	if err != nil || len(sessions) != 0 {
+		t.Fatalf("expected no active sessions, got %d, %v", len(sessions), err)
+	}
+
+	expectActive(t, sr, otherSession.ID, true)
+}
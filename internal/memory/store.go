@@ -0,0 +1,65 @@
+package memory
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/ukane-philemon/scomp/internal/admin"
+	"github.com/ukane-philemon/scomp/internal/class"
+	"github.com/ukane-philemon/scomp/internal/grading"
+	"github.com/ukane-philemon/scomp/internal/history"
+	"github.com/ukane-philemon/scomp/internal/job"
+	"github.com/ukane-philemon/scomp/internal/school"
+	"github.com/ukane-philemon/scomp/internal/session"
+	"github.com/ukane-philemon/scomp/internal/student"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Store is an in-memory database shared by the in-memory repositories. Data
+// held by a Store is lost when the process exits.
+type Store struct {
+	mtx            sync.RWMutex
+	schools        map[string]*school.School
+	admins         map[string]*admin.Admin
+	invitations    map[string]*admin.Invitation
+	apiKeys        map[string]*admin.APIKey
+	classes        map[string]*class.Class
+	scales         map[string]*grading.GradingScale
+	students       map[string]*student.Student
+	sessions       map[string]*session.Session
+	reportVersions map[string]*history.ReportVersion
+	jobs           map[string]*job.Job
+}
+
+// New creates a new instance of *Store.
+func New() *Store {
+	return &Store{
+		schools:        make(map[string]*school.School),
+		admins:         make(map[string]*admin.Admin),
+		invitations:    make(map[string]*admin.Invitation),
+		apiKeys:        make(map[string]*admin.APIKey),
+		classes:        make(map[string]*class.Class),
+		scales:         make(map[string]*grading.GradingScale),
+		students:       make(map[string]*student.Student),
+		sessions:       make(map[string]*session.Session),
+		reportVersions: make(map[string]*history.ReportVersion),
+		jobs:           make(map[string]*job.Job),
+	}
+}
+
+// clone returns a deep copy of v. Records are copied in and out of the store
+// so that callers cannot mutate stored data, mirroring a real database.
+func clone[T any](v *T) (*T, error) {
+	b, err := bson.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("bson.Marshal error: %w", err)
+	}
+
+	c := new(T)
+	err = bson.Unmarshal(b, c)
+	if err != nil {
+		return nil, fmt.Errorf("bson.Unmarshal error: %w", err)
+	}
+
+	return c, nil
+}
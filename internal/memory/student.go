@@ -0,0 +1,271 @@
+package memory
+
+import (
+	"fmt"
+
+	"github.com/ukane-philemon/scomp/internal/class"
+	"github.com/ukane-philemon/scomp/internal/db"
+	"github.com/ukane-philemon/scomp/internal/student"
+)
+
+// StudentRepository implements student.Repository.
+type StudentRepository struct {
+	store *Store
+}
+
+// NewStudentRepository creates a new instance of *StudentRepository.
+func NewStudentRepository(store *Store) student.Repository {
+	return &StudentRepository{
+		store: store,
+	}
+}
+
+// Create adds a students record. Returns db.ErrorInvalidRequest if the class
+// is not open or studentName already exists for classID.
+// Implements student.Repository.
+func (sr *StudentRepository) Create(schoolID, classID, studentName string, classSubjects []*class.Subject, subjectScores []*student.SubjectScore) (string, error) {
+	newStudent, err := student.NewStudent(schoolID, classID, studentName, classSubjects, subjectScores)
+	if err != nil {
+		return "", err
+	}
+
+	studentInfo, err := clone(newStudent)
+	if err != nil {
+		return "", err
+	}
+
+	sr.store.mtx.Lock()
+	defer sr.store.mtx.Unlock()
+
+	classInfo, err := sr.studentsClass(schoolID, classID, class.CheckCanChangeStudents)
+	if err != nil {
+		return "", err
+	}
+
+	if sr.nameExists(classID, studentName) {
+		return "", fmt.Errorf("%w: student %s already exists in this class", db.ErrorInvalidRequest, studentName)
+	}
+
+	sr.store.students[studentInfo.ID] = studentInfo
+	studentsChanged(classInfo)
+
+	return studentInfo.ID, nil
+}
+
+// Student returns the students that match provided arguments.
+// Implements student.Repository.
+func (sr *StudentRepository) Student(schoolID, classID, studentID string) (*student.Student, error) {
+	if schoolID == "" || classID == "" || studentID == "" {
+		return nil, fmt.Errorf("%w: missing required argument(s)", db.ErrorInvalidRequest)
+	}
+
+	sr.store.mtx.RLock()
+	defer sr.store.mtx.RUnlock()
+
+	studentInfo, found := sr.store.students[studentID]
+	if !found || studentInfo.SchoolID != schoolID || studentInfo.ClassID != classID {
+		return nil, fmt.Errorf("%w: no record found for student with ID %s", db.ErrorInvalidRequest, studentID)
+	}
+
+	return clone(studentInfo)
+}
+
+// Students returns all the students that match the provided classID.
+// Implements student.Repository.
+func (sr *StudentRepository) Students(schoolID, classID string) ([]*student.Student, error) {
+	if schoolID == "" || classID == "" {
+		return nil, fmt.Errorf("%w: missing schoolID or classID", db.ErrorInvalidRequest)
+	}
+
+	sr.store.mtx.RLock()
+	defer sr.store.mtx.RUnlock()
+
+	var students []*student.Student
+	for _, studentInfo := range sr.store.students {
+		if studentInfo.SchoolID != schoolID || studentInfo.ClassID != classID {
+			continue
+		}
+
+		s, err := clone(studentInfo)
+		if err != nil {
+			return nil, err
+		}
+
+		students = append(students, s)
+	}
+
+	return students, nil
+}
+
+// StudentScores returns a map of student ID to their subject scores.
+// Implements student.Repository.
+func (sr *StudentRepository) StudentScores(schoolID, classID string) (map[string][]*student.SubjectScore, error) {
+	students, err := sr.Students(schoolID, classID)
+	if err != nil {
+		return nil, err
+	}
+
+	studentsMap := make(map[string][]*student.SubjectScore, len(students))
+	for _, studentInfo := range students {
+		var scores []*student.SubjectScore
+		for _, r := range studentInfo.Report.Subjects {
+			scores = append(scores, r.SubjectScore)
+		}
+		studentsMap[studentInfo.ID] = scores
+	}
+
+	return studentsMap, nil
+}
+
+// UpdateScores replaces the subject scores of studentID with subjectScores and
+// clears the class report of the student.
+// Implements student.Repository.
+func (sr *StudentRepository) UpdateScores(schoolID, classID, studentID string, classSubjects []*class.Subject, subjectScores []*student.SubjectScore) error {
+	report, err := student.NewReport(classSubjects, subjectScores)
+	if err != nil {
+		return err
+	}
+
+	reportCopy, err := clone(report)
+	if err != nil {
+		return err
+	}
+
+	return sr.updateStudent(schoolID, classID, studentID, func(studentInfo *student.Student) error {
+		studentInfo.Report = reportCopy
+		return nil
+	})
+}
+
+// Rename changes the name of studentID to studentName. Returns
+// db.ErrorInvalidRequest if studentName already exists for classID.
+// Implements student.Repository.
+func (sr *StudentRepository) Rename(schoolID, classID, studentID, studentName string) error {
+	if studentName == "" {
+		return fmt.Errorf("%w: missing student name", db.ErrorInvalidRequest)
+	}
+
+	return sr.updateStudent(schoolID, classID, studentID, func(studentInfo *student.Student) error {
+		if sr.nameExists(classID, studentName) {
+			return fmt.Errorf("%w: student %s already exists in this class", db.ErrorInvalidRequest, studentName)
+		}
+
+		studentInfo.Name = studentName
+		return nil
+	})
+}
+
+// Remove deletes the record of studentID.
+// Implements student.Repository.
+func (sr *StudentRepository) Remove(schoolID, classID, studentID string) error {
+	return sr.updateStudent(schoolID, classID, studentID, func(studentInfo *student.Student) error {
+		delete(sr.store.students, studentID)
+		return nil
+	})
+}
+
+// Move moves studentID from classID to toClassID with subjectScores and
+// clears the class report of the student. Returns db.ErrorInvalidRequest if
+// toClassID is not open or the name of the student already exists for
+// toClassID.
+// Implements student.Repository.
+func (sr *StudentRepository) Move(schoolID, classID, studentID, toClassID string, toClassSubjects []*class.Subject, subjectScores []*student.SubjectScore) error {
+	if toClassID == "" {
+		return fmt.Errorf("%w: missing toClassID", db.ErrorInvalidRequest)
+	}
+
+	report, err := student.NewReport(toClassSubjects, subjectScores)
+	if err != nil {
+		return err
+	}
+
+	reportCopy, err := clone(report)
+	if err != nil {
+		return err
+	}
+
+	return sr.updateStudent(schoolID, classID, studentID, func(studentInfo *student.Student) error {
+		toClassInfo, err := sr.studentsClass(schoolID, toClassID, class.CheckCanChangeStudents)
+		if err != nil {
+			return err
+		}
+
+		if sr.nameExists(toClassID, studentInfo.Name) {
+			return fmt.Errorf("%w: a student with the same name already exists in class %s", db.ErrorInvalidRequest, toClassID)
+		}
+
+		studentInfo.ClassID = toClassID
+		studentInfo.Report = reportCopy
+		studentsChanged(toClassInfo)
+		return nil
+	})
+}
+
+// updateStudent calls update with the stored student that match studentID and
+// records the change of the students of the class if update succeeds. update
+// is called with the store locked.
+func (sr *StudentRepository) updateStudent(schoolID, classID, studentID string, update func(studentInfo *student.Student) error) error {
+	if schoolID == "" || classID == "" || studentID == "" {
+		return fmt.Errorf("%w: missing required argument(s)", db.ErrorInvalidRequest)
+	}
+
+	sr.store.mtx.Lock()
+	defer sr.store.mtx.Unlock()
+
+	classInfo, err := sr.studentsClass(schoolID, classID, class.CheckCanChangeStudents)
+	if err != nil {
+		return err
+	}
+
+	studentInfo, found := sr.store.students[studentID]
+	if !found || studentInfo.SchoolID != schoolID || studentInfo.ClassID != classID {
+		return fmt.Errorf("%w: no record found for student with ID %s", db.ErrorInvalidRequest, studentID)
+	}
+
+	err = update(studentInfo)
+	if err != nil {
+		return err
+	}
+
+	studentsChanged(classInfo)
+	return nil
+}
+
+// nameExists checks if a student of classID is named studentName. The store
+// must be locked.
+func (sr *StudentRepository) nameExists(classID, studentName string) bool {
+	for _, s := range sr.store.students {
+		if s.ClassID == classID && s.Name == studentName {
+			return true
+		}
+	}
+	return false
+}
+
+// studentsClass returns the stored class that match classID. Returns the
+// error of checkState if the students of the class cannot change in its
+// state. The store must be locked.
+func (sr *StudentRepository) studentsClass(schoolID, classID string, checkState func(state string) error) (*class.Class, error) {
+	classInfo, found := sr.store.classes[classID]
+	if !found || classInfo.SchoolID != schoolID {
+		return nil, fmt.Errorf("%w: no record found for class with ID %s", db.ErrorInvalidRequest, classID)
+	}
+
+	err := checkState(classInfo.State)
+	if err != nil {
+		return nil, err
+	}
+
+	return classInfo, nil
+}
+
+// studentsChanged records a change of the students of classInfo. The students
+// revision of the class is incremented and a reported class is open again
+// with a stale report. The store must be locked.
+func studentsChanged(classInfo *class.Class) {
+	classInfo.StudentsRevision++
+	if classInfo.State == class.StateReported {
+		classInfo.State = class.StateOpen
+		classInfo.ReportStale = true
+	}
+}
@@ -0,0 +1,274 @@
+package memory
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ukane-philemon/scomp/internal/class"
+	"github.com/ukane-philemon/scomp/internal/db"
+	"github.com/ukane-philemon/scomp/internal/student"
+)
+
+// testScores returns a score for every subject of testSubjects.
+func testScores(score int) []*student.SubjectScore {
+	subjects := testSubjects()
+	scores := make([]*student.SubjectScore, len(subjects))
+	for i, subject := range subjects {
+		scores[i] = &student.SubjectScore{Name: subject.Name, Score: score}
+	}
+	return scores
+}
+
+// newTestStudent adds a student named studentName to classID and returns their
+// ID.
+func newTestStudent(t *testing.T, sr student.Repository, classID, studentName string) string {
+	t.Helper()
+
+	studentID, err := sr.Create(testSchoolID, classID, studentName, testSubjects(), testScores(50))
+	if err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+	return studentID
+}
+
+func TestCreateStudent(t *testing.T) {
+	store := New()
+	cr, sr := NewClassRepository(store), NewStudentRepository(store)
+	classID := newTestClass(t, cr, "JSS 1")
+	otherClassID := newTestClass(t, cr, "JSS 2")
+
+	studentID := newTestStudent(t, sr, classID, "Ada")
+
+	// Student names are unique per class.
+	newTestStudent(t, sr, otherClassID, "Ada")
+	_, err := sr.Create(testSchoolID, classID, "Ada", testSubjects(), testScores(60))
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for a duplicate name, got %v", err)
+	}
+
+	_, err = sr.Create(testSchoolID, classID, "Bola", testSubjects(), testScores(-1))
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for a negative score, got %v", err)
+	}
+
+	studentScores, err := sr.StudentScores(testSchoolID, classID)
+	if err != nil {
+		t.Fatalf("StudentScores error: %v", err)
+	}
+
+	if len(studentScores) != 1 || len(studentScores[studentID]) != len(testSubjects()) {
+		t.Fatalf("expected the scores of student %s, got %v", studentID, studentScores)
+	}
+
+	_, err = sr.Student(testSchoolID, otherClassID, studentID)
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for a student of another class, got %v", err)
+	}
+}
+
+func TestCreateStudentRequiresClassNotComputing(t *testing.T) {
+	store := New()
+	cr, sr := NewClassRepository(store), NewStudentRepository(store)
+	classID := newTestClass(t, cr, "JSS 1")
+
+	_, err := sr.Create(testSchoolID, "unknown", "Ada", testSubjects(), testScores(60))
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for an unknown class, got %v", err)
+	}
+
+	_, err = sr.Create("other school", classID, "Ada", testSubjects(), testScores(60))
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for a class of another school, got %v", err)
+	}
+
+	store.classes[classID].State = class.StateComputing
+	_, err = sr.Create(testSchoolID, classID, "Ada", testSubjects(), testScores(60))
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for a computing class, got %v", err)
+	}
+
+	students, err := sr.Students(testSchoolID, classID)
+	if err != nil {
+		t.Fatalf("Students error: %v", err)
+	}
+
+	if len(students) != 0 {
+		t.Fatalf("expected no student in a computing class, got %d", len(students))
+	}
+}
+
+func TestStudentIsCopied(t *testing.T) {
+	store := New()
+	sr := NewStudentRepository(store)
+	classID := newTestClass(t, NewClassRepository(store), "JSS 1")
+
+	scores := testScores(50)
+	studentID, err := sr.Create(testSchoolID, classID, "Ada", testSubjects(), scores)
+	if err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+
+	// Changing the arguments or the returned students must not change the
+	// stored student.
+	scores[0].Score = 100
+	studentInfo, err := sr.Student(testSchoolID, classID, studentID)
+	if err != nil {
+		t.Fatalf("Student error: %v", err)
+	}
+
+	if studentInfo.Report.Subjects[0].Score != 50 {
+		t.Fatalf("stored student changed with the arguments of Create")
+	}
+
+	studentInfo.Report.Subjects[0].Score = 100
+	students, err := sr.Students(testSchoolID, classID)
+	if err != nil {
+		t.Fatalf("Students error: %v", err)
+	}
+
+	if len(students) != 1 || students[0].Report.Subjects[0].Score != 50 {
+		t.Fatalf("stored student changed with the returned student")
+	}
+}
+
+func TestRenameStudent(t *testing.T) {
+	store := New()
+	cr, sr := NewClassRepository(store), NewStudentRepository(store)
+	classID := newTestClass(t, cr, "JSS 1")
+	studentID := newTestStudent(t, sr, classID, "Ada")
+	newTestStudent(t, sr, classID, "Bola")
+
+	err := sr.Rename(testSchoolID, classID, studentID, "Bola")
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for a duplicate name, got %v", err)
+	}
+
+	err = sr.Rename(testSchoolID, classID, studentID, "Chidi")
+	if err != nil {
+		t.Fatalf("Rename error: %v", err)
+	}
+
+	studentInfo, err := sr.Student(testSchoolID, classID, studentID)
+	if err != nil {
+		t.Fatalf("Student error: %v", err)
+	}
+
+	if studentInfo.Name != "Chidi" {
+		t.Fatalf("expected the student to be renamed Chidi, got %s", studentInfo.Name)
+	}
+}
+
+func TestMoveStudent(t *testing.T) {
+	store := New()
+	cr, sr := NewClassRepository(store), NewStudentRepository(store)
+	classID := newTestClass(t, cr, "JSS 1")
+	toClassID := newTestClass(t, cr, "JSS 2")
+	studentID := newTestStudent(t, sr, classID, "Ada")
+	newTestStudent(t, sr, toClassID, "Bola")
+
+	// Bola is already in the other class.
+	err := sr.Rename(testSchoolID, classID, studentID, "Bola")
+	if err != nil {
+		t.Fatalf("Rename error: %v", err)
+	}
+
+	err = sr.Move(testSchoolID, classID, studentID, toClassID, testSubjects(), testScores(70))
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for a duplicate name, got %v", err)
+	}
+
+	err = sr.Rename(testSchoolID, classID, studentID, "Ada")
+	if err != nil {
+		t.Fatalf("Rename error: %v", err)
+	}
+
+	store.classes[toClassID].State = class.StateComputing
+	err = sr.Move(testSchoolID, classID, studentID, toClassID, testSubjects(), testScores(70))
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for a computing class, got %v", err)
+	}
+
+	store.classes[toClassID].State = class.StateOpen
+	fromRevision, toRevision := store.classes[classID].StudentsRevision, store.classes[toClassID].StudentsRevision
+	err = sr.Move(testSchoolID, classID, studentID, toClassID, testSubjects(), testScores(70))
+	if err != nil {
+		t.Fatalf("Move error: %v", err)
+	}
+
+	if _, err = sr.Student(testSchoolID, classID, studentID); !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected the student to leave class %s, got %v", classID, err)
+	}
+
+	studentScores, err := sr.StudentScores(testSchoolID, toClassID)
+	if err != nil {
+		t.Fatalf("StudentScores error: %v", err)
+	}
+
+	if scores := studentScores[studentID]; len(scores) == 0 || scores[0].Score != 70 {
+		t.Fatalf("expected the student to join class %s with the new scores, got %v", toClassID, scores)
+	}
+
+	if store.classes[classID].StudentsRevision != fromRevision+1 || store.classes[toClassID].StudentsRevision != toRevision+1 {
+		t.Fatal("expected the students of both classes to change")
+	}
+}
+
+func TestStudentChangesMakeReportStale(t *testing.T) {
+	store := New()
+	cr, sr := NewClassRepository(store), NewStudentRepository(store)
+	classID := newTestClass(t, cr, "JSS 1")
+	otherClassID := newTestClass(t, cr, "JSS 2")
+	adaID := newTestStudent(t, sr, classID, "Ada")
+	bolaID := newTestStudent(t, sr, classID, "Bola")
+	dayoID := newTestStudent(t, sr, otherClassID, "Dayo")
+
+	tests := []struct {
+		name   string
+		change func() error
+	}{
+		{
+			name:   "update scores",
+			change: func() error { return sr.UpdateScores(testSchoolID, classID, adaID, testSubjects(), testScores(80)) },
+		},
+		{
+			name:   "rename",
+			change: func() error { return sr.Rename(testSchoolID, classID, adaID, "Ada Obi") },
+		},
+		{
+			name:   "remove",
+			change: func() error { return sr.Remove(testSchoolID, classID, bolaID) },
+		},
+		{
+			name: "add",
+			change: func() error {
+				_, err := sr.Create(testSchoolID, classID, "Chidi", testSubjects(), testScores(70))
+				return err
+			},
+		},
+		{
+			name: "move in",
+			change: func() error {
+				return sr.Move(testSchoolID, otherClassID, dayoID, classID, testSubjects(), testScores(70))
+			},
+		},
+	}
+
+	for _, test := range tests {
+		classInfo := store.classes[classID]
+		classInfo.State, classInfo.ReportStale = class.StateComputing, false
+		if err := test.change(); !errors.Is(err, db.ErrorInvalidRequest) {
+			t.Fatalf("%s: expected db.ErrorInvalidRequest for a computing class, got %v", test.name, err)
+		}
+
+		classInfo.State = class.StateReported
+		revision := classInfo.StudentsRevision
+		if err := test.change(); err != nil {
+			t.Fatalf("%s: error: %v", test.name, err)
+		}
+
+		if classInfo.State != class.StateOpen || !classInfo.ReportStale || classInfo.StudentsRevision != revision+1 {
+			t.Fatalf("%s: expected an open class with a stale report and a new students revision, got %s, stale %v, revision %d",
+				test.name, classInfo.State, classInfo.ReportStale, classInfo.StudentsRevision)
+		}
+	}
+}
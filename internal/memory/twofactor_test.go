@@ -0,0 +1,196 @@
+package memory
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ukane-philemon/scomp/internal/admin"
+	"github.com/ukane-philemon/scomp/internal/db"
+	"github.com/ukane-philemon/scomp/internal/totp"
+)
+
+// totpCode returns the TOTP code of secret for the period offset periods from
+// now.
+func totpCode(t *testing.T, secret string, offset int64) string {
+	t.Helper()
+
+	code, err := totp.Code(secret, totp.Counter(time.Now())+offset)
+	if err != nil {
+		t.Fatalf("totp.Code error: %v", err)
+	}
+	return code
+}
+
+// newTwoFactorAdmin creates an admin with two-factor authentication enabled
+// by a code of the previous period and returns their ID, TOTP secret and
+// recovery codes.
+func newTwoFactorAdmin(t *testing.T, ar admin.Repository) (string, string, []string) {
+	t.Helper()
+
+	adminID, err := ar.CreateAccount(testSchoolID, "admin", "password", admin.RoleAdmin)
+	if err != nil {
+		t.Fatalf("CreateAccount error: %v", err)
+	}
+
+	secret, err := ar.EnrollTOTP(adminID)
+	if err != nil {
+		t.Fatalf("EnrollTOTP error: %v", err)
+	}
+
+	recoveryCodes, err := ar.ConfirmTOTP(adminID, totpCode(t, secret, -1))
+	if err != nil {
+		t.Fatalf("ConfirmTOTP error: %v", err)
+	}
+
+	return adminID, secret, recoveryCodes
+}
+
+func TestConfirmTOTP(t *testing.T) {
+	ar := NewAdminRepository(New())
+
+	adminID, err := ar.CreateAccount(testSchoolID, "admin", "password", admin.RoleAdmin)
+	if err != nil {
+		t.Fatalf("CreateAccount error: %v", err)
+	}
+
+	_, err = ar.ConfirmTOTP(adminID, "123456")
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest confirming before enrolling, got %v", err)
+	}
+
+	secret, err := ar.EnrollTOTP(adminID)
+	if err != nil {
+		t.Fatalf("EnrollTOTP error: %v", err)
+	}
+
+	// A code of another secret is refused.
+	otherSecret, err := totp.NewSecret()
+	if err != nil {
+		t.Fatalf("totp.NewSecret error: %v", err)
+	}
+
+	_, err = ar.ConfirmTOTP(adminID, totpCode(t, otherSecret, 0))
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for a wrong code, got %v", err)
+	}
+
+	recoveryCodes, err := ar.ConfirmTOTP(adminID, totpCode(t, secret, 0))
+	if err != nil {
+		t.Fatalf("ConfirmTOTP error: %v", err)
+	}
+
+	if len(recoveryCodes) == 0 {
+		t.Fatal("ConfirmTOTP returned no recovery codes")
+	}
+
+	adminInfo, err := ar.Admin(adminID)
+	if err != nil {
+		t.Fatalf("Admin error: %v", err)
+	}
+
+	if !adminInfo.TOTPEnabled {
+		t.Fatal("two-factor authentication was not enabled")
+	}
+
+	_, err = ar.EnrollTOTP(adminID)
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest enrolling again, got %v", err)
+	}
+}
+
+func TestVerifyTwoFactor(t *testing.T) {
+	ar := NewAdminRepository(New())
+	adminID, secret, recoveryCodes := newTwoFactorAdmin(t, ar)
+
+	adminInfo, err := ar.Admin(adminID)
+	if err != nil {
+		t.Fatalf("Admin error: %v", err)
+	}
+	nonce := adminInfo.TwoFactorNonce()
+
+	code := totpCode(t, secret, 0)
+	adminInfo, err = ar.VerifyTwoFactor(adminID, code)
+	if err != nil {
+		t.Fatalf("VerifyTwoFactor error: %v", err)
+	}
+
+	// Challenges issued before an accepted code cannot be used again.
+	if adminInfo.TwoFactorNonce() == nonce {
+		t.Fatal("expected the two-factor nonce to change after an accepted code")
+	}
+
+	_, err = ar.VerifyTwoFactor(adminID, code)
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for a replayed code, got %v", err)
+	}
+
+	// Recovery codes can only be used once.
+	_, err = ar.VerifyTwoFactor(adminID, recoveryCodes[0])
+	if err != nil {
+		t.Fatalf("VerifyTwoFactor error for a recovery code: %v", err)
+	}
+
+	_, err = ar.VerifyTwoFactor(adminID, recoveryCodes[0])
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for a used recovery code, got %v", err)
+	}
+
+	// Wrong codes count towards the account lockout.
+	for i := 0; i < admin.MaxFailedLoginAttempts; i++ {
+		ar.VerifyTwoFactor(adminID, "000000")
+	}
+
+	_, err = ar.VerifyTwoFactor(adminID, recoveryCodes[1])
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for a locked account, got %v", err)
+	}
+}
+
+func TestDisableTOTP(t *testing.T) {
+	ar := NewAdminRepository(New())
+	adminID, _, recoveryCodes := newTwoFactorAdmin(t, ar)
+
+	err := ar.DisableTOTP(adminID, "000000")
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for a wrong code, got %v", err)
+	}
+
+	err = ar.DisableTOTP(adminID, recoveryCodes[0])
+	if err != nil {
+		t.Fatalf("DisableTOTP error: %v", err)
+	}
+
+	adminInfo, err := ar.Admin(adminID)
+	if err != nil {
+		t.Fatalf("Admin error: %v", err)
+	}
+
+	if adminInfo.TOTPEnabled || adminInfo.TOTPSecret != "" || len(adminInfo.HashedRecoveryCodes) != 0 {
+		t.Fatal("DisableTOTP did not clear the two-factor settings")
+	}
+}
+
+func TestResetTOTP(t *testing.T) {
+	ar := NewAdminRepository(New())
+	adminID, _, _ := newTwoFactorAdmin(t, ar)
+
+	err := ar.ResetTOTP("other school", adminID)
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for an admin of another school, got %v", err)
+	}
+
+	err = ar.ResetTOTP(testSchoolID, adminID)
+	if err != nil {
+		t.Fatalf("ResetTOTP error: %v", err)
+	}
+
+	adminInfo, err := ar.Admin(adminID)
+	if err != nil {
+		t.Fatalf("Admin error: %v", err)
+	}
+
+	if adminInfo.TOTPEnabled {
+		t.Fatal("ResetTOTP did not disable two-factor authentication")
+	}
+}
@@ -0,0 +1,213 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ukane-philemon/scomp/internal/class"
+	"github.com/ukane-philemon/scomp/internal/db"
+	"github.com/ukane-philemon/scomp/internal/history"
+	"github.com/ukane-philemon/scomp/internal/student"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	idKey                   = "_id"
+	schoolIDKey             = "schoolID"
+	classIDKey              = "classID"
+	reportKey               = "report"
+	stateKey                = "state"
+	reportStaleKey          = "reportStale"
+	studentsRevisionKey     = "studentsRevision"
+	currentReportVersionKey = "currentReportVersion"
+	versionKey              = "version"
+	reportClassKey          = "report.class"
+	reportGeneratedAtKey    = "report.generatedAt"
+)
+
+// Matches checks that studentReport was saved with classReport. Both reports
+// are generated at the same time by the same computation.
+func Matches(classReport *class.ClassReport, studentReport *student.Report) bool {
+	return studentReport != nil && studentReport.Class != nil && studentReport.GeneratedAt == classReport.GeneratedAt
+}
+
+// ReportStore implements Store.
+type ReportStore struct {
+	ctx                     context.Context
+	classCollection         *mongo.Collection
+	studentCollection       *mongo.Collection
+	reportVersionCollection *mongo.Collection
+}
+
+// NewStore creates a new instance of *ReportStore.
+func NewStore(ctx context.Context, db *mongo.Database) Store {
+	return &ReportStore{
+		ctx:                     ctx,
+		classCollection:         db.Collection("classes"),
+		studentCollection:       db.Collection("students"),
+		reportVersionCollection: db.Collection("reportVersions"),
+	}
+}
+
+// CreateReports saves classReport and the reports of students as the next
+// version of the reports of classID and as the current reports of the class
+// and its students, and marks the class as reported in a single transaction.
+// Implements Store.
+func (rs *ReportStore) CreateReports(schoolID, classID, generatedBy string, studentsRevision int, classReport *class.ClassReport, students []*history.StudentReport) (*history.ReportVersion, error) {
+	if schoolID == "" || classID == "" {
+		return nil, fmt.Errorf("%w: missing schoolID or classID", db.ErrorInvalidRequest)
+	}
+
+	var reportVersion *history.ReportVersion
+	err := rs.withTransaction(func(ctx mongo.SessionContext) error {
+		var latest struct {
+			Version int `bson:"version"`
+		}
+		opts := options.FindOne().SetSort(bson.D{{Key: versionKey, Value: -1}}).SetProjection(bson.M{versionKey: 1})
+		err := rs.reportVersionCollection.FindOne(ctx, bson.M{schoolIDKey: schoolID, classIDKey: classID}, opts).Decode(&latest)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return fmt.Errorf("reportVersionCollection.FindOne error: %w", err)
+		}
+
+		reportVersion, err = history.NewReportVersion(schoolID, classID, generatedBy, latest.Version+1, studentsRevision, classReport, students)
+		if err != nil {
+			return err
+		}
+
+		err = rs.saveReports(ctx, schoolID, class.StateComputing, reportVersion)
+		if err != nil {
+			return err
+		}
+
+		_, err = rs.reportVersionCollection.InsertOne(ctx, reportVersion)
+		if err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				return fmt.Errorf("%w: another report of class with ID %s is being saved", db.ErrorInvalidRequest, classID)
+			}
+			return fmt.Errorf("reportVersionCollection.InsertOne error: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return reportVersion, nil
+}
+
+// RestoreReports saves the reports of reportVersion as the current reports of
+// its class and the students of the class and marks the class as reported in
+// a single transaction.
+// Implements Store.
+func (rs *ReportStore) RestoreReports(schoolID, fromState string, reportVersion *history.ReportVersion) error {
+	if schoolID == "" || reportVersion.ClassID == "" {
+		return fmt.Errorf("%w: missing schoolID or classID", db.ErrorInvalidRequest)
+	}
+
+	return rs.withTransaction(func(ctx mongo.SessionContext) error {
+		return rs.saveReports(ctx, schoolID, fromState, reportVersion)
+	})
+}
+
+// saveReports saves the reports of reportVersion as the current reports of its
+// class and the students of the class in the transaction of ctx if the class
+// is in the fromState state and its students did not change since
+// reportVersion was computed.
+func (rs *ReportStore) saveReports(ctx mongo.SessionContext, schoolID, fromState string, reportVersion *history.ReportVersion) error {
+	classID := reportVersion.ClassID
+	filter := bson.M{idKey: classID, schoolIDKey: schoolID, stateKey: fromState, studentsRevisionKey: reportVersion.StudentsRevision}
+	update := bson.M{"$set": bson.M{
+		reportKey:               reportVersion.ClassReport,
+		currentReportVersionKey: reportVersion.Version,
+		stateKey:                class.StateReported,
+		reportStaleKey:          false,
+	}}
+	res, err := rs.classCollection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(false))
+	if err != nil {
+		return fmt.Errorf("classCollection.UpdateOne error: %w", err)
+	}
+
+	if res.MatchedCount == 0 {
+		nClass, err := rs.classCollection.CountDocuments(ctx, bson.M{idKey: classID, schoolIDKey: schoolID, stateKey: fromState})
+		if err != nil {
+			return fmt.Errorf("classCollection.CountDocuments error: %w", err)
+		}
+
+		if nClass == 0 {
+			return fmt.Errorf("%w: report for class with ID %s was not updated, the class is not %s", db.ErrorInvalidRequest, classID, fromState)
+		}
+		return ErrorStudentsChanged
+	}
+
+	for _, studentReport := range reportVersion.Students {
+		filter := bson.M{idKey: studentReport.StudentID, schoolIDKey: schoolID, classIDKey: classID}
+		res, err := rs.studentCollection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{reportKey: studentReport.Report}}, options.Update().SetUpsert(false))
+		if err != nil {
+			return fmt.Errorf("studentCollection.UpdateOne error: %w", err)
+		}
+
+		if res.MatchedCount == 0 {
+			return fmt.Errorf("student with ID %s was not updated", studentReport.StudentID)
+		}
+	}
+
+	return nil
+}
+
+// withTransaction calls fn in a transaction, which is committed if fn returns
+// a nil error.
+func (rs *ReportStore) withTransaction(fn func(ctx mongo.SessionContext) error) error {
+	session, err := rs.classCollection.Database().Client().StartSession()
+	if err != nil {
+		return fmt.Errorf("Client().StartSession() error: %w", err)
+	}
+	defer session.EndSession(rs.ctx)
+
+	_, err = session.WithTransaction(rs.ctx, func(ctx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(ctx)
+	})
+	return err
+}
+
+// IncompleteReports returns the reported classes of every school whose current
+// report does not match the reports of all their students.
+// Implements Store.
+func (rs *ReportStore) IncompleteReports() ([]*class.Class, error) {
+	cursor, err := rs.classCollection.Find(rs.ctx, bson.M{stateKey: class.StateReported, reportKey: bson.M{"$ne": nil}})
+	if err != nil {
+		return nil, fmt.Errorf("classCollection.Find error: %w", err)
+	}
+
+	var classes []*class.Class
+	err = cursor.All(rs.ctx, &classes)
+	if err != nil {
+		return nil, fmt.Errorf("cursor.All error: %w", err)
+	}
+
+	var incompleteClasses []*class.Class
+	for _, classInfo := range classes {
+		// Students whose report does not match the class report, see Matches.
+		filter := bson.M{
+			schoolIDKey: classInfo.SchoolID,
+			classIDKey:  classInfo.ID,
+			"$or": bson.A{
+				bson.M{reportClassKey: nil},
+				bson.M{reportGeneratedAtKey: bson.M{"$ne": classInfo.Report.GeneratedAt}},
+			},
+		}
+		nStudents, err := rs.studentCollection.CountDocuments(rs.ctx, filter, options.Count().SetLimit(1))
+		if err != nil {
+			return nil, fmt.Errorf("studentCollection.CountDocuments error: %w", err)
+		}
+
+		if nStudents > 0 {
+			incompleteClasses = append(incompleteClasses, classInfo)
+		}
+	}
+
+	return incompleteClasses, nil
+}
@@ -0,0 +1,38 @@
+package report
+
+import (
+	"fmt"
+
+	"github.com/ukane-philemon/scomp/internal/class"
+	"github.com/ukane-philemon/scomp/internal/db"
+	"github.com/ukane-philemon/scomp/internal/history"
+)
+
+// ErrorStudentsChanged is returned when saving reports computed with students
+// of a class that changed since. The reports must be computed again.
+var ErrorStudentsChanged = fmt.Errorf("%w: students of the class changed since its report was computed, compute the report again", db.ErrorInvalidRequest)
+
+// Store saves the current reports of classes and their students. The report
+// of a class and the reports of its students are always saved together.
+type Store interface {
+	// CreateReports saves classReport and the reports of students as the next
+	// version of the reports of classID and as the current reports of the
+	// class and its students, and marks the class as reported with a report
+	// that is not stale, in a single transaction. Either everything is saved
+	// or nothing is. Returns db.ErrorInvalidRequest if the class is not
+	// computing, or ErrorStudentsChanged if the students revision of the
+	// class is not studentsRevision, the revision the reports were computed
+	// with.
+	CreateReports(schoolID, classID, generatedBy string, studentsRevision int, classReport *class.ClassReport, students []*history.StudentReport) (*history.ReportVersion, error)
+	// RestoreReports saves the reports of reportVersion as the current reports
+	// of its class and the students of the class and marks the class as
+	// reported with a report that is not stale in a single transaction.
+	// Returns db.ErrorInvalidRequest if the class is not in the fromState
+	// state, or ErrorStudentsChanged if the students of the class changed
+	// since reportVersion was computed.
+	RestoreReports(schoolID, fromState string, reportVersion *history.ReportVersion) error
+	// IncompleteReports returns the reported classes of every school whose
+	// current report does not match the reports of all their students, e.g
+	// because the reports were half-written by an older server.
+	IncompleteReports() ([]*class.Class, error)
+}
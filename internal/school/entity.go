@@ -0,0 +1,90 @@
+package school
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ukane-philemon/scomp/internal/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// DefaultName is the name of the school created for data that existed before
+// schools were introduced, and of the first school if no name is provided.
+const DefaultName = "Default school"
+
+const idKey = "_id"
+
+// School is a tenant. Admins, classes and students belong to exactly one
+// school and are never visible to admins of other schools.
+type School struct {
+	ID        string `json:"_id" bson:"_id"`
+	Name      string `json:"name" bson:"name"`
+	CreatedAt int64  `json:"createdAt" bson:"createdAt"`
+}
+
+// NewSchool returns a new *School named name.
+func NewSchool(name string) (*School, error) {
+	if name == "" {
+		return nil, fmt.Errorf("%w: missing school name", db.ErrorInvalidRequest)
+	}
+
+	return &School{
+		ID:        primitive.NewObjectID().Hex(),
+		Name:      name,
+		CreatedAt: time.Now().Unix(),
+	}, nil
+}
+
+// SchoolRepository implements Repository.
+type SchoolRepository struct {
+	ctx              context.Context
+	schoolCollection *mongo.Collection
+}
+
+// NewRepository creates a new instance of *SchoolRepository. The collection
+// indexes are created by db.MigrateMongoDB.
+func NewRepository(ctx context.Context, db *mongo.Database) Repository {
+	return &SchoolRepository{
+		ctx:              ctx,
+		schoolCollection: db.Collection("schools"),
+	}
+}
+
+// Create creates a new school and returns its ID. Returns
+// db.ErrorInvalidRequest if name is already used by another school.
+// Implements Repository.
+func (sr *SchoolRepository) Create(name string) (string, error) {
+	school, err := NewSchool(name)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = sr.schoolCollection.InsertOne(sr.ctx, school)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return "", fmt.Errorf("%w: school name %s already exists", db.ErrorInvalidRequest, name)
+		}
+		return "", fmt.Errorf("schoolCollection.InsertOne error: %w", err)
+	}
+
+	return school.ID, nil
+}
+
+// School returns the school that match schoolID.
+// Implements Repository.
+func (sr *SchoolRepository) School(schoolID string) (*School, error) {
+	var school *School
+	err := sr.schoolCollection.FindOne(sr.ctx, bson.M{idKey: schoolID}).Decode(&school)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("%w: no record found for school with ID %s", db.ErrorInvalidRequest, schoolID)
+		}
+		return nil, fmt.Errorf("schoolCollection.FindOne error: %w", err)
+	}
+
+	return school, nil
+}
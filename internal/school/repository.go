@@ -0,0 +1,9 @@
+package school
+
+type Repository interface {
+	// Create creates a new school and returns its ID. Returns
+	// db.ErrorInvalidRequest if name is already used by another school.
+	Create(name string) (string, error)
+	// School returns the school that match schoolID.
+	School(schoolID string) (*School, error)
+}
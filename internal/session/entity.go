@@ -0,0 +1,264 @@
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ukane-philemon/scomp/internal/db"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RefreshTokenExpiry is the lifetime of a refresh token. A session expires if
+// its refresh token is not used within this period.
+const RefreshTokenExpiry = 30 * 24 * time.Hour
+
+const (
+	idKey                         = "_id"
+	adminIDKey                    = "adminID"
+	hashedRefreshTokenKey         = "hashedRefreshToken"
+	previousHashedRefreshTokenKey = "previousHashedRefreshToken"
+	lastUsedAtKey                 = "lastUsedAt"
+	expiresAtKey                  = "expiresAt"
+	revokedAtKey                  = "revokedAt"
+)
+
+// Session is a login session of an admin. A session is active until it is
+// revoked or its refresh token expires.
+type Session struct {
+	ID      string `json:"_id" bson:"_id"`
+	AdminID string `json:"adminID" bson:"adminID"`
+	// HashedRefreshToken is the hash of the current refresh token.
+	HashedRefreshToken string `json:"-" bson:"hashedRefreshToken"`
+	// PreviousHashedRefreshToken is the hash of the last rotated refresh
+	// token, used to detect refresh token reuse.
+	PreviousHashedRefreshToken string `json:"-" bson:"previousHashedRefreshToken"`
+	UserAgent                  string `json:"userAgent" bson:"userAgent"`
+	IPAddress                  string `json:"ipAddress" bson:"ipAddress"`
+	CreatedAt                  int64  `json:"createdAt" bson:"createdAt"`
+	LastUsedAt                 int64  `json:"lastUsedAt" bson:"lastUsedAt"`
+	ExpiresAt                  int64  `json:"expiresAt" bson:"expiresAt"`
+	RevokedAt                  int64  `json:"revokedAt" bson:"revokedAt"` // 0 until revoked
+}
+
+// ClientInfo is information about the client that created a session.
+type ClientInfo struct {
+	UserAgent string
+	IPAddress string
+}
+
+// NewSession returns a new *Session for adminID and its refresh token.
+func NewSession(adminID string, client *ClientInfo) (*Session, string, error) {
+	if adminID == "" {
+		return nil, "", fmt.Errorf("%w: missing adminID", db.ErrorInvalidRequest)
+	}
+
+	if client == nil {
+		client = new(ClientInfo)
+	}
+
+	sessionID := primitive.NewObjectID().Hex()
+	refreshToken, hashedRefreshToken, err := NewRefreshToken(sessionID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	return &Session{
+		ID:                 sessionID,
+		AdminID:            adminID,
+		HashedRefreshToken: hashedRefreshToken,
+		UserAgent:          client.UserAgent,
+		IPAddress:          client.IPAddress,
+		CreatedAt:          now.Unix(),
+		LastUsedAt:         now.Unix(),
+		ExpiresAt:          now.Add(RefreshTokenExpiry).Unix(),
+	}, refreshToken, nil
+}
+
+// NewRefreshToken generates a new refresh token for sessionID and returns the
+// token and the hash to be stored.
+func NewRefreshToken(sessionID string) (string, string, error) {
+	secret := make([]byte, 32)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", "", fmt.Errorf("rand.Read error: %w", err)
+	}
+
+	encodedSecret := base64.RawURLEncoding.EncodeToString(secret)
+	return sessionID + "." + encodedSecret, hashSecret(encodedSecret), nil
+}
+
+// ParseRefreshToken returns the session ID and the hash of refreshToken.
+func ParseRefreshToken(refreshToken string) (string, string, error) {
+	sessionID, secret, found := strings.Cut(refreshToken, ".")
+	if !found || sessionID == "" || secret == "" {
+		return "", "", fmt.Errorf("%w: invalid refresh token", db.ErrorInvalidRequest)
+	}
+
+	return sessionID, hashSecret(secret), nil
+}
+
+func hashSecret(secret string) string {
+	hash := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(hash[:])
+}
+
+// SessionRepository implements Repository.
+type SessionRepository struct {
+	ctx               context.Context
+	sessionCollection *mongo.Collection
+}
+
+// NewRepository creates a new instance of *SessionRepository. The collection
+// indexes are created by db.MigrateMongoDB.
+func NewRepository(ctx context.Context, db *mongo.Database) Repository {
+	return &SessionRepository{
+		ctx:               ctx,
+		sessionCollection: db.Collection("sessions"),
+	}
+}
+
+// Create creates a new session for adminID and returns the session and its
+// refresh token.
+// Implements Repository.
+func (sr *SessionRepository) Create(adminID string, client *ClientInfo) (*Session, string, error) {
+	session, refreshToken, err := NewSession(adminID, client)
+	if err != nil {
+		return nil, "", err
+	}
+
+	_, err = sr.sessionCollection.InsertOne(sr.ctx, session)
+	if err != nil {
+		return nil, "", fmt.Errorf("sessionCollection.InsertOne error: %w", err)
+	}
+
+	return session, refreshToken, nil
+}
+
+// Refresh rotates the refresh token of an active session and returns the
+// session and its new refresh token. If an already rotated refresh token is
+// used, the session is revoked.
+// Implements Repository.
+func (sr *SessionRepository) Refresh(refreshToken string) (*Session, string, error) {
+	sessionID, hashedRefreshToken, err := ParseRefreshToken(refreshToken)
+	if err != nil {
+		return nil, "", err
+	}
+
+	newRefreshToken, newHashedRefreshToken, err := NewRefreshToken(sessionID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	filter := bson.M{
+		idKey:                 sessionID,
+		hashedRefreshTokenKey: hashedRefreshToken,
+		revokedAtKey:          0,
+		expiresAtKey:          bson.M{"$gt": now.Unix()},
+	}
+	update := bson.M{"$set": bson.M{
+		hashedRefreshTokenKey:         newHashedRefreshToken,
+		previousHashedRefreshTokenKey: hashedRefreshToken,
+		lastUsedAtKey:                 now.Unix(),
+		expiresAtKey:                  now.Add(RefreshTokenExpiry).Unix(),
+	}}
+
+	var session *Session
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = sr.sessionCollection.FindOneAndUpdate(sr.ctx, filter, update, opts).Decode(&session)
+	if err == nil {
+		return session, newRefreshToken, nil
+	}
+
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, "", fmt.Errorf("sessionCollection.FindOneAndUpdate error: %w", err)
+	}
+
+	// Revoke the session if a rotated refresh token was reused, it may have
+	// been stolen.
+	reuseFilter := bson.M{idKey: sessionID, previousHashedRefreshTokenKey: hashedRefreshToken, revokedAtKey: 0}
+	_, err = sr.sessionCollection.UpdateOne(sr.ctx, reuseFilter, bson.M{"$set": bson.M{revokedAtKey: now.Unix()}})
+	if err != nil {
+		return nil, "", fmt.Errorf("sessionCollection.UpdateOne error: %w", err)
+	}
+
+	return nil, "", fmt.Errorf("%w: refresh token is invalid or has expired, please login again", db.ErrorInvalidRequest)
+}
+
+// IsActive checks that sessionID has not been revoked or expired.
+// Implements Repository.
+func (sr *SessionRepository) IsActive(sessionID string) (bool, error) {
+	filter := activeSessionsFilter()
+	filter[idKey] = sessionID
+	nSession, err := sr.sessionCollection.CountDocuments(sr.ctx, filter, options.Count().SetLimit(1))
+	if err != nil {
+		return false, fmt.Errorf("sessionCollection.CountDocuments error: %w", err)
+	}
+
+	return nSession > 0, nil
+}
+
+// Revoke revokes the active session of adminID that match sessionID.
+// Implements Repository.
+func (sr *SessionRepository) Revoke(adminID, sessionID string) error {
+	filter := activeSessionsFilter()
+	filter[idKey] = sessionID
+	filter[adminIDKey] = adminID
+	res, err := sr.sessionCollection.UpdateOne(sr.ctx, filter, bson.M{"$set": bson.M{revokedAtKey: time.Now().Unix()}})
+	if err != nil {
+		return fmt.Errorf("sessionCollection.UpdateOne error: %w", err)
+	}
+
+	if res.ModifiedCount == 0 {
+		return fmt.Errorf("%w: no active session found with ID %s", db.ErrorInvalidRequest, sessionID)
+	}
+
+	return nil
+}
+
+// RevokeAll revokes all the active sessions of adminID and returns the number
+// of revoked sessions.
+// Implements Repository.
+func (sr *SessionRepository) RevokeAll(adminID string) (int, error) {
+	filter := activeSessionsFilter()
+	filter[adminIDKey] = adminID
+	res, err := sr.sessionCollection.UpdateMany(sr.ctx, filter, bson.M{"$set": bson.M{revokedAtKey: time.Now().Unix()}})
+	if err != nil {
+		return 0, fmt.Errorf("sessionCollection.UpdateMany error: %w", err)
+	}
+
+	return int(res.ModifiedCount), nil
+}
+
+// Sessions returns the active sessions of adminID, most recently used first.
+// Implements Repository.
+func (sr *SessionRepository) Sessions(adminID string) ([]*Session, error) {
+	filter := activeSessionsFilter()
+	filter[adminIDKey] = adminID
+	opts := options.Find().SetSort(bson.D{{Key: lastUsedAtKey, Value: -1}})
+	cur, err := sr.sessionCollection.Find(sr.ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("sessionCollection.Find error: %w", err)
+	}
+
+	var sessions []*Session
+	return sessions, cur.All(sr.ctx, &sessions)
+}
+
+func activeSessionsFilter() bson.M {
+	return bson.M{
+		revokedAtKey: 0,
+		expiresAtKey: bson.M{"$gt": time.Now().Unix()},
+	}
+}
@@ -0,0 +1,22 @@
+package session
+
+type Repository interface {
+	// Create creates a new session for adminID and returns the session and its
+	// refresh token.
+	Create(adminID string, client *ClientInfo) (*Session, string, error)
+	// Refresh rotates the refresh token of an active session and returns the
+	// session and its new refresh token. Returns db.ErrorInvalidRequest if the
+	// refresh token is invalid, expired or revoked. If an already rotated
+	// refresh token is used, the session is revoked.
+	Refresh(refreshToken string) (*Session, string, error)
+	// IsActive checks that sessionID has not been revoked or expired.
+	IsActive(sessionID string) (bool, error)
+	// Revoke revokes the active session of adminID that match sessionID.
+	Revoke(adminID, sessionID string) error
+	// RevokeAll revokes all the active sessions of adminID and returns the
+	// number of revoked sessions.
+	RevokeAll(adminID string) (int, error)
+	// Sessions returns the active sessions of adminID, most recently used
+	// first.
+	Sessions(adminID string) ([]*Session, error)
+}
@@ -0,0 +1,443 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ukane-philemon/scomp/internal/admin"
+	"github.com/ukane-philemon/scomp/internal/db"
+	"github.com/ukane-philemon/scomp/internal/totp"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const adminColumns = `id, school_id, username, hashed_password, role, created_at, failed_login_attempts, locked_until,
+	totp_secret, totp_enabled, totp_last_counter, hashed_recovery_codes`
+
+// AdminRepository implements admin.Repository.
+type AdminRepository struct {
+	ctx context.Context
+	db  *sql.DB
+}
+
+// NewAdminRepository creates a new instance of *AdminRepository.
+func NewAdminRepository(ctx context.Context, sqlDB *sql.DB) admin.Repository {
+	return &AdminRepository{
+		ctx: ctx,
+		db:  sqlDB,
+	}
+}
+
+// CreateAccount implements admin.Repository.
+func (ar *AdminRepository) CreateAccount(schoolID, username, password, role string) (string, error) {
+	if schoolID == "" || username == "" || password == "" {
+		return "", fmt.Errorf("%w: missing username or password", db.ErrorInvalidRequest)
+	}
+
+	if !admin.ValidRole(role) {
+		return "", fmt.Errorf("%w: invalid role %s", db.ErrorInvalidRequest, role)
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("bcrypt.GenerateFromPassword error: %w", err)
+	}
+
+	adminID := primitive.NewObjectID().Hex()
+	_, err = ar.db.ExecContext(ar.ctx, `INSERT INTO admins (id, school_id, username, hashed_password, role, created_at) VALUES ($1, $2, $3, $4, $5, $6)`,
+		adminID, schoolID, username, string(passwordHash), role, time.Now().Unix())
+	if err != nil {
+		if isUniqueViolation(err) {
+			return "", fmt.Errorf("%w: please try another username", db.ErrorInvalidRequest)
+		}
+		return "", fmt.Errorf("db.ExecContext error: %w", err)
+	}
+
+	return adminID, nil
+}
+
+// LoginAccount implements admin.Repository.
+func (ar *AdminRepository) LoginAccount(username, password string) (*admin.Admin, error) {
+	adminInfo, err := scanAdmin(ar.db.QueryRowContext(ar.ctx, `SELECT `+adminColumns+` FROM admins WHERE username = $1`, username))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, admin.ErrorIncorrectLogin
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if adminInfo.Locked(now) {
+		return nil, admin.ErrorIncorrectLogin
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(adminInfo.HashedPassword), []byte(password))
+	if err != nil {
+		if err = ar.recordFailedLogin(adminInfo.ID, now); err != nil {
+			return nil, err
+		}
+		return nil, admin.ErrorIncorrectLogin
+	}
+
+	if adminInfo.FailedLoginAttempts > 0 {
+		_, err = ar.db.ExecContext(ar.ctx, `UPDATE admins SET failed_login_attempts = 0 WHERE id = $1`, adminInfo.ID)
+		if err != nil {
+			return nil, fmt.Errorf("db.ExecContext error: %w", err)
+		}
+		adminInfo.FailedLoginAttempts = 0
+	}
+
+	return adminInfo, nil
+}
+
+// Admin implements admin.Repository.
+func (ar *AdminRepository) Admin(adminID string) (*admin.Admin, error) {
+	adminInfo, err := scanAdmin(ar.db.QueryRowContext(ar.ctx, `SELECT `+adminColumns+` FROM admins WHERE id = $1`, adminID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: admin not found", db.ErrorInvalidRequest)
+		}
+		return nil, err
+	}
+
+	return adminInfo, nil
+}
+
+// HasAccounts implements admin.Repository.
+func (ar *AdminRepository) HasAccounts() (bool, error) {
+	var hasAccounts bool
+	err := ar.db.QueryRowContext(ar.ctx, `SELECT EXISTS (SELECT 1 FROM admins)`).Scan(&hasAccounts)
+	if err != nil {
+		return false, fmt.Errorf("db.QueryRowContext error: %w", err)
+	}
+
+	return hasAccounts, nil
+}
+
+// SetRole implements admin.Repository.
+func (ar *AdminRepository) SetRole(schoolID, adminID, role string) error {
+	if !admin.ValidRole(role) {
+		return fmt.Errorf("%w: invalid role %s", db.ErrorInvalidRequest, role)
+	}
+
+	res, err := ar.db.ExecContext(ar.ctx, `UPDATE admins SET role = $1 WHERE id = $2 AND school_id = $3`, role, adminID, schoolID)
+	if err != nil {
+		return fmt.Errorf("db.ExecContext error: %w", err)
+	}
+
+	nUpdated, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("res.RowsAffected error: %w", err)
+	}
+
+	if nUpdated == 0 {
+		return fmt.Errorf("%w: admin not found", db.ErrorInvalidRequest)
+	}
+
+	return nil
+}
+
+// ChangePassword implements admin.Repository.
+func (ar *AdminRepository) ChangePassword(adminID, currentPassword, newPassword string) error {
+	adminInfo, err := ar.Admin(adminID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if err = adminInfo.CheckLocked(now); err != nil {
+		return err
+	}
+
+	err = bcrypt.CompareHashAndPassword([]byte(adminInfo.HashedPassword), []byte(currentPassword))
+	if err != nil {
+		if err = ar.recordFailedLogin(adminInfo.ID, now); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: current password is incorrect", db.ErrorInvalidRequest)
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("bcrypt.GenerateFromPassword error: %w", err)
+	}
+
+	// Fail if the password was changed since it was checked.
+	res, err := ar.db.ExecContext(ar.ctx, `UPDATE admins SET hashed_password = $1 WHERE id = $2 AND hashed_password = $3`,
+		string(passwordHash), adminID, adminInfo.HashedPassword)
+	if err != nil {
+		return fmt.Errorf("db.ExecContext error: %w", err)
+	}
+
+	nUpdated, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("res.RowsAffected error: %w", err)
+	}
+
+	if nUpdated == 0 {
+		return fmt.Errorf("%w: current password is incorrect", db.ErrorInvalidRequest)
+	}
+
+	return nil
+}
+
+// CreatePasswordReset implements admin.Repository.
+func (ar *AdminRepository) CreatePasswordReset(schoolID, adminID string) (string, int64, error) {
+	resetToken, hashedResetToken, err := admin.NewPasswordResetToken()
+	if err != nil {
+		return "", 0, err
+	}
+
+	expiresAt := time.Now().Add(admin.PasswordResetTokenExpiry).Unix()
+	res, err := ar.db.ExecContext(ar.ctx, `UPDATE admins SET hashed_reset_token = $1, reset_token_expires_at = $2 WHERE id = $3 AND school_id = $4`,
+		hashedResetToken, expiresAt, adminID, schoolID)
+	if err != nil {
+		return "", 0, fmt.Errorf("db.ExecContext error: %w", err)
+	}
+
+	nUpdated, err := res.RowsAffected()
+	if err != nil {
+		return "", 0, fmt.Errorf("res.RowsAffected error: %w", err)
+	}
+
+	if nUpdated == 0 {
+		return "", 0, fmt.Errorf("%w: admin not found", db.ErrorInvalidRequest)
+	}
+
+	return resetToken, expiresAt, nil
+}
+
+// ResetPassword implements admin.Repository.
+func (ar *AdminRepository) ResetPassword(resetToken, newPassword string) (string, error) {
+	if resetToken == "" {
+		return "", fmt.Errorf("%w: missing reset token", db.ErrorInvalidRequest)
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("bcrypt.GenerateFromPassword error: %w", err)
+	}
+
+	var adminID string
+	err = ar.db.QueryRowContext(ar.ctx, `UPDATE admins SET hashed_password = $1, hashed_reset_token = '', reset_token_expires_at = 0,
+		failed_login_attempts = 0, locked_until = 0 WHERE hashed_reset_token = $2 AND reset_token_expires_at > $3 RETURNING id`,
+		string(passwordHash), admin.HashPasswordResetToken(resetToken), time.Now().Unix()).Scan(&adminID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", fmt.Errorf("%w: reset token is invalid or has expired", db.ErrorInvalidRequest)
+		}
+		return "", fmt.Errorf("db.QueryRowContext error: %w", err)
+	}
+
+	return adminID, nil
+}
+
+// EnrollTOTP implements admin.Repository.
+func (ar *AdminRepository) EnrollTOTP(adminID string) (string, error) {
+	secret, err := totp.NewSecret()
+	if err != nil {
+		return "", err
+	}
+
+	res, err := ar.db.ExecContext(ar.ctx, `UPDATE admins SET totp_secret = $1 WHERE id = $2 AND totp_enabled = FALSE`, secret, adminID)
+	if err != nil {
+		return "", fmt.Errorf("db.ExecContext error: %w", err)
+	}
+
+	nUpdated, err := res.RowsAffected()
+	if err != nil {
+		return "", fmt.Errorf("res.RowsAffected error: %w", err)
+	}
+
+	if nUpdated == 0 {
+		return "", fmt.Errorf("%w: two-factor authentication is already enabled", db.ErrorInvalidRequest)
+	}
+
+	return secret, nil
+}
+
+// ConfirmTOTP implements admin.Repository.
+func (ar *AdminRepository) ConfirmTOTP(adminID, code string) ([]string, error) {
+	adminInfo, err := ar.Admin(adminID)
+	if err != nil {
+		return nil, err
+	}
+
+	counter, err := adminInfo.ConfirmTOTPCode(code)
+	if err != nil {
+		return nil, err
+	}
+
+	recoveryCodes, hashedRecoveryCodes, err := admin.NewRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	hashedRecoveryCodesJSON, err := json.Marshal(hashedRecoveryCodes)
+	if err != nil {
+		return nil, fmt.Errorf("json.Marshal error: %w", err)
+	}
+
+	// Fail if the admin enrolled again since the secret was read.
+	res, err := ar.db.ExecContext(ar.ctx, `UPDATE admins SET totp_enabled = TRUE, totp_last_counter = $1, hashed_recovery_codes = $2
+		WHERE id = $3 AND totp_secret = $4 AND totp_enabled = FALSE`, counter, string(hashedRecoveryCodesJSON), adminID, adminInfo.TOTPSecret)
+	if err != nil {
+		return nil, fmt.Errorf("db.ExecContext error: %w", err)
+	}
+
+	nUpdated, err := res.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("res.RowsAffected error: %w", err)
+	}
+
+	if nUpdated == 0 {
+		return nil, fmt.Errorf("%w: two-factor enrollment has changed, please enroll again", db.ErrorInvalidRequest)
+	}
+
+	return recoveryCodes, nil
+}
+
+// VerifyTwoFactor implements admin.Repository.
+func (ar *AdminRepository) VerifyTwoFactor(adminID, code string) (*admin.Admin, error) {
+	adminInfo, err := ar.Admin(adminID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if err = adminInfo.CheckLocked(now); err != nil {
+		return nil, err
+	}
+
+	match, ok := adminInfo.MatchTwoFactorCode(code, now)
+	if !ok {
+		if err = ar.recordFailedLogin(adminInfo.ID, now); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: two-factor code is invalid or has already been used", db.ErrorInvalidRequest)
+	}
+
+	// Mark the code as used. The update fails if it was used concurrently.
+	var res sql.Result
+	if match.HashedRecoveryCode != "" {
+		hashedRecoveryCodes := make([]string, 0, len(adminInfo.HashedRecoveryCodes))
+		for _, hashedRecoveryCode := range adminInfo.HashedRecoveryCodes {
+			if hashedRecoveryCode != match.HashedRecoveryCode {
+				hashedRecoveryCodes = append(hashedRecoveryCodes, hashedRecoveryCode)
+			}
+		}
+
+		oldHashedRecoveryCodesJSON, err := json.Marshal(adminInfo.HashedRecoveryCodes)
+		if err != nil {
+			return nil, fmt.Errorf("json.Marshal error: %w", err)
+		}
+
+		hashedRecoveryCodesJSON, err := json.Marshal(hashedRecoveryCodes)
+		if err != nil {
+			return nil, fmt.Errorf("json.Marshal error: %w", err)
+		}
+
+		res, err = ar.db.ExecContext(ar.ctx, `UPDATE admins SET hashed_recovery_codes = $1, failed_login_attempts = 0
+			WHERE id = $2 AND totp_enabled = TRUE AND hashed_recovery_codes = $3`,
+			string(hashedRecoveryCodesJSON), adminID, string(oldHashedRecoveryCodesJSON))
+		if err != nil {
+			return nil, fmt.Errorf("db.ExecContext error: %w", err)
+		}
+	} else {
+		res, err = ar.db.ExecContext(ar.ctx, `UPDATE admins SET totp_last_counter = $1, failed_login_attempts = 0
+			WHERE id = $2 AND totp_enabled = TRUE AND totp_last_counter < $1`, match.Counter, adminID)
+		if err != nil {
+			return nil, fmt.Errorf("db.ExecContext error: %w", err)
+		}
+	}
+
+	nUpdated, err := res.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("res.RowsAffected error: %w", err)
+	}
+
+	if nUpdated == 0 {
+		return nil, fmt.Errorf("%w: two-factor code is invalid or has already been used", db.ErrorInvalidRequest)
+	}
+
+	adminInfo.FailedLoginAttempts = 0
+	return adminInfo, nil
+}
+
+// DisableTOTP implements admin.Repository.
+func (ar *AdminRepository) DisableTOTP(adminID, code string) error {
+	_, err := ar.VerifyTwoFactor(adminID, code)
+	if err != nil {
+		return err
+	}
+
+	_, err = ar.db.ExecContext(ar.ctx, `UPDATE admins SET `+disableTOTPColumns+` WHERE id = $1`, adminID)
+	if err != nil {
+		return fmt.Errorf("db.ExecContext error: %w", err)
+	}
+
+	return nil
+}
+
+// ResetTOTP implements admin.Repository.
+func (ar *AdminRepository) ResetTOTP(schoolID, adminID string) error {
+	res, err := ar.db.ExecContext(ar.ctx, `UPDATE admins SET `+disableTOTPColumns+` WHERE id = $1 AND school_id = $2`, adminID, schoolID)
+	if err != nil {
+		return fmt.Errorf("db.ExecContext error: %w", err)
+	}
+
+	nUpdated, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("res.RowsAffected error: %w", err)
+	}
+
+	if nUpdated == 0 {
+		return fmt.Errorf("%w: admin not found", db.ErrorInvalidRequest)
+	}
+
+	return nil
+}
+
+// disableTOTPColumns are the column updates that disable two-factor
+// authentication.
+const disableTOTPColumns = `totp_secret = '', totp_enabled = FALSE, totp_last_counter = 0, hashed_recovery_codes = '[]'`
+
+// recordFailedLogin counts a failed login attempt of adminID and locks the
+// account if there have been too many.
+func (ar *AdminRepository) recordFailedLogin(adminID string, now time.Time) error {
+	_, err := ar.db.ExecContext(ar.ctx, `UPDATE admins SET
+		failed_login_attempts = CASE WHEN failed_login_attempts + 1 >= $1 THEN 0 ELSE failed_login_attempts + 1 END,
+		locked_until = CASE WHEN failed_login_attempts + 1 >= $1 THEN $2 ELSE locked_until END
+		WHERE id = $3`, admin.MaxFailedLoginAttempts, now.Add(admin.LockoutDuration).Unix(), adminID)
+	if err != nil {
+		return fmt.Errorf("db.ExecContext error: %w", err)
+	}
+
+	return nil
+}
+
+// scanAdmin scans an admin row. sql.ErrNoRows is returned as is.
+func scanAdmin(row rowScanner) (*admin.Admin, error) {
+	adminInfo := new(admin.Admin)
+	var hashedRecoveryCodes string
+	err := row.Scan(&adminInfo.ID, &adminInfo.SchoolID, &adminInfo.Username, &adminInfo.HashedPassword, &adminInfo.Role, &adminInfo.CreatedAt,
+		&adminInfo.FailedLoginAttempts, &adminInfo.LockedUntil, &adminInfo.TOTPSecret, &adminInfo.TOTPEnabled, &adminInfo.TOTPLastCounter,
+		&hashedRecoveryCodes)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("row.Scan error: %w", err)
+	}
+
+	err = json.Unmarshal([]byte(hashedRecoveryCodes), &adminInfo.HashedRecoveryCodes)
+	if err != nil {
+		return nil, fmt.Errorf("json.Unmarshal error: %w", err)
+	}
+
+	return adminInfo, nil
+}
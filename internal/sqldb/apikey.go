@@ -0,0 +1,138 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ukane-philemon/scomp/internal/admin"
+	"github.com/ukane-philemon/scomp/internal/db"
+)
+
+const apiKeyColumns = `id, school_id, name, hashed_secret, scope, class_ids, created_by, created_at, expires_at, last_used_at, revoked_at`
+
+// APIKeyRepository implements admin.APIKeyRepository.
+type APIKeyRepository struct {
+	ctx context.Context
+	db  *sql.DB
+}
+
+// NewAPIKeyRepository creates a new instance of *APIKeyRepository.
+func NewAPIKeyRepository(ctx context.Context, sqlDB *sql.DB) admin.APIKeyRepository {
+	return &APIKeyRepository{
+		ctx: ctx,
+		db:  sqlDB,
+	}
+}
+
+// Create implements admin.APIKeyRepository.
+func (kr *APIKeyRepository) Create(schoolID, createdBy, name, scope string, classIDs []string, validFor time.Duration) (*admin.APIKey, string, error) {
+	apiKey, key, err := admin.NewAPIKey(schoolID, createdBy, name, scope, classIDs, validFor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	classIDsJSON, err := json.Marshal(apiKey.ClassIDs)
+	if err != nil {
+		return nil, "", fmt.Errorf("json.Marshal error: %w", err)
+	}
+
+	_, err = kr.db.ExecContext(kr.ctx, `INSERT INTO api_keys (`+apiKeyColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		apiKey.ID, apiKey.SchoolID, apiKey.Name, apiKey.HashedSecret, apiKey.Scope, string(classIDsJSON), apiKey.CreatedBy,
+		apiKey.CreatedAt, apiKey.ExpiresAt, apiKey.LastUsedAt, apiKey.RevokedAt)
+	if err != nil {
+		return nil, "", fmt.Errorf("db.ExecContext error: %w", err)
+	}
+
+	return apiKey, key, nil
+}
+
+// Authenticate implements admin.APIKeyRepository.
+func (kr *APIKeyRepository) Authenticate(key string) (*admin.APIKey, error) {
+	apiKeyID, hashedSecret, err := admin.ParseAPIKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	row := kr.db.QueryRowContext(kr.ctx, `UPDATE api_keys SET last_used_at = $1
+		WHERE id = $2 AND hashed_secret = $3 AND revoked_at = 0 AND expires_at > $1 RETURNING `+apiKeyColumns,
+		time.Now().Unix(), apiKeyID, hashedSecret)
+	apiKey, err := scanAPIKey(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: API key is invalid, expired or has been revoked", db.ErrorInvalidRequest)
+		}
+		return nil, err
+	}
+
+	return apiKey, nil
+}
+
+// APIKeys implements admin.APIKeyRepository.
+func (kr *APIKeyRepository) APIKeys(schoolID string) ([]*admin.APIKey, error) {
+	rows, err := kr.db.QueryContext(kr.ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE school_id = $1 AND revoked_at = 0 AND expires_at > $2
+		ORDER BY created_at DESC`, schoolID, time.Now().Unix())
+	if err != nil {
+		return nil, fmt.Errorf("db.QueryContext error: %w", err)
+	}
+	defer rows.Close()
+
+	var apiKeys []*admin.APIKey
+	for rows.Next() {
+		apiKey, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		apiKeys = append(apiKeys, apiKey)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err error: %w", err)
+	}
+
+	return apiKeys, nil
+}
+
+// Revoke implements admin.APIKeyRepository.
+func (kr *APIKeyRepository) Revoke(schoolID, apiKeyID string) error {
+	res, err := kr.db.ExecContext(kr.ctx, `UPDATE api_keys SET revoked_at = $1 WHERE id = $2 AND school_id = $3 AND revoked_at = 0`,
+		time.Now().Unix(), apiKeyID, schoolID)
+	if err != nil {
+		return fmt.Errorf("db.ExecContext error: %w", err)
+	}
+
+	nUpdated, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("res.RowsAffected error: %w", err)
+	}
+
+	if nUpdated == 0 {
+		return fmt.Errorf("%w: no active API key found with ID %s", db.ErrorInvalidRequest, apiKeyID)
+	}
+
+	return nil
+}
+
+// scanAPIKey scans an API key row. sql.ErrNoRows is returned as is.
+func scanAPIKey(row rowScanner) (*admin.APIKey, error) {
+	apiKey := new(admin.APIKey)
+	var classIDs string
+	err := row.Scan(&apiKey.ID, &apiKey.SchoolID, &apiKey.Name, &apiKey.HashedSecret, &apiKey.Scope, &classIDs, &apiKey.CreatedBy,
+		&apiKey.CreatedAt, &apiKey.ExpiresAt, &apiKey.LastUsedAt, &apiKey.RevokedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("row.Scan error: %w", err)
+	}
+
+	err = json.Unmarshal([]byte(classIDs), &apiKey.ClassIDs)
+	if err != nil {
+		return nil, fmt.Errorf("json.Unmarshal error: %w", err)
+	}
+
+	return apiKey, nil
+}
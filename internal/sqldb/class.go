@@ -0,0 +1,183 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ukane-philemon/scomp/internal/class"
+	"github.com/ukane-philemon/scomp/internal/db"
+)
+
+const classColumns = `id, school_id, name, teacher_id, grading_scale_id, ranking_mode, ranking_basis, subjects, report, state, report_stale,
+	students_revision, current_report_version, created_at, last_updated_at`
+
+// ClassRepository implements class.Repository.
+type ClassRepository struct {
+	ctx context.Context
+	db  *sql.DB
+}
+
+// NewClassRepository creates a new instance of *ClassRepository.
+func NewClassRepository(ctx context.Context, sqlDB *sql.DB) class.Repository {
+	return &ClassRepository{
+		ctx: ctx,
+		db:  sqlDB,
+	}
+}
+
+// Create creates a new class in the database. Returns db.ErrorInvalidRequest
+// is the provided class name matches any class of the school.
+// Implements class.Repository.
+func (cr *ClassRepository) Create(schoolID, className, teacherID string, settings class.ReportSettings, subjects []*class.Subject) (string, error) {
+	classInfo, err := class.NewClass(schoolID, className, teacherID, settings, subjects)
+	if err != nil {
+		return "", err
+	}
+
+	subjectsJSON, err := json.Marshal(classInfo.Subjects)
+	if err != nil {
+		return "", fmt.Errorf("json.Marshal error: %w", err)
+	}
+
+	_, err = cr.db.ExecContext(cr.ctx, `INSERT INTO classes (id, school_id, name, teacher_id, grading_scale_id, ranking_mode, ranking_basis, subjects, state, created_at,
+		last_updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`, classInfo.ID, classInfo.SchoolID, classInfo.Name, classInfo.TeacherID,
+		classInfo.GradingScaleID, classInfo.RankingMode, classInfo.RankingBasis, string(subjectsJSON), classInfo.State, classInfo.CreatedAt, classInfo.LastUpdatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return "", fmt.Errorf("%w: class name %s already exists", db.ErrorInvalidRequest, className)
+		}
+		return "", fmt.Errorf("db.ExecContext error: %w", err)
+	}
+
+	return classInfo.ID, nil
+}
+
+// Class returns information for the class that match the provided classID.
+// Implements class.Repository.
+func (cr *ClassRepository) Class(schoolID, classID string) (*class.Class, error) {
+	if schoolID == "" || classID == "" {
+		return nil, fmt.Errorf("%w: missing schoolID or classID", db.ErrorInvalidRequest)
+	}
+
+	row := cr.db.QueryRowContext(cr.ctx, `SELECT `+classColumns+` FROM classes WHERE id = $1 AND school_id = $2`, classID, schoolID)
+	classInfo, err := scanClass(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: no record found for class with ID %s", db.ErrorInvalidRequest, classID)
+		}
+		return nil, err
+	}
+
+	return classInfo, nil
+}
+
+// Classes returns information for all the classes of the school.
+// Implements class.Repository.
+func (cr *ClassRepository) Classes(schoolID string, hasReport *bool) ([]*class.Class, error) {
+	if schoolID == "" {
+		return nil, fmt.Errorf("%w: missing schoolID", db.ErrorInvalidRequest)
+	}
+
+	query := `SELECT ` + classColumns + ` FROM classes WHERE school_id = $1`
+	if hasReport != nil {
+		if *hasReport {
+			query += ` AND report IS NOT NULL`
+		} else {
+			query += ` AND report IS NULL`
+		}
+	}
+
+	rows, err := cr.db.QueryContext(cr.ctx, query, schoolID)
+	if err != nil {
+		return nil, fmt.Errorf("db.QueryContext error: %w", err)
+	}
+	defer rows.Close()
+
+	var classes []*class.Class
+	for rows.Next() {
+		classInfo, err := scanClass(rows)
+		if err != nil {
+			return nil, err
+		}
+		classes = append(classes, classInfo)
+	}
+
+	return classes, rows.Err()
+}
+
+// Exists checks if classID exists.
+// Implements class.Repository.
+func (cr *ClassRepository) Exists(schoolID, classID string) (bool, error) {
+	if schoolID == "" || classID == "" {
+		return false, fmt.Errorf("%w: missing schoolID or classID", db.ErrorInvalidRequest)
+	}
+
+	var nClass int
+	err := cr.db.QueryRowContext(cr.ctx, `SELECT COUNT(*) FROM classes WHERE id = $1 AND school_id = $2`, classID, schoolID).Scan(&nClass)
+	if err != nil {
+		return false, fmt.Errorf("db.QueryRowContext error: %w", err)
+	}
+
+	return nClass > 0, nil
+}
+
+// Transition changes the state of the class that match classID from from to
+// to.
+// Implements class.Repository.
+func (cr *ClassRepository) Transition(schoolID, classID, from, to string) error {
+	if schoolID == "" || classID == "" {
+		return fmt.Errorf("%w: missing schoolID or classID", db.ErrorInvalidRequest)
+	}
+
+	res, err := cr.db.ExecContext(cr.ctx, `UPDATE classes SET state = $1 WHERE id = $2 AND school_id = $3 AND state = $4`, to, classID, schoolID, from)
+	if err != nil {
+		return fmt.Errorf("db.ExecContext error: %w", err)
+	}
+
+	nUpdated, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("res.RowsAffected error: %w", err)
+	}
+
+	if nUpdated == 0 {
+		return fmt.Errorf("%w: class with ID %s is not %s", db.ErrorInvalidRequest, classID, from)
+	}
+
+	return nil
+}
+
+// rowScanner is implemented by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanClass(row rowScanner) (*class.Class, error) {
+	var subjectsJSON string
+	var reportJSON sql.NullString
+	classInfo := new(class.Class)
+	err := row.Scan(&classInfo.ID, &classInfo.SchoolID, &classInfo.Name, &classInfo.TeacherID, &classInfo.GradingScaleID, &classInfo.RankingMode, &classInfo.RankingBasis, &subjectsJSON, &reportJSON,
+		&classInfo.State, &classInfo.ReportStale, &classInfo.StudentsRevision, &classInfo.CurrentReportVersion, &classInfo.CreatedAt, &classInfo.LastUpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("row.Scan error: %w", err)
+	}
+
+	err = json.Unmarshal([]byte(subjectsJSON), &classInfo.Subjects)
+	if err != nil {
+		return nil, fmt.Errorf("json.Unmarshal error: %w", err)
+	}
+
+	if reportJSON.Valid {
+		err = json.Unmarshal([]byte(reportJSON.String), &classInfo.Report)
+		if err != nil {
+			return nil, fmt.Errorf("json.Unmarshal error: %w", err)
+		}
+	}
+
+	return classInfo, nil
+}
@@ -0,0 +1,58 @@
+package sqldb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ukane-philemon/scomp/internal/class"
+	"github.com/ukane-philemon/scomp/internal/db"
+)
+
+// testSchoolID is the school of the records created by tests.
+const testSchoolID = "school"
+
+// testSubjects returns the subjects of the classes created by tests.
+func testSubjects() []*class.Subject {
+	subjects := make([]*class.Subject, 3)
+	for i := range subjects {
+		subjects[i] = &class.Subject{Name: fmt.Sprintf("Subject %d", i+1), MaxScore: 100, Weight: 1}
+	}
+	return subjects
+}
+
+// newTestClass creates a class named className and returns its ID.
+func newTestClass(t *testing.T, cr class.Repository, className string) string {
+	t.Helper()
+
+	classID, err := cr.Create(testSchoolID, className, "teacher", class.ReportSettings{}, testSubjects())
+	if err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+	return classID
+}
+
+func TestCreateClass(t *testing.T) {
+	cr := NewClassRepository(context.Background(), newTestDB(t))
+	classID := newTestClass(t, cr, "JSS 1")
+
+	classInfo, err := cr.Class(testSchoolID, classID)
+	if err != nil {
+		t.Fatalf("Class error: %v", err)
+	}
+
+	if classInfo.Name != "JSS 1" || len(classInfo.Subjects) != len(testSubjects()) || classInfo.Report != nil {
+		t.Fatalf("expected class JSS 1 with %d subjects and no report, got %+v", len(testSubjects()), classInfo)
+	}
+
+	_, err = cr.Create(testSchoolID, "JSS 1", "teacher", class.ReportSettings{}, testSubjects())
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for a duplicate name, got %v", err)
+	}
+
+	_, err = cr.Class(testSchoolID, "unknown")
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for an unknown class, got %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ukane-philemon/scomp/internal/db"
+	"github.com/ukane-philemon/scomp/internal/grading"
+)
+
+const gradingScaleColumns = `id, school_id, name, bands, created_at, last_updated_at`
+
+// GradingScaleRepository implements grading.Repository.
+type GradingScaleRepository struct {
+	ctx context.Context
+	db  *sql.DB
+}
+
+// NewGradingScaleRepository creates a new instance of
+// *GradingScaleRepository.
+func NewGradingScaleRepository(ctx context.Context, sqlDB *sql.DB) grading.Repository {
+	return &GradingScaleRepository{
+		ctx: ctx,
+		db:  sqlDB,
+	}
+}
+
+// Create implements grading.Repository.
+func (gr *GradingScaleRepository) Create(schoolID, name string, bands []*grading.GradeBand) (*grading.GradingScale, error) {
+	gradingScale, err := grading.NewGradingScale(schoolID, name, bands)
+	if err != nil {
+		return nil, err
+	}
+
+	bandsJSON, err := json.Marshal(gradingScale.Bands)
+	if err != nil {
+		return nil, fmt.Errorf("json.Marshal error: %w", err)
+	}
+
+	_, err = gr.db.ExecContext(gr.ctx, `INSERT INTO grading_scales (`+gradingScaleColumns+`) VALUES ($1, $2, $3, $4, $5, $6)`,
+		gradingScale.ID, gradingScale.SchoolID, gradingScale.Name, string(bandsJSON), gradingScale.CreatedAt, gradingScale.LastUpdatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, fmt.Errorf("%w: grading scale name %s already exists", db.ErrorInvalidRequest, gradingScale.Name)
+		}
+		return nil, fmt.Errorf("db.ExecContext error: %w", err)
+	}
+
+	return gradingScale, nil
+}
+
+// GradingScale implements grading.Repository.
+func (gr *GradingScaleRepository) GradingScale(schoolID, gradingScaleID string) (*grading.GradingScale, error) {
+	row := gr.db.QueryRowContext(gr.ctx, `SELECT `+gradingScaleColumns+` FROM grading_scales WHERE id = $1 AND school_id = $2`, gradingScaleID, schoolID)
+	gradingScale, err := scanGradingScale(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: no record found for grading scale with ID %s", db.ErrorInvalidRequest, gradingScaleID)
+		}
+		return nil, err
+	}
+
+	return gradingScale, nil
+}
+
+// GradingScales implements grading.Repository.
+func (gr *GradingScaleRepository) GradingScales(schoolID string) ([]*grading.GradingScale, error) {
+	rows, err := gr.db.QueryContext(gr.ctx, `SELECT `+gradingScaleColumns+` FROM grading_scales WHERE school_id = $1 ORDER BY name`, schoolID)
+	if err != nil {
+		return nil, fmt.Errorf("db.QueryContext error: %w", err)
+	}
+	defer rows.Close()
+
+	var gradingScales []*grading.GradingScale
+	for rows.Next() {
+		gradingScale, err := scanGradingScale(rows)
+		if err != nil {
+			return nil, err
+		}
+		gradingScales = append(gradingScales, gradingScale)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err error: %w", err)
+	}
+
+	return gradingScales, nil
+}
+
+// Update implements grading.Repository.
+func (gr *GradingScaleRepository) Update(schoolID, gradingScaleID, name string, bands []*grading.GradeBand) (*grading.GradingScale, error) {
+	name, bands, err := grading.ValidateGradingScale(name, bands)
+	if err != nil {
+		return nil, err
+	}
+
+	bandsJSON, err := json.Marshal(bands)
+	if err != nil {
+		return nil, fmt.Errorf("json.Marshal error: %w", err)
+	}
+
+	row := gr.db.QueryRowContext(gr.ctx, `UPDATE grading_scales SET name = $1, bands = $2, last_updated_at = $3 WHERE id = $4 AND school_id = $5
+		RETURNING `+gradingScaleColumns, name, string(bandsJSON), time.Now().Unix(), gradingScaleID, schoolID)
+	gradingScale, err := scanGradingScale(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: no record found for grading scale with ID %s", db.ErrorInvalidRequest, gradingScaleID)
+		}
+		if isUniqueViolation(err) {
+			return nil, fmt.Errorf("%w: grading scale name %s already exists", db.ErrorInvalidRequest, name)
+		}
+		return nil, err
+	}
+
+	return gradingScale, nil
+}
+
+// Delete implements grading.Repository.
+func (gr *GradingScaleRepository) Delete(schoolID, gradingScaleID string) error {
+	res, err := gr.db.ExecContext(gr.ctx, `DELETE FROM grading_scales WHERE id = $1 AND school_id = $2`, gradingScaleID, schoolID)
+	if err != nil {
+		return fmt.Errorf("db.ExecContext error: %w", err)
+	}
+
+	nDeleted, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("res.RowsAffected error: %w", err)
+	}
+
+	if nDeleted == 0 {
+		return fmt.Errorf("%w: no record found for grading scale with ID %s", db.ErrorInvalidRequest, gradingScaleID)
+	}
+
+	return nil
+}
+
+// scanGradingScale scans a grading scale row. sql.ErrNoRows is returned as
+// is.
+func scanGradingScale(row rowScanner) (*grading.GradingScale, error) {
+	gradingScale := new(grading.GradingScale)
+	var bandsJSON string
+	err := row.Scan(&gradingScale.ID, &gradingScale.SchoolID, &gradingScale.Name, &bandsJSON, &gradingScale.CreatedAt, &gradingScale.LastUpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("row.Scan error: %w", err)
+	}
+
+	err = json.Unmarshal([]byte(bandsJSON), &gradingScale.Bands)
+	if err != nil {
+		return nil, fmt.Errorf("json.Unmarshal error: %w", err)
+	}
+
+	return gradingScale, nil
+}
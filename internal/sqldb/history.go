@@ -0,0 +1,96 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ukane-philemon/scomp/internal/db"
+	"github.com/ukane-philemon/scomp/internal/history"
+)
+
+const reportVersionColumns = `id, school_id, class_id, version, generated_by, students_revision, class_report, students, generated_at`
+
+// ReportVersionRepository implements history.Repository.
+type ReportVersionRepository struct {
+	ctx context.Context
+	db  *sql.DB
+}
+
+// NewReportVersionRepository creates a new instance of
+// *ReportVersionRepository.
+func NewReportVersionRepository(ctx context.Context, sqlDB *sql.DB) history.Repository {
+	return &ReportVersionRepository{
+		ctx: ctx,
+		db:  sqlDB,
+	}
+}
+
+// ReportVersion implements history.Repository.
+func (vr *ReportVersionRepository) ReportVersion(schoolID, classID string, version int) (*history.ReportVersion, error) {
+	row := vr.db.QueryRowContext(vr.ctx, `SELECT `+reportVersionColumns+` FROM report_versions WHERE class_id = $1 AND school_id = $2 AND version = $3`,
+		classID, schoolID, version)
+	reportVersion, err := scanReportVersion(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: no report version %d found for class with ID %s", db.ErrorInvalidRequest, version, classID)
+		}
+		return nil, err
+	}
+
+	return reportVersion, nil
+}
+
+// ReportVersions implements history.Repository.
+func (vr *ReportVersionRepository) ReportVersions(schoolID, classID string) ([]*history.ReportVersion, error) {
+	rows, err := vr.db.QueryContext(vr.ctx, `SELECT `+reportVersionColumns+` FROM report_versions WHERE class_id = $1 AND school_id = $2 ORDER BY version DESC`,
+		classID, schoolID)
+	if err != nil {
+		return nil, fmt.Errorf("db.QueryContext error: %w", err)
+	}
+	defer rows.Close()
+
+	var reportVersions []*history.ReportVersion
+	for rows.Next() {
+		reportVersion, err := scanReportVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		reportVersions = append(reportVersions, reportVersion)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err error: %w", err)
+	}
+
+	return reportVersions, nil
+}
+
+// scanReportVersion scans a report version row. sql.ErrNoRows is returned as
+// is.
+func scanReportVersion(row rowScanner) (*history.ReportVersion, error) {
+	reportVersion := new(history.ReportVersion)
+	var classReportJSON, studentsJSON string
+	err := row.Scan(&reportVersion.ID, &reportVersion.SchoolID, &reportVersion.ClassID, &reportVersion.Version, &reportVersion.GeneratedBy,
+		&reportVersion.StudentsRevision, &classReportJSON, &studentsJSON, &reportVersion.GeneratedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("row.Scan error: %w", err)
+	}
+
+	err = json.Unmarshal([]byte(classReportJSON), &reportVersion.ClassReport)
+	if err != nil {
+		return nil, fmt.Errorf("json.Unmarshal error: %w", err)
+	}
+
+	err = json.Unmarshal([]byte(studentsJSON), &reportVersion.Students)
+	if err != nil {
+		return nil, fmt.Errorf("json.Unmarshal error: %w", err)
+	}
+
+	return reportVersion, nil
+}
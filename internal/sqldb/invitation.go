@@ -0,0 +1,89 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ukane-philemon/scomp/internal/admin"
+	"github.com/ukane-philemon/scomp/internal/db"
+)
+
+const invitationColumns = `id, school_id, hashed_code, role, created_by, created_at, expires_at, redeemed_at`
+
+// InvitationRepository implements admin.InvitationRepository.
+type InvitationRepository struct {
+	ctx context.Context
+	db  *sql.DB
+}
+
+// NewInvitationRepository creates a new instance of *InvitationRepository.
+func NewInvitationRepository(ctx context.Context, sqlDB *sql.DB) admin.InvitationRepository {
+	return &InvitationRepository{
+		ctx: ctx,
+		db:  sqlDB,
+	}
+}
+
+// Create implements admin.InvitationRepository.
+func (ir *InvitationRepository) Create(schoolID, createdBy, role string, validFor time.Duration) (*admin.Invitation, string, error) {
+	invitation, code, err := admin.NewInvitation(schoolID, createdBy, role, validFor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	_, err = ir.db.ExecContext(ir.ctx, `INSERT INTO invitations (`+invitationColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		invitation.ID, invitation.SchoolID, invitation.HashedCode, invitation.Role, invitation.CreatedBy, invitation.CreatedAt, invitation.ExpiresAt, invitation.RedeemedAt)
+	if err != nil {
+		return nil, "", fmt.Errorf("db.ExecContext error: %w", err)
+	}
+
+	return invitation, code, nil
+}
+
+// Redeem implements admin.InvitationRepository.
+func (ir *InvitationRepository) Redeem(code string) (*admin.Invitation, error) {
+	invitation := new(admin.Invitation)
+	err := ir.db.QueryRowContext(ir.ctx, `UPDATE invitations SET redeemed_at = $1 WHERE hashed_code = $2 AND redeemed_at = 0 AND expires_at > $1
+		RETURNING `+invitationColumns, time.Now().Unix(), admin.HashInvitationCode(code)).
+		Scan(&invitation.ID, &invitation.SchoolID, &invitation.HashedCode, &invitation.Role, &invitation.CreatedBy, &invitation.CreatedAt, &invitation.ExpiresAt, &invitation.RedeemedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: invitation code is invalid, expired or has already been used", db.ErrorInvalidRequest)
+		}
+		return nil, fmt.Errorf("row.Scan error: %w", err)
+	}
+
+	return invitation, nil
+}
+
+// Release implements admin.InvitationRepository.
+func (ir *InvitationRepository) Release(invitationID string) error {
+	_, err := ir.db.ExecContext(ir.ctx, `UPDATE invitations SET redeemed_at = 0 WHERE id = $1`, invitationID)
+	if err != nil {
+		return fmt.Errorf("db.ExecContext error: %w", err)
+	}
+
+	return nil
+}
+
+// Revoke implements admin.InvitationRepository.
+func (ir *InvitationRepository) Revoke(schoolID, invitationID string) error {
+	res, err := ir.db.ExecContext(ir.ctx, `DELETE FROM invitations WHERE id = $1 AND school_id = $2 AND redeemed_at = 0`, invitationID, schoolID)
+	if err != nil {
+		return fmt.Errorf("db.ExecContext error: %w", err)
+	}
+
+	nDeleted, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("res.RowsAffected error: %w", err)
+	}
+
+	if nDeleted == 0 {
+		return fmt.Errorf("%w: no unused invitation found with ID %s", db.ErrorInvalidRequest, invitationID)
+	}
+
+	return nil
+}
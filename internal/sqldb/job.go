@@ -0,0 +1,134 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ukane-philemon/scomp/internal/db"
+	"github.com/ukane-philemon/scomp/internal/job"
+)
+
+const jobColumns = `id, school_id, class_id, kind, state, requested_by, attempts, max_attempts, error, report_version, run_at, lease_expires_at,
+	created_at, started_at, finished_at`
+
+// JobRepository implements job.Repository.
+type JobRepository struct {
+	ctx context.Context
+	db  *sql.DB
+}
+
+// NewJobRepository creates a new instance of *JobRepository.
+func NewJobRepository(ctx context.Context, sqlDB *sql.DB) job.Repository {
+	return &JobRepository{
+		ctx: ctx,
+		db:  sqlDB,
+	}
+}
+
+// Create implements job.Repository.
+func (jr *JobRepository) Create(schoolID, classID, kind, requestedBy string) (*job.Job, error) {
+	newJob, err := job.NewJob(schoolID, classID, kind, requestedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = jr.db.ExecContext(jr.ctx, `INSERT INTO jobs (`+jobColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`,
+		newJob.ID, newJob.SchoolID, newJob.ClassID, newJob.Kind, newJob.State, newJob.RequestedBy, newJob.Attempts, newJob.MaxAttempts,
+		newJob.Error, newJob.ReportVersion, newJob.RunAt, newJob.LeaseExpiresAt, newJob.CreatedAt, newJob.StartedAt, newJob.FinishedAt)
+	if err != nil {
+		return nil, fmt.Errorf("db.ExecContext error: %w", err)
+	}
+
+	return newJob, nil
+}
+
+// Job implements job.Repository.
+func (jr *JobRepository) Job(schoolID, jobID string) (*job.Job, error) {
+	row := jr.db.QueryRowContext(jr.ctx, `SELECT `+jobColumns+` FROM jobs WHERE id = $1 AND school_id = $2`, jobID, schoolID)
+	jobInfo, err := scanJob(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: no record found for job with ID %s", db.ErrorInvalidRequest, jobID)
+		}
+		return nil, err
+	}
+
+	return jobInfo, nil
+}
+
+// Claim implements job.Repository. The due condition is checked again by the
+// update so that a job claimed by another worker in between is not claimed
+// twice.
+func (jr *JobRepository) Claim(now time.Time) (*job.Job, error) {
+	const dueCondition = `((state = $3 AND run_at <= $1) OR (state = $4 AND lease_expires_at <= $1))`
+	row := jr.db.QueryRowContext(jr.ctx, `UPDATE jobs SET state = $4, attempts = attempts + 1, lease_expires_at = $2, started_at = $1
+		WHERE id = (SELECT id FROM jobs WHERE `+dueCondition+` ORDER BY run_at LIMIT 1) AND `+dueCondition+`
+		RETURNING `+jobColumns, now.Unix(), now.Add(job.LeaseDuration).Unix(), job.StateQueued, job.StateRunning)
+	jobInfo, err := scanJob(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return jobInfo, nil
+}
+
+// Succeed implements job.Repository.
+func (jr *JobRepository) Succeed(schoolID, jobID string, attempt, reportVersion int) error {
+	return jr.finishAttempt(schoolID, jobID, attempt, `state = $5, error = '', report_version = $6, lease_expires_at = 0, finished_at = $7`,
+		job.StateSucceeded, reportVersion, time.Now().Unix())
+}
+
+// Retry implements job.Repository.
+func (jr *JobRepository) Retry(schoolID, jobID string, attempt int, errMsg string, runAt time.Time) error {
+	return jr.finishAttempt(schoolID, jobID, attempt, `state = $5, error = $6, run_at = $7, lease_expires_at = 0`,
+		job.StateQueued, errMsg, runAt.Unix())
+}
+
+// Fail implements job.Repository.
+func (jr *JobRepository) Fail(schoolID, jobID string, attempt int, errMsg string) error {
+	return jr.finishAttempt(schoolID, jobID, attempt, `state = $5, error = $6, lease_expires_at = 0, finished_at = $7`,
+		job.StateFailed, errMsg, time.Now().Unix())
+}
+
+// finishAttempt sets the columns of the job that match jobID if it is running
+// attempt. The placeholders of set start at $5.
+func (jr *JobRepository) finishAttempt(schoolID, jobID string, attempt int, set string, args ...any) error {
+	args = append([]any{jobID, schoolID, job.StateRunning, attempt}, args...)
+	res, err := jr.db.ExecContext(jr.ctx, `UPDATE jobs SET `+set+` WHERE id = $1 AND school_id = $2 AND state = $3 AND attempts = $4`, args...)
+	if err != nil {
+		return fmt.Errorf("db.ExecContext error: %w", err)
+	}
+
+	nUpdated, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("res.RowsAffected error: %w", err)
+	}
+
+	if nUpdated == 0 {
+		return fmt.Errorf("%w: job with ID %s is not running attempt %d", db.ErrorInvalidRequest, jobID, attempt)
+	}
+
+	return nil
+}
+
+// scanJob scans a job row. sql.ErrNoRows is returned as is.
+func scanJob(row rowScanner) (*job.Job, error) {
+	jobInfo := new(job.Job)
+	err := row.Scan(&jobInfo.ID, &jobInfo.SchoolID, &jobInfo.ClassID, &jobInfo.Kind, &jobInfo.State, &jobInfo.RequestedBy, &jobInfo.Attempts,
+		&jobInfo.MaxAttempts, &jobInfo.Error, &jobInfo.ReportVersion, &jobInfo.RunAt, &jobInfo.LeaseExpiresAt, &jobInfo.CreatedAt,
+		&jobInfo.StartedAt, &jobInfo.FinishedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("row.Scan error: %w", err)
+	}
+
+	return jobInfo, nil
+}
@@ -0,0 +1,64 @@
+package sqldb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ukane-philemon/scomp/internal/db"
+	"github.com/ukane-philemon/scomp/internal/job"
+)
+
+func TestFinishAttemptRequiresCurrentAttempt(t *testing.T) {
+	ctx, sqlDB := context.Background(), newTestDB(t)
+	jr := NewJobRepository(ctx, sqlDB)
+	classID := newTestClass(t, NewClassRepository(ctx, sqlDB), "JSS 1")
+
+	newJob, err := jr.Create(testSchoolID, classID, job.KindComputeClassReport, "teacher")
+	if err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+
+	err = jr.Fail(testSchoolID, newJob.ID, 0, "failed")
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for a queued job, got %v", err)
+	}
+
+	// The lease of the first attempt expires and another worker claims the
+	// job again.
+	now := time.Now()
+	for attempt := 1; attempt <= 2; attempt++ {
+		claimedJob, err := jr.Claim(now.Add(time.Duration(attempt) * job.LeaseDuration))
+		if err != nil {
+			t.Fatalf("Claim error: %v", err)
+		}
+
+		if claimedJob == nil || claimedJob.ID != newJob.ID || claimedJob.Attempts != attempt {
+			t.Fatalf("expected attempt %d of job %s, got %+v", attempt, newJob.ID, claimedJob)
+		}
+	}
+
+	err = jr.Fail(testSchoolID, newJob.ID, 1, "failed")
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for an expired attempt, got %v", err)
+	}
+
+	err = jr.Succeed("other school", newJob.ID, 2, 1)
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for another school, got %v", err)
+	}
+
+	if err := jr.Succeed(testSchoolID, newJob.ID, 2, 1); err != nil {
+		t.Fatalf("Succeed error: %v", err)
+	}
+
+	jobInfo, err := jr.Job(testSchoolID, newJob.ID)
+	if err != nil {
+		t.Fatalf("Job error: %v", err)
+	}
+
+	if jobInfo.State != job.StateSucceeded || jobInfo.ReportVersion != 1 {
+		t.Fatalf("expected a succeeded job with its report version, got %+v", jobInfo)
+	}
+}
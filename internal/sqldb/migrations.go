@@ -0,0 +1,496 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/ukane-philemon/scomp/internal/db"
+	"github.com/ukane-philemon/scomp/internal/school"
+)
+
+// migration is a SQL up-migration. stmts are executed in order followed by
+// fn, if set, in the same transaction. stmts must be supported by all the
+// drivers, fn receives the driver name for statements that are not.
+type migration struct {
+	description string
+	stmts       []string
+	fn          func(ctx context.Context, tx *sql.Tx, driver string) error
+}
+
+// migrations are the schema migrations applied in order. The version of a
+// migration is its index + 1. Never edit or reorder an existing migration,
+// append a new one instead.
+var migrations = []*migration{
+	{
+		description: "initial schema",
+		stmts: []string{
+			`CREATE TABLE admins (
+				id TEXT PRIMARY KEY,
+				username TEXT NOT NULL UNIQUE,
+				hashed_password TEXT NOT NULL,
+				created_at BIGINT NOT NULL
+			)`,
+			`CREATE TABLE classes (
+				id TEXT PRIMARY KEY,
+				name TEXT NOT NULL UNIQUE,
+				subjects TEXT NOT NULL,
+				report TEXT,
+				created_at TEXT NOT NULL,
+				last_updated_at TEXT NOT NULL
+			)`,
+			`CREATE TABLE students (
+				id TEXT PRIMARY KEY,
+				name TEXT NOT NULL,
+				class_id TEXT NOT NULL REFERENCES classes (id),
+				report TEXT NOT NULL,
+				created_at TEXT NOT NULL,
+				UNIQUE (name, class_id)
+			)`,
+			`CREATE INDEX students_class_id_idx ON students (class_id)`,
+		},
+	},
+	{
+		description: "store timestamps as int64 instead of string",
+		stmts: append(
+			convertColumnsToBigInt("classes", "created_at", "last_updated_at"),
+			convertColumnsToBigInt("students", "created_at")...,
+		),
+		fn: func(ctx context.Context, tx *sql.Tx, _ string) error {
+			for _, table := range []string{"classes", "students"} {
+				err := convertReportGeneratedAt(ctx, tx, table)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		description: "add sessions",
+		stmts: []string{
+			`CREATE TABLE sessions (
+				id TEXT PRIMARY KEY,
+				admin_id TEXT NOT NULL REFERENCES admins (id),
+				hashed_refresh_token TEXT NOT NULL,
+				previous_hashed_refresh_token TEXT NOT NULL DEFAULT '',
+				user_agent TEXT NOT NULL,
+				ip_address TEXT NOT NULL,
+				created_at BIGINT NOT NULL,
+				last_used_at BIGINT NOT NULL,
+				expires_at BIGINT NOT NULL,
+				revoked_at BIGINT NOT NULL DEFAULT 0
+			)`,
+			`CREATE INDEX sessions_admin_id_idx ON sessions (admin_id)`,
+		},
+	},
+	{
+		description: "add admin roles and class teachers",
+		stmts: []string{
+			`ALTER TABLE admins ADD COLUMN role TEXT NOT NULL DEFAULT ''`,
+			// Existing admins could do everything, keep it that way.
+			`UPDATE admins SET role = 'owner'`,
+			`ALTER TABLE classes ADD COLUMN teacher_id TEXT NOT NULL DEFAULT ''`,
+		},
+	},
+	{
+		description: "add invitations",
+		stmts: []string{
+			`CREATE TABLE invitations (
+				id TEXT PRIMARY KEY,
+				hashed_code TEXT NOT NULL UNIQUE,
+				role TEXT NOT NULL,
+				created_by TEXT NOT NULL,
+				created_at BIGINT NOT NULL,
+				expires_at BIGINT NOT NULL,
+				redeemed_at BIGINT NOT NULL DEFAULT 0
+			)`,
+		},
+	},
+	{
+		description: "add schools",
+		stmts: []string{
+			`CREATE TABLE schools (
+				id TEXT PRIMARY KEY,
+				name TEXT NOT NULL UNIQUE,
+				created_at BIGINT NOT NULL
+			)`,
+			`ALTER TABLE admins ADD COLUMN school_id TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE students ADD COLUMN school_id TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE invitations ADD COLUMN school_id TEXT NOT NULL DEFAULT ''`,
+			`CREATE INDEX admins_school_id_idx ON admins (school_id)`,
+		},
+		fn: func(ctx context.Context, tx *sql.Tx, driver string) error {
+			err := scopeClassNamesToSchools(ctx, tx, driver)
+			if err != nil {
+				return err
+			}
+			return moveToDefaultSchool(ctx, tx)
+		},
+	},
+	{
+		description: "add login lockout and password reset",
+		stmts: []string{
+			`ALTER TABLE admins ADD COLUMN failed_login_attempts INTEGER NOT NULL DEFAULT 0`,
+			`ALTER TABLE admins ADD COLUMN locked_until BIGINT NOT NULL DEFAULT 0`,
+			`ALTER TABLE admins ADD COLUMN hashed_reset_token TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE admins ADD COLUMN reset_token_expires_at BIGINT NOT NULL DEFAULT 0`,
+			`CREATE INDEX admins_hashed_reset_token_idx ON admins (hashed_reset_token)`,
+		},
+	},
+	{
+		description: "add two-factor authentication",
+		stmts: []string{
+			`ALTER TABLE admins ADD COLUMN totp_secret TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE admins ADD COLUMN totp_enabled BOOLEAN NOT NULL DEFAULT FALSE`,
+			`ALTER TABLE admins ADD COLUMN totp_last_counter BIGINT NOT NULL DEFAULT 0`,
+			`ALTER TABLE admins ADD COLUMN hashed_recovery_codes TEXT NOT NULL DEFAULT '[]'`,
+		},
+	},
+	{
+		description: "add API keys",
+		stmts: []string{
+			`CREATE TABLE api_keys (
+				id TEXT PRIMARY KEY,
+				school_id TEXT NOT NULL,
+				name TEXT NOT NULL,
+				hashed_secret TEXT NOT NULL,
+				scope TEXT NOT NULL,
+				class_ids TEXT NOT NULL,
+				created_by TEXT NOT NULL,
+				created_at BIGINT NOT NULL,
+				expires_at BIGINT NOT NULL,
+				last_used_at BIGINT NOT NULL DEFAULT 0,
+				revoked_at BIGINT NOT NULL DEFAULT 0
+			)`,
+			`CREATE INDEX api_keys_school_id_idx ON api_keys (school_id)`,
+		},
+	},
+	{
+		description: "add grading scales",
+		stmts: []string{
+			`CREATE TABLE grading_scales (
+				id TEXT PRIMARY KEY,
+				school_id TEXT NOT NULL,
+				name TEXT NOT NULL,
+				bands TEXT NOT NULL,
+				created_at BIGINT NOT NULL,
+				last_updated_at BIGINT NOT NULL,
+				UNIQUE (school_id, name)
+			)`,
+			`ALTER TABLE classes ADD COLUMN grading_scale_id TEXT NOT NULL DEFAULT ''`,
+		},
+	},
+	{
+		description: "add class ranking modes",
+		stmts: []string{
+			`ALTER TABLE classes ADD COLUMN ranking_mode TEXT NOT NULL DEFAULT 'competition'`,
+		},
+	},
+	{
+		description: "add class ranking bases",
+		stmts: []string{
+			`ALTER TABLE classes ADD COLUMN ranking_basis TEXT NOT NULL DEFAULT 'total'`,
+		},
+	},
+	{
+		description: "add report versions",
+		stmts: []string{
+			`CREATE TABLE report_versions (
+				id TEXT PRIMARY KEY,
+				school_id TEXT NOT NULL,
+				class_id TEXT NOT NULL REFERENCES classes (id),
+				version INTEGER NOT NULL,
+				generated_by TEXT NOT NULL,
+				class_report TEXT NOT NULL,
+				students TEXT NOT NULL,
+				generated_at BIGINT NOT NULL,
+				UNIQUE (class_id, version)
+			)`,
+			`ALTER TABLE classes ADD COLUMN current_report_version INTEGER NOT NULL DEFAULT 0`,
+		},
+	},
+	{
+		description: "add jobs",
+		stmts: []string{
+			`CREATE TABLE jobs (
+				id TEXT PRIMARY KEY,
+				school_id TEXT NOT NULL,
+				class_id TEXT NOT NULL,
+				kind TEXT NOT NULL,
+				state TEXT NOT NULL,
+				requested_by TEXT NOT NULL,
+				attempts INTEGER NOT NULL DEFAULT 0,
+				max_attempts INTEGER NOT NULL,
+				error TEXT NOT NULL DEFAULT '',
+				report_version INTEGER NOT NULL DEFAULT 0,
+				run_at BIGINT NOT NULL,
+				lease_expires_at BIGINT NOT NULL DEFAULT 0,
+				created_at BIGINT NOT NULL,
+				started_at BIGINT NOT NULL DEFAULT 0,
+				finished_at BIGINT NOT NULL DEFAULT 0
+			)`,
+			`CREATE INDEX jobs_state_run_at_idx ON jobs (state, run_at)`,
+		},
+	},
+	{
+		description: "add class states",
+		stmts: []string{
+			`ALTER TABLE classes ADD COLUMN state TEXT NOT NULL DEFAULT 'open'`,
+			`UPDATE classes SET state = 'reported' WHERE report IS NOT NULL`,
+			`UPDATE classes SET state = 'computing' WHERE id IN (SELECT class_id FROM jobs WHERE state IN ('queued', 'running'))`,
+		},
+	},
+	{
+		description: "add class and report version students revisions",
+		stmts: []string{
+			`ALTER TABLE classes ADD COLUMN report_stale BOOLEAN NOT NULL DEFAULT FALSE`,
+			`ALTER TABLE classes ADD COLUMN students_revision INTEGER NOT NULL DEFAULT 0`,
+			`ALTER TABLE report_versions ADD COLUMN students_revision INTEGER NOT NULL DEFAULT 0`,
+		},
+	},
+}
+
+// scopeClassNamesToSchools adds the school_id column to the classes table and
+// replaces the unique class name constraint with a unique (school_id, name)
+// constraint.
+func scopeClassNamesToSchools(ctx context.Context, tx *sql.Tx, driver string) error {
+	var stmts []string
+	switch driver {
+	case DriverPostgres:
+		stmts = []string{
+			`ALTER TABLE classes ADD COLUMN school_id TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE classes DROP CONSTRAINT classes_name_key`,
+			`ALTER TABLE classes ADD CONSTRAINT classes_school_id_name_key UNIQUE (school_id, name)`,
+		}
+	case DriverSQLite:
+		// SQLite cannot drop constraints, the table is recreated instead. The
+		// students table is recreated first to reference the new classes
+		// table, the reference is renamed with the table.
+		stmts = []string{
+			`CREATE TABLE classes_new (
+				id TEXT PRIMARY KEY,
+				school_id TEXT NOT NULL DEFAULT '',
+				name TEXT NOT NULL,
+				teacher_id TEXT NOT NULL DEFAULT '',
+				subjects TEXT NOT NULL,
+				report TEXT,
+				created_at BIGINT NOT NULL DEFAULT 0,
+				last_updated_at BIGINT NOT NULL DEFAULT 0,
+				UNIQUE (school_id, name)
+			)`,
+			`INSERT INTO classes_new (id, name, teacher_id, subjects, report, created_at, last_updated_at)
+				SELECT id, name, teacher_id, subjects, report, created_at, last_updated_at FROM classes`,
+			`CREATE TABLE students_new (
+				id TEXT PRIMARY KEY,
+				school_id TEXT NOT NULL DEFAULT '',
+				name TEXT NOT NULL,
+				class_id TEXT NOT NULL REFERENCES classes_new (id),
+				report TEXT NOT NULL,
+				created_at BIGINT NOT NULL DEFAULT 0,
+				UNIQUE (name, class_id)
+			)`,
+			`INSERT INTO students_new (id, school_id, name, class_id, report, created_at)
+				SELECT id, school_id, name, class_id, report, created_at FROM students`,
+			`DROP TABLE students`,
+			`DROP TABLE classes`,
+			`ALTER TABLE classes_new RENAME TO classes`,
+			`ALTER TABLE students_new RENAME TO students`,
+			`CREATE INDEX students_class_id_idx ON students (class_id)`,
+		}
+	default:
+		return fmt.Errorf("unsupported SQL driver %q", driver)
+	}
+
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("tx.ExecContext error: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// moveToDefaultSchool creates the default school and moves all the existing
+// admins, classes, students and invitations to it. The default school is not
+// created for empty databases.
+func moveToDefaultSchool(ctx context.Context, tx *sql.Tx) error {
+	tables := []string{"admins", "classes", "students", "invitations"}
+
+	var hasData bool
+	for _, table := range tables {
+		err := tx.QueryRowContext(ctx, fmt.Sprintf(`SELECT EXISTS (SELECT 1 FROM %s)`, table)).Scan(&hasData)
+		if err != nil {
+			return fmt.Errorf("tx.QueryRowContext error: %w", err)
+		}
+		if hasData {
+			break
+		}
+	}
+
+	if !hasData {
+		return nil
+	}
+
+	defaultSchool, err := school.NewSchool(school.DefaultName)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO schools (id, name, created_at) VALUES ($1, $2, $3)`,
+		defaultSchool.ID, defaultSchool.Name, defaultSchool.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("tx.ExecContext error: %w", err)
+	}
+
+	for _, table := range tables {
+		_, err = tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET school_id = $1 WHERE school_id = ''`, table), defaultSchool.ID)
+		if err != nil {
+			return fmt.Errorf("tx.ExecContext error: %w", err)
+		}
+	}
+
+	log.Printf("Moved existing records to the %q school...", defaultSchool.Name)
+	return nil
+}
+
+// convertColumnsToBigInt returns statements that convert TEXT columns to
+// BIGINT columns. Both SQLite and PostgreSQL support these statements.
+func convertColumnsToBigInt(table string, columns ...string) []string {
+	var stmts []string
+	for _, column := range columns {
+		oldColumn := column + "_old"
+		stmts = append(stmts,
+			fmt.Sprintf(`ALTER TABLE %s RENAME COLUMN %s TO %s`, table, column, oldColumn),
+			fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s BIGINT NOT NULL DEFAULT 0`, table, column),
+			fmt.Sprintf(`UPDATE %s SET %s = CAST(%s AS BIGINT) WHERE %s <> ''`, table, column, oldColumn, oldColumn),
+			fmt.Sprintf(`ALTER TABLE %s DROP COLUMN %s`, table, oldColumn),
+		)
+	}
+	return stmts
+}
+
+// convertReportGeneratedAt converts the generatedAt field of the JSON reports
+// stored in table from string to int64.
+func convertReportGeneratedAt(ctx context.Context, tx *sql.Tx, table string) error {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`SELECT id, report FROM %s WHERE report IS NOT NULL`, table))
+	if err != nil {
+		return fmt.Errorf("tx.QueryContext error: %w", err)
+	}
+
+	reports := make(map[string]string)
+	for rows.Next() {
+		var id, report string
+		if err := rows.Scan(&id, &report); err != nil {
+			rows.Close()
+			return fmt.Errorf("rows.Scan error: %w", err)
+		}
+		reports[id] = report
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("rows.Err error: %w", err)
+	}
+
+	for id, reportJSON := range reports {
+		var report map[string]any
+		err := json.Unmarshal([]byte(reportJSON), &report)
+		if err != nil {
+			return fmt.Errorf("json.Unmarshal error: %w", err)
+		}
+
+		generatedAtStr, ok := report["generatedAt"].(string)
+		if !ok {
+			continue
+		}
+
+		report["generatedAt"], _ = strconv.ParseInt(generatedAtStr, 10, 64)
+		b, err := json.Marshal(report)
+		if err != nil {
+			return fmt.Errorf("json.Marshal error: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET report = $1 WHERE id = $2`, table), string(b), id)
+		if err != nil {
+			return fmt.Errorf("tx.ExecContext error: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// SchemaVersion returns the schema version of the database and the latest
+// schema version known to this binary.
+func SchemaVersion(ctx context.Context, sqlDB *sql.DB) (current int, latest int, err error) {
+	_, err = sqlDB.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		applied_at BIGINT NOT NULL
+	)`)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	err = sqlDB.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	return current, len(migrations), nil
+}
+
+// Migrate applies all pending migrations to sqlDB and returns them. Each
+// migration is applied in its own transaction. If dryRun is true, the pending
+// migrations are returned without being applied. An error is returned if the
+// database schema is newer than this binary.
+func Migrate(ctx context.Context, sqlDB *sql.DB, dryRun bool) ([]*db.MigrationInfo, error) {
+	currentVersion, latestVersion, err := SchemaVersion(ctx, sqlDB)
+	if err != nil {
+		return nil, err
+	}
+
+	if currentVersion > latestVersion {
+		return nil, fmt.Errorf("database schema version %d is newer than the latest known version %d, please upgrade SCOMP", currentVersion, latestVersion)
+	}
+
+	driver := driverName(sqlDB)
+	var pending []*db.MigrationInfo
+	for index := currentVersion; index < latestVersion; index++ {
+		m := migrations[index]
+		info := &db.MigrationInfo{
+			Version:     index + 1,
+			Description: m.description,
+		}
+
+		if !dryRun {
+			err = withTx(ctx, sqlDB, func(tx *sql.Tx) error {
+				for _, stmt := range m.stmts {
+					if _, err := tx.ExecContext(ctx, stmt); err != nil {
+						return err
+					}
+				}
+
+				if m.fn != nil {
+					if err := m.fn(ctx, tx, driver); err != nil {
+						return err
+					}
+				}
+
+				_, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, applied_at) VALUES ($1, $2)`, info.Version, time.Now().Unix())
+				return err
+			})
+			if err != nil {
+				return nil, fmt.Errorf("migration %d (%s) failed: %w", info.Version, info.Description, err)
+			}
+
+			log.Printf("Applied database migration %d (%s)...", info.Version, info.Description)
+		}
+
+		pending = append(pending, info)
+	}
+
+	return pending, nil
+}
@@ -0,0 +1,123 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/ukane-philemon/scomp/internal/school"
+)
+
+// openTestDB returns an SQLite database without migrations that is closed
+// when the test ends.
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	sqlDB, err := Open(context.Background(), DriverSQLite, filepath.Join(t.TempDir(), "scomp.db"))
+	if err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	return sqlDB
+}
+
+func TestMigrate(t *testing.T) {
+	ctx, sqlDB := context.Background(), openTestDB(t)
+
+	pending, err := Migrate(ctx, sqlDB, true)
+	if err != nil {
+		t.Fatalf("Migrate dry run error: %v", err)
+	}
+
+	if len(pending) != len(migrations) {
+		t.Fatalf("expected %d pending migrations, got %d", len(migrations), len(pending))
+	}
+
+	current, latest, err := SchemaVersion(ctx, sqlDB)
+	if err != nil {
+		t.Fatalf("SchemaVersion error: %v", err)
+	}
+
+	if current != 0 || latest != len(migrations) {
+		t.Fatalf("expected schema version 0 of %d after a dry run, got %d of %d", len(migrations), current, latest)
+	}
+
+	applied, err := Migrate(ctx, sqlDB, false)
+	if err != nil {
+		t.Fatalf("Migrate error: %v", err)
+	}
+
+	for i, migration := range applied {
+		if migration.Version != i+1 || migration.Description != pending[i].Description {
+			t.Fatalf("expected migration %d (%s), got %d (%s)", i+1, pending[i].Description, migration.Version, migration.Description)
+		}
+	}
+
+	applied, err = Migrate(ctx, sqlDB, false)
+	if err != nil || len(applied) != 0 {
+		t.Fatalf("expected no pending migrations, got %d, %v", len(applied), err)
+	}
+
+	// A database migrated by a newer binary is refused.
+	_, err = sqlDB.ExecContext(ctx, `INSERT INTO schema_migrations (version, applied_at) VALUES ($1, 0)`, len(migrations)+1)
+	if err != nil {
+		t.Fatalf("failed to insert schema version: %v", err)
+	}
+
+	_, err = Migrate(ctx, sqlDB, false)
+	if err == nil {
+		t.Fatal("Migrate accepted a database newer than the binary")
+	}
+}
+
+func TestMigrateConvertsTimestamps(t *testing.T) {
+	ctx, sqlDB := context.Background(), openTestDB(t)
+
+	// Apply the initial schema only, when timestamps were strings.
+	_, _, err := SchemaVersion(ctx, sqlDB)
+	if err != nil {
+		t.Fatalf("SchemaVersion error: %v", err)
+	}
+
+	stmts := append(append([]string(nil), migrations[0].stmts...),
+		`INSERT INTO schema_migrations (version, applied_at) VALUES (1, 0)`,
+		`INSERT INTO classes (id, name, subjects, report, created_at, last_updated_at) VALUES ('class', 'JSS 1', '[]', '{"generatedAt":"1700000000"}', '1600000000', '')`,
+	)
+	for _, stmt := range stmts {
+		if _, err := sqlDB.ExecContext(ctx, stmt); err != nil {
+			t.Fatalf("failed to apply the initial schema: %v", err)
+		}
+	}
+
+	_, err = Migrate(ctx, sqlDB, false)
+	if err != nil {
+		t.Fatalf("Migrate error: %v", err)
+	}
+
+	// Existing records are moved to the default school.
+	var schoolID string
+	err = sqlDB.QueryRowContext(ctx, `SELECT school_id FROM classes WHERE id = 'class'`).Scan(&schoolID)
+	if err != nil {
+		t.Fatalf("failed to read the school of the class: %v", err)
+	}
+
+	schoolInfo, err := NewSchoolRepository(ctx, sqlDB).School(schoolID)
+	if err != nil {
+		t.Fatalf("School error: %v", err)
+	}
+
+	if schoolInfo.Name != school.DefaultName {
+		t.Fatalf("expected the class to be moved to the default school, got %s", schoolInfo.Name)
+	}
+
+	classInfo, err := NewClassRepository(ctx, sqlDB).Class(schoolID, "class")
+	if err != nil {
+		t.Fatalf("Class error: %v", err)
+	}
+
+	if classInfo.CreatedAt != 1600000000 || classInfo.LastUpdatedAt != 0 || classInfo.Report.GeneratedAt != 1700000000 {
+		t.Fatalf("expected converted timestamps, got %d, %d and %d", classInfo.CreatedAt, classInfo.LastUpdatedAt, classInfo.Report.GeneratedAt)
+	}
+}
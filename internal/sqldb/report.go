@@ -0,0 +1,226 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ukane-philemon/scomp/internal/class"
+	"github.com/ukane-philemon/scomp/internal/db"
+	"github.com/ukane-philemon/scomp/internal/history"
+	"github.com/ukane-philemon/scomp/internal/report"
+	"github.com/ukane-philemon/scomp/internal/student"
+)
+
+// ReportStore implements report.Store.
+type ReportStore struct {
+	ctx context.Context
+	db  *sql.DB
+}
+
+// NewReportStore creates a new instance of *ReportStore.
+func NewReportStore(ctx context.Context, sqlDB *sql.DB) report.Store {
+	return &ReportStore{
+		ctx: ctx,
+		db:  sqlDB,
+	}
+}
+
+// CreateReports saves classReport and the reports of students as the next
+// version of the reports of classID and as the current reports of the class
+// and its students, and marks the class as reported in a single transaction.
+// Implements report.Store.
+func (rs *ReportStore) CreateReports(schoolID, classID, generatedBy string, studentsRevision int, classReport *class.ClassReport, students []*history.StudentReport) (*history.ReportVersion, error) {
+	var reportVersion *history.ReportVersion
+	err := withTx(rs.ctx, rs.db, func(tx *sql.Tx) error {
+		var latestVersion int
+		err := tx.QueryRowContext(rs.ctx, `SELECT COALESCE(MAX(version), 0) FROM report_versions WHERE class_id = $1 AND school_id = $2`,
+			classID, schoolID).Scan(&latestVersion)
+		if err != nil {
+			return fmt.Errorf("tx.QueryRowContext error: %w", err)
+		}
+
+		reportVersion, err = history.NewReportVersion(schoolID, classID, generatedBy, latestVersion+1, studentsRevision, classReport, students)
+		if err != nil {
+			return err
+		}
+
+		err = rs.saveReports(tx, schoolID, class.StateComputing, reportVersion)
+		if err != nil {
+			return err
+		}
+
+		classReportJSON, err := json.Marshal(reportVersion.ClassReport)
+		if err != nil {
+			return fmt.Errorf("json.Marshal error: %w", err)
+		}
+
+		studentsJSON, err := json.Marshal(reportVersion.Students)
+		if err != nil {
+			return fmt.Errorf("json.Marshal error: %w", err)
+		}
+
+		_, err = tx.ExecContext(rs.ctx, `INSERT INTO report_versions (`+reportVersionColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+			reportVersion.ID, reportVersion.SchoolID, reportVersion.ClassID, reportVersion.Version, reportVersion.GeneratedBy,
+			reportVersion.StudentsRevision, string(classReportJSON), string(studentsJSON), reportVersion.GeneratedAt)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return fmt.Errorf("%w: another report of class with ID %s is being saved", db.ErrorInvalidRequest, classID)
+			}
+			return fmt.Errorf("tx.ExecContext error: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return reportVersion, nil
+}
+
+// RestoreReports saves the reports of reportVersion as the current reports of
+// its class and the students of the class and marks the class as reported in
+// a single transaction.
+// Implements report.Store.
+func (rs *ReportStore) RestoreReports(schoolID, fromState string, reportVersion *history.ReportVersion) error {
+	if schoolID == "" || reportVersion.ClassID == "" {
+		return fmt.Errorf("%w: missing schoolID or classID", db.ErrorInvalidRequest)
+	}
+
+	return withTx(rs.ctx, rs.db, func(tx *sql.Tx) error {
+		return rs.saveReports(tx, schoolID, fromState, reportVersion)
+	})
+}
+
+// saveReports saves the reports of reportVersion as the current reports of its
+// class and the students of the class in tx if the class is in the fromState
+// state and its students did not change since reportVersion was computed.
+func (rs *ReportStore) saveReports(tx *sql.Tx, schoolID, fromState string, reportVersion *history.ReportVersion) error {
+	classID := reportVersion.ClassID
+	classReportJSON, err := json.Marshal(reportVersion.ClassReport)
+	if err != nil {
+		return fmt.Errorf("json.Marshal error: %w", err)
+	}
+
+	res, err := tx.ExecContext(rs.ctx, `UPDATE classes SET report = $1, current_report_version = $2, state = $3, report_stale = FALSE
+		WHERE id = $4 AND school_id = $5 AND state = $6 AND students_revision = $7`,
+		string(classReportJSON), reportVersion.Version, class.StateReported, classID, schoolID, fromState, reportVersion.StudentsRevision)
+	if err != nil {
+		return fmt.Errorf("tx.ExecContext error: %w", err)
+	}
+
+	nUpdated, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("res.RowsAffected error: %w", err)
+	}
+
+	if nUpdated == 0 {
+		var nClass int
+		err = tx.QueryRowContext(rs.ctx, `SELECT COUNT(*) FROM classes WHERE id = $1 AND school_id = $2 AND state = $3`, classID, schoolID, fromState).Scan(&nClass)
+		if err != nil {
+			return fmt.Errorf("tx.QueryRowContext error: %w", err)
+		}
+
+		if nClass == 0 {
+			return fmt.Errorf("%w: report for class with ID %s was not updated, the class is not %s", db.ErrorInvalidRequest, classID, fromState)
+		}
+		return report.ErrorStudentsChanged
+	}
+
+	for _, studentReport := range reportVersion.Students {
+		reportJSON, err := json.Marshal(studentReport.Report)
+		if err != nil {
+			return fmt.Errorf("json.Marshal error: %w", err)
+		}
+
+		res, err := tx.ExecContext(rs.ctx, `UPDATE students SET report = $1 WHERE id = $2 AND school_id = $3 AND class_id = $4`,
+			string(reportJSON), studentReport.StudentID, schoolID, classID)
+		if err != nil {
+			return fmt.Errorf("tx.ExecContext error: %w", err)
+		}
+
+		nUpdated, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("res.RowsAffected error: %w", err)
+		}
+
+		if nUpdated == 0 {
+			return fmt.Errorf("student with ID %s was not updated", studentReport.StudentID)
+		}
+	}
+
+	return nil
+}
+
+// IncompleteReports returns the reported classes of every school whose current
+// report does not match the reports of all their students.
+// Implements report.Store.
+func (rs *ReportStore) IncompleteReports() ([]*class.Class, error) {
+	rows, err := rs.db.QueryContext(rs.ctx, `SELECT `+classColumns+` FROM classes WHERE state = $1 AND report IS NOT NULL`, class.StateReported)
+	if err != nil {
+		return nil, fmt.Errorf("db.QueryContext error: %w", err)
+	}
+	defer rows.Close()
+
+	var classes []*class.Class
+	for rows.Next() {
+		classInfo, err := scanClass(rows)
+		if err != nil {
+			return nil, err
+		}
+		classes = append(classes, classInfo)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err error: %w", err)
+	}
+	// SQLite databases have a single connection, which must be released
+	// before the students are queried.
+	rows.Close()
+
+	var incompleteClasses []*class.Class
+	for _, classInfo := range classes {
+		complete, err := rs.studentReportsMatch(classInfo)
+		if err != nil {
+			return nil, err
+		}
+
+		if !complete {
+			incompleteClasses = append(incompleteClasses, classInfo)
+		}
+	}
+
+	return incompleteClasses, nil
+}
+
+// studentReportsMatch checks that the reports of all the students of
+// classInfo match its report.
+func (rs *ReportStore) studentReportsMatch(classInfo *class.Class) (bool, error) {
+	rows, err := rs.db.QueryContext(rs.ctx, `SELECT report FROM students WHERE class_id = $1 AND school_id = $2`, classInfo.ID, classInfo.SchoolID)
+	if err != nil {
+		return false, fmt.Errorf("db.QueryContext error: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var reportJSON string
+		err = rows.Scan(&reportJSON)
+		if err != nil {
+			return false, fmt.Errorf("rows.Scan error: %w", err)
+		}
+
+		var studentReport *student.Report
+		err = json.Unmarshal([]byte(reportJSON), &studentReport)
+		if err != nil {
+			return false, fmt.Errorf("json.Unmarshal error: %w", err)
+		}
+
+		if !report.Matches(classInfo.Report, studentReport) {
+			return false, nil
+		}
+	}
+
+	return true, rows.Err()
+}
@@ -0,0 +1,207 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/ukane-philemon/scomp/internal/class"
+	"github.com/ukane-philemon/scomp/internal/db"
+	"github.com/ukane-philemon/scomp/internal/history"
+	"github.com/ukane-philemon/scomp/internal/report"
+	"github.com/ukane-philemon/scomp/internal/student"
+)
+
+// newReportClass creates a computing class with a student for every name
+// in studentNames and returns the class and the IDs of the students.
+func newReportClass(t *testing.T, sqlDB *sql.DB, studentNames ...string) (*class.Class, []string) {
+	t.Helper()
+
+	ctx := context.Background()
+	classRepo := NewClassRepository(ctx, sqlDB)
+	studentRepo := NewStudentRepository(ctx, sqlDB)
+
+	subjects := []*class.Subject{{Name: "Maths", MaxScore: 100, Weight: 1}}
+	classID, err := classRepo.Create(testSchoolID, "JSS1", "", class.ReportSettings{}, subjects)
+	if err != nil {
+		t.Fatalf("ClassRepository.Create error: %v", err)
+	}
+
+	var studentIDs []string
+	for i, name := range studentNames {
+		studentID, err := studentRepo.Create(testSchoolID, classID, name, subjects, []*student.SubjectScore{{Name: "Maths", Score: 50 + i}})
+		if err != nil {
+			t.Fatalf("StudentRepository.Create error: %v", err)
+		}
+		studentIDs = append(studentIDs, studentID)
+	}
+
+	err = classRepo.Transition(testSchoolID, classID, class.StateOpen, class.StateComputing)
+	if err != nil {
+		t.Fatalf("ClassRepository.Transition error: %v", err)
+	}
+
+	classInfo, err := classRepo.Class(testSchoolID, classID)
+	if err != nil {
+		t.Fatalf("ClassRepository.Class error: %v", err)
+	}
+
+	return classInfo, studentIDs
+}
+
+// studentReports returns reports generated at generatedAt for studentIDs,
+// ranked in order.
+func studentReports(generatedAt int64, studentIDs ...string) []*history.StudentReport {
+	var reports []*history.StudentReport
+	for i, studentID := range studentIDs {
+		reports = append(reports, &history.StudentReport{
+			StudentID: studentID,
+			Report: &student.Report{
+				Class:       &student.StudentClassReport{Position: i + 1},
+				GeneratedAt: generatedAt,
+			},
+		})
+	}
+	return reports
+}
+
+func TestCreateReportsSavesEverythingOrNothing(t *testing.T) {
+	ctx := context.Background()
+	sqlDB := newTestDB(t)
+	classInfo, studentIDs := newReportClass(t, sqlDB, "Ada", "Bola")
+	reportStore := NewReportStore(ctx, sqlDB)
+	classRepo := NewClassRepository(ctx, sqlDB)
+	studentRepo := NewStudentRepository(ctx, sqlDB)
+	versionRepo := NewReportVersionRepository(ctx, sqlDB)
+
+	// The report of the first student is saved before the missing student
+	// fails the transaction.
+	students := studentReports(1, studentIDs[0], "missing")
+	_, err := reportStore.CreateReports(testSchoolID, classInfo.ID, "", classInfo.StudentsRevision, &class.ClassReport{GeneratedAt: 1}, students)
+	if err == nil {
+		t.Fatal("CreateReports saved the reports of a missing student")
+	}
+
+	gotClass, err := classRepo.Class(testSchoolID, classInfo.ID)
+	if err != nil {
+		t.Fatalf("ClassRepository.Class error: %v", err)
+	}
+	if gotClass.Report != nil || gotClass.CurrentReportVersion != 0 {
+		t.Fatalf("class was updated by a failed CreateReports: report %v, version %d",
+			gotClass.Report, gotClass.CurrentReportVersion)
+	}
+
+	gotStudent, err := studentRepo.Student(testSchoolID, classInfo.ID, studentIDs[0])
+	if err != nil {
+		t.Fatalf("StudentRepository.Student error: %v", err)
+	}
+	if gotStudent.Report.Class != nil {
+		t.Fatal("student report was updated by a failed CreateReports")
+	}
+
+	versions, err := versionRepo.ReportVersions(testSchoolID, classInfo.ID)
+	if err != nil {
+		t.Fatalf("ReportVersions error: %v", err)
+	}
+	if len(versions) != 0 {
+		t.Fatalf("failed CreateReports saved %d report versions", len(versions))
+	}
+
+	// The same reports without the missing student are saved.
+	students = studentReports(2, studentIDs...)
+	reportVersion, err := reportStore.CreateReports(testSchoolID, classInfo.ID, "", classInfo.StudentsRevision, &class.ClassReport{GeneratedAt: 2}, students)
+	if err != nil {
+		t.Fatalf("CreateReports error: %v", err)
+	}
+	if reportVersion.Version != 1 {
+		t.Fatalf("expected report version 1, got %d", reportVersion.Version)
+	}
+
+	gotClass, err = classRepo.Class(testSchoolID, classInfo.ID)
+	if err != nil {
+		t.Fatalf("ClassRepository.Class error: %v", err)
+	}
+	if gotClass.CurrentReportVersion != 1 || gotClass.Report.GeneratedAt != 2 {
+		t.Fatalf("class report was not saved: version %d", gotClass.CurrentReportVersion)
+	}
+
+	for _, studentID := range studentIDs {
+		gotStudent, err := studentRepo.Student(testSchoolID, classInfo.ID, studentID)
+		if err != nil {
+			t.Fatalf("StudentRepository.Student error: %v", err)
+		}
+		if gotStudent.Report.GeneratedAt != 2 || gotStudent.Report.Class == nil {
+			t.Fatalf("report of student %s was not saved", studentID)
+		}
+	}
+
+	// The class is reported, a new report is only saved after it is
+	// computing again.
+	if gotClass.State != class.StateReported {
+		t.Fatalf("expected a reported class, got %s", gotClass.State)
+	}
+
+	_, err = reportStore.CreateReports(testSchoolID, classInfo.ID, "", classInfo.StudentsRevision, &class.ClassReport{GeneratedAt: 3}, studentReports(3, studentIDs...))
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for a reported class, got %v", err)
+	}
+}
+
+func TestRestoreReportsRollsBack(t *testing.T) {
+	ctx := context.Background()
+	sqlDB := newTestDB(t)
+	classInfo, studentIDs := newReportClass(t, sqlDB, "Ada", "Bola")
+	reportStore := NewReportStore(ctx, sqlDB)
+	studentRepo := NewStudentRepository(ctx, sqlDB)
+
+	reportVersion, err := reportStore.CreateReports(testSchoolID, classInfo.ID, "", classInfo.StudentsRevision, &class.ClassReport{GeneratedAt: 1}, studentReports(1, studentIDs...))
+	if err != nil {
+		t.Fatalf("CreateReports error: %v", err)
+	}
+
+	// A version whose second student no longer exists.
+	reportVersion.ClassReport = &class.ClassReport{GeneratedAt: 2}
+	reportVersion.Students = studentReports(2, studentIDs[0], "missing")
+	err = reportStore.RestoreReports(testSchoolID, class.StateReported, reportVersion)
+	if err == nil {
+		t.Fatal("RestoreReports saved the reports of a missing student")
+	}
+
+	gotStudent, err := studentRepo.Student(testSchoolID, classInfo.ID, studentIDs[0])
+	if err != nil {
+		t.Fatalf("StudentRepository.Student error: %v", err)
+	}
+	if gotStudent.Report.GeneratedAt != 1 {
+		t.Fatal("student report was updated by a failed RestoreReports")
+	}
+
+	gotClass, err := NewClassRepository(ctx, sqlDB).Class(testSchoolID, classInfo.ID)
+	if err != nil {
+		t.Fatalf("ClassRepository.Class error: %v", err)
+	}
+	if gotClass.Report.GeneratedAt != 1 {
+		t.Fatal("class report was updated by a failed RestoreReports")
+	}
+}
+
+func TestCreateReportsRejectsChangedStudents(t *testing.T) {
+	ctx := context.Background()
+	sqlDB := newTestDB(t)
+	classInfo, studentIDs := newReportClass(t, sqlDB, "Ada")
+	reportStore := NewReportStore(ctx, sqlDB)
+	versionRepo := NewReportVersionRepository(ctx, sqlDB)
+
+	_, err := reportStore.CreateReports(testSchoolID, classInfo.ID, "", classInfo.StudentsRevision-1, &class.ClassReport{GeneratedAt: 1}, studentReports(1, studentIDs...))
+	if !errors.Is(err, report.ErrorStudentsChanged) {
+		t.Fatalf("expected report.ErrorStudentsChanged, got %v", err)
+	}
+
+	versions, err := versionRepo.ReportVersions(testSchoolID, classInfo.ID)
+	if err != nil {
+		t.Fatalf("ReportVersions error: %v", err)
+	}
+	if len(versions) != 0 {
+		t.Fatalf("failed CreateReports saved %d report versions", len(versions))
+	}
+}
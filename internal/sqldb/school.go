@@ -0,0 +1,59 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/ukane-philemon/scomp/internal/db"
+	"github.com/ukane-philemon/scomp/internal/school"
+)
+
+// SchoolRepository implements school.Repository.
+type SchoolRepository struct {
+	ctx context.Context
+	db  *sql.DB
+}
+
+// NewSchoolRepository creates a new instance of *SchoolRepository.
+func NewSchoolRepository(ctx context.Context, sqlDB *sql.DB) school.Repository {
+	return &SchoolRepository{
+		ctx: ctx,
+		db:  sqlDB,
+	}
+}
+
+// Create implements school.Repository.
+func (sr *SchoolRepository) Create(name string) (string, error) {
+	schoolInfo, err := school.NewSchool(name)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = sr.db.ExecContext(sr.ctx, `INSERT INTO schools (id, name, created_at) VALUES ($1, $2, $3)`,
+		schoolInfo.ID, schoolInfo.Name, schoolInfo.CreatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return "", fmt.Errorf("%w: school name %s already exists", db.ErrorInvalidRequest, name)
+		}
+		return "", fmt.Errorf("db.ExecContext error: %w", err)
+	}
+
+	return schoolInfo.ID, nil
+}
+
+// School implements school.Repository.
+func (sr *SchoolRepository) School(schoolID string) (*school.School, error) {
+	schoolInfo := new(school.School)
+	err := sr.db.QueryRowContext(sr.ctx, `SELECT id, name, created_at FROM schools WHERE id = $1`, schoolID).
+		Scan(&schoolInfo.ID, &schoolInfo.Name, &schoolInfo.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: no record found for school with ID %s", db.ErrorInvalidRequest, schoolID)
+		}
+		return nil, fmt.Errorf("db.QueryRowContext error: %w", err)
+	}
+
+	return schoolInfo, nil
+}
@@ -0,0 +1,171 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ukane-philemon/scomp/internal/db"
+	"github.com/ukane-philemon/scomp/internal/session"
+)
+
+const sessionColumns = `id, admin_id, hashed_refresh_token, previous_hashed_refresh_token, user_agent, ip_address, created_at, last_used_at, expires_at, revoked_at`
+
+// SessionRepository implements session.Repository.
+type SessionRepository struct {
+	ctx context.Context
+	db  *sql.DB
+}
+
+// NewSessionRepository creates a new instance of *SessionRepository.
+func NewSessionRepository(ctx context.Context, sqlDB *sql.DB) session.Repository {
+	return &SessionRepository{
+		ctx: ctx,
+		db:  sqlDB,
+	}
+}
+
+// Create implements session.Repository.
+func (sr *SessionRepository) Create(adminID string, client *session.ClientInfo) (*session.Session, string, error) {
+	sessionInfo, refreshToken, err := session.NewSession(adminID, client)
+	if err != nil {
+		return nil, "", err
+	}
+
+	_, err = sr.db.ExecContext(sr.ctx, `INSERT INTO sessions (`+sessionColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		sessionInfo.ID, sessionInfo.AdminID, sessionInfo.HashedRefreshToken, sessionInfo.PreviousHashedRefreshToken, sessionInfo.UserAgent,
+		sessionInfo.IPAddress, sessionInfo.CreatedAt, sessionInfo.LastUsedAt, sessionInfo.ExpiresAt, sessionInfo.RevokedAt)
+	if err != nil {
+		return nil, "", fmt.Errorf("db.ExecContext error: %w", err)
+	}
+
+	return sessionInfo, refreshToken, nil
+}
+
+// Refresh implements session.Repository.
+func (sr *SessionRepository) Refresh(refreshToken string) (*session.Session, string, error) {
+	sessionID, hashedRefreshToken, err := session.ParseRefreshToken(refreshToken)
+	if err != nil {
+		return nil, "", err
+	}
+
+	newRefreshToken, newHashedRefreshToken, err := session.NewRefreshToken(sessionID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	row := sr.db.QueryRowContext(sr.ctx, `UPDATE sessions SET hashed_refresh_token = $1, previous_hashed_refresh_token = $2, last_used_at = $3, expires_at = $4
+		WHERE id = $5 AND hashed_refresh_token = $2 AND revoked_at = 0 AND expires_at > $3 RETURNING `+sessionColumns,
+		newHashedRefreshToken, hashedRefreshToken, now.Unix(), now.Add(session.RefreshTokenExpiry).Unix(), sessionID)
+	sessionInfo, err := scanSession(row)
+	if err == nil {
+		return sessionInfo, newRefreshToken, nil
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, "", err
+	}
+
+	// Revoke the session if a rotated refresh token was reused, it may have
+	// been stolen.
+	_, err = sr.db.ExecContext(sr.ctx, `UPDATE sessions SET revoked_at = $1 WHERE id = $2 AND previous_hashed_refresh_token = $3 AND revoked_at = 0`,
+		now.Unix(), sessionID, hashedRefreshToken)
+	if err != nil {
+		return nil, "", fmt.Errorf("db.ExecContext error: %w", err)
+	}
+
+	return nil, "", fmt.Errorf("%w: refresh token is invalid or has expired, please login again", db.ErrorInvalidRequest)
+}
+
+// IsActive implements session.Repository.
+func (sr *SessionRepository) IsActive(sessionID string) (bool, error) {
+	var active bool
+	err := sr.db.QueryRowContext(sr.ctx, `SELECT EXISTS (SELECT 1 FROM sessions WHERE id = $1 AND revoked_at = 0 AND expires_at > $2)`,
+		sessionID, time.Now().Unix()).Scan(&active)
+	if err != nil {
+		return false, fmt.Errorf("db.QueryRowContext error: %w", err)
+	}
+
+	return active, nil
+}
+
+// Revoke implements session.Repository.
+func (sr *SessionRepository) Revoke(adminID, sessionID string) error {
+	now := time.Now().Unix()
+	res, err := sr.db.ExecContext(sr.ctx, `UPDATE sessions SET revoked_at = $1 WHERE id = $2 AND admin_id = $3 AND revoked_at = 0 AND expires_at > $1`,
+		now, sessionID, adminID)
+	if err != nil {
+		return fmt.Errorf("db.ExecContext error: %w", err)
+	}
+
+	nRevoked, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("res.RowsAffected error: %w", err)
+	}
+
+	if nRevoked == 0 {
+		return fmt.Errorf("%w: no active session found with ID %s", db.ErrorInvalidRequest, sessionID)
+	}
+
+	return nil
+}
+
+// RevokeAll implements session.Repository.
+func (sr *SessionRepository) RevokeAll(adminID string) (int, error) {
+	now := time.Now().Unix()
+	res, err := sr.db.ExecContext(sr.ctx, `UPDATE sessions SET revoked_at = $1 WHERE admin_id = $2 AND revoked_at = 0 AND expires_at > $1`,
+		now, adminID)
+	if err != nil {
+		return 0, fmt.Errorf("db.ExecContext error: %w", err)
+	}
+
+	nRevoked, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("res.RowsAffected error: %w", err)
+	}
+
+	return int(nRevoked), nil
+}
+
+// Sessions implements session.Repository.
+func (sr *SessionRepository) Sessions(adminID string) ([]*session.Session, error) {
+	rows, err := sr.db.QueryContext(sr.ctx, `SELECT `+sessionColumns+` FROM sessions WHERE admin_id = $1 AND revoked_at = 0 AND expires_at > $2
+		ORDER BY last_used_at DESC`, adminID, time.Now().Unix())
+	if err != nil {
+		return nil, fmt.Errorf("db.QueryContext error: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []*session.Session
+	for rows.Next() {
+		sessionInfo, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, sessionInfo)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows.Err error: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// scanSession scans a session row. sql.ErrNoRows is returned as is.
+func scanSession(row rowScanner) (*session.Session, error) {
+	s := new(session.Session)
+	err := row.Scan(&s.ID, &s.AdminID, &s.HashedRefreshToken, &s.PreviousHashedRefreshToken, &s.UserAgent,
+		&s.IPAddress, &s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt, &s.RevokedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("row.Scan error: %w", err)
+	}
+
+	return s, nil
+}
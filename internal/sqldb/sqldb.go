@@ -0,0 +1,124 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// Supported SQL drivers.
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+)
+
+// postgresUniqueViolation is the PostgreSQL error code for unique constraint
+// violations.
+const postgresUniqueViolation = "23505"
+
+// Open connects to the SQL database specified by driver and dataSource. Use
+// Migrate to apply pending schema migrations. For DriverSQLite, dataSource is
+// the path to the database file.
+func Open(ctx context.Context, driver, dataSource string) (*sql.DB, error) {
+	if dataSource == "" {
+		return nil, errors.New("missing database data source")
+	}
+
+	switch driver {
+	case DriverSQLite:
+		dataSource = fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)", dataSource)
+	case DriverPostgres:
+	default:
+		return nil, fmt.Errorf("unsupported SQL driver %q", driver)
+	}
+
+	sqlDB, err := sql.Open(driver, dataSource)
+	if err != nil {
+		return nil, fmt.Errorf("sql.Open error: %w", err)
+	}
+
+	if driver == DriverSQLite {
+		// SQLite allows only one writer at a time.
+		sqlDB.SetMaxOpenConns(1)
+	}
+
+	err = sqlDB.PingContext(ctx)
+	if err != nil {
+		sqlDB.Close()
+		return nil, fmt.Errorf("sqlDB.PingContext error: %w", err)
+	}
+
+	log.Printf("%s database has been connected and pinged successfully...", driver)
+
+	return sqlDB, nil
+}
+
+// Shutdown attempts to close sqlDB.
+func Shutdown(sqlDB *sql.DB) error {
+	err := sqlDB.Close()
+	if err != nil {
+		return fmt.Errorf("sqlDB.Close error: %w", err)
+	}
+
+	log.Println("Database has been shutdown successfully...")
+
+	return nil
+}
+
+// driverName returns the name of the driver of sqlDB, one of DriverSQLite or
+// DriverPostgres.
+func driverName(sqlDB *sql.DB) string {
+	switch sqlDB.Driver().(type) {
+	case *sqlite.Driver:
+		return DriverSQLite
+	case *pq.Driver:
+		return DriverPostgres
+	default:
+		return ""
+	}
+}
+
+// isUniqueViolation checks if err was caused by a unique constraint.
+func isUniqueViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE || sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == postgresUniqueViolation
+	}
+
+	return false
+}
+
+// withTx runs fn in a database transaction. The transaction is rolled back if
+// fn returns an error.
+func withTx(ctx context.Context, sqlDB *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := sqlDB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("sqlDB.BeginTx error: %w", err)
+	}
+
+	err = fn(tx)
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			log.Printf("tx.Rollback error: %v", rbErr)
+		}
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("tx.Commit error: %w", err)
+	}
+
+	return nil
+}
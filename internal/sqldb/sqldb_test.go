@@ -0,0 +1,62 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// newTestDB returns a migrated SQLite database that is closed when the test
+// ends.
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	ctx := context.Background()
+	sqlDB, err := Open(ctx, DriverSQLite, filepath.Join(t.TempDir(), "scomp.db"))
+	if err != nil {
+		t.Fatalf("Open error: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	_, err = Migrate(ctx, sqlDB, false)
+	if err != nil {
+		t.Fatalf("Migrate error: %v", err)
+	}
+
+	return sqlDB
+}
+
+func TestIsUniqueViolation(t *testing.T) {
+	sqlDB := newTestDB(t)
+	ctx := context.Background()
+
+	insertAdmin := func(id string, username any) error {
+		_, err := sqlDB.ExecContext(ctx, `INSERT INTO admins (id, username, hashed_password, created_at) VALUES ($1, $2, 'hash', 0)`, id, username)
+		return err
+	}
+
+	err := insertAdmin("1", "admin")
+	if err != nil {
+		t.Fatalf("insert error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "unique column", err: insertAdmin("2", "admin"), want: true},
+		{name: "primary key", err: insertAdmin("1", "another admin"), want: true},
+		{name: "not null column", err: insertAdmin("3", nil), want: false},
+		{name: "other error", err: errors.New("other error"), want: false},
+		{name: "no error", err: nil, want: false},
+	}
+
+	for _, test := range tests {
+		if got := isUniqueViolation(test.err); got != test.want {
+			t.Errorf("%s: expected %v for %v, got %v", test.name, test.want, test.err, got)
+		}
+	}
+}
@@ -0,0 +1,329 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ukane-philemon/scomp/internal/class"
+	"github.com/ukane-philemon/scomp/internal/db"
+	"github.com/ukane-philemon/scomp/internal/student"
+)
+
+const studentColumns = `id, school_id, name, class_id, report, created_at`
+
+// StudentRepository implements student.Repository.
+type StudentRepository struct {
+	ctx context.Context
+	db  *sql.DB
+}
+
+// NewStudentRepository creates a new instance of *StudentRepository.
+func NewStudentRepository(ctx context.Context, sqlDB *sql.DB) student.Repository {
+	return &StudentRepository{
+		ctx: ctx,
+		db:  sqlDB,
+	}
+}
+
+// Create adds a students record. Returns db.ErrorInvalidRequest if the class
+// is not open or studentName already exists for classID.
+// Implements student.Repository.
+func (sr *StudentRepository) Create(schoolID, classID, studentName string, classSubjects []*class.Subject, subjectScores []*student.SubjectScore) (string, error) {
+	studentInfo, err := student.NewStudent(schoolID, classID, studentName, classSubjects, subjectScores)
+	if err != nil {
+		return "", err
+	}
+
+	reportJSON, err := json.Marshal(studentInfo.Report)
+	if err != nil {
+		return "", fmt.Errorf("json.Marshal error: %w", err)
+	}
+
+	err = withTx(sr.ctx, sr.db, func(tx *sql.Tx) error {
+		err := sr.studentsChanged(tx, schoolID, classID, class.CheckCanChangeStudents)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(sr.ctx, `INSERT INTO students (id, school_id, name, class_id, report, created_at) VALUES ($1, $2, $3, $4, $5, $6)`,
+			studentInfo.ID, studentInfo.SchoolID, studentInfo.Name, studentInfo.ClassID, string(reportJSON), studentInfo.CreatedAt)
+		if err != nil {
+			if isUniqueViolation(err) {
+				return fmt.Errorf("%w: student %s already exists in this class", db.ErrorInvalidRequest, studentName)
+			}
+			return fmt.Errorf("tx.ExecContext error: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return studentInfo.ID, nil
+}
+
+// Student returns the students that match provided arguments.
+// Implements student.Repository.
+func (sr *StudentRepository) Student(schoolID, classID, studentID string) (*student.Student, error) {
+	if schoolID == "" || classID == "" || studentID == "" {
+		return nil, fmt.Errorf("%w: missing required argument(s)", db.ErrorInvalidRequest)
+	}
+
+	row := sr.db.QueryRowContext(sr.ctx, `SELECT `+studentColumns+` FROM students WHERE id = $1 AND school_id = $2 AND class_id = $3`,
+		studentID, schoolID, classID)
+	studentInfo, err := scanStudent(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: no record found for student with ID %s", db.ErrorInvalidRequest, studentID)
+		}
+		return nil, err
+	}
+
+	return studentInfo, nil
+}
+
+// Students returns all the students that match the provided classID.
+// Implements student.Repository.
+func (sr *StudentRepository) Students(schoolID, classID string) ([]*student.Student, error) {
+	if schoolID == "" || classID == "" {
+		return nil, fmt.Errorf("%w: missing schoolID or classID", db.ErrorInvalidRequest)
+	}
+
+	rows, err := sr.db.QueryContext(sr.ctx, `SELECT `+studentColumns+` FROM students WHERE school_id = $1 AND class_id = $2`, schoolID, classID)
+	if err != nil {
+		return nil, fmt.Errorf("db.QueryContext error: %w", err)
+	}
+	defer rows.Close()
+
+	var students []*student.Student
+	for rows.Next() {
+		studentInfo, err := scanStudent(rows)
+		if err != nil {
+			return nil, err
+		}
+		students = append(students, studentInfo)
+	}
+
+	return students, rows.Err()
+}
+
+// StudentScores returns a map of student ID to their subject scores.
+// Implements student.Repository.
+func (sr *StudentRepository) StudentScores(schoolID, classID string) (map[string][]*student.SubjectScore, error) {
+	students, err := sr.Students(schoolID, classID)
+	if err != nil {
+		return nil, err
+	}
+
+	studentsMap := make(map[string][]*student.SubjectScore, len(students))
+	for _, studentInfo := range students {
+		var scores []*student.SubjectScore
+		for _, r := range studentInfo.Report.Subjects {
+			scores = append(scores, r.SubjectScore)
+		}
+		studentsMap[studentInfo.ID] = scores
+	}
+
+	return studentsMap, nil
+}
+
+func scanStudent(row rowScanner) (*student.Student, error) {
+	var reportJSON string
+	studentInfo := new(student.Student)
+	err := row.Scan(&studentInfo.ID, &studentInfo.SchoolID, &studentInfo.Name, &studentInfo.ClassID, &reportJSON, &studentInfo.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("row.Scan error: %w", err)
+	}
+
+	err = json.Unmarshal([]byte(reportJSON), &studentInfo.Report)
+	if err != nil {
+		return nil, fmt.Errorf("json.Unmarshal error: %w", err)
+	}
+
+	return studentInfo, nil
+}
+
+// UpdateScores replaces the subject scores of studentID with subjectScores and
+// clears the class report of the student.
+// Implements student.Repository.
+func (sr *StudentRepository) UpdateScores(schoolID, classID, studentID string, classSubjects []*class.Subject, subjectScores []*student.SubjectScore) error {
+	report, err := student.NewReport(classSubjects, subjectScores)
+	if err != nil {
+		return err
+	}
+
+	reportJSON, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("json.Marshal error: %w", err)
+	}
+
+	return withTx(sr.ctx, sr.db, func(tx *sql.Tx) error {
+		err := sr.studentsChanged(tx, schoolID, classID, class.CheckCanChangeStudents)
+		if err != nil {
+			return err
+		}
+
+		return sr.updateStudent(tx, schoolID, classID, studentID, `report = $4`, string(reportJSON))
+	})
+}
+
+// Rename changes the name of studentID to studentName. Returns
+// db.ErrorInvalidRequest if studentName already exists for classID.
+// Implements student.Repository.
+func (sr *StudentRepository) Rename(schoolID, classID, studentID, studentName string) error {
+	if studentName == "" {
+		return fmt.Errorf("%w: missing student name", db.ErrorInvalidRequest)
+	}
+
+	return withTx(sr.ctx, sr.db, func(tx *sql.Tx) error {
+		err := sr.studentsChanged(tx, schoolID, classID, class.CheckCanChangeStudents)
+		if err != nil {
+			return err
+		}
+
+		err = sr.updateStudent(tx, schoolID, classID, studentID, `name = $4`, studentName)
+		if isUniqueViolation(err) {
+			return fmt.Errorf("%w: student %s already exists in this class", db.ErrorInvalidRequest, studentName)
+		}
+		return err
+	})
+}
+
+// Remove deletes the record of studentID.
+// Implements student.Repository.
+func (sr *StudentRepository) Remove(schoolID, classID, studentID string) error {
+	if schoolID == "" || classID == "" || studentID == "" {
+		return fmt.Errorf("%w: missing required argument(s)", db.ErrorInvalidRequest)
+	}
+
+	return withTx(sr.ctx, sr.db, func(tx *sql.Tx) error {
+		err := sr.studentsChanged(tx, schoolID, classID, class.CheckCanChangeStudents)
+		if err != nil {
+			return err
+		}
+
+		res, err := tx.ExecContext(sr.ctx, `DELETE FROM students WHERE id = $1 AND school_id = $2 AND class_id = $3`, studentID, schoolID, classID)
+		if err != nil {
+			return fmt.Errorf("tx.ExecContext error: %w", err)
+		}
+
+		nDeleted, err := res.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("res.RowsAffected error: %w", err)
+		}
+
+		if nDeleted == 0 {
+			return fmt.Errorf("%w: no record found for student with ID %s", db.ErrorInvalidRequest, studentID)
+		}
+
+		return nil
+	})
+}
+
+// Move moves studentID from classID to toClassID with subjectScores and
+// clears the class report of the student. Returns db.ErrorInvalidRequest if
+// toClassID is not open or the name of the student already exists for
+// toClassID.
+// Implements student.Repository.
+func (sr *StudentRepository) Move(schoolID, classID, studentID, toClassID string, toClassSubjects []*class.Subject, subjectScores []*student.SubjectScore) error {
+	if toClassID == "" {
+		return fmt.Errorf("%w: missing toClassID", db.ErrorInvalidRequest)
+	}
+
+	report, err := student.NewReport(toClassSubjects, subjectScores)
+	if err != nil {
+		return err
+	}
+
+	reportJSON, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("json.Marshal error: %w", err)
+	}
+
+	return withTx(sr.ctx, sr.db, func(tx *sql.Tx) error {
+		err := sr.studentsChanged(tx, schoolID, classID, class.CheckCanChangeStudents)
+		if err != nil {
+			return err
+		}
+
+		err = sr.studentsChanged(tx, schoolID, toClassID, class.CheckCanChangeStudents)
+		if err != nil {
+			return err
+		}
+
+		err = sr.updateStudent(tx, schoolID, classID, studentID, `class_id = $4, report = $5`, toClassID, string(reportJSON))
+		if isUniqueViolation(err) {
+			return fmt.Errorf("%w: a student with the same name already exists in class %s", db.ErrorInvalidRequest, toClassID)
+		}
+		return err
+	})
+}
+
+// updateStudent sets the columns of the student that match studentID in tx.
+// The placeholders of set start at $4.
+func (sr *StudentRepository) updateStudent(tx *sql.Tx, schoolID, classID, studentID, set string, args ...any) error {
+	if schoolID == "" || classID == "" || studentID == "" {
+		return fmt.Errorf("%w: missing required argument(s)", db.ErrorInvalidRequest)
+	}
+
+	args = append([]any{studentID, schoolID, classID}, args...)
+	res, err := tx.ExecContext(sr.ctx, `UPDATE students SET `+set+` WHERE id = $1 AND school_id = $2 AND class_id = $3`, args...)
+	if err != nil {
+		return fmt.Errorf("tx.ExecContext error: %w", err)
+	}
+
+	nUpdated, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("res.RowsAffected error: %w", err)
+	}
+
+	if nUpdated == 0 {
+		return fmt.Errorf("%w: no record found for student with ID %s", db.ErrorInvalidRequest, studentID)
+	}
+
+	return nil
+}
+
+// studentsChanged records a change of the students of the class that match
+// classID in tx. The students revision of the class is incremented and a
+// reported class is open again with a stale report. Returns the error of
+// checkState if the students of the class cannot change in its state.
+func (sr *StudentRepository) studentsChanged(tx *sql.Tx, schoolID, classID string, checkState func(state string) error) error {
+	if schoolID == "" || classID == "" {
+		return fmt.Errorf("%w: missing schoolID or classID", db.ErrorInvalidRequest)
+	}
+
+	// The update locks the class until tx ends, the state it returns cannot
+	// change before the students do.
+	var state string
+	err := tx.QueryRowContext(sr.ctx, `UPDATE classes SET students_revision = students_revision + 1 WHERE id = $1 AND school_id = $2 RETURNING state`,
+		classID, schoolID).Scan(&state)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: no record found for class with ID %s", db.ErrorInvalidRequest, classID)
+		}
+		return fmt.Errorf("tx.QueryRowContext error: %w", err)
+	}
+
+	err = checkState(state)
+	if err != nil {
+		return err
+	}
+
+	if state == class.StateReported {
+		_, err = tx.ExecContext(sr.ctx, `UPDATE classes SET state = $1, report_stale = TRUE WHERE id = $2 AND school_id = $3`,
+			class.StateOpen, classID, schoolID)
+		if err != nil {
+			return fmt.Errorf("tx.ExecContext error: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package sqldb
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ukane-philemon/scomp/internal/class"
+	"github.com/ukane-philemon/scomp/internal/db"
+	"github.com/ukane-philemon/scomp/internal/student"
+)
+
+// testScores returns a score for every subject of testSubjects.
+func testScores(score int) []*student.SubjectScore {
+	subjects := testSubjects()
+	scores := make([]*student.SubjectScore, len(subjects))
+	for i, subject := range subjects {
+		scores[i] = &student.SubjectScore{Name: subject.Name, Score: score}
+	}
+	return scores
+}
+
+// newTestStudent adds a student named studentName to classID and returns their
+// ID.
+func newTestStudent(t *testing.T, sr student.Repository, classID, studentName string) string {
+	t.Helper()
+
+	studentID, err := sr.Create(testSchoolID, classID, studentName, testSubjects(), testScores(50))
+	if err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+	return studentID
+}
+
+func TestCreateStudent(t *testing.T) {
+	ctx, sqlDB := context.Background(), newTestDB(t)
+	cr, sr := NewClassRepository(ctx, sqlDB), NewStudentRepository(ctx, sqlDB)
+	classID := newTestClass(t, cr, "JSS 1")
+	otherClassID := newTestClass(t, cr, "JSS 2")
+
+	studentID := newTestStudent(t, sr, classID, "Ada")
+
+	// Student names are unique per class.
+	newTestStudent(t, sr, otherClassID, "Ada")
+	_, err := sr.Create(testSchoolID, classID, "Ada", testSubjects(), testScores(60))
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for a duplicate name, got %v", err)
+	}
+
+	studentScores, err := sr.StudentScores(testSchoolID, classID)
+	if err != nil {
+		t.Fatalf("StudentScores error: %v", err)
+	}
+
+	if len(studentScores) != 1 || len(studentScores[studentID]) != len(testSubjects()) {
+		t.Fatalf("expected the scores of student %s, got %v", studentID, studentScores)
+	}
+
+	_, err = sr.Student(testSchoolID, otherClassID, studentID)
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for a student of another class, got %v", err)
+	}
+}
+
+func TestCreateStudentRequiresClassNotComputing(t *testing.T) {
+	ctx, sqlDB := context.Background(), newTestDB(t)
+	cr, sr := NewClassRepository(ctx, sqlDB), NewStudentRepository(ctx, sqlDB)
+	classID := newTestClass(t, cr, "JSS 1")
+
+	_, err := sr.Create(testSchoolID, "unknown", "Ada", testSubjects(), testScores(60))
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for an unknown class, got %v", err)
+	}
+
+	_, err = sr.Create("other school", classID, "Ada", testSubjects(), testScores(60))
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for a class of another school, got %v", err)
+	}
+
+	err = cr.Transition(testSchoolID, classID, class.StateOpen, class.StateComputing)
+	if err != nil {
+		t.Fatalf("Transition error: %v", err)
+	}
+
+	_, err = sr.Create(testSchoolID, classID, "Ada", testSubjects(), testScores(60))
+	if !errors.Is(err, db.ErrorInvalidRequest) {
+		t.Fatalf("expected db.ErrorInvalidRequest for a computing class, got %v", err)
+	}
+
+	students, err := sr.Students(testSchoolID, classID)
+	if err != nil {
+		t.Fatalf("Students error: %v", err)
+	}
+
+	if len(students) != 0 {
+		t.Fatalf("expected no student in a computing class, got %d", len(students))
+	}
+}
+
+func TestAddStudentToReportedClass(t *testing.T) {
+	ctx, sqlDB := context.Background(), newTestDB(t)
+	cr, sr := NewClassRepository(ctx, sqlDB), NewStudentRepository(ctx, sqlDB)
+	classID := newTestClass(t, cr, "JSS 1")
+	toClassID := newTestClass(t, cr, "JSS 2")
+
+	studentID, err := sr.Create(testSchoolID, classID, "Ada", testSubjects(), testScores(60))
+	if err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+
+	reportClass := func(classID string) {
+		for _, transition := range [][2]string{{class.StateOpen, class.StateComputing}, {class.StateComputing, class.StateReported}} {
+			err := cr.Transition(testSchoolID, classID, transition[0], transition[1])
+			if err != nil {
+				t.Fatalf("Transition error: %v", err)
+			}
+		}
+	}
+
+	expectStale := func(action, classID string) {
+		classInfo, err := cr.Class(testSchoolID, classID)
+		if err != nil {
+			t.Fatalf("Class error: %v", err)
+		}
+
+		if classInfo.State != class.StateOpen || !classInfo.ReportStale {
+			t.Fatalf("%s: expected an open class with a stale report, got %s, stale %v", action, classInfo.State, classInfo.ReportStale)
+		}
+	}
+
+	reportClass(classID)
+	_, err = sr.Create(testSchoolID, classID, "Bola", testSubjects(), testScores(70))
+	if err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+	expectStale("add", classID)
+
+	reportClass(toClassID)
+	err = sr.Move(testSchoolID, classID, studentID, toClassID, testSubjects(), testScores(60))
+	if err != nil {
+		t.Fatalf("Move error: %v", err)
+	}
+	expectStale("move in", toClassID)
+}
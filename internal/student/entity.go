@@ -4,8 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
+	"github.com/ukane-philemon/scomp/internal/class"
 	"github.com/ukane-philemon/scomp/internal/db"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -14,128 +16,287 @@ import (
 )
 
 const (
-	idKey      = "_id"
-	nameKey    = "name"
-	classIDKey = "classID"
-	reportKey  = "report"
+	idKey       = "_id"
+	schoolIDKey = "schoolID"
+	classIDKey  = "classID"
+	nameKey     = "name"
+	reportKey   = "report"
+
+	// Keys of the classes collection.
+	stateKey            = "state"
+	reportStaleKey      = "reportStale"
+	studentsRevisionKey = "studentsRevision"
 )
 
 type Student struct {
 	ID        string  `json:"_id" bson:"_id"`
+	SchoolID  string  `json:"schoolID" bson:"schoolID"`
 	Name      string  `json:"name" bson:"name"`
 	ClassID   string  `json:"classID" bson:"classID"`
 	Report    *Report `json:"report" bson:"report"`
-	CreatedAt string  `json:"createdAt" bson:"createdAt"`
+	CreatedAt int64   `json:"createdAt" bson:"createdAt"`
 }
 
 type Report struct {
 	Subjects    []*SubjectReport    `json:"subjects" bson:"subjects"`
 	Class       *StudentClassReport `json:"class" bson:"class"`
-	GeneratedAt string              `json:"generatedAt" bson:"generatedAt"`
+	GeneratedAt int64               `json:"generatedAt" bson:"generatedAt"`
 }
 
 type StudentClassReport struct {
-	Position             int    `json:"position" bson:"position"`
-	Grade                string `json:"grade" bson:"grade"`
-	TotalScore           int    `json:"totalScore" bson:"totalScore"`
-	TotalScorePercentage string `json:"totalScorePercentage" bson:"totalScorePercentage"`
+	Position int `json:"position" bson:"position"`
+	// PositionShared is true if other students of the class have the same
+	// total score.
+	PositionShared       bool    `json:"positionShared" bson:"positionShared"`
+	Grade                string  `json:"grade" bson:"grade"`
+	Remark               string  `json:"remark" bson:"remark"`
+	GPA                  float64 `json:"gpa" bson:"gpa"`
+	TotalScore           int     `json:"totalScore" bson:"totalScore"`
+	TotalScorePercentage string  `json:"totalScorePercentage" bson:"totalScorePercentage"`
+	// AveragePercentage is the average percentage of the subject scores.
+	AveragePercentage float64 `json:"averagePercentage" bson:"averagePercentage"`
+	// WeightedPercentage is the average percentage of the subject scores
+	// weighted with the subject weights.
+	WeightedPercentage float64 `json:"weightedPercentage" bson:"weightedPercentage"`
+	// Percentage is the percentage the student is ranked and graded by, one
+	// of the percentages above depending on the ranking basis of the class.
+	Percentage float64 `json:"percentage" bson:"percentage"`
+	// PercentileRank is the percentage of the students of the class who
+	// ranked below the student, counting half of the students who share the
+	// position.
+	PercentileRank float64 `json:"percentileRank" bson:"percentileRank"`
 }
 
 type SubjectReport struct {
 	*SubjectScore `bson:"inline"`
-	Grade         string `json:"grade,omitempty" bson:"grade"`
-	Position      int    `json:"position,omitempty" bson:"position"`
+	Grade         string  `json:"grade,omitempty" bson:"grade"`
+	GradePoint    float64 `json:"gradePoint" bson:"gradePoint"`
+	Remark        string  `json:"remark" bson:"remark"`
+	Position      int     `json:"position,omitempty" bson:"position"`
+	// PositionShared is true if other students of the class have the same
+	// subject score.
+	PositionShared bool `json:"positionShared" bson:"positionShared"`
+	// PercentileRank is the percentage of the students scored in the subject
+	// who ranked below the student, counting half of the students who share
+	// the position.
+	PercentileRank float64 `json:"percentileRank" bson:"percentileRank"`
 }
 
+// PositionLabel returns the position as displayed on reports, e.g "2nd" or
+// "2nd=" if the position is shared.
+func (r *StudentClassReport) PositionLabel() string {
+	return positionLabel(r.Position, r.PositionShared)
+}
+
+// PositionLabel returns the position as displayed on reports, e.g "2nd" or
+// "2nd=" if the position is shared.
+func (r *SubjectReport) PositionLabel() string {
+	return positionLabel(r.Position, r.PositionShared)
+}
+
+func positionLabel(position int, shared bool) string {
+	if position == 0 {
+		return ""
+	}
+
+	suffix := "th"
+	switch position % 10 {
+	case 1:
+		suffix = "st"
+	case 2:
+		suffix = "nd"
+	case 3:
+		suffix = "rd"
+	}
+	if position%100 >= 11 && position%100 <= 13 {
+		suffix = "th"
+	}
+
+	label := strconv.Itoa(position) + suffix
+	if shared {
+		label += "="
+	}
+	return label
+}
+
+// Statuses of subject scores that are not counted in reports.
+const (
+	ScoreStatusAbsent     = "absent"
+	ScoreStatusExempt     = "exempt"
+	ScoreStatusIncomplete = "incomplete"
+)
+
 type SubjectScore struct {
 	Name  string `json:"name" bson:"name"`
 	Score int    `json:"score" bson:"score"`
+	// Status is empty if the subject was scored, otherwise ScoreStatusAbsent,
+	// ScoreStatusExempt or ScoreStatusIncomplete and Score is 0.
+	Status string `json:"status,omitempty" bson:"status,omitempty"`
+	// Components are the scores of the components of the subject, Score is
+	// their sum. Empty if the subject has no components.
+	Components []*ComponentScore `json:"components,omitempty" bson:"components,omitempty"`
 }
 
-// StudentRepository implements Repository.
-type StudentRepository struct {
-	ctx               context.Context
-	studentCollection *mongo.Collection
+// Scored checks that the subject was scored. Subjects that were not scored
+// are not counted in totals, percentages and positions.
+func (s *SubjectScore) Scored() bool {
+	return s.Status == ""
+}
+
+// ComponentScore is the score of a component, e.g a test or an exam, of a
+// subject.
+type ComponentScore struct {
+	Name  string `json:"name" bson:"name"`
+	Score int    `json:"score" bson:"score"`
 }
 
-// NewRepository creates a new instance of *StudentRepository.
-func NewRepository(ctx context.Context, db *mongo.Database) (Repository, error) {
-	studentCollectionIndex := mongo.IndexModel{
-		Keys: bson.D{{
-			Key:   nameKey,
-			Value: 1,
-		}, {
-			Key:   classIDKey,
-			Value: 1,
-		}},
-		Options: options.Index().SetUnique(true),
+// NewStudent returns a new *Student of classID. subjectScores must have one
+// score for every subject in classSubjects, the subjects of the class, except
+// for optional subjects the student does not take.
+func NewStudent(schoolID, classID, studentName string, classSubjects []*class.Subject, subjectScores []*SubjectScore) (*Student, error) {
+	if schoolID == "" || classID == "" || studentName == "" {
+		return nil, fmt.Errorf("%w: missing required argument(s)", db.ErrorInvalidRequest)
 	}
 
-	// Create a unique index on the class collection.
-	classCollection := db.Collection("students")
-	_, err := classCollection.Indexes().CreateOne(ctx, studentCollectionIndex)
+	report, err := NewReport(classSubjects, subjectScores)
 	if err != nil {
 		return nil, err
 	}
 
-	return &StudentRepository{
-		ctx:               ctx,
-		studentCollection: classCollection,
+	return &Student{
+		ID:        primitive.NewObjectID().Hex(),
+		SchoolID:  schoolID,
+		Name:      studentName,
+		ClassID:   classID,
+		Report:    report,
+		CreatedAt: time.Now().Unix(),
 	}, nil
 }
 
-// Create adds a students record. Returns db.ErrorInvalidRequest if studentName
-// already exists for classID.
-// Implements Repository.
-func (sr *StudentRepository) Create(classID string, studentName string, subjectScores []*SubjectScore) (string, error) {
-	if classID == "" || studentName == "" || len(subjectScores) == 0 {
-		return "", fmt.Errorf("%w: missing required argument(s)", db.ErrorInvalidRequest)
-	}
-
-	if len(subjectScores) != db.RequiredClassSubjects {
-		return "", fmt.Errorf("%w: %d class subjects are required to save a student's record", db.ErrorInvalidRequest, db.RequiredClassSubjects)
+// NewReport returns the report of a student with subjectScores and without
+// positions and grades, i.e the report of a student whose class report is not
+// computed. subjectScores must have one score for every subject in
+// classSubjects except for optional subjects.
+func NewReport(classSubjects []*class.Subject, subjectScores []*SubjectScore) (*Report, error) {
+	if len(subjectScores) == 0 {
+		return nil, fmt.Errorf("%w: missing required argument(s)", db.ErrorInvalidRequest)
 	}
 
-	student := &Student{
-		ID:        primitive.NewObjectID().Hex(),
-		Name:      studentName,
-		ClassID:   classID,
-		Report:    new(Report),
-		CreatedAt: fmt.Sprint(time.Now().Unix()),
+	isClassSubject := make(map[string]bool, len(classSubjects))
+	for _, subject := range classSubjects {
+		isClassSubject[subject.Name] = true
 	}
 
+	scores := make(map[string]*SubjectScore, len(subjectScores))
 	for index, subject := range subjectScores {
 		if subject.Name == "" {
-			return "", fmt.Errorf("%w: student subject %d is missing subject name", db.ErrorInvalidRequest, index+1)
+			return nil, fmt.Errorf("%w: student subject %d is missing subject name", db.ErrorInvalidRequest, index+1)
+		}
+
+		if !isClassSubject[subject.Name] {
+			return nil, fmt.Errorf("%w: subject name %s does not exist, check spelling as subject names are case sensitive",
+				db.ErrorInvalidRequest, subject.Name)
 		}
 
 		if subject.Score < 0 {
-			return "", fmt.Errorf("%w: subject %s has an invalid score %d", db.ErrorInvalidRequest, subject.Name, subject.Score)
+			return nil, fmt.Errorf("%w: subject %s has an invalid score %d", db.ErrorInvalidRequest, subject.Name, subject.Score)
+		}
+
+		switch subject.Status {
+		case "":
+		case ScoreStatusAbsent, ScoreStatusExempt, ScoreStatusIncomplete:
+			if subject.Score != 0 || len(subject.Components) > 0 {
+				return nil, fmt.Errorf("%w: subject %s is marked %s and cannot have a score", db.ErrorInvalidRequest, subject.Name, subject.Status)
+			}
+		default:
+			return nil, fmt.Errorf("%w: subject %s has an invalid status %s", db.ErrorInvalidRequest, subject.Name, subject.Status)
+		}
+
+		if scores[subject.Name] != nil {
+			return nil, fmt.Errorf("%w: more than one score for subject %s", db.ErrorInvalidRequest, subject.Name)
+		}
+		scores[subject.Name] = subject
+	}
+
+	report := new(Report)
+
+	// Keep subject scores in the order of the class subjects.
+	for _, classSubject := range classSubjects {
+		subject, found := scores[classSubject.Name]
+		if !found {
+			if classSubject.Optional {
+				continue
+			}
+			return nil, fmt.Errorf("%w: missing score for subject %s", db.ErrorInvalidRequest, classSubject.Name)
 		}
 
-		student.Report.Subjects = append(student.Report.Subjects, &SubjectReport{
+		report.Subjects = append(report.Subjects, &SubjectReport{
 			SubjectScore: subject,
 		})
 	}
 
-	// Create student record.
-	res, err := sr.studentCollection.InsertOne(sr.ctx, student)
+	return report, nil
+}
+
+// StudentRepository implements Repository.
+type StudentRepository struct {
+	ctx               context.Context
+	classCollection   *mongo.Collection
+	studentCollection *mongo.Collection
+}
+
+// NewRepository creates a new instance of *StudentRepository. The collection
+// indexes are created by db.MigrateMongoDB.
+func NewRepository(ctx context.Context, db *mongo.Database) Repository {
+	return &StudentRepository{
+		ctx:               ctx,
+		classCollection:   db.Collection("classes"),
+		studentCollection: db.Collection("students"),
+	}
+}
+
+// Create adds a students record. Returns db.ErrorInvalidRequest if the class
+// is not open or studentName already exists for classID.
+// Implements Repository.
+func (sr *StudentRepository) Create(schoolID, classID, studentName string, classSubjects []*class.Subject, subjectScores []*SubjectScore) (string, error) {
+	student, err := NewStudent(schoolID, classID, studentName, classSubjects, subjectScores)
 	if err != nil {
-		return "", fmt.Errorf("studentCollection.InsertOne error: %w", err)
+		return "", err
 	}
 
-	return res.InsertedID.(string), nil
+	err = sr.withTransaction(func(ctx mongo.SessionContext) error {
+		err := sr.studentsChanged(ctx, schoolID, classID, class.CheckCanChangeStudents)
+		if err != nil {
+			return err
+		}
+
+		// Create student record.
+		_, err = sr.studentCollection.InsertOne(ctx, student)
+		if err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				return fmt.Errorf("%w: student %s already exists in this class", db.ErrorInvalidRequest, studentName)
+			}
+			return fmt.Errorf("studentCollection.InsertOne error: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return student.ID, nil
 }
 
 // Student returns the students that match provided arguments.
 // Implements Repository.
-func (sr *StudentRepository) Student(classID string, studentID string) (*Student, error) {
-	if classID == "" || studentID == "" {
+func (sr *StudentRepository) Student(schoolID, classID, studentID string) (*Student, error) {
+	if schoolID == "" || classID == "" || studentID == "" {
 		return nil, fmt.Errorf("%w: missing required argument(s)", db.ErrorInvalidRequest)
 	}
 
 	var student *Student
-	studentFilter := bson.M{idKey: studentID, classIDKey: classID}
+	studentFilter := bson.M{idKey: studentID, schoolIDKey: schoolID, classIDKey: classID}
 	err := sr.studentCollection.FindOne(sr.ctx, studentFilter).Decode(&student)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
@@ -149,12 +310,12 @@ func (sr *StudentRepository) Student(classID string, studentID string) (*Student
 
 // Students returns all the students that match the provided classID.
 // Implements Repository.
-func (sr *StudentRepository) Students(classID string) ([]*Student, error) {
-	if classID == "" {
-		return nil, fmt.Errorf("%w: missing classID", db.ErrorInvalidRequest)
+func (sr *StudentRepository) Students(schoolID, classID string) ([]*Student, error) {
+	if schoolID == "" || classID == "" {
+		return nil, fmt.Errorf("%w: missing schoolID or classID", db.ErrorInvalidRequest)
 	}
 
-	cur, err := sr.studentCollection.Find(sr.ctx, bson.M{classIDKey: classID})
+	cur, err := sr.studentCollection.Find(sr.ctx, bson.M{schoolIDKey: schoolID, classIDKey: classID})
 	if err != nil {
 		return nil, fmt.Errorf("studentCollection.Find error: %w", err)
 	}
@@ -165,12 +326,12 @@ func (sr *StudentRepository) Students(classID string) ([]*Student, error) {
 
 // StudentScores returns a map of student ID to their subject scores.
 // Implements Repository.
-func (sr *StudentRepository) StudentScores(classID string) (map[string][]*SubjectScore, error) {
-	if classID == "" {
-		return nil, fmt.Errorf("%w: missing classID", db.ErrorInvalidRequest)
+func (sr *StudentRepository) StudentScores(schoolID, classID string) (map[string][]*SubjectScore, error) {
+	if schoolID == "" || classID == "" {
+		return nil, fmt.Errorf("%w: missing schoolID or classID", db.ErrorInvalidRequest)
 	}
 
-	cur, err := sr.studentCollection.Find(sr.ctx, bson.M{classIDKey: classID})
+	cur, err := sr.studentCollection.Find(sr.ctx, bson.M{schoolIDKey: schoolID, classIDKey: classID})
 	if err != nil {
 		return nil, fmt.Errorf("studentCollection.Find error: %w", err)
 	}
@@ -189,43 +350,178 @@ func (sr *StudentRepository) StudentScores(classID string) (map[string][]*Subjec
 	return studentsMap, nil
 }
 
-func studentSubjectScores(report []*SubjectReport) []*SubjectScore {
-	var scores []*SubjectScore
-	for _, r := range report {
-		scores = append(scores, r.SubjectScore)
+// UpdateScores replaces the subject scores of studentID with subjectScores and
+// clears the class report of the student.
+// Implements Repository.
+func (sr *StudentRepository) UpdateScores(schoolID, classID, studentID string, classSubjects []*class.Subject, subjectScores []*SubjectScore) error {
+	report, err := NewReport(classSubjects, subjectScores)
+	if err != nil {
+		return err
 	}
-	return scores
+
+	return sr.withTransaction(func(ctx mongo.SessionContext) error {
+		err := sr.studentsChanged(ctx, schoolID, classID, class.CheckCanChangeStudents)
+		if err != nil {
+			return err
+		}
+
+		return sr.updateStudent(ctx, schoolID, classID, studentID, bson.M{reportKey: report})
+	})
 }
 
-// SaveStudentReports saves the students report specified.
+// Rename changes the name of studentID to studentName. Returns
+// db.ErrorInvalidRequest if studentName already exists for classID.
 // Implements Repository.
-func (sr *StudentRepository) SaveStudentReports(reports map[string]*Report) error {
-	session, err := sr.studentCollection.Database().Client().StartSession()
-	if err != nil {
-		return fmt.Errorf("Client().StartSession() error: %w", err)
+func (sr *StudentRepository) Rename(schoolID, classID, studentID, studentName string) error {
+	if studentName == "" {
+		return fmt.Errorf("%w: missing student name", db.ErrorInvalidRequest)
 	}
-	defer session.EndSession(sr.ctx)
 
-	saveStudentReportFn := func(ctx mongo.SessionContext) (interface{}, error) {
-		for studentID, report := range reports {
-			update := bson.M{"$set": bson.M{reportKey: report}}
-			res, err := sr.studentCollection.UpdateOne(ctx, bson.M{idKey: studentID}, update, options.Update().SetUpsert(false))
-			if err != nil {
-				return nil, fmt.Errorf("studentCollection.UpdateOne error: %w", err)
-			}
+	return sr.withTransaction(func(ctx mongo.SessionContext) error {
+		err := sr.studentsChanged(ctx, schoolID, classID, class.CheckCanChangeStudents)
+		if err != nil {
+			return err
+		}
 
-			if res.ModifiedCount == 0 {
-				return nil, fmt.Errorf("student with ID %s was not updated", studentID)
-			}
+		err = sr.updateStudent(ctx, schoolID, classID, studentID, bson.M{nameKey: studentName})
+		if mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("%w: student %s already exists in this class", db.ErrorInvalidRequest, studentName)
+		}
+		return err
+	})
+}
+
+// Remove deletes the record of studentID.
+// Implements Repository.
+func (sr *StudentRepository) Remove(schoolID, classID, studentID string) error {
+	if schoolID == "" || classID == "" || studentID == "" {
+		return fmt.Errorf("%w: missing required argument(s)", db.ErrorInvalidRequest)
+	}
+
+	return sr.withTransaction(func(ctx mongo.SessionContext) error {
+		err := sr.studentsChanged(ctx, schoolID, classID, class.CheckCanChangeStudents)
+		if err != nil {
+			return err
+		}
+
+		res, err := sr.studentCollection.DeleteOne(ctx, bson.M{idKey: studentID, schoolIDKey: schoolID, classIDKey: classID})
+		if err != nil {
+			return fmt.Errorf("studentCollection.DeleteOne error: %w", err)
+		}
+
+		if res.DeletedCount == 0 {
+			return fmt.Errorf("%w: no record found for student with ID %s", db.ErrorInvalidRequest, studentID)
 		}
 
-		return nil, nil
+		return nil
+	})
+}
+
+// Move moves studentID from classID to toClassID with subjectScores and
+// clears the class report of the student. Returns db.ErrorInvalidRequest if
+// toClassID is not open or the name of the student already exists for
+// toClassID.
+// Implements Repository.
+func (sr *StudentRepository) Move(schoolID, classID, studentID, toClassID string, toClassSubjects []*class.Subject, subjectScores []*SubjectScore) error {
+	if toClassID == "" {
+		return fmt.Errorf("%w: missing toClassID", db.ErrorInvalidRequest)
 	}
 
-	_, err = session.WithTransaction(sr.ctx, saveStudentReportFn)
+	report, err := NewReport(toClassSubjects, subjectScores)
 	if err != nil {
 		return err
 	}
 
+	return sr.withTransaction(func(ctx mongo.SessionContext) error {
+		err := sr.studentsChanged(ctx, schoolID, classID, class.CheckCanChangeStudents)
+		if err != nil {
+			return err
+		}
+
+		err = sr.studentsChanged(ctx, schoolID, toClassID, class.CheckCanChangeStudents)
+		if err != nil {
+			return err
+		}
+
+		err = sr.updateStudent(ctx, schoolID, classID, studentID, bson.M{classIDKey: toClassID, reportKey: report})
+		if mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("%w: a student with the same name already exists in class %s", db.ErrorInvalidRequest, toClassID)
+		}
+		return err
+	})
+}
+
+// updateStudent sets the fields of the student that match studentID in the
+// transaction of ctx.
+func (sr *StudentRepository) updateStudent(ctx mongo.SessionContext, schoolID, classID, studentID string, fields bson.M) error {
+	if schoolID == "" || classID == "" || studentID == "" {
+		return fmt.Errorf("%w: missing required argument(s)", db.ErrorInvalidRequest)
+	}
+
+	res, err := sr.studentCollection.UpdateOne(ctx, bson.M{idKey: studentID, schoolIDKey: schoolID, classIDKey: classID}, bson.M{"$set": fields})
+	if err != nil {
+		return fmt.Errorf("studentCollection.UpdateOne error: %w", err)
+	}
+
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("%w: no record found for student with ID %s", db.ErrorInvalidRequest, studentID)
+	}
+
 	return nil
 }
+
+// studentsChanged records a change of the students of the class that match
+// classID in the transaction of ctx. The students revision of the class is
+// incremented and a reported class is open again with a stale report. Returns
+// the error of checkState if the students of the class cannot change in the
+// state it had before the update.
+func (sr *StudentRepository) studentsChanged(ctx mongo.SessionContext, schoolID, classID string, checkState func(state string) error) error {
+	if schoolID == "" || classID == "" {
+		return fmt.Errorf("%w: missing schoolID or classID", db.ErrorInvalidRequest)
+	}
+
+	// The expressions of the stage use the values of the class before the
+	// update.
+	isReported := bson.M{"$eq": bson.A{"$" + stateKey, class.StateReported}}
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{
+		studentsRevisionKey: bson.M{"$add": bson.A{"$" + studentsRevisionKey, 1}},
+		reportStaleKey:      bson.M{"$or": bson.A{"$" + reportStaleKey, isReported}},
+		stateKey:            bson.M{"$cond": bson.A{isReported, class.StateOpen, "$" + stateKey}},
+	}}}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.Before).SetProjection(bson.M{stateKey: 1})
+
+	var classInfo *class.Class
+	err := sr.classCollection.FindOneAndUpdate(ctx, bson.M{idKey: classID, schoolIDKey: schoolID}, update, opts).Decode(&classInfo)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return fmt.Errorf("%w: no record found for class with ID %s", db.ErrorInvalidRequest, classID)
+		}
+		return fmt.Errorf("classCollection.FindOneAndUpdate error: %w", err)
+	}
+
+	// The update is discarded with the transaction.
+	return checkState(classInfo.State)
+}
+
+// withTransaction calls fn in a transaction, which is committed if fn returns
+// a nil error.
+func (sr *StudentRepository) withTransaction(fn func(ctx mongo.SessionContext) error) error {
+	session, err := sr.studentCollection.Database().Client().StartSession()
+	if err != nil {
+		return fmt.Errorf("Client().StartSession() error: %w", err)
+	}
+	defer session.EndSession(sr.ctx)
+
+	_, err = session.WithTransaction(sr.ctx, func(ctx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(ctx)
+	})
+	return err
+}
+
+func studentSubjectScores(report []*SubjectReport) []*SubjectScore {
+	var scores []*SubjectScore
+	for _, r := range report {
+		scores = append(scores, r.SubjectScore)
+	}
+	return scores
+}
@@ -0,0 +1,83 @@
+package student
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ukane-philemon/scomp/internal/class"
+	"github.com/ukane-philemon/scomp/internal/db"
+)
+
+func TestNewStudent(t *testing.T) {
+	classSubjects := []*class.Subject{{Name: "Maths", MaxScore: 100}, {Name: "English", MaxScore: 100}}
+
+	studentInfo, err := NewStudent("school", "class", "Ada", classSubjects, []*SubjectScore{{Name: "English", Score: 60}, {Name: "Maths", Score: 70}})
+	if err != nil {
+		t.Fatalf("NewStudent error: %v", err)
+	}
+
+	subjects := studentInfo.Report.Subjects
+	if len(subjects) != 2 || subjects[0].Name != "Maths" || subjects[1].Name != "English" {
+		t.Fatal("expected the subject scores in the order of the class subjects")
+	}
+
+	tests := []struct {
+		name   string
+		scores []*SubjectScore
+	}{
+		{name: "no scores"},
+		{name: "missing score", scores: []*SubjectScore{{Name: "Maths", Score: 70}}},
+		{name: "unknown subject", scores: []*SubjectScore{{Name: "Maths", Score: 70}, {Name: "english", Score: 60}}},
+		{name: "duplicate score", scores: []*SubjectScore{{Name: "Maths", Score: 70}, {Name: "Maths", Score: 60}}},
+		{name: "negative score", scores: []*SubjectScore{{Name: "Maths", Score: 70}, {Name: "English", Score: -1}}},
+		{name: "missing subject name", scores: []*SubjectScore{{Score: 70}, {Name: "English", Score: 60}}},
+	}
+
+	for _, test := range tests {
+		_, err := NewStudent("school", "class", "Ada", classSubjects, test.scores)
+		if !errors.Is(err, db.ErrorInvalidRequest) {
+			t.Errorf("%s: expected db.ErrorInvalidRequest, got %v", test.name, err)
+		}
+	}
+}
+
+func TestNewStudentScoreStatus(t *testing.T) {
+	classSubjects := []*class.Subject{{Name: "Maths", MaxScore: 100}, {Name: "French", MaxScore: 100, Optional: true}}
+
+	studentInfo, err := NewStudent("school", "class", "Ada", classSubjects, []*SubjectScore{{Name: "Maths", Score: 70}})
+	if err != nil {
+		t.Fatalf("NewStudent error: %v", err)
+	}
+
+	if subjects := studentInfo.Report.Subjects; len(subjects) != 1 || subjects[0].Name != "Maths" {
+		t.Fatal("expected the optional subject to be left out")
+	}
+
+	for _, status := range []string{ScoreStatusAbsent, ScoreStatusExempt, ScoreStatusIncomplete} {
+		studentInfo, err := NewStudent("school", "class", "Ada", classSubjects, []*SubjectScore{{Name: "Maths", Status: status}})
+		if err != nil {
+			t.Fatalf("%s: NewStudent error: %v", status, err)
+		}
+
+		if studentInfo.Report.Subjects[0].Scored() {
+			t.Errorf("%s: expected the subject not to be scored", status)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		scores []*SubjectScore
+	}{
+		{name: "absent with a score", scores: []*SubjectScore{{Name: "Maths", Score: 70, Status: ScoreStatusAbsent}}},
+		{name: "exempt with components", scores: []*SubjectScore{{Name: "Maths", Status: ScoreStatusExempt, Components: []*ComponentScore{{Name: "Test", Score: 10}}}}},
+		{name: "invalid status", scores: []*SubjectScore{{Name: "Maths", Status: "sick"}}},
+		{name: "missing required subject", scores: []*SubjectScore{{Name: "French", Score: 70}}},
+	}
+
+	for _, test := range tests {
+		_, err := NewStudent("school", "class", "Ada", classSubjects, test.scores)
+		if !errors.Is(err, db.ErrorInvalidRequest) {
+			t.Errorf("%s: expected db.ErrorInvalidRequest, got %v", test.name, err)
+		}
+	}
+}
@@ -1,15 +1,38 @@
 package student
 
+import "github.com/ukane-philemon/scomp/internal/class"
+
+// Repository is the student store. Every method is scoped to the school that
+// match schoolID, students of other schools are never returned or modified.
+//
+// Methods that change students also record the change of the students of
+// their class in the same transaction: the students revision of the class is
+// incremented and a reported class is open again with a stale report. They
+// return db.ErrorInvalidRequest if the report of the class is being computed.
 type Repository interface {
-	// Create adds a students record. Returns db.ErrorInvalidRequest if
-	// studentName already exists for classID.
-	Create(classID string, studentName string, subjectScores []*SubjectScore) (string, error)
+	// Create adds a students record with a score for every subject in
+	// classSubjects, optional subjects can be left out. Returns
+	// db.ErrorInvalidRequest if the class is not open or studentName already
+	// exists for classID.
+	Create(schoolID, classID, studentName string, classSubjects []*class.Subject, subjectScores []*SubjectScore) (string, error)
 	// Student returns the students that match provided arguments.
-	Student(classID string, studentID string) (*Student, error)
+	Student(schoolID, classID, studentID string) (*Student, error)
 	// Students returns all the students that match the provided classID.
-	Students(classID string) ([]*Student, error)
+	Students(schoolID, classID string) ([]*Student, error)
 	// StudentScores returns a map of student ID to their subject scores.
-	StudentScores(classID string) (map[string][]*SubjectScore, error)
-	// SaveStudentReports saves the students report specified.
-	SaveStudentReports(reports map[string]*Report) error
+	StudentScores(schoolID, classID string) (map[string][]*SubjectScore, error)
+	// UpdateScores replaces the subject scores of studentID with
+	// subjectScores, checked against classSubjects like Create. The class
+	// report of the student is cleared.
+	UpdateScores(schoolID, classID, studentID string, classSubjects []*class.Subject, subjectScores []*SubjectScore) error
+	// Rename changes the name of studentID to studentName. Returns
+	// db.ErrorInvalidRequest if studentName already exists for classID.
+	Rename(schoolID, classID, studentID, studentName string) error
+	// Remove deletes the record of studentID.
+	Remove(schoolID, classID, studentID string) error
+	// Move moves studentID from classID to toClassID with subjectScores,
+	// checked against toClassSubjects like Create. The class report of the
+	// student is cleared. Returns db.ErrorInvalidRequest if toClassID is not
+	// open or the name of the student already exists for toClassID.
+	Move(schoolID, classID, studentID, toClassID string, toClassSubjects []*class.Subject, subjectScores []*SubjectScore) error
 }
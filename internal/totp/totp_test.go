@@ -0,0 +1,81 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret is the base32 encoding of the SHA1 secret of the RFC 6238 test
+// vectors, "12345678901234567890".
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCode(t *testing.T) {
+	// RFC 6238 appendix B, truncated to the last 6 digits of the 8 digits
+	// codes.
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{unix: 59, code: "287082"},
+		{unix: 1111111109, code: "081804"},
+		{unix: 1111111111, code: "050471"},
+		{unix: 1234567890, code: "005924"},
+		{unix: 2000000000, code: "279037"},
+		{unix: 20000000000, code: "353130"},
+	}
+
+	for _, test := range tests {
+		code, err := Code(rfcSecret, Counter(time.Unix(test.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code error at %d: %v", test.unix, err)
+		}
+
+		if code != test.code || len(code) != Digits {
+			t.Errorf("expected code %s at %d, got %s", test.code, test.unix, code)
+		}
+	}
+}
+
+func TestCodeInvalidSecret(t *testing.T) {
+	_, err := Code("not base32!", 1)
+	if err == nil {
+		t.Fatal("Code accepted an invalid secret")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	counter := Counter(now)
+
+	tests := []struct {
+		name        string
+		code        string
+		wantOK      bool
+		wantCounter int64
+	}{
+		{name: "current period", code: "050471", wantOK: true, wantCounter: counter},
+		{name: "previous period", code: mustCode(t, counter-1), wantOK: true, wantCounter: counter - 1},
+		{name: "next period", code: mustCode(t, counter+1), wantOK: true, wantCounter: counter + 1},
+		{name: "outside skew", code: mustCode(t, counter-2)},
+		{name: "wrong code", code: "000000"},
+		{name: "too short", code: "50471"},
+		{name: "too long", code: "0504710"},
+	}
+
+	for _, test := range tests {
+		gotCounter, ok := Validate(rfcSecret, test.code, now)
+		if ok != test.wantOK || gotCounter != test.wantCounter {
+			t.Errorf("%s: expected (%d, %v), got (%d, %v)", test.name, test.wantCounter, test.wantOK, gotCounter, ok)
+		}
+	}
+}
+
+func mustCode(t *testing.T, counter int64) string {
+	t.Helper()
+
+	code, err := Code(rfcSecret, counter)
+	if err != nil {
+		t.Fatalf("Code error: %v", err)
+	}
+	return code
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ukane-philemon/scomp/internal/db"
+	"github.com/ukane-philemon/scomp/internal/sqldb"
+)
+
+// runMigrate applies pending database migrations for the storage backend
+// specified. Pending migrations are only listed if the -dry-run flag is set
+// in args.
+func runMigrate(storage, dbName, dbURL string, args []string) error {
+	migrateFlags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dryRun := migrateFlags.Bool("dry-run", false, "List pending migrations without applying them")
+	err := migrateFlags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	var current, latest int
+	var migrations []*db.MigrationInfo
+	switch storage {
+	case storageMemory:
+		log.Println("In-memory storage does not require migrations...")
+		return nil
+
+	case storageMongoDB:
+		mdb, err := db.NewMongoDB(ctx, dbName, dbURL)
+		if err != nil {
+			return fmt.Errorf("mongodb.New error: %v", err)
+		}
+		defer db.ShutdownMongoDB(ctx, mdb)
+
+		current, latest, err = db.MongoDBSchemaVersion(ctx, mdb)
+		if err != nil {
+			return err
+		}
+
+		migrations, err = db.MigrateMongoDB(ctx, mdb, *dryRun)
+		if err != nil {
+			return err
+		}
+
+	case storageSQLite, storagePostgres:
+		sqlDB, err := sqldb.Open(ctx, storage, dbURL)
+		if err != nil {
+			return fmt.Errorf("sqldb.Open error: %v", err)
+		}
+		defer sqldb.Shutdown(sqlDB)
+
+		current, latest, err = sqldb.SchemaVersion(ctx, sqlDB)
+		if err != nil {
+			return err
+		}
+
+		migrations, err = sqldb.Migrate(ctx, sqlDB, *dryRun)
+		if err != nil {
+			return err
+		}
+
+	default:
+		return fmt.Errorf("unsupported storage backend %q", storage)
+	}
+
+	log.Printf("Database schema version: %d, latest version: %d", current, latest)
+	if len(migrations) == 0 {
+		log.Println("Database schema is up to date...")
+		return nil
+	}
+
+	action := "Applied"
+	if *dryRun {
+		action = "Pending"
+	}
+
+	for _, migration := range migrations {
+		log.Printf("%s migration %d: %s", action, migration.Version, migration.Description)
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ukane-philemon/scomp/graph"
+	"github.com/ukane-philemon/scomp/internal/admin"
+)
+
+// runCreateSchool creates a new school named with the -name flag in args and
+// an invitation to create the owner account of the school. The invitation
+// code is only printed once.
+func runCreateSchool(storage, dbName, dbURL string, args []string) error {
+	schoolFlags := flag.NewFlagSet("create-school", flag.ExitOnError)
+	name := schoolFlags.String("name", "", "Name of the new school")
+	validFor := schoolFlags.Duration("valid-for", 72*time.Hour, "How long the owner invitation is valid for")
+	err := schoolFlags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if *name == "" {
+		return fmt.Errorf("the -name flag is required")
+	}
+
+	if storage == storageMemory {
+		return fmt.Errorf("schools cannot be created for in-memory storage, the first school is created when the server starts")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	resolver := new(graph.Resolver)
+	shutdownStorage, err := setupStorage(ctx, resolver, storage, dbName, dbURL)
+	if err != nil {
+		return err
+	}
+	defer shutdownStorage(ctx)
+
+	schoolID, err := resolver.SchoolRepository.Create(*name)
+	if err != nil {
+		return fmt.Errorf("SchoolRepository.Create error: %v", err)
+	}
+
+	_, code, err := resolver.InvitationRepository.Create(schoolID, "", admin.RoleOwner, *validFor)
+	if err != nil {
+		return fmt.Errorf("InvitationRepository.Create error: %v", err)
+	}
+
+	log.Printf("Created school %s with ID %s", *name, schoolID)
+	log.Printf("Owner invitation code (expires in %s): %s", *validFor, code)
+	return nil
+}
@@ -8,10 +8,15 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/99designs/gqlgen/graphql/playground"
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
@@ -21,32 +26,77 @@ import (
 	"github.com/ukane-philemon/scomp/internal/auth"
 	"github.com/ukane-philemon/scomp/internal/class"
 	"github.com/ukane-philemon/scomp/internal/db"
+	"github.com/ukane-philemon/scomp/internal/grading"
+	"github.com/ukane-philemon/scomp/internal/history"
+	"github.com/ukane-philemon/scomp/internal/job"
+	"github.com/ukane-philemon/scomp/internal/memory"
+	"github.com/ukane-philemon/scomp/internal/report"
+	"github.com/ukane-philemon/scomp/internal/school"
+	"github.com/ukane-philemon/scomp/internal/session"
+	"github.com/ukane-philemon/scomp/internal/sqldb"
 	"github.com/ukane-philemon/scomp/internal/student"
 )
 
 const defaultPort = "8080"
 
+// defaultJobWorkers and maxJobWorkers are the default and maximum number of
+// job workers, see jobWorkers.
+const (
+	defaultJobWorkers = 2
+	maxJobWorkers     = 32
+)
+
+// Supported storage backends.
+const (
+	storageMongoDB  = "mongodb"
+	storageMemory   = "memory"
+	storageSQLite   = "sqlite"
+	storagePostgres = "postgres"
+)
+
 func main() {
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = defaultPort
 	}
 
-	dbURL := os.Getenv("DB_URL")
-	if dbURL == "" {
-		log.Fatal("DB_URL environment variable is not set")
-	}
-
 	var isDevMode bool
+	var storage string
 	flag.BoolVar(&isDevMode, "dev", false, "Run server in development mode")
+	flag.StringVar(&storage, "storage", storageMongoDB, "Storage backend to use, one of: mongodb, memory, sqlite, postgres")
 	flag.Parse()
 
+	dbURL := os.Getenv("DB_URL")
+	if dbURL == "" && (storage == storageMongoDB || storage == storagePostgres) {
+		log.Fatal("DB_URL environment variable is not set")
+	}
+
 	var dbName = "scomp"
 	if isDevMode {
 		dbName = "dev_scomp"
 	}
 
-	serverError := runServer(port, dbName, dbURL)
+	if dbURL == "" && storage == storageSQLite {
+		dbURL = dbName + ".db"
+	}
+
+	switch flag.Arg(0) {
+	case "migrate":
+		err := runMigrate(storage, dbName, dbURL, flag.Args()[1:])
+		if err != nil {
+			log.Fatalf("SCOMP migrate error: %v", err)
+		}
+		return
+
+	case "create-school":
+		err := runCreateSchool(storage, dbName, dbURL, flag.Args()[1:])
+		if err != nil {
+			log.Fatalf("SCOMP create-school error: %v", err)
+		}
+		return
+	}
+
+	serverError := runServer(port, storage, dbName, dbURL)
 	if serverError != nil {
 		log.Fatalf("SCOMP shutdown error: %v", serverError)
 	}
@@ -55,45 +105,79 @@ func main() {
 }
 
 // runServer prepares and starts the server.
-func runServer(port, dbName, dbURL string) error {
+func runServer(port, storage, dbName, dbURL string) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	mdb, err := db.NewMongoDB(ctx, dbName, dbURL)
+	resolver := new(graph.Resolver)
+	shutdownStorage, err := setupStorage(ctx, resolver, storage, dbName, dbURL)
 	if err != nil {
-		return fmt.Errorf("mongodb.New error: %v", err)
+		return err
 	}
 
-	resolver := new(graph.Resolver)
-
-	resolver.AdminRepository, err = admin.NewRepository(ctx, mdb)
+	resolver.PasswordPolicy, err = passwordPolicy()
 	if err != nil {
-		return fmt.Errorf("admin.NewRepository error: %v", err)
+		return err
 	}
 
-	resolver.ClassRepository, err = class.NewRepository(ctx, mdb)
+	err = bootstrapOwner(resolver.SchoolRepository, resolver.AdminRepository, resolver.PasswordPolicy)
 	if err != nil {
-		return fmt.Errorf("class.NewRepository error: %v", err)
+		return err
 	}
 
-	resolver.StudentRepository, err = student.NewRepository(ctx, mdb)
+	authKeys, err := authKeysConfig()
 	if err != nil {
-		return fmt.Errorf("student.NewRepository error: %v", err)
+		return err
 	}
 
-	resolver.AuthenticationRepository, err = auth.NewRepository()
+	resolver.AuthenticationRepository, err = auth.NewRepository(authKeys)
 	if err != nil {
 		return fmt.Errorf("auth.NewRepository error: %v", err)
 	}
 
-	srv := handler.NewDefaultServer(graph.NewExecutableSchema(graph.Config{Resolvers: resolver}))
+	workers, err := jobWorkers()
+	if err != nil {
+		return err
+	}
+
+	// Repair reports half-written by older servers before jobs compute new
+	// reports.
+	err = resolver.RecoverReports()
+	if err != nil {
+		return err
+	}
+
+	// Job workers are stopped before waiting for asynchronous tasks on
+	// shutdown, an interrupted job is claimed again after its lease expires.
+	jobCtx, stopJobWorkers := context.WithCancel(ctx)
+	defer stopJobWorkers()
+	resolver.StartJobWorkers(jobCtx, workers)
+
+	srv := handler.New(graph.NewExecutableSchema(graph.Config{
+		Resolvers:  resolver,
+		Directives: graph.DirectiveRoot{HasRole: graph.HasRoleDirective},
+	}))
+	// Same as handler.NewDefaultServer with websockets authenticated like
+	// HTTP requests for subscriptions.
+	srv.AddTransport(transport.Websocket{
+		KeepAlivePingInterval: 10 * time.Second,
+		InitFunc:              graph.WebsocketInitFunc(resolver.AuthenticationRepository, resolver.SessionRepository, resolver.AdminRepository, resolver.APIKeyRepository),
+	})
+	srv.AddTransport(transport.Options{})
+	srv.AddTransport(transport.GET{})
+	srv.AddTransport(transport.POST{})
+	srv.AddTransport(transport.MultipartForm{})
+	srv.SetQueryCache(lru.New(1000))
+	srv.Use(extension.Introspection{})
+	srv.Use(extension.AutomaticPersistedQuery{Cache: lru.New(100)})
 	chiMux := chi.NewMux()
 	chiMux.Use(middleware.Logger)
 	chiMux.Use(middleware.Recoverer)
 	chiMux.Use(httprate.LimitByIP(20, 1*time.Minute))
-	chiMux.Use(graph.AuthMiddleware(resolver.AuthenticationRepository))
+	chiMux.Use(graph.AuthMiddleware(resolver.AuthenticationRepository, resolver.SessionRepository, resolver.AdminRepository, resolver.APIKeyRepository))
 	chiMux.Handle("/", playground.Handler("GraphQL playground", "/scomp"))
 	chiMux.Handle("/scomp", srv)
+	chiMux.Get("/.well-known/jwks.json", jwksHandler(resolver.AuthenticationRepository))
 
 	s := http.Server{
 		Addr:         "0.0.0.0:" + port,
@@ -107,6 +191,7 @@ func runServer(port, dbName, dbURL string) error {
 	signal.Notify(shutdownChan, syscall.SIGINT, syscall.SIGTERM)
 	go func() {
 		<-shutdownChan
+		stopJobWorkers()
 		resolver.Wait() // wait for asynchronous tasks to finish.
 		cancel()
 
@@ -119,9 +204,9 @@ func runServer(port, dbName, dbURL string) error {
 
 		dbShutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
-		err = db.ShutdownMongoDB(dbShutdownCtx, mdb)
+		err = shutdownStorage(dbShutdownCtx)
 		if err != nil {
-			log.Printf("db.ShutdownMongoDB error: %v", err)
+			log.Printf("storage shutdown error: %v", err)
 		}
 	}()
 
@@ -141,3 +226,230 @@ func runServer(port, dbName, dbURL string) error {
 
 	return serverError
 }
+
+// authKeysConfig returns the JWT keys configuration from the JWT_KEYS_FILE or
+// JWT_SECRET environment variables. Returns nil if neither is set.
+func authKeysConfig() (*auth.KeysConfig, error) {
+	if keysFile := os.Getenv("JWT_KEYS_FILE"); keysFile != "" {
+		cfg, err := auth.LoadKeysFile(keysFile)
+		if err != nil {
+			return nil, fmt.Errorf("auth.LoadKeysFile error: %v", err)
+		}
+		return cfg, nil
+	}
+
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		return auth.NewSecretKeysConfig(secret), nil
+	}
+
+	return nil, nil
+}
+
+// jobWorkers returns the number of job workers configured with the
+// JOB_WORKERS environment variable, defaultJobWorkers if it is not set.
+func jobWorkers() (int, error) {
+	workers := os.Getenv("JOB_WORKERS")
+	if workers == "" {
+		return defaultJobWorkers, nil
+	}
+
+	n, err := strconv.Atoi(workers)
+	if err != nil || n < 1 || n > maxJobWorkers {
+		return 0, fmt.Errorf("JOB_WORKERS must be a number between 1 and %d", maxJobWorkers)
+	}
+
+	return n, nil
+}
+
+// passwordPolicy returns the password policy configured with the
+// PASSWORD_MIN_LENGTH and PASSWORD_REQUIRE environment variables.
+// PASSWORD_REQUIRE is a comma separated list of the character classes a
+// password must have: upper, lower, digit and symbol. The default policy is
+// used for variables that are not set.
+func passwordPolicy() (*admin.PasswordPolicy, error) {
+	policy := admin.DefaultPasswordPolicy()
+	if minLength := os.Getenv("PASSWORD_MIN_LENGTH"); minLength != "" {
+		n, err := strconv.Atoi(minLength)
+		if err != nil || n < 1 || n > admin.MaxPasswordLength {
+			return nil, fmt.Errorf("PASSWORD_MIN_LENGTH must be a number between 1 and %d", admin.MaxPasswordLength)
+		}
+		policy.MinLength = n
+	}
+
+	if require, found := os.LookupEnv("PASSWORD_REQUIRE"); found {
+		policy.RequireUppercase, policy.RequireLowercase, policy.RequireDigit, policy.RequireSymbol = false, false, false, false
+		for _, class := range strings.Split(require, ",") {
+			switch strings.TrimSpace(class) {
+			case "upper":
+				policy.RequireUppercase = true
+			case "lower":
+				policy.RequireLowercase = true
+			case "digit":
+				policy.RequireDigit = true
+			case "symbol":
+				policy.RequireSymbol = true
+			case "":
+			default:
+				return nil, fmt.Errorf("unknown PASSWORD_REQUIRE character class %q", class)
+			}
+		}
+	}
+
+	return policy, nil
+}
+
+// bootstrapOwner creates the first school and its owner account from the
+// SCHOOL_NAME, OWNER_USERNAME and OWNER_PASSWORD environment variables if no
+// admin account exists. Other accounts are created with invitations issued by
+// an owner.
+func bootstrapOwner(schoolRepo school.Repository, adminRepo admin.Repository, policy *admin.PasswordPolicy) error {
+	hasAccounts, err := adminRepo.HasAccounts()
+	if err != nil {
+		return fmt.Errorf("adminRepo.HasAccounts error: %v", err)
+	}
+
+	if hasAccounts {
+		return nil
+	}
+
+	username, password := os.Getenv("OWNER_USERNAME"), os.Getenv("OWNER_PASSWORD")
+	if username == "" || password == "" {
+		log.Println("WARNING: no admin account exists, set the OWNER_USERNAME and OWNER_PASSWORD environment variables to create the owner account...")
+		return nil
+	}
+
+	err = policy.Validate(password)
+	if err != nil {
+		return fmt.Errorf("invalid OWNER_PASSWORD: %v", err)
+	}
+
+	schoolName := os.Getenv("SCHOOL_NAME")
+	if schoolName == "" {
+		schoolName = school.DefaultName
+	}
+
+	schoolID, err := schoolRepo.Create(schoolName)
+	if err != nil {
+		return fmt.Errorf("schoolRepo.Create error: %v", err)
+	}
+
+	_, err = adminRepo.CreateAccount(schoolID, username, password, admin.RoleOwner)
+	if err != nil {
+		return fmt.Errorf("adminRepo.CreateAccount error: %v", err)
+	}
+
+	log.Printf("Created school %s and owner account %s...", schoolName, username)
+	return nil
+}
+
+// jwksHandler serves the public keys that can be used to verify auth tokens.
+func jwksHandler(authRepo auth.Repository) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		jwks, err := authRepo.JWKS()
+		if err != nil {
+			log.Printf("SERVER ERROR: authRepo.JWKS %v", err)
+			http.Error(res, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		res.Header().Set("Content-Type", "application/json")
+		res.Write(jwks)
+	}
+}
+
+// setupStorage connects to the storage backend specified and sets the
+// repositories of the resolver. Returns an error if the database schema is not
+// the latest version known to this binary. The returned function should be
+// used to shutdown the storage backend.
+func setupStorage(ctx context.Context, resolver *graph.Resolver, storage, dbName, dbURL string) (func(context.Context) error, error) {
+	switch storage {
+	case storageMemory:
+		store := memory.New()
+		resolver.SchoolRepository = memory.NewSchoolRepository(store)
+		resolver.GradingScaleRepository = memory.NewGradingScaleRepository(store)
+		resolver.AdminRepository = memory.NewAdminRepository(store)
+		resolver.InvitationRepository = memory.NewInvitationRepository(store)
+		resolver.APIKeyRepository = memory.NewAPIKeyRepository(store)
+		resolver.ClassRepository = memory.NewClassRepository(store)
+		resolver.StudentRepository = memory.NewStudentRepository(store)
+		resolver.ReportVersionRepository = memory.NewReportVersionRepository(store)
+		resolver.JobRepository = memory.NewJobRepository(store)
+		resolver.ReportStore = memory.NewReportStore(store)
+		resolver.SessionRepository = memory.NewSessionRepository(store)
+		log.Println("Using in-memory storage, data will be lost on shutdown...")
+		return func(context.Context) error { return nil }, nil
+
+	case storageMongoDB:
+		mdb, err := db.NewMongoDB(ctx, dbName, dbURL)
+		if err != nil {
+			return nil, fmt.Errorf("mongodb.New error: %v", err)
+		}
+
+		current, latest, err := db.MongoDBSchemaVersion(ctx, mdb)
+		if err == nil {
+			err = checkSchemaVersion(storage, current, latest)
+		}
+		if err != nil {
+			db.ShutdownMongoDB(ctx, mdb)
+			return nil, err
+		}
+
+		resolver.SchoolRepository = school.NewRepository(ctx, mdb)
+		resolver.GradingScaleRepository = grading.NewRepository(ctx, mdb)
+		resolver.AdminRepository = admin.NewRepository(ctx, mdb)
+		resolver.InvitationRepository = admin.NewInvitationRepository(ctx, mdb)
+		resolver.APIKeyRepository = admin.NewAPIKeyRepository(ctx, mdb)
+		resolver.ClassRepository = class.NewRepository(ctx, mdb)
+		resolver.StudentRepository = student.NewRepository(ctx, mdb)
+		resolver.ReportVersionRepository = history.NewRepository(ctx, mdb)
+		resolver.JobRepository = job.NewRepository(ctx, mdb)
+		resolver.ReportStore = report.NewStore(ctx, mdb)
+		resolver.SessionRepository = session.NewRepository(ctx, mdb)
+		return func(ctx context.Context) error { return db.ShutdownMongoDB(ctx, mdb) }, nil
+
+	case storageSQLite, storagePostgres:
+		sqlDB, err := sqldb.Open(ctx, storage, dbURL)
+		if err != nil {
+			return nil, fmt.Errorf("sqldb.Open error: %v", err)
+		}
+
+		current, latest, err := sqldb.SchemaVersion(ctx, sqlDB)
+		if err == nil {
+			err = checkSchemaVersion(storage, current, latest)
+		}
+		if err != nil {
+			sqldb.Shutdown(sqlDB)
+			return nil, err
+		}
+
+		resolver.SchoolRepository = sqldb.NewSchoolRepository(ctx, sqlDB)
+		resolver.GradingScaleRepository = sqldb.NewGradingScaleRepository(ctx, sqlDB)
+		resolver.AdminRepository = sqldb.NewAdminRepository(ctx, sqlDB)
+		resolver.InvitationRepository = sqldb.NewInvitationRepository(ctx, sqlDB)
+		resolver.APIKeyRepository = sqldb.NewAPIKeyRepository(ctx, sqlDB)
+		resolver.ClassRepository = sqldb.NewClassRepository(ctx, sqlDB)
+		resolver.StudentRepository = sqldb.NewStudentRepository(ctx, sqlDB)
+		resolver.ReportVersionRepository = sqldb.NewReportVersionRepository(ctx, sqlDB)
+		resolver.JobRepository = sqldb.NewJobRepository(ctx, sqlDB)
+		resolver.ReportStore = sqldb.NewReportStore(ctx, sqlDB)
+		resolver.SessionRepository = sqldb.NewSessionRepository(ctx, sqlDB)
+		return func(context.Context) error { return sqldb.Shutdown(sqlDB) }, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported storage backend %q", storage)
+	}
+}
+
+// checkSchemaVersion returns an error if the current schema version of the
+// database of storage is not the latest version known to this binary.
+// Migrations are only applied by the migrate subcommand, never on startup.
+func checkSchemaVersion(storage string, current, latest int) error {
+	switch {
+	case current < latest:
+		return fmt.Errorf("database schema version %d is behind the latest version %d, run `scomp --storage %s migrate` before starting the server", current, latest, storage)
+	case current > latest:
+		return fmt.Errorf("database schema version %d is newer than the latest known version %d, please upgrade SCOMP", current, latest)
+	default:
+		return nil
+	}
+}
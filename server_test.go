@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ukane-philemon/scomp/graph"
+	"github.com/ukane-philemon/scomp/internal/admin"
+	"github.com/ukane-philemon/scomp/internal/auth"
+	"github.com/ukane-philemon/scomp/internal/memory"
+	"github.com/ukane-philemon/scomp/internal/school"
+)
+
+func TestJWKSHandler(t *testing.T) {
+	authRepo, err := auth.NewRepository(auth.NewSecretKeysConfig(base64.StdEncoding.EncodeToString(make([]byte, 32))))
+	if err != nil {
+		t.Fatalf("auth.NewRepository error: %v", err)
+	}
+
+	res := httptest.NewRecorder()
+	jwksHandler(authRepo)(res, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+
+	if res.Code != http.StatusOK || res.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("expected a JSON response, got %d %s", res.Code, res.Header().Get("Content-Type"))
+	}
+
+	// HMAC keys are never published.
+	if body := res.Body.String(); body != `{"keys":[]}` {
+		t.Fatalf("expected no keys, got %s", body)
+	}
+}
+
+func TestBootstrapOwner(t *testing.T) {
+	store := memory.New()
+	schoolRepo, adminRepo := memory.NewSchoolRepository(store), memory.NewAdminRepository(store)
+
+	// Nothing is created without credentials.
+	t.Setenv("OWNER_USERNAME", "")
+	t.Setenv("OWNER_PASSWORD", "")
+	t.Setenv("SCHOOL_NAME", "")
+	err := bootstrapOwner(schoolRepo, adminRepo, admin.DefaultPasswordPolicy())
+	if err != nil {
+		t.Fatalf("bootstrapOwner error: %v", err)
+	}
+
+	if hasAccounts, _ := adminRepo.HasAccounts(); hasAccounts {
+		t.Fatal("expected no account without credentials")
+	}
+
+	// The owner password must satisfy the password policy.
+	t.Setenv("OWNER_USERNAME", "owner")
+	t.Setenv("OWNER_PASSWORD", "password")
+	err = bootstrapOwner(schoolRepo, adminRepo, admin.DefaultPasswordPolicy())
+	if err == nil {
+		t.Fatal("bootstrapOwner accepted a weak owner password")
+	}
+
+	t.Setenv("OWNER_PASSWORD", "Passw0rd")
+	err = bootstrapOwner(schoolRepo, adminRepo, admin.DefaultPasswordPolicy())
+	if err != nil {
+		t.Fatalf("bootstrapOwner error: %v", err)
+	}
+
+	owner, err := adminRepo.LoginAccount("owner", "Passw0rd")
+	if err != nil {
+		t.Fatalf("LoginAccount error: %v", err)
+	}
+
+	if owner.Role != admin.RoleOwner {
+		t.Fatalf("expected role %s, got %s", admin.RoleOwner, owner.Role)
+	}
+
+	// The owner belongs to the school named by SCHOOL_NAME, or the default
+	// school.
+	schoolInfo, err := schoolRepo.School(owner.SchoolID)
+	if err != nil {
+		t.Fatalf("School error: %v", err)
+	}
+
+	if schoolInfo.Name != school.DefaultName {
+		t.Fatalf("expected school %s, got %s", school.DefaultName, schoolInfo.Name)
+	}
+
+	// The owner is only bootstrapped while no account exists.
+	t.Setenv("OWNER_USERNAME", "another")
+	err = bootstrapOwner(schoolRepo, adminRepo, admin.DefaultPasswordPolicy())
+	if err != nil {
+		t.Fatalf("bootstrapOwner error: %v", err)
+	}
+
+	_, err = adminRepo.LoginAccount("another", "Passw0rd")
+	if err == nil {
+		t.Fatal("expected no second owner account")
+	}
+}
+
+func TestSetupStorageRequiresMigrations(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "scomp.db")
+
+	// A new database is not migrated on startup.
+	_, err := setupStorage(ctx, new(graph.Resolver), storageSQLite, "", dbPath)
+	if err == nil || !strings.Contains(err.Error(), "migrate") {
+		t.Fatalf("expected an error asking to run migrate, got %v", err)
+	}
+
+	err = runMigrate(storageSQLite, "", dbPath, nil)
+	if err != nil {
+		t.Fatalf("runMigrate error: %v", err)
+	}
+
+	shutdown, err := setupStorage(ctx, new(graph.Resolver), storageSQLite, "", dbPath)
+	if err != nil {
+		t.Fatalf("setupStorage error after migrate: %v", err)
+	}
+
+	if err = shutdown(ctx); err != nil {
+		t.Fatalf("shutdown error: %v", err)
+	}
+}
+
+func TestCheckSchemaVersion(t *testing.T) {
+	tests := []struct {
+		current, latest int
+		wantErr         bool
+	}{
+		{current: 0, latest: 3, wantErr: true},
+		{current: 2, latest: 3, wantErr: true},
+		{current: 3, latest: 3},
+		{current: 4, latest: 3, wantErr: true},
+	}
+
+	for _, test := range tests {
+		err := checkSchemaVersion(storageSQLite, test.current, test.latest)
+		if (err != nil) != test.wantErr {
+			t.Errorf("version %d of %d: expected error %v, got %v", test.current, test.latest, test.wantErr, err)
+		}
+	}
+}